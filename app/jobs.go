@@ -0,0 +1,87 @@
+package app
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/domain"
+	"log"
+	"time"
+)
+
+// reservationReaperInterval is how often expired reservations are released. Until then they already stop counting as
+// reserved, the reaper only records that they expired
+const reservationReaperInterval = 30 * time.Second
+
+// reapReservations releases expired reservations every interval until ctx is done
+func reapReservations(ctx context.Context, useCase domain.ReservationUseCase, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := useCase.ExpireReservations(ctx)
+			if err != nil {
+				log.Println("failed to expire reservations", err)
+				continue
+			}
+			if expired > 0 {
+				log.Println("expired", expired, "reservations")
+			}
+		}
+	}
+}
+
+const (
+	// reorderSettleInterval is how often the items whose stock changed are evaluated against their reorder policies.
+	// Waiting for the tick gives the changes time to commit
+	reorderSettleInterval = time.Second
+	// reorderSweepInterval is how often every item with reorder policies is evaluated, catching whatever the queue
+	// missed
+	reorderSweepInterval = 5 * time.Minute
+	// stockChangesQueue is how many changed items can wait for evaluation
+	stockChangesQueue = 1024
+)
+
+// stockChanges queues the items whose stock changed for evaluateReorderPoints
+type stockChanges chan string
+
+func (s stockChanges) StockChanged(itemID string) {
+	select {
+	case s <- itemID:
+	default:
+		// the queue is full, the next sweep evaluates the item
+	}
+}
+
+// evaluateReorderPoints evaluates the items queued in changes every settle interval, and every item with reorder
+// policies every sweep interval, until ctx is done
+func evaluateReorderPoints(ctx context.Context, useCase domain.StockAlertUseCase, changes stockChanges,
+	settle time.Duration, sweep time.Duration) {
+	settleTicker := time.NewTicker(settle)
+	defer settleTicker.Stop()
+	sweepTicker := time.NewTicker(sweep)
+	defer sweepTicker.Stop()
+
+	pending := map[string]bool{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case itemID := <-changes:
+			pending[itemID] = true
+		case <-settleTicker.C:
+			for itemID := range pending {
+				if err := useCase.Evaluate(ctx, itemID); err != nil {
+					log.Println("failed to evaluate the reorder policies of item", itemID, err)
+				}
+			}
+			pending = map[string]bool{}
+		case <-sweepTicker.C:
+			if err := useCase.EvaluateAll(ctx); err != nil {
+				log.Println("failed to evaluate reorder policies", err)
+			}
+		}
+	}
+}
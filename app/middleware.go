@@ -0,0 +1,29 @@
+package app
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nuzurie/shopify/utils/audit"
+)
+
+const (
+	actorHeader         = "X-Actor"
+	correlationIDHeader = "X-Correlation-ID"
+)
+
+// auditContext records who made the request and under which correlation id, so changes can be traced back to it.
+// A correlation id is generated when the caller didn't send one, and echoed back either way
+func auditContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		correlationID := c.GetHeader(correlationIDHeader)
+		if correlationID == "" {
+			correlationID = uuid.NewString()
+		}
+		c.Header(correlationIDHeader, correlationID)
+
+		ctx := audit.WithActor(c.Request.Context(), c.GetHeader(actorHeader))
+		ctx = audit.WithCorrelationID(ctx, correlationID)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"github.com/nuzurie/shopify/db"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+)
+
+const migrateUsage = `usage: shopify migrate <command>
+
+commands:
+  up [version]   apply pending migrations, up to version if given
+  down [steps]   revert the last steps migrations, 1 by default
+  status         list migrations and whether they are applied
+  seed <file>    run a SQL file, e.g. db/seed.sql, against an up to date schema`
+
+// Migrate runs the migrate subcommand against the database in DATABASE_URL
+func Migrate(args []string) {
+	if len(args) == 0 {
+		log.Fatalln(migrateUsage)
+	}
+
+	ctx := context.Background()
+	database, err := db.Open(ctx, os.Getenv("DATABASE_URL"))
+	if err != nil {
+		log.Fatalln("db failed", err)
+	}
+	defer database.Close()
+
+	switch args[0] {
+	case "up":
+		err = db.MigrateUp(ctx, database, intArg(args, 0))
+	case "down":
+		err = db.MigrateDown(ctx, database, intArg(args, 1))
+	case "status":
+		err = migrationStatus(ctx, database)
+	case "seed":
+		if len(args) < 2 {
+			log.Fatalln(migrateUsage)
+		}
+		err = seed(ctx, database, args[1])
+	default:
+		log.Fatalln(migrateUsage)
+	}
+	if err != nil {
+		log.Fatalln("migrate failed: ", err)
+	}
+
+	version, err := db.SchemaVersion(ctx, database, database.Dialect())
+	if err != nil {
+		log.Fatalln(err)
+	}
+	log.Println("Database schema is at version", version)
+}
+
+func intArg(args []string, fallback int) int {
+	if len(args) < 2 {
+		return fallback
+	}
+	value, err := strconv.Atoi(args[1])
+	if err != nil || value < 0 {
+		log.Fatalln(migrateUsage)
+	}
+	return value
+}
+
+func migrationStatus(ctx context.Context, database db.DB) error {
+	migrations, err := db.Migrations(database.Dialect())
+	if err != nil {
+		return err
+	}
+	version, err := db.SchemaVersion(ctx, database, database.Dialect())
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		state := "pending"
+		if migration.Version <= version {
+			state = "applied"
+		}
+		fmt.Printf("%04d %-40s %s\n", migration.Version, migration.Name, state)
+	}
+	return nil
+}
+
+func seed(ctx context.Context, database db.DB, file string) error {
+	if err := db.CheckSchema(ctx, database); err != nil {
+		return err
+	}
+	script, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	_, err = database.Exec(ctx, string(script))
+	return err
+}
@@ -4,47 +4,265 @@ import (
 	"context"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v4/pgxpool"
+	http16 "github.com/nuzurie/shopify/currency/delivery/http"
+	repository16 "github.com/nuzurie/shopify/currency/repository"
+	usecase16 "github.com/nuzurie/shopify/currency/usecase"
+	http14 "github.com/nuzurie/shopify/cyclecount/delivery/http"
+	repository14 "github.com/nuzurie/shopify/cyclecount/repository"
+	usecase14 "github.com/nuzurie/shopify/cyclecount/usecase"
+	"github.com/nuzurie/shopify/db"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	http11 "github.com/nuzurie/shopify/forecast/delivery/http"
+	usecase11 "github.com/nuzurie/shopify/forecast/usecase"
 	http2 "github.com/nuzurie/shopify/inventory/delivery/http"
 	repository2 "github.com/nuzurie/shopify/inventory/repository"
 	usecase2 "github.com/nuzurie/shopify/inventory/usecase"
 	"github.com/nuzurie/shopify/item/delivery/http"
 	"github.com/nuzurie/shopify/item/repository"
 	"github.com/nuzurie/shopify/item/usecase"
+	http3 "github.com/nuzurie/shopify/location/delivery/http"
+	repository3 "github.com/nuzurie/shopify/location/repository"
+	usecase3 "github.com/nuzurie/shopify/location/usecase"
+	http12 "github.com/nuzurie/shopify/lot/delivery/http"
+	repository12 "github.com/nuzurie/shopify/lot/repository"
+	usecase12 "github.com/nuzurie/shopify/lot/usecase"
+	http7 "github.com/nuzurie/shopify/purchaseorder/delivery/http"
+	repository7 "github.com/nuzurie/shopify/purchaseorder/repository"
+	usecase7 "github.com/nuzurie/shopify/purchaseorder/usecase"
+	http10 "github.com/nuzurie/shopify/reorder/delivery/http"
+	repository10 "github.com/nuzurie/shopify/reorder/repository"
+	usecase10 "github.com/nuzurie/shopify/reorder/usecase"
+	http5 "github.com/nuzurie/shopify/reservation/delivery/http"
+	repository5 "github.com/nuzurie/shopify/reservation/repository"
+	usecase5 "github.com/nuzurie/shopify/reservation/usecase"
+	http9 "github.com/nuzurie/shopify/returns/delivery/http"
+	repository9 "github.com/nuzurie/shopify/returns/repository"
+	usecase9 "github.com/nuzurie/shopify/returns/usecase"
+	http8 "github.com/nuzurie/shopify/salesorder/delivery/http"
+	repository8 "github.com/nuzurie/shopify/salesorder/repository"
+	usecase8 "github.com/nuzurie/shopify/salesorder/usecase"
+	http13 "github.com/nuzurie/shopify/serial/delivery/http"
+	repository13 "github.com/nuzurie/shopify/serial/repository"
+	usecase13 "github.com/nuzurie/shopify/serial/usecase"
+	http6 "github.com/nuzurie/shopify/supplier/delivery/http"
+	repository6 "github.com/nuzurie/shopify/supplier/repository"
+	usecase6 "github.com/nuzurie/shopify/supplier/usecase"
+	http4 "github.com/nuzurie/shopify/transfer/delivery/http"
+	repository4 "github.com/nuzurie/shopify/transfer/repository"
+	usecase4 "github.com/nuzurie/shopify/transfer/usecase"
+	http15 "github.com/nuzurie/shopify/valuation/delivery/http"
+	repository15 "github.com/nuzurie/shopify/valuation/repository"
+	usecase15 "github.com/nuzurie/shopify/valuation/usecase"
+	"github.com/shopspring/decimal"
 	"log"
 	"os"
+	"strings"
 	"time"
 )
 
-func Server(itemHandler *http.ItemHandler, inventoryHandler *http2.InventoryHandler) *gin.Engine {
+// Handlers serve the endpoints of every feature
+type Handlers struct {
+	Item          *http.ItemHandler
+	Inventory     *http2.InventoryHandler
+	Location      *http3.LocationHandler
+	Transfer      *http4.TransferHandler
+	Reservation   *http5.ReservationHandler
+	Supplier      *http6.SupplierHandler
+	SupplierItem  *http6.SupplierItemHandler
+	PurchaseOrder *http7.PurchaseOrderHandler
+	SalesOrder    *http8.SalesOrderHandler
+	Return        *http9.ReturnHandler
+	ReorderPolicy *http10.ReorderPolicyHandler
+	StockAlert    *http10.StockAlertHandler
+	Forecast      *http11.ForecastHandler
+	Lot           *http12.LotHandler
+	Serial        *http13.SerialHandler
+	CycleCount    *http14.CycleCountHandler
+	Valuation     *http15.ValuationHandler
+	CurrencyRate  *http16.CurrencyRateHandler
+}
+
+func Server(handlers Handlers) *gin.Engine {
 	router := gin.Default()
-	router.Use(cors.Default())
-	mapItemUrls(itemHandler, router)
-	mapInventoryUrls(inventoryHandler, router)
+	router.Use(cors.New(corsConfig()), auditContext())
+	mapItemUrls(handlers.Item, router)
+	mapInventoryUrls(handlers.Inventory, router)
+	mapLocationUrls(handlers.Location, router)
+	mapTransferUrls(handlers.Transfer, router)
+	mapReservationUrls(handlers.Reservation, router)
+	mapSupplierUrls(handlers.Supplier, router)
+	mapSupplierItemUrls(handlers.SupplierItem, router)
+	mapPurchaseOrderUrls(handlers.PurchaseOrder, router)
+	mapSalesOrderUrls(handlers.SalesOrder, router)
+	mapReturnUrls(handlers.Return, router)
+	mapReorderPolicyUrls(handlers.ReorderPolicy, router)
+	mapStockAlertUrls(handlers.StockAlert, router)
+	mapForecastUrls(handlers.Forecast, router)
+	mapLotUrls(handlers.Lot, router)
+	mapSerialUrls(handlers.Serial, router)
+	mapCycleCountUrls(handlers.CycleCount, router)
+	mapValuationUrls(handlers.Valuation, router)
+	mapCurrencyRateUrls(handlers.CurrencyRate, router)
 	return router
 }
 
+// corsConfig lets browsers send the audit and precondition headers along with the defaults, and read the ETag
+func corsConfig() cors.Config {
+	config := cors.DefaultConfig()
+	config.AllowAllOrigins = true
+	config.AddAllowHeaders(actorHeader, correlationIDHeader, "If-Match")
+	config.AddExposeHeaders(correlationIDHeader, "ETag")
+	return config
+}
+
 func Start() {
-	pool, err := pgxpool.Connect(context.Background(), os.Getenv("DATABASE_URL"))
-	if err != nil {
-		log.Println(os.Getenv("DATABASE_URL"))
-		log.Fatalln("db failed", err)
-	}
+	// prices were JSON numbers before they were exact, and stay numbers so existing clients keep reading them. Decimals
+	// are still accepted as numbers or strings
+	decimal.MarshalJSONWithoutQuotes = true
+	storage := repositories(os.Getenv("DATABASE_URL"))
 
-	itemRepository, err := repository.NewItemRepository(pool)
-	if err != nil {
-		log.Fatalln("Failed to initialize item table ", err)
+	changes := make(stockChanges, stockChangesQueue)
+	itemUseCase := usecase.NewItemUseCase(storage.items, storage.inventory, time.Second)
+	inventoryUseCase := usecase2.NewInventoryUseCase(storage.items, storage.inventory, storage.movements,
+		storage.reservations, storage.locations, storage.lots, storage.serials, storage.costs, changes,
+		storage.transactor, time.Second*300)
+	locationUseCase := usecase3.NewLocationUseCase(storage.locations, time.Second)
+	transferUseCase := usecase4.NewTransferUseCase(storage.transfers, storage.items, storage.inventory,
+		storage.lots, inventoryUseCase, storage.transactor, time.Second*300)
+	reservationUseCase := usecase5.NewReservationUseCase(storage.reservations, storage.items, storage.inventory,
+		storage.locations, storage.lots, inventoryUseCase, changes, storage.transactor, time.Second*30)
+	supplierUseCase := usecase6.NewSupplierUseCase(storage.suppliers, time.Second)
+	supplierItemUseCase := usecase6.NewSupplierItemUseCase(storage.supplierItems, storage.transactor, time.Second)
+	purchaseOrderUseCase := usecase7.NewPurchaseOrderUseCase(storage.purchaseOrders, storage.items,
+		storage.suppliers, storage.supplierItems, storage.currencyRates, inventoryUseCase, storage.transactor,
+		time.Second*300)
+	salesOrderUseCase := usecase8.NewSalesOrderUseCase(storage.salesOrders, storage.items, storage.locations,
+		reservationUseCase, storage.transactor, time.Second*300)
+	returnUseCase := usecase9.NewReturnUseCase(storage.returns, storage.items, storage.salesOrders, storage.locations,
+		inventoryUseCase, storage.transactor, time.Second*300)
+	reorderPolicyUseCase := usecase10.NewReorderPolicyUseCase(storage.reorderPolicies, storage.items,
+		storage.locations, changes, time.Second)
+	stockAlertUseCase := usecase10.NewStockAlertUseCase(storage.stockAlerts, storage.reorderPolicies,
+		storage.suppliers, storage.supplierItems, inventoryUseCase, purchaseOrderUseCase, storage.transactor,
+		time.Second*30)
+	forecastUseCase := usecase11.NewForecastUseCase(inventoryUseCase, storage.movements, storage.suppliers,
+		storage.supplierItems, time.Second*30)
+	lotUseCase := usecase12.NewLotUseCase(storage.lots, storage.items, storage.locations, storage.inventory,
+		inventoryUseCase, changes, storage.transactor, time.Second*300)
+	serialUseCase := usecase13.NewSerialUseCase(storage.serials, time.Second)
+	cycleCountUseCase := usecase14.NewCycleCountUseCase(storage.cycleCounts, storage.items, storage.inventory,
+		storage.locations, storage.movements, storage.currencyRates, inventoryUseCase, storage.transactor,
+		time.Second*300)
+	valuationUseCase := usecase15.NewValuationUseCase(storage.costs, time.Second*30)
+	currencyRateUseCase := usecase16.NewCurrencyRateUseCase(storage.currencyRates, time.Second)
+	go reapReservations(context.Background(), reservationUseCase, reservationReaperInterval)
+	go evaluateReorderPoints(context.Background(), stockAlertUseCase, changes, reorderSettleInterval,
+		reorderSweepInterval)
+
+	router := Server(Handlers{
+		Item:          http.NewItemHandler(itemUseCase),
+		Inventory:     http2.NewInventoryHandler(inventoryUseCase),
+		Location:      http3.NewLocationHandler(locationUseCase),
+		Transfer:      http4.NewTransferHandler(transferUseCase),
+		Reservation:   http5.NewReservationHandler(reservationUseCase),
+		Supplier:      http6.NewSupplierHandler(supplierUseCase),
+		SupplierItem:  http6.NewSupplierItemHandler(supplierItemUseCase),
+		PurchaseOrder: http7.NewPurchaseOrderHandler(purchaseOrderUseCase),
+		SalesOrder:    http8.NewSalesOrderHandler(salesOrderUseCase),
+		Return:        http9.NewReturnHandler(returnUseCase),
+		ReorderPolicy: http10.NewReorderPolicyHandler(reorderPolicyUseCase),
+		StockAlert:    http10.NewStockAlertHandler(stockAlertUseCase),
+		Forecast:      http11.NewForecastHandler(forecastUseCase),
+		Lot:           http12.NewLotHandler(lotUseCase),
+		Serial:        http13.NewSerialHandler(serialUseCase),
+		CycleCount:    http14.NewCycleCountHandler(cycleCountUseCase),
+		Valuation:     http15.NewValuationHandler(valuationUseCase),
+		CurrencyRate:  http16.NewCurrencyRateHandler(currencyRateUseCase),
+	})
+	router.Run()
+}
+
+// storage holds the repositories of the selected backend, and the transactor spanning them
+type storage struct {
+	items           domain.ItemRepository
+	inventory       domain.InventoryRepository
+	movements       domain.MovementRepository
+	locations       domain.LocationRepository
+	transfers       domain.TransferRepository
+	reservations    domain.ReservationRepository
+	suppliers       domain.SupplierRepository
+	supplierItems   domain.SupplierItemRepository
+	purchaseOrders  domain.PurchaseOrderRepository
+	salesOrders     domain.SalesOrderRepository
+	returns         domain.ReturnRepository
+	reorderPolicies domain.ReorderPolicyRepository
+	stockAlerts     domain.StockAlertRepository
+	lots            domain.LotRepository
+	serials         domain.SerialRepository
+	cycleCounts     domain.CycleCountRepository
+	costs           domain.CostRepository
+	currencyRates   domain.CurrencyRateRepository
+	transactor      domain.Transactor
+}
+
+// repositories picks the storage backend from the scheme of the database url. memory:// keeps everything in process,
+// which is handy for tests and demos, sqlite:// and postgres:// are described in db.Open
+func repositories(databaseURL string) storage {
+	if strings.HasPrefix(databaseURL, "memory:") {
+		log.Println("Using in-memory storage")
+		store := memory.NewStore()
+		return storage{
+			items:           repository.NewMemoryItemRepository(store),
+			inventory:       repository2.NewMemoryInventoryRepository(store),
+			movements:       repository2.NewMemoryMovementRepository(store),
+			locations:       repository3.NewMemoryLocationRepository(store),
+			transfers:       repository4.NewMemoryTransferRepository(store),
+			reservations:    repository5.NewMemoryReservationRepository(store),
+			suppliers:       repository6.NewMemorySupplierRepository(store),
+			supplierItems:   repository6.NewMemorySupplierItemRepository(store),
+			purchaseOrders:  repository7.NewMemoryPurchaseOrderRepository(store),
+			salesOrders:     repository8.NewMemorySalesOrderRepository(store),
+			returns:         repository9.NewMemoryReturnRepository(store),
+			reorderPolicies: repository10.NewMemoryReorderPolicyRepository(store),
+			stockAlerts:     repository10.NewMemoryStockAlertRepository(store),
+			lots:            repository12.NewMemoryLotRepository(store),
+			serials:         repository13.NewMemorySerialRepository(store),
+			cycleCounts:     repository14.NewMemoryCycleCountRepository(store),
+			costs:           repository15.NewMemoryCostRepository(store),
+			currencyRates:   repository16.NewMemoryCurrencyRateRepository(store),
+			transactor:      store,
+		}
 	}
-	itemUseCase := usecase.NewItemUseCase(itemRepository, time.Second)
-	itemHandler := http.NewItemHandler(itemUseCase)
 
-	inventoryRepository, err := repository2.NewInventoryRepository(pool)
+	database, err := db.Open(context.Background(), databaseURL)
 	if err != nil {
-		log.Fatalln("Failed to initialize item table ", err)
+		log.Println(databaseURL)
+		log.Fatalln("db failed", err)
+	}
+	if err = db.CheckSchema(context.Background(), database); err != nil {
+		log.Fatalln("Refusing to start: ", err)
 	}
-	inventoryUseCase := usecase2.NewInventoryUseCase(itemRepository, inventoryRepository, time.Second*300)
-	inventoryHandler := http2.NewInventoryHandler(inventoryUseCase)
 
-	router := Server(itemHandler, inventoryHandler)
-	router.Run()
+	return storage{
+		items:           repository.NewItemRepository(database),
+		inventory:       repository2.NewInventoryRepository(database),
+		movements:       repository2.NewMovementRepository(database),
+		locations:       repository3.NewLocationRepository(database),
+		transfers:       repository4.NewTransferRepository(database),
+		reservations:    repository5.NewReservationRepository(database),
+		suppliers:       repository6.NewSupplierRepository(database),
+		supplierItems:   repository6.NewSupplierItemRepository(database),
+		purchaseOrders:  repository7.NewPurchaseOrderRepository(database),
+		salesOrders:     repository8.NewSalesOrderRepository(database),
+		returns:         repository9.NewReturnRepository(database),
+		reorderPolicies: repository10.NewReorderPolicyRepository(database),
+		stockAlerts:     repository10.NewStockAlertRepository(database),
+		lots:            repository12.NewLotRepository(database),
+		serials:         repository13.NewSerialRepository(database),
+		cycleCounts:     repository14.NewCycleCountRepository(database),
+		costs:           repository15.NewCostRepository(database),
+		currencyRates:   repository16.NewCurrencyRateRepository(database),
+		transactor:      db.NewTransactor(database),
+	}
 }
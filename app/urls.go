@@ -2,12 +2,29 @@ package app
 
 import (
 	"github.com/gin-gonic/gin"
+	http16 "github.com/nuzurie/shopify/currency/delivery/http"
+	http14 "github.com/nuzurie/shopify/cyclecount/delivery/http"
+	http11 "github.com/nuzurie/shopify/forecast/delivery/http"
 	http2 "github.com/nuzurie/shopify/inventory/delivery/http"
 	"github.com/nuzurie/shopify/item/delivery/http"
+	http3 "github.com/nuzurie/shopify/location/delivery/http"
+	http12 "github.com/nuzurie/shopify/lot/delivery/http"
+	http7 "github.com/nuzurie/shopify/purchaseorder/delivery/http"
+	http10 "github.com/nuzurie/shopify/reorder/delivery/http"
+	http5 "github.com/nuzurie/shopify/reservation/delivery/http"
+	http9 "github.com/nuzurie/shopify/returns/delivery/http"
+	http8 "github.com/nuzurie/shopify/salesorder/delivery/http"
+	http13 "github.com/nuzurie/shopify/serial/delivery/http"
+	http6 "github.com/nuzurie/shopify/supplier/delivery/http"
+	http4 "github.com/nuzurie/shopify/transfer/delivery/http"
+	http15 "github.com/nuzurie/shopify/valuation/delivery/http"
 )
 
 func mapItemUrls(handler *http.ItemHandler, r *gin.Engine) {
 	r.GET("/items", handler.GetAll)
+	r.GET("/items/:id", handler.GetOne)
+	r.GET("/items/by-sku/:sku", handler.GetBySKU)
+	r.GET("/items/by-barcode/:code", handler.GetByBarcode)
 	r.POST("/items", handler.Create)
 	r.PUT("/items/:id", handler.Update)
 	r.DELETE("/items/:id", handler.Delete)
@@ -17,5 +34,135 @@ func mapInventoryUrls(handler *http2.InventoryHandler, r *gin.Engine) {
 	r.GET("/inventory", handler.GetAll)
 	r.GET("/inventory/:id", handler.GetInventoryForItem)
 	r.POST("/inventory", handler.CreateOrUpdate)
+	r.POST("/inventory/:id/adjustments", handler.Adjust)
+	r.GET("/inventory/:id/movements", handler.GetMovements)
 	r.DELETE("/inventory/:id", handler.Delete)
 }
+
+func mapLocationUrls(handler *http3.LocationHandler, r *gin.Engine) {
+	r.GET("/locations", handler.GetAll)
+	r.GET("/locations/:id", handler.GetOne)
+	r.POST("/locations", handler.Create)
+	r.PUT("/locations/:id", handler.Update)
+	r.DELETE("/locations/:id", handler.Delete)
+}
+
+func mapTransferUrls(handler *http4.TransferHandler, r *gin.Engine) {
+	r.GET("/transfers", handler.GetAll)
+	r.GET("/transfers/:id", handler.GetOne)
+	r.POST("/transfers", handler.Create)
+	r.POST("/transfers/:id/ship", handler.Ship)
+	r.POST("/transfers/:id/receive", handler.Receive)
+	r.POST("/transfers/:id/cancel", handler.Cancel)
+}
+
+func mapReservationUrls(handler *http5.ReservationHandler, r *gin.Engine) {
+	r.GET("/reservations", handler.GetAll)
+	r.GET("/reservations/:id", handler.GetOne)
+	r.POST("/reservations", handler.Reserve)
+	r.POST("/reservations/:id/confirm", handler.Confirm)
+	r.POST("/reservations/:id/release", handler.Release)
+}
+
+func mapSupplierUrls(handler *http6.SupplierHandler, r *gin.Engine) {
+	r.GET("/suppliers", handler.GetAll)
+	r.GET("/suppliers/:id", handler.GetOne)
+	r.POST("/suppliers", handler.Create)
+	r.PUT("/suppliers/:id", handler.Update)
+	r.DELETE("/suppliers/:id", handler.Delete)
+}
+
+func mapSupplierItemUrls(handler *http6.SupplierItemHandler, r *gin.Engine) {
+	r.GET("/suppliers/:id/items", handler.GetForSupplier)
+	r.GET("/suppliers/:id/items/:itemId", handler.GetOne)
+	r.POST("/suppliers/:id/items", handler.Create)
+	r.PUT("/suppliers/:id/items/:itemId", handler.Update)
+	r.DELETE("/suppliers/:id/items/:itemId", handler.Delete)
+	r.GET("/items/:id/suppliers", handler.GetForItem)
+}
+
+func mapPurchaseOrderUrls(handler *http7.PurchaseOrderHandler, r *gin.Engine) {
+	r.GET("/purchase-orders", handler.GetAll)
+	r.GET("/purchase-orders/:id", handler.GetOne)
+	r.POST("/purchase-orders", handler.Create)
+	r.PUT("/purchase-orders/:id", handler.Update)
+	r.POST("/purchase-orders/:id/send", handler.Send)
+	r.POST("/purchase-orders/:id/receive", handler.Receive)
+	r.POST("/purchase-orders/:id/close", handler.Close)
+}
+
+func mapSalesOrderUrls(handler *http8.SalesOrderHandler, r *gin.Engine) {
+	r.GET("/sales-orders", handler.GetAll)
+	r.GET("/sales-orders/:id", handler.GetOne)
+	r.POST("/sales-orders", handler.Create)
+	r.POST("/sales-orders/:id/pick", handler.Pick)
+	r.POST("/sales-orders/:id/pack", handler.Pack)
+	r.POST("/sales-orders/:id/ship", handler.Ship)
+	r.POST("/sales-orders/:id/cancel", handler.Cancel)
+}
+
+func mapReturnUrls(handler *http9.ReturnHandler, r *gin.Engine) {
+	r.GET("/returns", handler.GetAll)
+	r.GET("/returns/:id", handler.GetOne)
+	r.POST("/returns", handler.Create)
+	r.POST("/returns/:id/inspect", handler.Inspect)
+	r.GET("/reports/write-offs", handler.WriteOffs)
+}
+
+func mapReorderPolicyUrls(handler *http10.ReorderPolicyHandler, r *gin.Engine) {
+	r.GET("/reorder-policies", handler.GetAll)
+	r.GET("/reorder-policies/:id", handler.GetOne)
+	r.POST("/reorder-policies", handler.Create)
+	r.PUT("/reorder-policies/:id", handler.Update)
+	r.DELETE("/reorder-policies/:id", handler.Delete)
+}
+
+func mapStockAlertUrls(handler *http10.StockAlertHandler, r *gin.Engine) {
+	r.GET("/alerts", handler.GetAll)
+	r.GET("/alerts/:id", handler.GetOne)
+	r.POST("/alerts/:id/acknowledge", handler.Acknowledge)
+	r.POST("/alerts/:id/resolve", handler.Resolve)
+}
+
+func mapForecastUrls(handler *http11.ForecastHandler, r *gin.Engine) {
+	r.GET("/inventory/:id/forecast", handler.GetForItem)
+	r.GET("/reports/forecast", handler.GetAll)
+}
+
+func mapLotUrls(handler *http12.LotHandler, r *gin.Engine) {
+	r.GET("/lots", handler.GetAll)
+	r.GET("/lots/:id", handler.GetOne)
+	r.POST("/lots", handler.Receive)
+	r.POST("/lots/:id/adjustments", handler.Adjust)
+	r.POST("/lots/:id/block", handler.Block)
+	r.POST("/lots/:id/unblock", handler.Unblock)
+	r.GET("/reports/expiring-lots", handler.Expiring)
+}
+
+func mapSerialUrls(handler *http13.SerialHandler, r *gin.Engine) {
+	r.GET("/serials", handler.GetAll)
+	r.GET("/serials/:id", handler.GetOne)
+}
+
+func mapCycleCountUrls(handler *http14.CycleCountHandler, r *gin.Engine) {
+	r.GET("/cycle-counts", handler.GetAll)
+	r.GET("/cycle-counts/:id", handler.GetOne)
+	r.POST("/cycle-counts", handler.Create)
+	r.POST("/cycle-counts/:id/counts", handler.Count)
+	r.POST("/cycle-counts/:id/approve", handler.Approve)
+	r.POST("/cycle-counts/:id/cancel", handler.Cancel)
+	r.GET("/reports/abc", handler.Classify)
+	r.GET("/reports/count-variances", handler.Variances)
+}
+
+func mapValuationUrls(handler *http15.ValuationHandler, r *gin.Engine) {
+	r.GET("/items/:id/cost-layers", handler.GetLayers)
+	r.GET("/reports/valuation", handler.Valuation)
+}
+
+func mapCurrencyRateUrls(handler *http16.CurrencyRateHandler, r *gin.Engine) {
+	r.GET("/currency-rates", handler.GetAll)
+	r.GET("/currency-rates/:currency", handler.GetOne)
+	r.PUT("/currency-rates/:currency", handler.Set)
+	r.DELETE("/currency-rates/:currency", handler.Delete)
+}
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"net/http"
+	"strings"
+)
+
+type CurrencyRateHandler struct {
+	useCase domain.CurrencyRateUseCase
+}
+
+func NewCurrencyRateHandler(useCase domain.CurrencyRateUseCase) *CurrencyRateHandler {
+	return &CurrencyRateHandler{useCase: useCase}
+}
+
+func (h *CurrencyRateHandler) GetAll(c *gin.Context) {
+	ctx := c.Request.Context()
+	rates, err := h.useCase.GetAll(ctx)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+func (h *CurrencyRateHandler) GetOne(c *gin.Context) {
+	currency := strings.ToUpper(c.Param("currency"))
+	if currency == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("currency not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	rate, err := h.useCase.GetOne(ctx, currency)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, rate)
+}
+
+// Set takes the rate of the currency in the body, e.g. {"rate": "1.08"}
+func (h *CurrencyRateHandler) Set(c *gin.Context) {
+	currency := strings.ToUpper(c.Param("currency"))
+	if currency == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("currency not provided"))
+		return
+	}
+
+	var rate domain.CurrencyRate
+	if err := c.ShouldBindJSON(&rate); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid currency rate body"))
+		return
+	}
+
+	rate.Currency = currency
+	ctx := c.Request.Context()
+	saved, err := h.useCase.Set(ctx, &rate)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
+
+func (h *CurrencyRateHandler) Delete(c *gin.Context) {
+	currency := strings.ToUpper(c.Param("currency"))
+	if currency == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("currency not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	err := h.useCase.Delete(ctx, currency)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "deleted successfully"})
+}
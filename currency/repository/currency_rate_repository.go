@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+)
+
+type currencyRateRepository struct {
+	db db.DB
+}
+
+const (
+	getByCurrency = `SELECT currency, rate, updated_at FROM currency_rate WHERE currency=$1`
+	getAll        = `SELECT currency, rate, updated_at FROM currency_rate ORDER BY currency`
+	save          = `INSERT INTO currency_rate (currency, rate, updated_at) VALUES ($1, $2, $3)
+			ON CONFLICT (currency) DO UPDATE SET rate=excluded.rate, updated_at=excluded.updated_at`
+	deleteByCurrency = `DELETE FROM currency_rate WHERE currency=$1`
+)
+
+// NewCurrencyRateRepository stores conversion rates in a SQL database, Postgres or SQLite. The schema must be migrated
+func NewCurrencyRateRepository(database db.DB) domain.CurrencyRateRepository {
+	return &currencyRateRepository{db: database}
+}
+
+func (r *currencyRateRepository) GetAll(ctx context.Context) ([]domain.CurrencyRate, error) {
+	rows, err := r.db.Query(ctx, getAll)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var rates []domain.CurrencyRate
+	for rows.Next() {
+		var rate domain.CurrencyRate
+		if err = rows.Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt); err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		rates = append(rates, rate)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return rates, nil
+}
+
+func (r *currencyRateRepository) GetOne(ctx context.Context, currency string) (*domain.CurrencyRate, error) {
+	var rate domain.CurrencyRate
+	err := r.db.QueryRow(ctx, getByCurrency, currency).Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt)
+	if err == db.ErrNoRows {
+		return &domain.CurrencyRate{}, nil
+	}
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return &rate, nil
+}
+
+func (r *currencyRateRepository) Save(ctx context.Context, rate *domain.CurrencyRate) (*domain.CurrencyRate, error) {
+	if _, err := r.db.Exec(ctx, save, rate.Currency, rate.Rate, rate.UpdatedAt); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return rate, nil
+}
+
+func (r *currencyRateRepository) Delete(ctx context.Context, currency string) error {
+	if _, err := r.db.Exec(ctx, deleteByCurrency, currency); err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+
+	return nil
+}
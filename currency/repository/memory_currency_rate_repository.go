@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"sort"
+)
+
+type memoryCurrencyRateRepository struct {
+	store *memory.Store
+}
+
+// NewMemoryCurrencyRateRepository keeps conversion rates in store instead of a database. It's meant for tests and demos
+func NewMemoryCurrencyRateRepository(store *memory.Store) domain.CurrencyRateRepository {
+	return &memoryCurrencyRateRepository{store: store}
+}
+
+func (r *memoryCurrencyRateRepository) GetAll(ctx context.Context) ([]domain.CurrencyRate, error) {
+	defer r.store.Read(ctx)()
+
+	var rates []domain.CurrencyRate
+	for _, rate := range r.store.CurrencyRates {
+		rates = append(rates, rate)
+	}
+	sort.Slice(rates, func(a, b int) bool {
+		return rates[a].Currency < rates[b].Currency
+	})
+	return rates, nil
+}
+
+func (r *memoryCurrencyRateRepository) GetOne(ctx context.Context, currency string) (*domain.CurrencyRate, error) {
+	defer r.store.Read(ctx)()
+
+	rate := r.store.CurrencyRates[currency]
+	return &rate, nil
+}
+
+func (r *memoryCurrencyRateRepository) Save(ctx context.Context,
+	rate *domain.CurrencyRate) (*domain.CurrencyRate, error) {
+	defer r.store.Write(ctx)()
+
+	r.store.CurrencyRates[rate.Currency] = *rate
+	return rate, nil
+}
+
+func (r *memoryCurrencyRateRepository) Delete(ctx context.Context, currency string) error {
+	defer r.store.Write(ctx)()
+
+	delete(r.store.CurrencyRates, currency)
+	return nil
+}
@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"time"
+)
+
+type currencyRateUseCase struct {
+	rateRepository domain.CurrencyRateRepository
+	timeout        time.Duration
+}
+
+func NewCurrencyRateUseCase(repository domain.CurrencyRateRepository,
+	timeout time.Duration) domain.CurrencyRateUseCase {
+	return &currencyRateUseCase{rateRepository: repository, timeout: timeout}
+}
+
+func (u *currencyRateUseCase) GetAll(ctx context.Context) ([]domain.CurrencyRate, error) {
+	c, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	rates, err := u.rateRepository.GetAll(c)
+	if err != nil {
+		return nil, err
+	}
+	if len(rates) == 0 {
+		return nil, errors.NewNotFoundError("no currency rates found")
+	}
+
+	return rates, nil
+}
+
+func (u *currencyRateUseCase) GetOne(ctx context.Context, currency string) (*domain.CurrencyRate, error) {
+	c, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	rate, err := u.rateRepository.GetOne(c, currency)
+	if err != nil {
+		return nil, err
+	}
+	if rate.Currency == "" {
+		return nil, errors.NewNotFoundError("no rate found for the currency")
+	}
+
+	return rate, nil
+}
+
+func (u *currencyRateUseCase) Set(ctx context.Context, rate *domain.CurrencyRate) (*domain.CurrencyRate, error) {
+	c, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	if !domain.IsCurrencyCode(rate.Currency) {
+		return nil, errors.NewBadRequestError("invalid request. Currency must be an ISO 4217 code like " +
+			domain.DefaultCurrency)
+	}
+	// rates are quoted against the default currency, so its own can't be anything but 1
+	if rate.Currency == domain.DefaultCurrency {
+		return nil, errors.NewBadRequestError("the rate of " + domain.DefaultCurrency + " is always 1")
+	}
+	if rate.Rate.Sign() <= 0 {
+		return nil, errors.NewBadRequestError("invalid request. Rate must be more than 0")
+	}
+
+	rate.UpdatedAt = time.Now()
+	return u.rateRepository.Save(c, rate)
+}
+
+func (u *currencyRateUseCase) Delete(ctx context.Context, currency string) error {
+	c, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	if currency == domain.DefaultCurrency {
+		return errors.NewBadRequestError("the rate of " + domain.DefaultCurrency + " can't be deleted")
+	}
+
+	existing, err := u.rateRepository.GetOne(c, currency)
+	if err != nil {
+		return err
+	}
+	if existing.Currency == "" {
+		return errors.NewNotFoundError("no rate found for the currency")
+	}
+
+	return u.rateRepository.Delete(c, currency)
+}
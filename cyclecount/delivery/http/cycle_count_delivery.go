@@ -0,0 +1,283 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/specification"
+	"github.com/nuzurie/shopify/utils/errors"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CycleCountHandler struct {
+	useCase domain.CycleCountUseCase
+}
+
+func NewCycleCountHandler(useCase domain.CycleCountUseCase) *CycleCountHandler {
+	return &CycleCountHandler{useCase: useCase}
+}
+
+func (h *CycleCountHandler) GetAll(c *gin.Context) {
+	var filter domain.CycleCountFilter
+	status, _ := c.GetQuery("status")
+	filter.Status = domain.CycleCountStatus(status)
+	filter.LocationID, _ = c.GetQuery("location")
+
+	var count int64
+	if countQuery, ok := c.GetQuery("count"); ok {
+		count, _ = strconv.ParseInt(countQuery, 10, 64)
+	} else {
+		count = 20
+	}
+
+	var offset int64
+	if offsetQuery, ok := c.GetQuery("offset"); ok {
+		offset, _ = strconv.ParseInt(offsetQuery, 10, 64)
+	}
+
+	ctx := c.Request.Context()
+	cycleCounts, err := h.useCase.GetAll(ctx, int(count), int(offset), filter)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, cycleCounts)
+}
+
+func (h *CycleCountHandler) GetOne(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	cycleCount, err := h.useCase.GetOne(ctx, id)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, cycleCount)
+}
+
+// Create takes the location, ABC class and blindness of the count in the body, and narrows the inventory counted
+// down with the same query parameters as listing inventory
+func (h *CycleCountHandler) Create(c *gin.Context) {
+	var cycleCount domain.CycleCount
+	if err := c.ShouldBindJSON(&cycleCount); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid cycle count body"))
+		return
+	}
+
+	name, _ := c.GetQuery("name")
+	description, _ := c.GetQuery("description")
+	var minPrice, maxPrice *decimal.Decimal
+	if minPriceQuery, ok := c.GetQuery("min-price"); ok {
+		if price, err := decimal.NewFromString(minPriceQuery); err == nil {
+			minPrice = &price
+		}
+	}
+	if maxPriceQuery, ok := c.GetQuery("max-price"); ok {
+		if price, err := decimal.NewFromString(maxPriceQuery); err == nil {
+			maxPrice = &price
+		}
+	}
+	currency, _ := c.GetQuery("currency")
+
+	itemSpec := specification.NewItemSpecification(name, description, minPrice, maxPrice,
+		strings.ToUpper(currency))
+
+	minQuantityQuery, _ := c.GetQuery("min-quantity")
+	minQuantity, err := strconv.ParseInt(minQuantityQuery, 10, 64)
+	if err != nil {
+		minQuantity = 0
+	}
+	maxQuantityQuery, ok := c.GetQuery("max-quantity")
+	var maxQuantity int64
+	if ok {
+		maxQuantity, err = strconv.ParseInt(maxQuantityQuery, 10, 64)
+		if err != nil {
+			maxQuantity = -1
+		}
+	} else {
+		maxQuantity = -1
+	}
+
+	lot, _ := c.GetQuery("lot")
+	inventorySpec := specification.NewInventorySpecification(int(minQuantity), int(maxQuantity),
+		cycleCount.LocationID, lot, itemSpec)
+
+	ctx := c.Request.Context()
+	created, err := h.useCase.Create(ctx, &cycleCount, inventorySpec)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *CycleCountHandler) Count(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	var entry domain.CycleCountEntry
+	if err := c.ShouldBindJSON(&entry); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid count body"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	cycleCount, err := h.useCase.Count(ctx, id, entry)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, cycleCount)
+}
+
+func (h *CycleCountHandler) Approve(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	cycleCount, err := h.useCase.Approve(ctx, id)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, cycleCount)
+}
+
+func (h *CycleCountHandler) Cancel(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	cycleCount, err := h.useCase.Cancel(ctx, id)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, cycleCount)
+}
+
+func (h *CycleCountHandler) Classify(c *gin.Context) {
+	days := domain.DefaultABCWindowDays
+	if daysQuery, ok := c.GetQuery("days"); ok {
+		var err error
+		if days, err = strconv.Atoi(daysQuery); err != nil {
+			c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid days"))
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	classes, err := h.useCase.Classify(ctx, days)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, classes)
+}
+
+func (h *CycleCountHandler) Variances(c *gin.Context) {
+	var from, to time.Time
+	var err error
+	if fromQuery, ok := c.GetQuery("from"); ok {
+		if from, err = parseTime(fromQuery); err != nil {
+			c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid from date"))
+			return
+		}
+	}
+	if toQuery, ok := c.GetQuery("to"); ok {
+		if to, err = parseTime(toQuery); err != nil {
+			c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid to date"))
+			return
+		}
+	}
+	by, _ := c.GetQuery("by")
+
+	ctx := c.Request.Context()
+	variances, err := h.useCase.Variances(ctx, from, to, domain.VarianceGrouping(by))
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, variances)
+}
+
+// parseTime accepts RFC 3339 timestamps or plain dates, which are taken as midnight UTC
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/nuzurie/shopify/db"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"strings"
+	"time"
+)
+
+type cycleCountRepository struct {
+	db db.DB
+}
+
+const (
+	getAll = `SELECT id, location_id, abc_class, blind, status, created_by, approved_by, created_at, updated_at,
+			approved_at, cancelled_at FROM cycle_count WHERE %s ORDER BY created_at DESC, id LIMIT $%d OFFSET $%d`
+	getByID = `SELECT id, location_id, abc_class, blind, status, created_by, approved_by, created_at, updated_at,
+			approved_at, cancelled_at FROM cycle_count WHERE id=$1`
+	getLines = `SELECT inventory_id, item_id, location_id, expected, counted, counted_by, counted_at
+			FROM cycle_count_line WHERE cycle_count_id=$1 ORDER BY location_id, item_id`
+	save = `INSERT INTO cycle_count (id, location_id, abc_class, blind, status, created_by, approved_by, created_at,
+			updated_at, approved_at, cancelled_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	saveLine = `INSERT INTO cycle_count_line (cycle_count_id, inventory_id, item_id, location_id, expected, counted,
+			counted_by, counted_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	update = `UPDATE cycle_count SET status=$2, approved_by=$3, updated_at=$4, approved_at=$5, cancelled_at=$6
+			WHERE id=$1`
+	deleteLines = `DELETE FROM cycle_count_line WHERE cycle_count_id=$1`
+	// variances sums the counted lines of approved counts, valued at the current price of their item if it still exists
+	variances = `SELECT %s, COUNT(*), SUM(line.counted - line.expected),
+			SUM(CASE WHEN line.counted < line.expected THEN line.expected - line.counted ELSE 0 END),
+			SUM(CASE WHEN line.counted > line.expected THEN line.counted - line.expected ELSE 0 END),
+			SUM((line.counted - line.expected) * COALESCE(item.price * currency_rate.rate, 0))
+			FROM cycle_count_line line JOIN cycle_count ON cycle_count.id = line.cycle_count_id
+			LEFT JOIN item ON item.id = line.item_id LEFT JOIN currency_rate ON currency_rate.currency = item.currency
+			WHERE cycle_count.status='approved' AND cycle_count.approved_at>=$1 AND cycle_count.approved_at<$2
+			AND line.counted IS NOT NULL GROUP BY %s ORDER BY %s`
+	// SQLite has no row locks, but it only ever runs one transaction at a time
+	forUpdate = ` FOR UPDATE`
+)
+
+// NewCycleCountRepository stores cycle counts in a SQL database, Postgres or SQLite. The schema must be migrated
+func NewCycleCountRepository(database db.DB) domain.CycleCountRepository {
+	return &cycleCountRepository{db: database}
+}
+
+func (r *cycleCountRepository) GetAll(ctx context.Context, count int, offset int,
+	filter domain.CycleCountFilter) ([]domain.CycleCount, error) {
+	conditions := []string{"1=1"}
+	var args []interface{}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status=$%d", len(args)))
+	}
+	if filter.LocationID != "" {
+		args = append(args, filter.LocationID)
+		conditions = append(conditions, fmt.Sprintf("location_id=$%d", len(args)))
+	}
+
+	query := fmt.Sprintf(getAll, strings.Join(conditions, " AND "), len(args)+1, len(args)+2)
+	rows, err := r.db.Query(ctx, query, append(args, count, offset)...)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var cycleCounts []domain.CycleCount
+	for rows.Next() {
+		cycleCount, err := scan(rows)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		cycleCounts = append(cycleCounts, *cycleCount)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	rows.Close()
+
+	for index := range cycleCounts {
+		if cycleCounts[index].Lines, err = r.getLines(ctx, cycleCounts[index].ID); err != nil {
+			return nil, err
+		}
+	}
+	return cycleCounts, nil
+}
+
+func (r *cycleCountRepository) GetOne(ctx context.Context, id string) (*domain.CycleCount, error) {
+	return r.getOne(ctx, getByID, id)
+}
+
+func (r *cycleCountRepository) GetOneForUpdate(ctx context.Context, id string) (*domain.CycleCount, error) {
+	query := getByID
+	if r.db.Dialect() == domain.Postgres {
+		query += forUpdate
+	}
+	return r.getOne(ctx, query, id)
+}
+
+func (r *cycleCountRepository) getOne(ctx context.Context, query string, id string) (*domain.CycleCount, error) {
+	cycleCount, err := scan(r.db.QueryRow(ctx, query, id))
+	if err == db.ErrNoRows {
+		return &domain.CycleCount{}, nil
+	}
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	if cycleCount.Lines, err = r.getLines(ctx, cycleCount.ID); err != nil {
+		return nil, err
+	}
+	return cycleCount, nil
+}
+
+func scan(row db.Row) (*domain.CycleCount, error) {
+	var cycleCount domain.CycleCount
+	err := row.Scan(&cycleCount.ID, &cycleCount.LocationID, &cycleCount.ABCClass, &cycleCount.Blind,
+		&cycleCount.Status, &cycleCount.CreatedBy, &cycleCount.ApprovedBy, &cycleCount.CreatedAt,
+		&cycleCount.UpdatedAt, &cycleCount.ApprovedAt, &cycleCount.CancelledAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cycleCount, nil
+}
+
+func (r *cycleCountRepository) getLines(ctx context.Context, cycleCountID string) ([]domain.CycleCountLine, error) {
+	rows, err := r.db.Query(ctx, getLines, cycleCountID)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var lines []domain.CycleCountLine
+	for rows.Next() {
+		var line domain.CycleCountLine
+		var expected int
+		err = rows.Scan(&line.InventoryID, &line.ItemID, &line.LocationID, &expected, &line.Counted,
+			&line.CountedBy, &line.CountedAt)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		line.Expected = &expected
+		lines = append(lines, line)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return lines, nil
+}
+
+func (r *cycleCountRepository) Save(ctx context.Context, cycleCount *domain.CycleCount) (*domain.CycleCount, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, save, cycleCount.ID, cycleCount.LocationID, cycleCount.ABCClass, cycleCount.Blind,
+		cycleCount.Status, cycleCount.CreatedBy, cycleCount.ApprovedBy, cycleCount.CreatedAt, cycleCount.UpdatedAt,
+		cycleCount.ApprovedAt, cycleCount.CancelledAt)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	if err = saveLines(ctx, tx, cycleCount); err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return cycleCount, nil
+}
+
+func (r *cycleCountRepository) Edit(ctx context.Context, cycleCount *domain.CycleCount) (*domain.CycleCount, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, update, cycleCount.ID, cycleCount.Status, cycleCount.ApprovedBy, cycleCount.UpdatedAt,
+		cycleCount.ApprovedAt, cycleCount.CancelledAt)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	if _, err = tx.Exec(ctx, deleteLines, cycleCount.ID); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	if err = saveLines(ctx, tx, cycleCount); err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return cycleCount, nil
+}
+
+func saveLines(ctx context.Context, tx db.Tx, cycleCount *domain.CycleCount) error {
+	for _, line := range cycleCount.Lines {
+		_, err := tx.Exec(ctx, saveLine, cycleCount.ID, line.InventoryID, line.ItemID, line.LocationID,
+			*line.Expected, line.Counted, line.CountedBy, line.CountedAt)
+		if err != nil {
+			return errors.NewInternalServerError(err.Error())
+		}
+	}
+	return nil
+}
+
+func (r *cycleCountRepository) Variances(ctx context.Context, from time.Time, to time.Time,
+	by domain.VarianceGrouping) ([]domain.CountVariance, error) {
+	column := "line.item_id"
+	if by == domain.VarianceByCounter {
+		column = "line.counted_by"
+	}
+	rows, err := r.db.Query(ctx, fmt.Sprintf(variances, column, column, column), from, to)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var result []domain.CountVariance
+	for rows.Next() {
+		var variance domain.CountVariance
+		var key string
+		err = rows.Scan(&key, &variance.Lines, &variance.Variance, &variance.Shrinkage, &variance.Overage,
+			&variance.Value)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		if by == domain.VarianceByCounter {
+			variance.Counter = key
+		} else {
+			variance.ItemID = key
+		}
+		result = append(result, variance)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return result, nil
+}
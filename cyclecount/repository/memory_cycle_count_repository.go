@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"github.com/shopspring/decimal"
+	"sort"
+	"time"
+)
+
+type memoryCycleCountRepository struct {
+	store *memory.Store
+}
+
+// NewMemoryCycleCountRepository keeps cycle counts in store instead of a database. store must be shared with the item
+// repository so variances can be valued at the prices of the items
+func NewMemoryCycleCountRepository(store *memory.Store) domain.CycleCountRepository {
+	return &memoryCycleCountRepository{store: store}
+}
+
+func (r *memoryCycleCountRepository) GetAll(ctx context.Context, count int, offset int,
+	filter domain.CycleCountFilter) ([]domain.CycleCount, error) {
+	defer r.store.Read(ctx)()
+
+	var cycleCounts []domain.CycleCount
+	for _, cycleCount := range r.store.CycleCounts {
+		if (filter.Status == "" || cycleCount.Status == filter.Status) &&
+			(filter.LocationID == "" || cycleCount.LocationID == filter.LocationID) {
+			cycleCounts = append(cycleCounts, copyCycleCount(cycleCount))
+		}
+	}
+	sort.Slice(cycleCounts, func(a, b int) bool {
+		if !cycleCounts[a].CreatedAt.Equal(cycleCounts[b].CreatedAt) {
+			return cycleCounts[a].CreatedAt.After(cycleCounts[b].CreatedAt)
+		}
+		return cycleCounts[a].ID < cycleCounts[b].ID
+	})
+
+	start, end := memory.Page(len(cycleCounts), count, offset)
+	return cycleCounts[start:end], nil
+}
+
+func (r *memoryCycleCountRepository) GetOne(ctx context.Context, id string) (*domain.CycleCount, error) {
+	defer r.store.Read(ctx)()
+
+	cycleCount := copyCycleCount(r.store.CycleCounts[id])
+	return &cycleCount, nil
+}
+
+// GetOneForUpdate needs no lock of its own, transactions hold the whole store
+func (r *memoryCycleCountRepository) GetOneForUpdate(ctx context.Context, id string) (*domain.CycleCount, error) {
+	return r.GetOne(ctx, id)
+}
+
+func (r *memoryCycleCountRepository) Save(ctx context.Context,
+	cycleCount *domain.CycleCount) (*domain.CycleCount, error) {
+	defer r.store.Write(ctx)()
+
+	if _, ok := r.store.CycleCounts[cycleCount.ID]; ok {
+		return nil, errors.NewConflictError("cycle count already exists")
+	}
+	r.store.CycleCounts[cycleCount.ID] = copyCycleCount(*cycleCount)
+	return cycleCount, nil
+}
+
+func (r *memoryCycleCountRepository) Edit(ctx context.Context,
+	cycleCount *domain.CycleCount) (*domain.CycleCount, error) {
+	defer r.store.Write(ctx)()
+
+	existing, ok := r.store.CycleCounts[cycleCount.ID]
+	if !ok {
+		return cycleCount, nil
+	}
+	existing.Status = cycleCount.Status
+	existing.ApprovedBy = cycleCount.ApprovedBy
+	existing.UpdatedAt = cycleCount.UpdatedAt
+	existing.ApprovedAt = cycleCount.ApprovedAt
+	existing.CancelledAt = cycleCount.CancelledAt
+	existing.Lines = cycleCount.Lines
+	r.store.CycleCounts[cycleCount.ID] = copyCycleCount(existing)
+	return cycleCount, nil
+}
+
+func (r *memoryCycleCountRepository) Variances(ctx context.Context, from time.Time, to time.Time,
+	by domain.VarianceGrouping) ([]domain.CountVariance, error) {
+	defer r.store.Read(ctx)()
+
+	sums := map[string]*domain.CountVariance{}
+	var keys []string
+	for _, cycleCount := range r.store.CycleCounts {
+		if cycleCount.Status != domain.CycleCountApproved || cycleCount.ApprovedAt.Before(from) ||
+			!cycleCount.ApprovedAt.Before(to) {
+			continue
+		}
+		for _, line := range cycleCount.Lines {
+			if line.Counted == nil {
+				continue
+			}
+			key := line.ItemID
+			if by == domain.VarianceByCounter {
+				key = line.CountedBy
+			}
+			sum, ok := sums[key]
+			if !ok {
+				sum = &domain.CountVariance{}
+				if by == domain.VarianceByCounter {
+					sum.Counter = key
+				} else {
+					sum.ItemID = key
+				}
+				sums[key] = sum
+				keys = append(keys, key)
+			}
+
+			variance := *line.Counted - *line.Expected
+			sum.Lines++
+			sum.Variance += variance
+			if variance < 0 {
+				sum.Shrinkage -= variance
+			} else {
+				sum.Overage += variance
+			}
+			if item, ok := r.store.Items[line.ItemID]; ok {
+				if rate, ok := r.store.CurrencyRates[item.Currency]; ok {
+					sum.Value = sum.Value.Add(decimal.NewFromInt(int64(variance)).Mul(item.Price).Mul(rate.Rate))
+				}
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	var result []domain.CountVariance
+	for _, key := range keys {
+		result = append(result, *sums[key])
+	}
+	return result, nil
+}
+
+// copyCycleCount keeps the lines held by the store from being changed through the counts handed out. The counts they
+// point to are never changed in place, only replaced
+func copyCycleCount(cycleCount domain.CycleCount) domain.CycleCount {
+	cycleCount.Lines = append([]domain.CycleCountLine(nil), cycleCount.Lines...)
+	return cycleCount
+}
@@ -0,0 +1,414 @@
+package usecase
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/audit"
+	"github.com/nuzurie/shopify/utils/errors"
+	"github.com/shopspring/decimal"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxCountLines is how many inventories a cycle count lists at most
+	maxCountLines = 1000
+	// defaultReportPeriod is how far back the variance report goes unless told otherwise
+	defaultReportPeriod = 30 * 24 * time.Hour
+)
+
+type cycleCountUseCase struct {
+	cycleCountRepository domain.CycleCountRepository
+	itemRepository       domain.ItemRepository
+	inventoryRepository  domain.InventoryRepository
+	locationRepository   domain.LocationRepository
+	movementRepository   domain.MovementRepository
+	rateRepository       domain.CurrencyRateRepository
+	inventoryUseCase     domain.InventoryUseCase
+	transactor           domain.Transactor
+	timeout              time.Duration
+}
+
+// NewCycleCountUseCase lists inventory from inventoryRepository to be counted, and posts the variances of approved
+// counts through inventoryUseCase in the same transaction as the approval. Items are classified by the sales the
+// ledger in movementRepository recorded, valued in the default currency at the rates of rateRepository
+func NewCycleCountUseCase(cycleCountRepository domain.CycleCountRepository, itemRepository domain.ItemRepository,
+	inventoryRepository domain.InventoryRepository, locationRepository domain.LocationRepository,
+	movementRepository domain.MovementRepository, rateRepository domain.CurrencyRateRepository,
+	inventoryUseCase domain.InventoryUseCase, transactor domain.Transactor,
+	timeout time.Duration) domain.CycleCountUseCase {
+	return &cycleCountUseCase{cycleCountRepository: cycleCountRepository, itemRepository: itemRepository,
+		inventoryRepository: inventoryRepository, locationRepository: locationRepository,
+		movementRepository: movementRepository, rateRepository: rateRepository, inventoryUseCase: inventoryUseCase,
+		transactor: transactor, timeout: timeout}
+}
+
+func (u *cycleCountUseCase) GetAll(ctx context.Context, count int, offset int,
+	filter domain.CycleCountFilter) ([]domain.CycleCount, error) {
+	c, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return nil, errors.NewBadRequestError("invalid status")
+	}
+
+	cycleCounts, err := u.cycleCountRepository.GetAll(c, count, offset, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(cycleCounts) == 0 {
+		return nil, errors.NewNotFoundError("no cycle counts found")
+	}
+
+	for index := range cycleCounts {
+		present(&cycleCounts[index])
+	}
+	return cycleCounts, nil
+}
+
+func (u *cycleCountUseCase) GetOne(ctx context.Context, id string) (*domain.CycleCount, error) {
+	c, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	cycleCount, err := u.cycleCountRepository.GetOne(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if cycleCount.ID == "" {
+		return nil, errors.NewNotFoundError("no such cycle count found")
+	}
+
+	present(cycleCount)
+	return cycleCount, nil
+}
+
+func (u *cycleCountUseCase) Create(ctx context.Context, cycleCount *domain.CycleCount,
+	filter domain.InventorySpecification) (*domain.CycleCount, error) {
+	c, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	if cycleCount.ABCClass != "" && !cycleCount.ABCClass.IsValid() {
+		return nil, errors.NewBadRequestError("invalid request. ABC class must be A, B or C")
+	}
+	if cycleCount.LocationID != "" {
+		location, err := u.locationRepository.GetOne(c, cycleCount.LocationID)
+		if err != nil {
+			return nil, err
+		}
+		if location.ID == "" {
+			return nil, errors.NewBadRequestError("no location with such ID exists")
+		}
+	}
+
+	inventoryItems, err := u.inventoryRepository.GetAll(c, maxCountLines+1, 0, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(inventoryItems) > maxCountLines {
+		return nil, errors.NewBadRequestError("invalid request. More than " + strconv.Itoa(maxCountLines) +
+			" inventories match, narrow the count down")
+	}
+
+	var classes map[string]domain.ABCClass
+	if cycleCount.ABCClass != "" {
+		itemClasses, err := u.classify(c, domain.DefaultABCWindowDays)
+		if err != nil {
+			return nil, err
+		}
+		classes = map[string]domain.ABCClass{}
+		for _, itemClass := range itemClasses {
+			classes[itemClass.ItemID] = itemClass.Class
+		}
+	}
+
+	items := map[string]*domain.Item{}
+	var lines []domain.CycleCountLine
+	for _, inventory := range inventoryItems {
+		if cycleCount.LocationID != "" && inventory.LocationID != cycleCount.LocationID {
+			continue
+		}
+		if classes != nil {
+			class, ok := classes[inventory.Item.ID]
+			if !ok {
+				class = domain.ABCClassC
+			}
+			if class != cycleCount.ABCClass {
+				continue
+			}
+		}
+		item, ok := items[inventory.Item.ID]
+		if !ok {
+			if item, err = u.itemRepository.GetOne(c, inventory.Item.ID); err != nil {
+				return nil, err
+			}
+			items[inventory.Item.ID] = item
+		}
+		// the stock of serialized items is known unit by unit, so it's never counted in bulk
+		if item.Serialized {
+			continue
+		}
+
+		expected := inventory.Quantity
+		lines = append(lines, domain.CycleCountLine{InventoryID: inventory.ID, ItemID: inventory.Item.ID,
+			LocationID: inventory.LocationID, Expected: &expected})
+	}
+	if len(lines) == 0 {
+		return nil, errors.NewNotFoundError("no inventory found to count")
+	}
+	sort.Slice(lines, func(a, b int) bool {
+		if lines[a].LocationID != lines[b].LocationID {
+			return lines[a].LocationID < lines[b].LocationID
+		}
+		return lines[a].ItemID < lines[b].ItemID
+	})
+
+	cycleCount.ID = uuid.NewString()
+	cycleCount.Status = domain.CycleCountOpen
+	cycleCount.Lines = lines
+	cycleCount.CreatedBy = audit.Actor(c)
+	cycleCount.ApprovedBy = ""
+	cycleCount.CreatedAt = time.Now()
+	cycleCount.UpdatedAt = cycleCount.CreatedAt
+	cycleCount.ApprovedAt = nil
+	cycleCount.CancelledAt = nil
+	if _, err = u.cycleCountRepository.Save(c, cycleCount); err != nil {
+		return nil, err
+	}
+
+	present(cycleCount)
+	return cycleCount, nil
+}
+
+func (u *cycleCountUseCase) Count(ctx context.Context, id string,
+	entry domain.CycleCountEntry) (*domain.CycleCount, error) {
+	if len(entry.Lines) == 0 {
+		return nil, errors.NewBadRequestError("invalid request. Counts need at least one line")
+	}
+
+	return u.update(ctx, id, func(c context.Context, cycleCount *domain.CycleCount) error {
+		if cycleCount.Status != domain.CycleCountOpen {
+			return errors.NewConflictError("only open cycle counts can be counted, this one is " +
+				string(cycleCount.Status))
+		}
+
+		lines := map[string]int{}
+		for index, line := range cycleCount.Lines {
+			lines[line.InventoryID] = index
+		}
+		seen := map[string]bool{}
+		now := time.Now()
+		for _, counted := range entry.Lines {
+			index, ok := lines[counted.InventoryID]
+			if !ok {
+				return errors.NewBadRequestError("invalid request. Inventory " + counted.InventoryID +
+					" isn't on the cycle count")
+			}
+			if seen[counted.InventoryID] {
+				return errors.NewBadRequestError("invalid request. Inventory " + counted.InventoryID +
+					" is on more than one line")
+			}
+			seen[counted.InventoryID] = true
+			if counted.Counted < 0 {
+				return errors.NewBadRequestError("invalid request. Counted quantity can't be less than 0")
+			}
+
+			// the variance is against what was on hand when the stock was counted, so stock moving in the meantime
+			// isn't mistaken for a variance
+			inventory, err := u.inventoryRepository.GetByID(c, counted.InventoryID)
+			if err != nil {
+				return err
+			}
+			expected, quantity := inventory.Quantity, counted.Counted
+			countedAt := now
+			cycleCount.Lines[index].Expected = &expected
+			cycleCount.Lines[index].Counted = &quantity
+			cycleCount.Lines[index].CountedBy = audit.Actor(c)
+			cycleCount.Lines[index].CountedAt = &countedAt
+		}
+		return nil
+	})
+}
+
+func (u *cycleCountUseCase) Approve(ctx context.Context, id string) (*domain.CycleCount, error) {
+	return u.update(ctx, id, func(c context.Context, cycleCount *domain.CycleCount) error {
+		if cycleCount.Status != domain.CycleCountOpen {
+			return errors.NewConflictError("only open cycle counts can be approved, this one is " +
+				string(cycleCount.Status))
+		}
+
+		uncounted := 0
+		for _, line := range cycleCount.Lines {
+			if line.Counted == nil {
+				uncounted++
+			}
+		}
+		if uncounted > 0 {
+			return errors.NewConflictError(strconv.Itoa(uncounted) + " lines of the cycle count haven't been counted")
+		}
+
+		for _, line := range cycleCount.Lines {
+			variance := *line.Counted - *line.Expected
+			if variance == 0 {
+				continue
+			}
+			_, err := u.inventoryUseCase.MoveStock(c, line.ItemID, line.LocationID,
+				domain.StockAdjustment{Delta: variance, Reason: domain.ReasonCycleCount})
+			if err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		cycleCount.Status = domain.CycleCountApproved
+		cycleCount.ApprovedBy = audit.Actor(c)
+		cycleCount.ApprovedAt = &now
+		return nil
+	})
+}
+
+func (u *cycleCountUseCase) Cancel(ctx context.Context, id string) (*domain.CycleCount, error) {
+	return u.update(ctx, id, func(c context.Context, cycleCount *domain.CycleCount) error {
+		if cycleCount.Status != domain.CycleCountOpen {
+			return errors.NewConflictError("only open cycle counts can be cancelled, this one is " +
+				string(cycleCount.Status))
+		}
+
+		now := time.Now()
+		cycleCount.Status = domain.CycleCountCancelled
+		cycleCount.CancelledAt = &now
+		return nil
+	})
+}
+
+// update applies change to the cycle count with the given id and saves it, in one transaction with the stock it posts
+func (u *cycleCountUseCase) update(ctx context.Context, id string,
+	change func(c context.Context, cycleCount *domain.CycleCount) error) (*domain.CycleCount, error) {
+	c, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	var cycleCount *domain.CycleCount
+	err := u.transactor.WithinTransaction(c, func(c context.Context) error {
+		var err error
+		cycleCount, err = u.cycleCountRepository.GetOneForUpdate(c, id)
+		if err != nil {
+			return err
+		}
+		if cycleCount.ID == "" {
+			return errors.NewNotFoundError("no such cycle count found")
+		}
+
+		if err = change(c, cycleCount); err != nil {
+			return err
+		}
+		cycleCount.UpdatedAt = time.Now()
+		_, err = u.cycleCountRepository.Edit(c, cycleCount)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	present(cycleCount)
+	return cycleCount, nil
+}
+
+// present works out the variances of the counted lines, and hides them along with the expected quantities while a
+// blind count is open
+func present(cycleCount *domain.CycleCount) {
+	hidden := cycleCount.Blind && cycleCount.Status == domain.CycleCountOpen
+	for index, line := range cycleCount.Lines {
+		cycleCount.Lines[index].Variance = nil
+		if hidden {
+			cycleCount.Lines[index].Expected = nil
+			continue
+		}
+		if line.Counted != nil && line.Expected != nil {
+			variance := *line.Counted - *line.Expected
+			cycleCount.Lines[index].Variance = &variance
+		}
+	}
+}
+
+func (u *cycleCountUseCase) Classify(ctx context.Context, days int) ([]domain.ItemClass, error) {
+	c, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	if days <= 0 {
+		return nil, errors.NewBadRequestError("invalid request. Days must be more than 0")
+	}
+
+	classes, err := u.classify(c, days)
+	if err != nil {
+		return nil, err
+	}
+	if len(classes) == 0 {
+		return nil, errors.NewNotFoundError("no sales found to classify items by")
+	}
+
+	return classes, nil
+}
+
+// classify ranks the items sold over the last days by the value they sold for at their current price, converted to
+// the default currency. Items priced in a currency with no rate are valued at nothing
+func (u *cycleCountUseCase) classify(c context.Context, days int) ([]domain.ItemClass, error) {
+	sales, err := u.movementRepository.GetSales(c, "", time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return nil, err
+	}
+	rates, err := u.rateRepository.GetAll(c)
+	if err != nil {
+		return nil, err
+	}
+	converter := domain.NewRates(rates)
+
+	prices := map[string]decimal.Decimal{}
+	salesValues := map[string]decimal.Decimal{}
+	for _, sale := range sales {
+		price, ok := prices[sale.ItemID]
+		if !ok {
+			item, err := u.itemRepository.GetOne(c, sale.ItemID)
+			if err != nil {
+				return nil, err
+			}
+			price, _ = converter.Convert(item.Price, item.Currency, domain.DefaultCurrency)
+			prices[sale.ItemID] = price
+		}
+		salesValues[sale.ItemID] = salesValues[sale.ItemID].Add(decimal.NewFromInt(int64(sale.Quantity)).Mul(price))
+	}
+
+	return domain.ClassifyABC(salesValues), nil
+}
+
+func (u *cycleCountUseCase) Variances(ctx context.Context, from time.Time, to time.Time,
+	by domain.VarianceGrouping) ([]domain.CountVariance, error) {
+	c, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	if by == "" {
+		by = domain.VarianceByItem
+	}
+	if !by.IsValid() {
+		return nil, errors.NewBadRequestError("invalid request. Variances are by item or by counter")
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultReportPeriod)
+	}
+	if !from.Before(to) {
+		return nil, errors.NewBadRequestError("invalid request. from must be before to")
+	}
+
+	variances, err := u.cycleCountRepository.Variances(c, from, to, by)
+	if err != nil {
+		return nil, err
+	}
+	if len(variances) == 0 {
+		return nil, errors.NewNotFoundError("no count variances found")
+	}
+
+	return variances, nil
+}
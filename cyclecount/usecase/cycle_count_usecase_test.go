@@ -0,0 +1,190 @@
+package usecase
+
+import (
+	"context"
+	repository9 "github.com/nuzurie/shopify/currency/repository"
+	repository6 "github.com/nuzurie/shopify/cyclecount/repository"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/inventory/repository"
+	usecase2 "github.com/nuzurie/shopify/inventory/usecase"
+	repository2 "github.com/nuzurie/shopify/item/repository"
+	repository3 "github.com/nuzurie/shopify/location/repository"
+	repository4 "github.com/nuzurie/shopify/lot/repository"
+	repository5 "github.com/nuzurie/shopify/reservation/repository"
+	repository7 "github.com/nuzurie/shopify/serial/repository"
+	"github.com/nuzurie/shopify/specification"
+	"github.com/nuzurie/shopify/utils/errors"
+	repository8 "github.com/nuzurie/shopify/valuation/repository"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestApprove(t *testing.T) {
+	cases := []struct {
+		name string
+		// counted is the quantity counted of each item, leaving out the items that weren't counted
+		counted map[string]int
+		// sold is how many hats sell after they were counted
+		sold int
+		want int
+		// hats and scarves are the stock on hand once the count was approved, or not
+		hats    int
+		scarves int
+	}{
+		{"short", map[string]int{"hat": 8, "scarf": 4}, 0, 0, 8, 4},
+		{"over", map[string]int{"hat": 10, "scarf": 7}, 0, 0, 10, 7},
+		{"sold after counting", map[string]int{"hat": 8, "scarf": 4}, 3, 0, 5, 4},
+		{"uncounted line", map[string]int{"hat": 8}, 0, http.StatusConflict, 10, 4},
+	}
+	for _, c := range cases {
+		cycleCountUseCase, inventoryUseCase, store := newCycleCountUseCase(t)
+		ctx := context.Background()
+		cycleCount, err := cycleCountUseCase.Create(ctx, &domain.CycleCount{LocationID: domain.DefaultLocationID},
+			specification.NewInventorySpecification(0, -1, "", "", specification.And()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var entry domain.CycleCountEntry
+		for _, line := range cycleCount.Lines {
+			if counted, ok := c.counted[line.ItemID]; ok {
+				entry.Lines = append(entry.Lines, domain.CycleCountEntryLine{InventoryID: line.InventoryID,
+					Counted: counted})
+			}
+		}
+		if _, err = cycleCountUseCase.Count(ctx, cycleCount.ID, entry); err != nil {
+			t.Fatal(err)
+		}
+		if c.sold != 0 {
+			if _, err = inventoryUseCase.MoveStock(ctx, "hat", domain.DefaultLocationID,
+				domain.StockAdjustment{Delta: -c.sold, Reason: domain.ReasonSold}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// counting alone posts nothing
+		if posted := countAdjustments(store); len(posted) != 0 {
+			t.Errorf("%s: counting posted %+v before the count was approved", c.name, posted)
+		}
+
+		if _, err = cycleCountUseCase.Approve(ctx, cycleCount.ID); statusOf(err) != c.want {
+			t.Errorf("%s: Approve failed with %d (%v), want %d", c.name, statusOf(err), err, c.want)
+		}
+		for itemID, want := range map[string]int{"hat": c.hats, "scarf": c.scarves} {
+			stock, err := inventoryUseCase.GetInventoryForItem(ctx, itemID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stock.Quantity != want {
+				t.Errorf("%s: %d of %s on hand, want %d", c.name, stock.Quantity, itemID, want)
+			}
+		}
+		// the ledger records one cycle count adjustment per line that was off
+		var posted int
+		for _, movement := range countAdjustments(store) {
+			posted += movement.Delta
+		}
+		if want := c.hats + c.scarves + c.sold - 14; posted != want {
+			t.Errorf("%s: cycle count adjustments sum to %d, want %d", c.name, posted, want)
+		}
+	}
+}
+
+func TestCycleCountStatus(t *testing.T) {
+	cycleCountUseCase, _, store := newCycleCountUseCase(t)
+	ctx := context.Background()
+	cycleCount, err := cycleCountUseCase.Create(ctx, &domain.CycleCount{},
+		specification.NewInventorySpecification(0, -1, "", "", specification.And()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := domain.CycleCountEntry{Lines: []domain.CycleCountEntryLine{
+		{InventoryID: cycleCount.Lines[0].InventoryID, Counted: 1},
+		{InventoryID: cycleCount.Lines[1].InventoryID, Counted: 1},
+	}}
+
+	cases := []struct {
+		name   string
+		change func() (*domain.CycleCount, error)
+		want   int
+	}{
+		{"count", func() (*domain.CycleCount, error) {
+			return cycleCountUseCase.Count(ctx, cycleCount.ID, entry)
+		}, 0},
+		{"cancel", func() (*domain.CycleCount, error) {
+			return cycleCountUseCase.Cancel(ctx, cycleCount.ID)
+		}, 0},
+		{"approve once cancelled", func() (*domain.CycleCount, error) {
+			return cycleCountUseCase.Approve(ctx, cycleCount.ID)
+		}, http.StatusConflict},
+		{"count once cancelled", func() (*domain.CycleCount, error) {
+			return cycleCountUseCase.Count(ctx, cycleCount.ID, entry)
+		}, http.StatusConflict},
+		{"unknown count", func() (*domain.CycleCount, error) {
+			return cycleCountUseCase.Approve(ctx, "missing")
+		}, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		if _, err := c.change(); statusOf(err) != c.want {
+			t.Errorf("%s: failed with %d (%v), want %d", c.name, statusOf(err), err, c.want)
+		}
+	}
+	if posted := countAdjustments(store); len(posted) != 0 {
+		t.Errorf("cancelled count posted %+v", posted)
+	}
+}
+
+// countAdjustments is the movements cycle counts posted
+func countAdjustments(store *memory.Store) []domain.StockMovement {
+	var movements []domain.StockMovement
+	for _, movement := range store.Movements {
+		if movement.Reason == domain.ReasonCycleCount {
+			movements = append(movements, movement)
+		}
+	}
+	return movements
+}
+
+// ignoreStockChanges is a StockWatcher for tests that don't evaluate reorder points
+type ignoreStockChanges struct{}
+
+func (ignoreStockChanges) StockChanged(string) {}
+
+// newCycleCountUseCase works on a store holding 10 hats and 4 scarves at the default location
+func newCycleCountUseCase(t *testing.T) (domain.CycleCountUseCase, domain.InventoryUseCase, *memory.Store) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	itemRepository := repository2.NewMemoryItemRepository(store)
+	inventoryRepository := repository.NewMemoryInventoryRepository(store)
+	movementRepository := repository.NewMemoryMovementRepository(store)
+	locationRepository := repository3.NewMemoryLocationRepository(store)
+	inventoryUseCase := usecase2.NewInventoryUseCase(itemRepository, inventoryRepository, movementRepository,
+		repository5.NewMemoryReservationRepository(store), locationRepository,
+		repository4.NewMemoryLotRepository(store), repository7.NewMemorySerialRepository(store),
+		repository8.NewMemoryCostRepository(store), ignoreStockChanges{}, store, time.Second)
+	for itemID, quantity := range map[string]int{"hat": 10, "scarf": 4} {
+		if _, err := itemRepository.Save(ctx, &domain.Item{ID: itemID, Name: itemID, Version: 1}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := inventoryUseCase.MoveStock(ctx, itemID, domain.DefaultLocationID,
+			domain.StockAdjustment{Delta: quantity, Reason: domain.ReasonReceived}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return NewCycleCountUseCase(repository6.NewMemoryCycleCountRepository(store), itemRepository,
+		inventoryRepository, locationRepository, movementRepository, repository9.NewMemoryCurrencyRateRepository(store),
+		inventoryUseCase, store, time.Second), inventoryUseCase, store
+}
+
+// statusOf is the status code of err, or 0 if there is none
+func statusOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	if restError, ok := err.(*errors.RestError); ok {
+		return restError.Code
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/nuzurie/shopify/domain"
+	"strings"
+)
+
+// ErrNoRows is returned by Row.Scan when the query selected nothing, whichever driver ran it
+var ErrNoRows = errors.New("no rows in result set")
+
+// Querier runs statements. Placeholders are written $1, $2... for every dialect
+type Querier interface {
+	// Exec runs a statement and returns the number of rows it affected
+	Exec(ctx context.Context, query string, args ...interface{}) (int64, error)
+	Query(ctx context.Context, query string, args ...interface{}) (Rows, error)
+	QueryRow(ctx context.Context, query string, args ...interface{}) Row
+}
+
+// DB is a connection pool the SQL repositories run on. It's implemented for pgx and database/sql so the same
+// repositories serve Postgres and SQLite
+type DB interface {
+	Querier
+	Dialect() domain.Dialect
+	Begin(ctx context.Context) (Tx, error)
+	Close()
+}
+
+type Tx interface {
+	Querier
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+type Rows interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+	Close()
+}
+
+type Row interface {
+	Scan(dest ...interface{}) error
+}
+
+// Open connects to the database the url points at. postgres:// and postgresql:// urls are served by pgx, sqlite://
+// urls by an embedded SQLite database at the path that follows the scheme
+func Open(ctx context.Context, databaseURL string) (DB, error) {
+	switch {
+	case strings.HasPrefix(databaseURL, "postgres://"), strings.HasPrefix(databaseURL, "postgresql://"):
+		return OpenPostgres(ctx, databaseURL)
+	case strings.HasPrefix(databaseURL, "sqlite://"):
+		return OpenSQLite(strings.TrimPrefix(databaseURL, "sqlite://"))
+	}
+	return nil, fmt.Errorf("unsupported database url %q", databaseURL)
+}
+
+// IsForeignKeyViolation reports whether err was caused by a foreign key constraint, on either dialect
+func IsForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23503"
+	}
+	return strings.Contains(err.Error(), "FOREIGN KEY constraint failed")
+}
+
+// IsUniqueViolation reports whether err was caused by a unique or primary key constraint, on either dialect
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505"
+	}
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
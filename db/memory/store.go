@@ -0,0 +1,258 @@
+package memory
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/shopspring/decimal"
+	"sync"
+	"time"
+)
+
+// Store holds the tables of the in-memory backend. Repositories built on the same Store see each other's rows, which
+// is how the foreign key from inventory to item is enforced.
+type Store struct {
+	mu             sync.RWMutex
+	Items          map[string]domain.Item
+	Inventory      map[string]domain.InventoryItem
+	Movements      []domain.StockMovement
+	Locations      map[string]domain.Location
+	Transfers      map[string]domain.Transfer
+	Reservations   map[string]domain.Reservation
+	Suppliers      map[string]domain.Supplier
+	PurchaseOrders map[string]domain.PurchaseOrder
+	// SupplierItems are keyed by SupplierItemKey
+	SupplierItems map[string]domain.SupplierItem
+	SalesOrders   map[string]domain.SalesOrder
+	Returns       map[string]domain.Return
+	// ReorderPolicies and their StockAlerts are removed together by DeleteReorderPolicies
+	ReorderPolicies map[string]domain.ReorderPolicy
+	StockAlerts     map[string]domain.StockAlert
+	Lots            map[string]domain.Lot
+	// Serials and their SerialEvents go along with their item
+	Serials      map[string]domain.Serial
+	SerialEvents []domain.SerialEvent
+	CycleCounts  map[string]domain.CycleCount
+	// CostLayers and CostConsumptions outlive their item, like the ledger
+	CostLayers       map[string]domain.CostLayer
+	CostConsumptions []domain.CostConsumption
+	// CurrencyRates are keyed by currency
+	CurrencyRates map[string]domain.CurrencyRate
+}
+
+// NewStore returns a store holding the default and quarantine locations and the rate of the default currency only, as
+// a freshly migrated database would
+func NewStore() *Store {
+	store := newStore()
+	now := time.Now()
+	store.Locations[domain.DefaultLocationID] = domain.Location{ID: domain.DefaultLocationID, Name: "Default",
+		Type: domain.LocationStorage, CreatedAt: now, UpdatedAt: now}
+	store.Locations[domain.QuarantineLocationID] = domain.Location{ID: domain.QuarantineLocationID,
+		Name: "Quarantine", Type: domain.LocationQuarantine, CreatedAt: now, UpdatedAt: now}
+	store.CurrencyRates[domain.DefaultCurrency] = domain.CurrencyRate{Currency: domain.DefaultCurrency,
+		Rate: decimal.NewFromInt(1), UpdatedAt: now}
+	return store
+}
+
+func newStore() *Store {
+	return &Store{
+		Items:           map[string]domain.Item{},
+		Inventory:       map[string]domain.InventoryItem{},
+		Locations:       map[string]domain.Location{},
+		Transfers:       map[string]domain.Transfer{},
+		Reservations:    map[string]domain.Reservation{},
+		Suppliers:       map[string]domain.Supplier{},
+		PurchaseOrders:  map[string]domain.PurchaseOrder{},
+		SupplierItems:   map[string]domain.SupplierItem{},
+		SalesOrders:     map[string]domain.SalesOrder{},
+		Returns:         map[string]domain.Return{},
+		ReorderPolicies: map[string]domain.ReorderPolicy{},
+		StockAlerts:     map[string]domain.StockAlert{},
+		Lots:            map[string]domain.Lot{},
+		Serials:         map[string]domain.Serial{},
+		CycleCounts:     map[string]domain.CycleCount{},
+		CostLayers:      map[string]domain.CostLayer{},
+		CurrencyRates:   map[string]domain.CurrencyRate{},
+	}
+}
+
+type txKey struct{}
+
+// Read locks the store for reading and returns the matching unlock. Within a transaction the store is already
+// locked, so both are no-ops
+func (s *Store) Read(ctx context.Context) func() {
+	if s.inTransaction(ctx) {
+		return func() {}
+	}
+	s.mu.RLock()
+	return s.mu.RUnlock
+}
+
+// Write locks the store for writing and returns the matching unlock. Within a transaction the store is already
+// locked, so both are no-ops
+func (s *Store) Write(ctx context.Context) func() {
+	if s.inTransaction(ctx) {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+func (s *Store) inTransaction(ctx context.Context) bool {
+	store, ok := ctx.Value(txKey{}).(*Store)
+	return ok && store == s
+}
+
+// WithinTransaction holds the write lock while fn runs and restores the tables as they were if it fails
+func (s *Store) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.inTransaction(ctx) {
+		return fn(ctx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.clone()
+	if err := fn(context.WithValue(ctx, txKey{}, s)); err != nil {
+		s.restore(snapshot)
+		return err
+	}
+	return nil
+}
+
+func (s *Store) clone() *Store {
+	clone := newStore()
+	for id, item := range s.Items {
+		clone.Items[id] = item
+	}
+	for id, inventory := range s.Inventory {
+		clone.Inventory[id] = inventory
+	}
+	clone.Movements = append(clone.Movements, s.Movements...)
+	for id, location := range s.Locations {
+		clone.Locations[id] = location
+	}
+	for id, transfer := range s.Transfers {
+		clone.Transfers[id] = transfer
+	}
+	for id, reservation := range s.Reservations {
+		clone.Reservations[id] = reservation
+	}
+	for id, supplier := range s.Suppliers {
+		clone.Suppliers[id] = supplier
+	}
+	for id, order := range s.PurchaseOrders {
+		clone.PurchaseOrders[id] = order
+	}
+	for key, supplierItem := range s.SupplierItems {
+		clone.SupplierItems[key] = supplierItem
+	}
+	for id, order := range s.SalesOrders {
+		clone.SalesOrders[id] = order
+	}
+	for id, customerReturn := range s.Returns {
+		clone.Returns[id] = customerReturn
+	}
+	for id, policy := range s.ReorderPolicies {
+		clone.ReorderPolicies[id] = policy
+	}
+	for id, alert := range s.StockAlerts {
+		clone.StockAlerts[id] = alert
+	}
+	for id, lot := range s.Lots {
+		clone.Lots[id] = lot
+	}
+	for id, serial := range s.Serials {
+		clone.Serials[id] = serial
+	}
+	clone.SerialEvents = append(clone.SerialEvents, s.SerialEvents...)
+	for id, cycleCount := range s.CycleCounts {
+		clone.CycleCounts[id] = cycleCount
+	}
+	for id, layer := range s.CostLayers {
+		clone.CostLayers[id] = layer
+	}
+	clone.CostConsumptions = append(clone.CostConsumptions, s.CostConsumptions...)
+	for currency, rate := range s.CurrencyRates {
+		clone.CurrencyRates[currency] = rate
+	}
+	return clone
+}
+
+func (s *Store) restore(snapshot *Store) {
+	s.Items = snapshot.Items
+	s.Inventory = snapshot.Inventory
+	s.Movements = snapshot.Movements
+	s.Locations = snapshot.Locations
+	s.Transfers = snapshot.Transfers
+	s.Reservations = snapshot.Reservations
+	s.Suppliers = snapshot.Suppliers
+	s.PurchaseOrders = snapshot.PurchaseOrders
+	s.SupplierItems = snapshot.SupplierItems
+	s.SalesOrders = snapshot.SalesOrders
+	s.Returns = snapshot.Returns
+	s.ReorderPolicies = snapshot.ReorderPolicies
+	s.StockAlerts = snapshot.StockAlerts
+	s.Lots = snapshot.Lots
+	s.Serials = snapshot.Serials
+	s.SerialEvents = snapshot.SerialEvents
+	s.CycleCounts = snapshot.CycleCounts
+	s.CostLayers = snapshot.CostLayers
+	s.CostConsumptions = snapshot.CostConsumptions
+	s.CurrencyRates = snapshot.CurrencyRates
+}
+
+// DeleteReorderPolicies removes the reorder policies match picks along with their alerts, as the database cascades
+// the delete. The store must be locked for writing
+func (s *Store) DeleteReorderPolicies(match func(policy domain.ReorderPolicy) bool) {
+	for id, policy := range s.ReorderPolicies {
+		if !match(policy) {
+			continue
+		}
+		for alertID, alert := range s.StockAlerts {
+			if alert.PolicyID == id {
+				delete(s.StockAlerts, alertID)
+			}
+		}
+		delete(s.ReorderPolicies, id)
+	}
+}
+
+// DeleteSerials removes the serials match picks along with their events, as the database cascades the delete. The
+// store must be locked for writing
+func (s *Store) DeleteSerials(match func(serial domain.Serial) bool) {
+	deleted := map[string]bool{}
+	for id, serial := range s.Serials {
+		if match(serial) {
+			deleted[id] = true
+			delete(s.Serials, id)
+		}
+	}
+	if len(deleted) == 0 {
+		return
+	}
+
+	var events []domain.SerialEvent
+	for _, event := range s.SerialEvents {
+		if !deleted[event.SerialID] {
+			events = append(events, event)
+		}
+	}
+	s.SerialEvents = events
+}
+
+// SupplierItemKey is the key of the link between a supplier and an item in SupplierItems
+func SupplierItemKey(supplierID string, itemID string) string {
+	return supplierID + "/" + itemID
+}
+
+// Page returns the bounds of the page of n rows starting at offset, clamped to the rows available
+func Page(n, count, offset int) (int, int) {
+	if offset > n {
+		offset = n
+	}
+	end := offset + count
+	if count < 0 || end > n {
+		end = n
+	}
+	return offset, end
+}
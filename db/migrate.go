@@ -0,0 +1,234 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"github.com/nuzurie/shopify/domain"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// migrations holds one directory of scripts per dialect, named <version>_<name>.up.sql and <version>_<name>.down.sql
+//
+//go:embed migrations
+var migrations embed.FS
+
+var migrationName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+const (
+	createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version int PRIMARY KEY,
+	name text NOT NULL,
+	applied_at timestamp NOT NULL
+	)`
+	postgresMigrationsTableExists = `SELECT to_regclass('schema_migrations') IS NOT NULL`
+	sqliteMigrationsTableExists   = `SELECT COUNT(*) > 0 FROM sqlite_master WHERE type='table' AND name='schema_migrations'`
+	getSchemaVersion              = `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`
+	// serializes migrations run concurrently against the same Postgres database
+	lockMigrations  = `SELECT pg_advisory_xact_lock(7245019)`
+	saveMigration   = `INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`
+	deleteMigration = `DELETE FROM schema_migrations WHERE version=$1`
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrations returns the migrations known to this build for dialect, oldest first
+func Migrations(dialect domain.Dialect) ([]Migration, error) {
+	dir := path.Join("migrations", dialect.String())
+	entries, err := fs.ReadDir(migrations, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		script, err := fs.ReadFile(migrations, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(script)
+		} else {
+			migration.Down = string(script)
+		}
+	}
+
+	var all []Migration
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down script", migration.Version, migration.Name)
+		}
+		all = append(all, *migration)
+	}
+	sort.Slice(all, func(a, b int) bool {
+		return all[a].Version < all[b].Version
+	})
+	return all, nil
+}
+
+// LatestVersion is the schema version this build expects
+func LatestVersion(dialect domain.Dialect) (int, error) {
+	all, err := Migrations(dialect)
+	if err != nil || len(all) == 0 {
+		return 0, err
+	}
+	return all[len(all)-1].Version, nil
+}
+
+// SchemaVersion returns the version of the last migration applied to database, 0 if none ever was
+func SchemaVersion(ctx context.Context, database Querier, dialect domain.Dialect) (int, error) {
+	exists := postgresMigrationsTableExists
+	if dialect == domain.SQLite {
+		exists = sqliteMigrationsTableExists
+	}
+
+	var tableExists bool
+	if err := database.QueryRow(ctx, exists).Scan(&tableExists); err != nil {
+		return 0, err
+	}
+	if !tableExists {
+		return 0, nil
+	}
+
+	var version int
+	err := database.QueryRow(ctx, getSchemaVersion).Scan(&version)
+	return version, err
+}
+
+// CheckSchema refuses databases that aren't exactly at the version this build expects, either because migrations
+// are pending or because a newer build already migrated them
+func CheckSchema(ctx context.Context, database DB) error {
+	latest, err := LatestVersion(database.Dialect())
+	if err != nil {
+		return err
+	}
+	version, err := SchemaVersion(ctx, database, database.Dialect())
+	if err != nil {
+		return err
+	}
+
+	if version < latest {
+		return fmt.Errorf("database schema is at version %d but this build needs %d, run `shopify migrate up`",
+			version, latest)
+	}
+	if version > latest {
+		return fmt.Errorf("database schema is at version %d, newer than the %d this build knows about", version, latest)
+	}
+	return nil
+}
+
+// MigrateUp applies pending migrations up to and including target, or all of them if target is 0. Each migration
+// runs in its own transaction along with its schema_migrations row
+func MigrateUp(ctx context.Context, database DB, target int) error {
+	all, err := Migrations(database.Dialect())
+	if err != nil {
+		return err
+	}
+	if _, err = database.Exec(ctx, createMigrationsTable); err != nil {
+		return err
+	}
+
+	for _, migration := range all {
+		if target > 0 && migration.Version > target {
+			break
+		}
+		err = inMigrationTx(ctx, database, func(tx Tx, version int) error {
+			if version >= migration.Version {
+				return nil
+			}
+			if _, err := tx.Exec(ctx, migration.Up); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			_, err := tx.Exec(ctx, saveMigration, migration.Version, migration.Name, time.Now())
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateDown reverts the last steps migrations applied
+func MigrateDown(ctx context.Context, database DB, steps int) error {
+	all, err := Migrations(database.Dialect())
+	if err != nil {
+		return err
+	}
+	byVersion := map[int]Migration{}
+	for _, migration := range all {
+		byVersion[migration.Version] = migration
+	}
+	if _, err = database.Exec(ctx, createMigrationsTable); err != nil {
+		return err
+	}
+
+	for ; steps > 0; steps-- {
+		done := false
+		err = inMigrationTx(ctx, database, func(tx Tx, version int) error {
+			if version == 0 {
+				done = true
+				return nil
+			}
+			migration, ok := byVersion[version]
+			if !ok {
+				return fmt.Errorf("schema version %d is unknown to this build, it can't be reverted", version)
+			}
+			if _, err := tx.Exec(ctx, migration.Down); err != nil {
+				return fmt.Errorf("reverting migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			_, err := tx.Exec(ctx, deleteMigration, migration.Version)
+			return err
+		})
+		if err != nil || done {
+			return err
+		}
+	}
+	return nil
+}
+
+// inMigrationTx runs fn with the schema version read inside the transaction, after taking the migration lock
+func inMigrationTx(ctx context.Context, database DB, fn func(tx Tx, version int) error) error {
+	tx, err := database.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if database.Dialect() == domain.Postgres {
+		if _, err = tx.Exec(ctx, lockMigrations); err != nil {
+			return err
+		}
+	}
+	var version int
+	if err = tx.QueryRow(ctx, getSchemaVersion).Scan(&version); err != nil {
+		return err
+	}
+	if err = fn(tx, version); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestMigrationRoundTrip migrates a fresh SQLite database all the way up, down and up again, checking every step
+// leaves the schema the version it claims
+func TestMigrationRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	database, err := OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	latest, err := LatestVersion(database.Dialect())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if CheckSchema(ctx, database) == nil {
+		t.Fatal("CheckSchema accepted an empty database")
+	}
+
+	schemas := map[int]string{0: schema(t, database)}
+	for version := 1; version <= latest; version++ {
+		if err = MigrateUp(ctx, database, version); err != nil {
+			t.Fatal(err)
+		}
+		schemas[version] = schema(t, database)
+	}
+	if err = CheckSchema(ctx, database); err != nil {
+		t.Fatalf("CheckSchema refused a migrated database: %v", err)
+	}
+
+	steps := []struct {
+		name    string
+		migrate func() error
+		version int
+	}{
+		{"up to date", func() error { return MigrateUp(ctx, database, 0) }, latest},
+		{"one down", func() error { return MigrateDown(ctx, database, 1) }, latest - 1},
+		{"one up", func() error { return MigrateUp(ctx, database, latest) }, latest},
+		{"all the way down", func() error { return MigrateDown(ctx, database, latest) }, 0},
+		{"past the bottom", func() error { return MigrateDown(ctx, database, 1) }, 0},
+		{"all the way up", func() error { return MigrateUp(ctx, database, 0) }, latest},
+	}
+	for _, step := range steps {
+		if err = step.migrate(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		version, err := SchemaVersion(ctx, database, database.Dialect())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != step.version {
+			t.Errorf("%s: at version %d, want %d", step.name, version, step.version)
+		}
+		if got := schema(t, database); got != schemas[step.version] {
+			t.Errorf("%s: schema\n%s\nwant the schema of version %d\n%s", step.name, got, step.version,
+				schemas[step.version])
+		}
+	}
+}
+
+// schema describes the tables of an SQLite database, their columns, indexes and foreign keys, leaving out the table
+// migrations are tracked in. It compares what the tables are rather than how they were written, which a rebuilt table
+// changes
+func schema(t *testing.T, database DB) string {
+	tables := query(t, database, `SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name <> 'schema_migrations' ORDER BY name`)
+
+	var description []string
+	for _, table := range tables {
+		description = append(description, "table "+table)
+		for _, pragma := range []string{
+			`SELECT name || ' ' || type || ' ' || "notnull" || ' ' || COALESCE(dflt_value, '') || ' ' || pk
+				FROM pragma_table_info($1) ORDER BY name`,
+			`SELECT 'index ' || name || ' ' || "unique" || ' ' || origin || ' ' || partial
+				FROM pragma_index_list($1) WHERE origin <> 'pk' ORDER BY name`,
+			`SELECT 'references ' || "table" || ' ' || "from" || ' ' || COALESCE("to", '') || ' ' || on_delete
+				FROM pragma_foreign_key_list($1) ORDER BY "table", "from"`,
+		} {
+			description = append(description, query(t, database, pragma, table)...)
+		}
+	}
+	for _, index := range query(t, database, `SELECT name FROM sqlite_master
+		WHERE type = 'index' AND tbl_name <> 'schema_migrations' ORDER BY name`) {
+		description = append(description, "index "+index+": "+
+			strings.Join(query(t, database, `SELECT COALESCE(name, '') FROM pragma_index_info($1)`, index), ", "))
+	}
+	return strings.Join(description, "\n")
+}
+
+func query(t *testing.T, database DB, query string, args ...interface{}) []string {
+	rows, err := database.Query(context.Background(), query, args...)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err = rows.Scan(&value); err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, value)
+	}
+	return values
+}
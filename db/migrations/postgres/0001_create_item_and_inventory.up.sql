@@ -0,0 +1,18 @@
+-- IF NOT EXISTS adopts databases whose tables were created before migrations existed
+CREATE TABLE IF NOT EXISTS item (
+    id text PRIMARY KEY,
+    name text NOT NULL,
+    description text,
+    price float,
+    created_at timestamp without time zone,
+    updated_at timestamp without time zone
+);
+
+CREATE TABLE IF NOT EXISTS inventory (
+    id text PRIMARY KEY,
+    quantity int,
+    updated_at timestamp without time zone,
+    item_id text,
+    FOREIGN KEY (item_id)
+        REFERENCES item(id)
+);
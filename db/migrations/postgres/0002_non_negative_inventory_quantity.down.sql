@@ -0,0 +1 @@
+ALTER TABLE inventory DROP CONSTRAINT inventory_quantity_non_negative;
@@ -0,0 +1,2 @@
+DROP TABLE inventory_movement;
+DROP FUNCTION inventory_movement_immutable();
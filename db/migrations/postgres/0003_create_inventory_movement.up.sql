@@ -0,0 +1,21 @@
+CREATE TABLE inventory_movement (
+    id text PRIMARY KEY,
+    inventory_id text NOT NULL,
+    delta int NOT NULL,
+    quantity int NOT NULL,
+    reason text NOT NULL,
+    actor text NOT NULL,
+    correlation_id text NOT NULL,
+    created_at timestamp without time zone NOT NULL
+);
+CREATE INDEX inventory_movement_inventory_id_created_at ON inventory_movement (inventory_id, created_at);
+
+-- the ledger is append-only, corrections are recorded as new movements
+CREATE FUNCTION inventory_movement_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'inventory_movement is append-only';
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER inventory_movement_immutable
+    BEFORE UPDATE OR DELETE ON inventory_movement
+    FOR EACH ROW EXECUTE PROCEDURE inventory_movement_immutable();
@@ -0,0 +1,3 @@
+-- bumped on every write so clients can detect concurrent changes through ETags
+ALTER TABLE item ADD COLUMN version int NOT NULL DEFAULT 1;
+ALTER TABLE inventory ADD COLUMN version int NOT NULL DEFAULT 1;
@@ -0,0 +1,4 @@
+-- stock kept elsewhere than the default location can't be represented without locations
+DELETE FROM inventory WHERE location_id <> 'default';
+ALTER TABLE inventory DROP COLUMN location_id;
+DROP TABLE location;
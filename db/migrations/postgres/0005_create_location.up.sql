@@ -0,0 +1,13 @@
+CREATE TABLE location (
+    id text PRIMARY KEY,
+    name text NOT NULL,
+    address text NOT NULL DEFAULT '',
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL
+);
+INSERT INTO location (id, name, created_at, updated_at) VALUES ('default', 'Default', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+
+-- existing stock is assumed to be kept at the default location
+ALTER TABLE inventory ADD COLUMN location_id text NOT NULL DEFAULT 'default' REFERENCES location(id);
+ALTER TABLE inventory ALTER COLUMN location_id DROP DEFAULT;
+ALTER TABLE inventory ADD CONSTRAINT inventory_item_id_location_id_key UNIQUE (item_id, location_id);
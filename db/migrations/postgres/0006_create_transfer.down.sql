@@ -0,0 +1,2 @@
+DROP TABLE transfer_line;
+DROP TABLE transfer;
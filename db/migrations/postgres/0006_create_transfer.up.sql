@@ -0,0 +1,22 @@
+CREATE TABLE transfer (
+    id text PRIMARY KEY,
+    source_location_id text NOT NULL REFERENCES location(id),
+    destination_location_id text NOT NULL REFERENCES location(id),
+    status text NOT NULL,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL,
+    shipped_at timestamp without time zone,
+    received_at timestamp without time zone
+);
+CREATE INDEX transfer_status_created_at ON transfer (status, created_at);
+
+-- like the ledger, lines outlive the items they moved
+CREATE TABLE transfer_line (
+    transfer_id text NOT NULL REFERENCES transfer(id),
+    item_id text NOT NULL,
+    quantity int NOT NULL CHECK (quantity > 0),
+    shipped int NOT NULL DEFAULT 0,
+    received int NOT NULL DEFAULT 0,
+    discrepancy int NOT NULL DEFAULT 0,
+    PRIMARY KEY (transfer_id, item_id)
+);
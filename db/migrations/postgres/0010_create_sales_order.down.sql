@@ -0,0 +1,6 @@
+DROP TABLE sales_order_line;
+DROP TABLE sales_order;
+
+-- allocations expire straight away rather than hold stock forever
+UPDATE reservation SET expires_at = updated_at WHERE expires_at IS NULL;
+ALTER TABLE reservation ALTER COLUMN expires_at SET NOT NULL;
@@ -0,0 +1,24 @@
+-- sales orders allocate stock through reservations that last until the order ships or is cancelled
+ALTER TABLE reservation ALTER COLUMN expires_at DROP NOT NULL;
+
+CREATE TABLE sales_order (
+    id text PRIMARY KEY,
+    customer text NOT NULL,
+    location_id text NOT NULL REFERENCES location(id),
+    status text NOT NULL,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL,
+    shipped_at timestamp without time zone,
+    cancelled_at timestamp without time zone
+);
+CREATE INDEX sales_order_status_created_at ON sales_order (status, created_at);
+CREATE INDEX sales_order_customer ON sales_order (customer);
+
+-- like the ledger, lines outlive the items they sold
+CREATE TABLE sales_order_line (
+    sales_order_id text NOT NULL REFERENCES sales_order(id),
+    item_id text NOT NULL,
+    quantity int NOT NULL CHECK (quantity > 0),
+    reservation_id text NOT NULL REFERENCES reservation(id),
+    PRIMARY KEY (sales_order_id, item_id)
+);
@@ -0,0 +1,23 @@
+-- quarantined stock is on hand but can't be sold
+ALTER TABLE location ADD COLUMN type text NOT NULL DEFAULT 'storage';
+INSERT INTO location (id, name, type, created_at, updated_at)
+VALUES ('quarantine', 'Quarantine', 'quarantine', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+ON CONFLICT (id) DO UPDATE SET type='quarantine';
+
+CREATE TABLE customer_return (
+    id text PRIMARY KEY,
+    item_id text NOT NULL,
+    sales_order_id text REFERENCES sales_order(id),
+    quantity int NOT NULL CHECK (quantity > 0),
+    reason text NOT NULL,
+    condition text NOT NULL,
+    status text NOT NULL,
+    location_id text REFERENCES location(id),
+    notes text NOT NULL DEFAULT '',
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL,
+    inspected_at timestamp without time zone
+);
+CREATE INDEX customer_return_status_created_at ON customer_return (status, created_at);
+CREATE INDEX customer_return_sales_order_id ON customer_return (sales_order_id);
+CREATE INDEX customer_return_status_inspected_at ON customer_return (status, inspected_at);
@@ -0,0 +1,35 @@
+-- policies go along with the item or location they watch, and alerts with their policy
+CREATE TABLE reorder_policy (
+    id text PRIMARY KEY,
+    item_id text NOT NULL REFERENCES item(id) ON DELETE CASCADE,
+    location_id text REFERENCES location(id) ON DELETE CASCADE,
+    reorder_point int NOT NULL CHECK (reorder_point >= 0),
+    reorder_quantity int NOT NULL CHECK (reorder_quantity > 0),
+    draft_purchase_order boolean NOT NULL DEFAULT false,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL
+);
+-- an item has one policy per location, and one for its stock over all locations
+CREATE UNIQUE INDEX reorder_policy_item_id_location_id ON reorder_policy (item_id, location_id)
+    WHERE location_id IS NOT NULL;
+CREATE UNIQUE INDEX reorder_policy_item_id ON reorder_policy (item_id) WHERE location_id IS NULL;
+CREATE INDEX reorder_policy_location_id ON reorder_policy (location_id);
+
+CREATE TABLE stock_alert (
+    id text PRIMARY KEY,
+    policy_id text NOT NULL REFERENCES reorder_policy(id) ON DELETE CASCADE,
+    item_id text NOT NULL,
+    location_id text,
+    reorder_point int NOT NULL,
+    available int NOT NULL,
+    status text NOT NULL,
+    purchase_order_id text REFERENCES purchase_order(id),
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL,
+    acknowledged_at timestamp without time zone,
+    resolved_at timestamp without time zone
+);
+-- a policy has at most one alert that isn't resolved
+CREATE UNIQUE INDEX stock_alert_unresolved ON stock_alert (policy_id) WHERE status <> 'resolved';
+CREATE INDEX stock_alert_status_created_at ON stock_alert (status, created_at);
+CREATE INDEX stock_alert_item_id ON stock_alert (item_id);
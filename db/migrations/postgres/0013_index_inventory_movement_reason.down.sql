@@ -0,0 +1 @@
+DROP INDEX inventory_movement_reason_created_at;
@@ -0,0 +1,3 @@
+DROP TABLE serial_event;
+DROP TABLE serial;
+ALTER TABLE item DROP COLUMN serialized;
@@ -0,0 +1,44 @@
+-- items pick the order their cost layers are consumed in
+ALTER TABLE item ADD COLUMN costing_method text NOT NULL DEFAULT 'fifo';
+-- the cost of goods of the units a movement moved, if it was costed
+ALTER TABLE inventory_movement ADD COLUMN cost numeric;
+
+-- like the ledger, cost layers and consumptions outlive the items they cost
+CREATE TABLE cost_layer (
+    id text PRIMARY KEY,
+    item_id text NOT NULL,
+    movement_id text NOT NULL DEFAULT '',
+    quantity int NOT NULL CHECK (quantity > 0),
+    unit_cost numeric NOT NULL CHECK (unit_cost >= 0),
+    remaining int NOT NULL CHECK (remaining >= 0),
+    remaining_value numeric NOT NULL,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL
+);
+CREATE INDEX cost_layer_item_id_created_at ON cost_layer (item_id, created_at);
+CREATE INDEX cost_layer_created_at ON cost_layer (created_at);
+
+CREATE TABLE cost_consumption (
+    id text PRIMARY KEY,
+    item_id text NOT NULL,
+    layer_id text NOT NULL DEFAULT '',
+    movement_id text NOT NULL,
+    quantity int NOT NULL CHECK (quantity > 0),
+    cost numeric NOT NULL,
+    created_at timestamp without time zone NOT NULL
+);
+CREATE INDEX cost_consumption_created_at ON cost_consumption (created_at);
+
+-- the stock already owned, in transit included, opens with a layer per item. What it cost is unknown, so it's carried
+-- at nothing
+INSERT INTO cost_layer (id, item_id, movement_id, quantity, unit_cost, remaining, remaining_value, created_at,
+    updated_at)
+SELECT 'opening-' || item_id, item_id, '', SUM(quantity), 0, SUM(quantity), 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+FROM (
+    SELECT item_id, quantity FROM inventory
+    UNION ALL
+    SELECT transfer_line.item_id, transfer_line.shipped - transfer_line.received
+    FROM transfer_line JOIN transfer ON transfer.id = transfer_line.transfer_id
+    WHERE transfer.status IN ('shipped', 'partially_received')
+) stock
+GROUP BY item_id HAVING SUM(quantity) > 0;
@@ -0,0 +1,6 @@
+DROP TABLE currency_rate;
+DROP TABLE item_price;
+ALTER TABLE item DROP COLUMN currency;
+ALTER TABLE item ALTER COLUMN price DROP NOT NULL;
+ALTER TABLE item ALTER COLUMN price DROP DEFAULT;
+ALTER TABLE item ALTER COLUMN price TYPE float USING price::float;
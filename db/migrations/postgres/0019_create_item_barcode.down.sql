@@ -0,0 +1,4 @@
+DROP TABLE item_barcode;
+ALTER TABLE item DROP COLUMN base_unit;
+DROP INDEX item_sku;
+ALTER TABLE item DROP COLUMN sku;
@@ -0,0 +1,13 @@
+-- the merchant's own code for an item, unique among the items that have one
+ALTER TABLE item ADD COLUMN sku text NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX item_sku ON item (sku) WHERE sku <> '';
+-- the unit quantities of the item are kept in
+ALTER TABLE item ADD COLUMN base_unit text NOT NULL DEFAULT 'each';
+
+-- the codes an item is labelled with. A code identifies one item only
+CREATE TABLE item_barcode (
+    code text PRIMARY KEY,
+    item_id text NOT NULL REFERENCES item (id) ON DELETE CASCADE,
+    symbology text NOT NULL
+);
+CREATE INDEX item_barcode_item_id ON item_barcode (item_id);
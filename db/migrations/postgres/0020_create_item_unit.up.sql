@@ -0,0 +1,7 @@
+-- the other units an item is counted in, and how many of its base unit each one holds
+CREATE TABLE item_unit (
+    item_id text NOT NULL REFERENCES item (id) ON DELETE CASCADE,
+    unit text NOT NULL,
+    factor integer NOT NULL CHECK (factor > 0),
+    PRIMARY KEY (item_id, unit)
+);
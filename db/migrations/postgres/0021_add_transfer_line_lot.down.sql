@@ -0,0 +1 @@
+ALTER TABLE transfer_line DROP COLUMN lot_id;
@@ -0,0 +1,2 @@
+-- the lot a line ships from at the source, and arrives in at the destination, if any
+ALTER TABLE transfer_line ADD COLUMN lot_id text NOT NULL DEFAULT '';
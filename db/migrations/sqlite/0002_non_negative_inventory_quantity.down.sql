@@ -0,0 +1,11 @@
+CREATE TABLE inventory_old (
+    id text PRIMARY KEY,
+    quantity int,
+    updated_at timestamp,
+    item_id text,
+    FOREIGN KEY (item_id)
+        REFERENCES item(id)
+);
+INSERT INTO inventory_old (id, quantity, updated_at, item_id) SELECT id, quantity, updated_at, item_id FROM inventory;
+DROP TABLE inventory;
+ALTER TABLE inventory_old RENAME TO inventory;
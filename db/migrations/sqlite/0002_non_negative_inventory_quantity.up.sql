@@ -0,0 +1,12 @@
+-- SQLite can't add a constraint to an existing table, so the table is rebuilt
+CREATE TABLE inventory_new (
+    id text PRIMARY KEY,
+    quantity int CHECK (quantity >= 0),
+    updated_at timestamp,
+    item_id text,
+    FOREIGN KEY (item_id)
+        REFERENCES item(id)
+);
+INSERT INTO inventory_new (id, quantity, updated_at, item_id) SELECT id, quantity, updated_at, item_id FROM inventory;
+DROP TABLE inventory;
+ALTER TABLE inventory_new RENAME TO inventory;
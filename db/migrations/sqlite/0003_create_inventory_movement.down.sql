@@ -0,0 +1 @@
+DROP TABLE inventory_movement;
@@ -0,0 +1,21 @@
+CREATE TABLE inventory_movement (
+    id text PRIMARY KEY,
+    inventory_id text NOT NULL,
+    delta int NOT NULL,
+    quantity int NOT NULL,
+    reason text NOT NULL,
+    actor text NOT NULL,
+    correlation_id text NOT NULL,
+    created_at timestamp NOT NULL
+);
+CREATE INDEX inventory_movement_inventory_id_created_at ON inventory_movement (inventory_id, created_at);
+
+-- the ledger is append-only, corrections are recorded as new movements
+CREATE TRIGGER inventory_movement_no_update BEFORE UPDATE ON inventory_movement
+BEGIN
+    SELECT RAISE(ABORT, 'inventory_movement is append-only');
+END;
+CREATE TRIGGER inventory_movement_no_delete BEFORE DELETE ON inventory_movement
+BEGIN
+    SELECT RAISE(ABORT, 'inventory_movement is append-only');
+END;
@@ -0,0 +1,2 @@
+ALTER TABLE inventory DROP COLUMN version;
+ALTER TABLE item DROP COLUMN version;
@@ -0,0 +1,15 @@
+-- stock kept elsewhere than the default location can't be represented without locations
+CREATE TABLE inventory_old (
+    id text PRIMARY KEY,
+    quantity int CHECK (quantity >= 0),
+    updated_at timestamp,
+    item_id text,
+    version int NOT NULL DEFAULT 1,
+    FOREIGN KEY (item_id)
+        REFERENCES item(id)
+);
+INSERT INTO inventory_old (id, quantity, updated_at, item_id, version)
+SELECT id, quantity, updated_at, item_id, version FROM inventory WHERE location_id = 'default';
+DROP TABLE inventory;
+ALTER TABLE inventory_old RENAME TO inventory;
+DROP TABLE location;
@@ -0,0 +1,28 @@
+CREATE TABLE location (
+    id text PRIMARY KEY,
+    name text NOT NULL,
+    address text NOT NULL DEFAULT '',
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL
+);
+INSERT INTO location (id, name, created_at, updated_at) VALUES ('default', 'Default', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+
+-- SQLite can't add a column that references another table, so the table is rebuilt. Existing stock is assumed to be
+-- kept at the default location
+CREATE TABLE inventory_new (
+    id text PRIMARY KEY,
+    quantity int CHECK (quantity >= 0),
+    updated_at timestamp,
+    item_id text,
+    version int NOT NULL DEFAULT 1,
+    location_id text NOT NULL,
+    FOREIGN KEY (item_id)
+        REFERENCES item(id),
+    FOREIGN KEY (location_id)
+        REFERENCES location(id),
+    UNIQUE (item_id, location_id)
+);
+INSERT INTO inventory_new (id, quantity, updated_at, item_id, version, location_id)
+SELECT id, quantity, updated_at, item_id, version, 'default' FROM inventory;
+DROP TABLE inventory;
+ALTER TABLE inventory_new RENAME TO inventory;
@@ -0,0 +1,16 @@
+-- like the ledger, reservations outlive the inventory they held
+CREATE TABLE reservation (
+    id text PRIMARY KEY,
+    item_id text NOT NULL,
+    location_id text NOT NULL,
+    inventory_id text NOT NULL,
+    quantity int NOT NULL CHECK (quantity > 0),
+    owner text NOT NULL,
+    status text NOT NULL,
+    expires_at timestamp NOT NULL,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL
+);
+CREATE INDEX reservation_status_inventory_id ON reservation (status, inventory_id);
+CREATE INDEX reservation_status_expires_at ON reservation (status, expires_at);
+CREATE INDEX reservation_owner ON reservation (owner);
@@ -0,0 +1,5 @@
+DROP TABLE purchase_order_receipt_line;
+DROP TABLE purchase_order_receipt;
+DROP TABLE purchase_order_line;
+DROP TABLE purchase_order;
+DROP TABLE supplier;
@@ -0,0 +1,45 @@
+CREATE TABLE supplier (
+    id text PRIMARY KEY,
+    name text NOT NULL,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL
+);
+
+CREATE TABLE purchase_order (
+    id text PRIMARY KEY,
+    supplier_id text NOT NULL REFERENCES supplier(id),
+    location_id text NOT NULL REFERENCES location(id),
+    status text NOT NULL,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    sent_at timestamp,
+    received_at timestamp,
+    closed_at timestamp
+);
+CREATE INDEX purchase_order_status_created_at ON purchase_order (status, created_at);
+CREATE INDEX purchase_order_supplier_id ON purchase_order (supplier_id);
+
+-- like the ledger, lines and receipts outlive the items they bought
+CREATE TABLE purchase_order_line (
+    purchase_order_id text NOT NULL REFERENCES purchase_order(id),
+    item_id text NOT NULL,
+    quantity int NOT NULL CHECK (quantity > 0),
+    unit_cost text NOT NULL DEFAULT '0' CHECK (CAST(unit_cost AS REAL) >= 0),
+    received int NOT NULL DEFAULT 0,
+    PRIMARY KEY (purchase_order_id, item_id)
+);
+
+CREATE TABLE purchase_order_receipt (
+    id text PRIMARY KEY,
+    purchase_order_id text NOT NULL REFERENCES purchase_order(id),
+    actor text NOT NULL,
+    received_at timestamp NOT NULL
+);
+CREATE INDEX purchase_order_receipt_purchase_order_id ON purchase_order_receipt (purchase_order_id);
+
+CREATE TABLE purchase_order_receipt_line (
+    receipt_id text NOT NULL REFERENCES purchase_order_receipt(id),
+    item_id text NOT NULL,
+    quantity int NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (receipt_id, item_id)
+);
@@ -0,0 +1,8 @@
+DROP TABLE supplier_item;
+
+ALTER TABLE supplier DROP COLUMN currency;
+ALTER TABLE supplier DROP COLUMN minimum_order_quantity;
+ALTER TABLE supplier DROP COLUMN lead_time_days;
+ALTER TABLE supplier DROP COLUMN phone;
+ALTER TABLE supplier DROP COLUMN email;
+ALTER TABLE supplier DROP COLUMN contact_name;
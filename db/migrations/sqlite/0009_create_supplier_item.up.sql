@@ -0,0 +1,21 @@
+ALTER TABLE supplier ADD COLUMN contact_name text NOT NULL DEFAULT '';
+ALTER TABLE supplier ADD COLUMN email text NOT NULL DEFAULT '';
+ALTER TABLE supplier ADD COLUMN phone text NOT NULL DEFAULT '';
+ALTER TABLE supplier ADD COLUMN lead_time_days int NOT NULL DEFAULT 0 CHECK (lead_time_days >= 0);
+ALTER TABLE supplier ADD COLUMN minimum_order_quantity int NOT NULL DEFAULT 1 CHECK (minimum_order_quantity > 0);
+ALTER TABLE supplier ADD COLUMN currency text NOT NULL DEFAULT 'USD';
+
+-- links go along with the supplier or item they belong to
+CREATE TABLE supplier_item (
+    supplier_id text NOT NULL REFERENCES supplier(id) ON DELETE CASCADE,
+    item_id text NOT NULL REFERENCES item(id) ON DELETE CASCADE,
+    supplier_sku text NOT NULL DEFAULT '',
+    unit_cost text NOT NULL DEFAULT '0' CHECK (CAST(unit_cost AS REAL) >= 0),
+    preferred boolean NOT NULL DEFAULT false,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    PRIMARY KEY (supplier_id, item_id)
+);
+CREATE INDEX supplier_item_item_id ON supplier_item (item_id);
+-- an item has at most one preferred supplier
+CREATE UNIQUE INDEX supplier_item_preferred ON supplier_item (item_id) WHERE preferred;
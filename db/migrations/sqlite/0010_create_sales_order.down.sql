@@ -0,0 +1,23 @@
+DROP TABLE sales_order_line;
+DROP TABLE sales_order;
+
+-- allocations expire straight away rather than hold stock forever
+CREATE TABLE reservation_new (
+    id text PRIMARY KEY,
+    item_id text NOT NULL,
+    location_id text NOT NULL,
+    inventory_id text NOT NULL,
+    quantity int NOT NULL CHECK (quantity > 0),
+    owner text NOT NULL,
+    status text NOT NULL,
+    expires_at timestamp NOT NULL,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL
+);
+INSERT INTO reservation_new SELECT id, item_id, location_id, inventory_id, quantity, owner, status,
+    COALESCE(expires_at, updated_at), created_at, updated_at FROM reservation;
+DROP TABLE reservation;
+ALTER TABLE reservation_new RENAME TO reservation;
+CREATE INDEX reservation_status_inventory_id ON reservation (status, inventory_id);
+CREATE INDEX reservation_status_expires_at ON reservation (status, expires_at);
+CREATE INDEX reservation_owner ON reservation (owner);
@@ -0,0 +1,43 @@
+-- sales orders allocate stock through reservations that last until the order ships or is cancelled. SQLite can't drop
+-- a NOT NULL constraint, so the table is rebuilt
+CREATE TABLE reservation_new (
+    id text PRIMARY KEY,
+    item_id text NOT NULL,
+    location_id text NOT NULL,
+    inventory_id text NOT NULL,
+    quantity int NOT NULL CHECK (quantity > 0),
+    owner text NOT NULL,
+    status text NOT NULL,
+    expires_at timestamp,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL
+);
+INSERT INTO reservation_new SELECT id, item_id, location_id, inventory_id, quantity, owner, status, expires_at,
+    created_at, updated_at FROM reservation;
+DROP TABLE reservation;
+ALTER TABLE reservation_new RENAME TO reservation;
+CREATE INDEX reservation_status_inventory_id ON reservation (status, inventory_id);
+CREATE INDEX reservation_status_expires_at ON reservation (status, expires_at);
+CREATE INDEX reservation_owner ON reservation (owner);
+
+CREATE TABLE sales_order (
+    id text PRIMARY KEY,
+    customer text NOT NULL,
+    location_id text NOT NULL REFERENCES location(id),
+    status text NOT NULL,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    shipped_at timestamp,
+    cancelled_at timestamp
+);
+CREATE INDEX sales_order_status_created_at ON sales_order (status, created_at);
+CREATE INDEX sales_order_customer ON sales_order (customer);
+
+-- like the ledger, lines outlive the items they sold
+CREATE TABLE sales_order_line (
+    sales_order_id text NOT NULL REFERENCES sales_order(id),
+    item_id text NOT NULL,
+    quantity int NOT NULL CHECK (quantity > 0),
+    reservation_id text NOT NULL REFERENCES reservation(id),
+    PRIMARY KEY (sales_order_id, item_id)
+);
@@ -0,0 +1,9 @@
+DROP TABLE customer_return;
+
+-- the quarantine location stays if anything still references it
+DELETE FROM location WHERE id='quarantine'
+    AND NOT EXISTS (SELECT 1 FROM inventory WHERE location_id='quarantine')
+    AND NOT EXISTS (SELECT 1 FROM transfer WHERE source_location_id='quarantine' OR destination_location_id='quarantine')
+    AND NOT EXISTS (SELECT 1 FROM purchase_order WHERE location_id='quarantine')
+    AND NOT EXISTS (SELECT 1 FROM sales_order WHERE location_id='quarantine');
+ALTER TABLE location DROP COLUMN type;
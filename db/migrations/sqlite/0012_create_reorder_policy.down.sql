@@ -0,0 +1,2 @@
+DROP TABLE stock_alert;
+DROP TABLE reorder_policy;
@@ -0,0 +1,2 @@
+-- forecasts read the sales of a period
+CREATE INDEX inventory_movement_reason_created_at ON inventory_movement (reason, created_at);
@@ -0,0 +1,16 @@
+-- lots go along with their inventory. Emptied lots are kept so recalls can still be traced
+CREATE TABLE lot (
+    id text PRIMARY KEY,
+    inventory_id text NOT NULL REFERENCES inventory(id) ON DELETE CASCADE,
+    lot_number text NOT NULL,
+    quantity int NOT NULL CHECK (quantity >= 0),
+    manufactured_at timestamp,
+    expires_at timestamp,
+    blocked boolean NOT NULL DEFAULT false,
+    block_reason text NOT NULL DEFAULT '',
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL
+);
+CREATE UNIQUE INDEX lot_inventory_id_lot_number ON lot (inventory_id, lot_number);
+CREATE INDEX lot_lot_number ON lot (lot_number);
+CREATE INDEX lot_expires_at ON lot (expires_at);
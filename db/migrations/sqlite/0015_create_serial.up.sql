@@ -0,0 +1,27 @@
+-- serialized items keep one serial per unit
+ALTER TABLE item ADD COLUMN serialized boolean NOT NULL DEFAULT false;
+
+CREATE TABLE serial (
+    id text PRIMARY KEY,
+    item_id text NOT NULL REFERENCES item(id) ON DELETE CASCADE,
+    serial_number text NOT NULL,
+    status text NOT NULL,
+    location_id text,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL
+);
+CREATE UNIQUE INDEX serial_item_id_serial_number ON serial (item_id, serial_number);
+CREATE INDEX serial_serial_number ON serial (serial_number);
+CREATE INDEX serial_item_id_location_id ON serial (item_id, location_id);
+
+CREATE TABLE serial_event (
+    id text PRIMARY KEY,
+    serial_id text NOT NULL REFERENCES serial(id) ON DELETE CASCADE,
+    status text NOT NULL,
+    location_id text NOT NULL,
+    reason text NOT NULL,
+    movement_id text NOT NULL,
+    actor text NOT NULL DEFAULT '',
+    created_at timestamp NOT NULL
+);
+CREATE INDEX serial_event_serial_id_created_at ON serial_event (serial_id, created_at);
@@ -0,0 +1,2 @@
+DROP TABLE cycle_count_line;
+DROP TABLE cycle_count;
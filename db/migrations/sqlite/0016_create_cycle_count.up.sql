@@ -0,0 +1,30 @@
+-- cycle counts record counted stock and the variances their approval posted to the ledger
+CREATE TABLE cycle_count (
+    id text PRIMARY KEY,
+    location_id text NOT NULL DEFAULT '',
+    abc_class text NOT NULL DEFAULT '',
+    blind boolean NOT NULL,
+    status text NOT NULL,
+    created_by text NOT NULL DEFAULT '',
+    approved_by text NOT NULL DEFAULT '',
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    approved_at timestamp,
+    cancelled_at timestamp
+);
+CREATE INDEX cycle_count_status_created_at ON cycle_count (status, created_at);
+CREATE INDEX cycle_count_approved_at ON cycle_count (approved_at);
+
+-- like the ledger, lines outlive the inventory and items they counted
+CREATE TABLE cycle_count_line (
+    cycle_count_id text NOT NULL REFERENCES cycle_count(id) ON DELETE CASCADE,
+    inventory_id text NOT NULL,
+    item_id text NOT NULL,
+    location_id text NOT NULL,
+    expected int NOT NULL,
+    counted int CHECK (counted >= 0),
+    counted_by text NOT NULL DEFAULT '',
+    counted_at timestamp,
+    PRIMARY KEY (cycle_count_id, inventory_id)
+);
+CREATE INDEX cycle_count_line_item_id ON cycle_count_line (item_id);
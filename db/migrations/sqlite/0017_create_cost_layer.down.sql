@@ -0,0 +1,4 @@
+DROP TABLE cost_consumption;
+DROP TABLE cost_layer;
+ALTER TABLE inventory_movement DROP COLUMN cost;
+ALTER TABLE item DROP COLUMN costing_method;
@@ -0,0 +1,3 @@
+DROP TABLE currency_rate;
+DROP TABLE item_price;
+ALTER TABLE item DROP COLUMN currency;
@@ -0,0 +1,21 @@
+-- prices are exact amounts in the currency of the item. SQLite has no decimal type, so they keep being stored as REAL,
+-- which holds amounts of up to 15 significant digits exactly
+UPDATE item SET price = 0 WHERE price IS NULL;
+ALTER TABLE item ADD COLUMN currency text NOT NULL DEFAULT 'USD';
+
+-- the price list of an item, in currencies other than its own
+CREATE TABLE item_price (
+    item_id text NOT NULL REFERENCES item (id) ON DELETE CASCADE,
+    currency text NOT NULL,
+    amount numeric NOT NULL CHECK (amount >= 0),
+    PRIMARY KEY (item_id, currency)
+);
+CREATE INDEX item_price_currency_amount ON item_price (currency, amount);
+
+-- what one unit of a currency is worth in USD, which reports are given in
+CREATE TABLE currency_rate (
+    currency text PRIMARY KEY,
+    rate numeric NOT NULL CHECK (rate > 0),
+    updated_at timestamp NOT NULL
+);
+INSERT INTO currency_rate (currency, rate, updated_at) VALUES ('USD', 1, CURRENT_TIMESTAMP);
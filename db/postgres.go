@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/nuzurie/shopify/domain"
+)
+
+type postgres struct {
+	pool *pgxpool.Pool
+}
+
+func OpenPostgres(ctx context.Context, databaseURL string) (DB, error) {
+	pool, err := pgxpool.Connect(ctx, databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	return &contextual{DB: &postgres{pool: pool}}, nil
+}
+
+func (p *postgres) Dialect() domain.Dialect {
+	return domain.Postgres
+}
+
+func (p *postgres) Exec(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	tag, err := p.pool.Exec(ctx, query, args...)
+	return tag.RowsAffected(), err
+}
+
+func (p *postgres) Query(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	return p.pool.Query(ctx, query, args...)
+}
+
+func (p *postgres) QueryRow(ctx context.Context, query string, args ...interface{}) Row {
+	return postgresRow{row: p.pool.QueryRow(ctx, query, args...)}
+}
+
+func (p *postgres) Begin(ctx context.Context) (Tx, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return postgresTx{tx: tx}, nil
+}
+
+func (p *postgres) Close() {
+	p.pool.Close()
+}
+
+type postgresTx struct {
+	tx pgx.Tx
+}
+
+func (t postgresTx) Exec(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	tag, err := t.tx.Exec(ctx, query, args...)
+	return tag.RowsAffected(), err
+}
+
+func (t postgresTx) Query(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	return t.tx.Query(ctx, query, args...)
+}
+
+func (t postgresTx) QueryRow(ctx context.Context, query string, args ...interface{}) Row {
+	return postgresRow{row: t.tx.QueryRow(ctx, query, args...)}
+}
+
+func (t postgresTx) Commit(ctx context.Context) error {
+	return t.tx.Commit(ctx)
+}
+
+func (t postgresTx) Rollback(ctx context.Context) error {
+	return t.tx.Rollback(ctx)
+}
+
+type postgresRow struct {
+	row pgx.Row
+}
+
+func (r postgresRow) Scan(dest ...interface{}) error {
+	err := r.row.Scan(dest...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNoRows
+	}
+	return err
+}
@@ -0,0 +1,24 @@
+-- Sample entries for local runs. Apply with `shopify migrate seed db/seed.sql` once the schema is migrated
+INSERT INTO item (id, name, description, price, created_at, updated_at)
+VALUES ('abcdef', 'creative name 1', 'some keywords to search for', 1.99, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO item (id, name, description, price, created_at, updated_at)
+VALUES ('jdasoidjp', 'additional item', 'these items were added with sql script from docker file', 12.99, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO item (id, name, description, price, created_at, updated_at)
+VALUES ('apodad', 'creme brulee', 'dockerfile in backend repo db folder', 99.99, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO item (id, name, description, price, created_at, updated_at)
+VALUES ('oaifoad', 'expensive', 'some keywords to search for', 0.99, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO inventory (id, quantity, updated_at, item_id, location_id)
+VALUES ('asdad', 30, CURRENT_TIMESTAMP, 'apodad', 'default')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO inventory (id, quantity, updated_at, item_id, location_id)
+VALUES ('asawqe', 302, CURRENT_TIMESTAMP, 'jdasoidjp', 'default')
+ON CONFLICT DO NOTHING;
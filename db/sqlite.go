@@ -0,0 +1,158 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/nuzurie/shopify/domain"
+	"strings"
+	"time"
+
+	// registers the pure Go sqlite driver, so no cgo is needed
+	_ "modernc.org/sqlite"
+)
+
+type sqlite struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens the database file at path, or a private in-memory database for :memory:. Foreign keys are enforced
+// and a single connection is used, so writes are serialized the way a single node deployment expects
+func OpenSQLite(path string) (DB, error) {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	dsn := path + separator + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite"
+
+	database, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	database.SetMaxOpenConns(1)
+	database.SetConnMaxLifetime(0)
+	if err = database.Ping(); err != nil {
+		database.Close()
+		return nil, err
+	}
+	return &contextual{DB: &sqlite{db: database}}, nil
+}
+
+func (s *sqlite) Dialect() domain.Dialect {
+	return domain.SQLite
+}
+
+func (s *sqlite) Exec(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	return sqlExec(s.db.ExecContext(ctx, query, utc(args)...))
+}
+
+func (s *sqlite) Query(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	rows, err := s.db.QueryContext(ctx, query, utc(args)...)
+	if err != nil {
+		return nil, err
+	}
+	return sqlRows{rows: rows}, nil
+}
+
+func (s *sqlite) QueryRow(ctx context.Context, query string, args ...interface{}) Row {
+	return sqlRow{row: s.db.QueryRowContext(ctx, query, utc(args)...)}
+}
+
+func (s *sqlite) Begin(ctx context.Context) (Tx, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return sqlTx{tx: tx}, nil
+}
+
+func (s *sqlite) Close() {
+	s.db.Close()
+}
+
+type sqlTx struct {
+	tx *sql.Tx
+}
+
+func (t sqlTx) Exec(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	return sqlExec(t.tx.ExecContext(ctx, query, utc(args)...))
+}
+
+func (t sqlTx) Query(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	rows, err := t.tx.QueryContext(ctx, query, utc(args)...)
+	if err != nil {
+		return nil, err
+	}
+	return sqlRows{rows: rows}, nil
+}
+
+func (t sqlTx) QueryRow(ctx context.Context, query string, args ...interface{}) Row {
+	return sqlRow{row: t.tx.QueryRowContext(ctx, query, utc(args)...)}
+}
+
+func (t sqlTx) Commit(ctx context.Context) error {
+	return t.tx.Commit()
+}
+
+// Rollback is a no-op once the transaction is committed, like it is for pgx
+func (t sqlTx) Rollback(ctx context.Context) error {
+	err := t.tx.Rollback()
+	if errors.Is(err, sql.ErrTxDone) {
+		return nil
+	}
+	return err
+}
+
+type sqlRows struct {
+	rows *sql.Rows
+}
+
+func (r sqlRows) Next() bool {
+	return r.rows.Next()
+}
+
+func (r sqlRows) Scan(dest ...interface{}) error {
+	return r.rows.Scan(dest...)
+}
+
+func (r sqlRows) Err() error {
+	return r.rows.Err()
+}
+
+func (r sqlRows) Close() {
+	r.rows.Close()
+}
+
+type sqlRow struct {
+	row *sql.Row
+}
+
+func (r sqlRow) Scan(dest ...interface{}) error {
+	err := r.row.Scan(dest...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNoRows
+	}
+	return err
+}
+
+// utc converts time arguments to UTC. SQLite stores times as text, which only sorts chronologically in a single zone
+func utc(args []interface{}) []interface{} {
+	for index, arg := range args {
+		switch t := arg.(type) {
+		case time.Time:
+			args[index] = t.UTC()
+		case *time.Time:
+			if t != nil {
+				args[index] = t.UTC()
+			}
+		}
+	}
+	return args
+}
+
+func sqlExec(result sql.Result, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/domain"
+)
+
+type txKey struct{}
+
+// txState is what a transaction stores in the context of the calls made within it
+type txState struct {
+	db DB
+	tx Tx
+}
+
+// contextual routes calls made within a transaction started by its Transactor through that transaction, so
+// repositories don't need to know whether they run in one
+type contextual struct {
+	DB
+}
+
+func (c *contextual) querier(ctx context.Context) Querier {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok && state.db == c {
+		return state.tx
+	}
+	return c.DB
+}
+
+func (c *contextual) Exec(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	return c.querier(ctx).Exec(ctx, query, args...)
+}
+
+func (c *contextual) Query(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	return c.querier(ctx).Query(ctx, query, args...)
+}
+
+func (c *contextual) QueryRow(ctx context.Context, query string, args ...interface{}) Row {
+	return c.querier(ctx).QueryRow(ctx, query, args...)
+}
+
+// Begin within a transaction returns a Tx whose Commit and Rollback are left to the outer transaction
+func (c *contextual) Begin(ctx context.Context) (Tx, error) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok && state.db == c {
+		return nestedTx{Querier: state.tx}, nil
+	}
+	return c.DB.Begin(ctx)
+}
+
+type nestedTx struct {
+	Querier
+}
+
+func (n nestedTx) Commit(ctx context.Context) error {
+	return nil
+}
+
+func (n nestedTx) Rollback(ctx context.Context) error {
+	return nil
+}
+
+type transactor struct {
+	db *contextual
+}
+
+// NewTransactor runs transactions on database, which must have been returned by Open
+func NewTransactor(database DB) domain.Transactor {
+	return &transactor{db: database.(*contextual)}
+}
+
+func (t *transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok && state.db == t.db {
+		return fn(ctx)
+	}
+
+	tx, err := t.db.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err = fn(context.WithValue(ctx, txKey{}, &txState{db: t.db, tx: tx})); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,105 @@
+package domain
+
+// Symbology is the barcode standard a code is encoded in
+type Symbology string
+
+const (
+	// SymbologyEAN13 codes are 13 digits, the last a check digit
+	SymbologyEAN13 Symbology = "ean13"
+	// SymbologyUPCA codes are 12 digits, the last a check digit
+	SymbologyUPCA Symbology = "upc_a"
+	// SymbologyCode128 codes are up to 48 printable ASCII characters. Their check symbol is verified by the scanner and
+	// isn't part of the code it reads
+	SymbologyCode128 Symbology = "code128"
+	// maxCode128Length is the most characters a Code 128 code fits on a label
+	maxCode128Length = 48
+)
+
+func (s Symbology) IsValid() bool {
+	switch s {
+	case SymbologyEAN13, SymbologyUPCA, SymbologyCode128:
+		return true
+	}
+	return false
+}
+
+// Barcode is a code an item is labelled with, which scanners read to look the item up
+type Barcode struct {
+	Code string `json:"code"`
+	// Symbology is inferred from the code if left empty: 13 digits are EAN-13, 12 digits UPC-A and anything else
+	// Code 128
+	Symbology Symbology `json:"symbology"`
+}
+
+// InferSymbology picks the symbology a code is most likely encoded in
+func InferSymbology(code string) Symbology {
+	switch {
+	case len(code) == 13 && isDigits(code):
+		return SymbologyEAN13
+	case len(code) == 12 && isDigits(code):
+		return SymbologyUPCA
+	}
+	return SymbologyCode128
+}
+
+// IsValid checks the code against its symbology, the check digit included
+func (b Barcode) IsValid() bool {
+	switch b.Symbology {
+	case SymbologyEAN13:
+		return len(b.Code) == 13 && isDigits(b.Code) && hasCheckDigit(b.Code)
+	case SymbologyUPCA:
+		return len(b.Code) == 12 && isDigits(b.Code) && hasCheckDigit(b.Code)
+	case SymbologyCode128:
+		if b.Code == "" || len(b.Code) > maxCode128Length {
+			return false
+		}
+		for index := 0; index < len(b.Code); index++ {
+			if b.Code[index] < ' ' || b.Code[index] > '~' {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// EquivalentCodes returns the codes a scanner may read off the same label as code. A UPC-A code is the EAN-13 code
+// with a leading zero dropped, and some scanners report one as the other
+func EquivalentCodes(code string) []string {
+	codes := []string{code}
+	if isDigits(code) {
+		switch {
+		case len(code) == 12:
+			codes = append(codes, "0"+code)
+		case len(code) == 13 && code[0] == '0':
+			codes = append(codes, code[1:])
+		}
+	}
+	return codes
+}
+
+// hasCheckDigit reports whether the last digit of an EAN or UPC code is its check digit. Counting from the check
+// digit, digits at even positions weigh 3 and the others 1, and the weighted sum must be a multiple of 10
+func hasCheckDigit(code string) bool {
+	sum := 0
+	for index := 0; index < len(code); index++ {
+		digit := int(code[len(code)-1-index] - '0')
+		if index%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
+
+func isDigits(code string) bool {
+	if code == "" {
+		return false
+	}
+	for index := 0; index < len(code); index++ {
+		if code[index] < '0' || code[index] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBarcodeIsValid(t *testing.T) {
+	cases := []struct {
+		name    string
+		barcode Barcode
+		want    bool
+	}{
+		{"EAN-13", Barcode{Code: "4006381333931", Symbology: SymbologyEAN13}, true},
+		{"EAN-13 with a wrong check digit", Barcode{Code: "4006381333932", Symbology: SymbologyEAN13}, false},
+		{"EAN-13 one digit short", Barcode{Code: "400638133393", Symbology: SymbologyEAN13}, false},
+		{"UPC-A", Barcode{Code: "036000291452", Symbology: SymbologyUPCA}, true},
+		{"UPC-A with a wrong check digit", Barcode{Code: "036000291453", Symbology: SymbologyUPCA}, false},
+		{"UPC-A with a letter", Barcode{Code: "03600029145A", Symbology: SymbologyUPCA}, false},
+		{"Code 128", Barcode{Code: "HAT-1 blue", Symbology: SymbologyCode128}, true},
+		{"Code 128 with a tab", Barcode{Code: "HAT\t1", Symbology: SymbologyCode128}, false},
+		{"Code 128 too long", Barcode{Code: strings.Repeat("A", 49), Symbology: SymbologyCode128}, false},
+		{"empty Code 128", Barcode{Symbology: SymbologyCode128}, false},
+		{"unknown symbology", Barcode{Code: "4006381333931", Symbology: "qr"}, false},
+	}
+	for _, c := range cases {
+		if got := c.barcode.IsValid(); got != c.want {
+			t.Errorf("%s: IsValid = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestInferSymbology(t *testing.T) {
+	cases := map[string]Symbology{
+		"4006381333931": SymbologyEAN13,
+		"036000291452":  SymbologyUPCA,
+		"36000291452":   SymbologyCode128,
+		"HAT-1":         SymbologyCode128,
+	}
+	for code, want := range cases {
+		if got := InferSymbology(code); got != want {
+			t.Errorf("InferSymbology(%s) = %s, want %s", code, got, want)
+		}
+	}
+}
+
+func TestEquivalentCodes(t *testing.T) {
+	cases := map[string][]string{
+		"036000291452":  {"036000291452", "0036000291452"},
+		"0036000291452": {"0036000291452", "036000291452"},
+		"4006381333931": {"4006381333931"},
+		"HAT-1":         {"HAT-1"},
+	}
+	for code, want := range cases {
+		if got := EquivalentCodes(code); strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("EquivalentCodes(%s) = %v, want %v", code, got, want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// DefaultCurrency is the currency of prices, and of suppliers, that don't name one. Conversion rates are quoted against
+// it, so it is also the currency reports are given in
+const DefaultCurrency = "USD"
+
+// currencyPlaces are the decimal places of the minor unit of the ISO 4217 currencies that don't have 2
+var currencyPlaces = map[string]int32{
+	"BHD": 3, "BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "IQD": 3, "ISK": 0, "JOD": 3, "JPY": 0, "KMF": 0, "KRW": 0,
+	"KWD": 3, "LYD": 3, "OMR": 3, "PYG": 0, "RWF": 0, "TND": 3, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0,
+	"XOF": 0, "XPF": 0,
+}
+
+// CurrencyPlaces is the number of decimal places of the minor unit of a currency, 2 for most
+func CurrencyPlaces(currency string) int32 {
+	if places, ok := currencyPlaces[currency]; ok {
+		return places
+	}
+	return 2
+}
+
+// IsCurrencyCode reports whether code has the shape of an ISO 4217 code, three upper case letters
+func IsCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, letter := range code {
+		if letter < 'A' || letter > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// ItemPrice is the price of an item in a currency other than its own, from the price list of the item
+type ItemPrice struct {
+	Currency string          `json:"currency"`
+	Amount   decimal.Decimal `json:"amount"`
+}
+
+// CurrencyRate is what one unit of Currency is worth in DefaultCurrency
+type CurrencyRate struct {
+	Currency  string          `json:"currency"`
+	Rate      decimal.Decimal `json:"rate"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// Rates are the conversion rates of currencies, keyed by currency
+type Rates map[string]decimal.Decimal
+
+func NewRates(rates []CurrencyRate) Rates {
+	converted := Rates{DefaultCurrency: decimal.NewFromInt(1)}
+	for _, rate := range rates {
+		converted[rate.Currency] = rate.Rate
+	}
+	return converted
+}
+
+// Convert converts amount from one currency to another, rounded to the minor unit of the currency converted to. It
+// reports false if either currency has no rate
+func (r Rates) Convert(amount decimal.Decimal, from string, to string) (decimal.Decimal, bool) {
+	return r.convert(amount, from, to, CurrencyPlaces(to))
+}
+
+// ConvertCost is Convert for costs, which are rounded to CostPlaces rather than the minor unit of the currency
+func (r Rates) ConvertCost(cost decimal.Decimal, from string, to string) (decimal.Decimal, bool) {
+	return r.convert(cost, from, to, CostPlaces)
+}
+
+func (r Rates) convert(amount decimal.Decimal, from string, to string, places int32) (decimal.Decimal, bool) {
+	if from == to {
+		return amount, true
+	}
+	fromRate, ok := r[from]
+	if !ok {
+		return decimal.Decimal{}, false
+	}
+	toRate, ok := r[to]
+	if !ok {
+		return decimal.Decimal{}, false
+	}
+	return amount.Mul(fromRate).DivRound(toRate, places), true
+}
+
+type CurrencyRateUseCase interface {
+	// GetAll returns the rates by currency, that of DefaultCurrency included
+	GetAll(ctx context.Context) ([]CurrencyRate, error)
+	GetOne(ctx context.Context, currency string) (*CurrencyRate, error)
+	// Set creates or overwrites the rate of a currency. The rate of DefaultCurrency is always 1
+	Set(ctx context.Context, rate *CurrencyRate) (*CurrencyRate, error)
+	Delete(ctx context.Context, currency string) error
+}
+
+type CurrencyRateRepository interface {
+	// GetAll returns the rates by currency
+	GetAll(ctx context.Context) ([]CurrencyRate, error)
+	GetOne(ctx context.Context, currency string) (*CurrencyRate, error)
+	// Save creates the rate of a currency or overwrites the one it has
+	Save(ctx context.Context, rate *CurrencyRate) (*CurrencyRate, error)
+	Delete(ctx context.Context, currency string) error
+}
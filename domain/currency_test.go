@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	// a euro is worth 1.1 dollars, a pound 1.25 and a yen 0.0067
+	rates := NewRates([]CurrencyRate{
+		{Currency: "EUR", Rate: decimal.RequireFromString("1.1")},
+		{Currency: "GBP", Rate: decimal.RequireFromString("1.25")},
+		{Currency: "JPY", Rate: decimal.RequireFromString("0.0067")},
+	})
+
+	cases := []struct {
+		name   string
+		amount string
+		from   string
+		to     string
+		want   string
+		// cost is what the amount converts to as a cost
+		cost string
+		ok   bool
+	}{
+		{"to the default currency", "10", "EUR", DefaultCurrency, "11", "11", true},
+		{"from the default currency", "11", DefaultCurrency, "EUR", "10", "10", true},
+		{"between other currencies", "10", "EUR", "GBP", "8.8", "8.8", true},
+		{"to the same currency", "4.99", "JPY", "JPY", "4.99", "4.99", true},
+		{"rounded to the cent", "1", DefaultCurrency, "EUR", "0.91", "0.909091", true},
+		{"to a currency without cents", "10", DefaultCurrency, "JPY", "1493", "1492.537313", true},
+		{"from a currency without a rate", "10", "CHF", DefaultCurrency, "", "", false},
+		{"to a currency without a rate", "10", DefaultCurrency, "CHF", "", "", false},
+	}
+	for _, c := range cases {
+		converted, ok := rates.Convert(decimal.RequireFromString(c.amount), c.from, c.to)
+		if ok != c.ok {
+			t.Errorf("%s: Convert reported %v, want %v", c.name, ok, c.ok)
+			continue
+		}
+		if ok && !converted.Equal(decimal.RequireFromString(c.want)) {
+			t.Errorf("%s: converted to %s, want %s", c.name, converted, c.want)
+		}
+		if cost, _ := rates.ConvertCost(decimal.RequireFromString(c.amount), c.from, c.to); ok &&
+			!cost.Equal(decimal.RequireFromString(c.cost)) {
+			t.Errorf("%s: converted to a cost of %s, want %s", c.name, cost, c.cost)
+		}
+	}
+}
+
+func TestPriceIn(t *testing.T) {
+	item := Item{Price: decimal.RequireFromString("4.99"), Currency: DefaultCurrency,
+		Prices: []ItemPrice{{Currency: "EUR", Amount: decimal.RequireFromString("4.5")}}}
+
+	cases := []struct {
+		currency string
+		want     string
+		ok       bool
+	}{
+		{DefaultCurrency, "4.99", true},
+		{"EUR", "4.5", true},
+		{"GBP", "", false},
+	}
+	for _, c := range cases {
+		price, ok := item.PriceIn(c.currency)
+		if ok != c.ok || (ok && !price.Equal(decimal.RequireFromString(c.want))) {
+			t.Errorf("PriceIn(%s) = %s, %v, want %s, %v", c.currency, price, ok, c.want, c.ok)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package domain
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"sort"
+	"time"
+)
+
+const (
+	// DefaultABCWindowDays is how many days of sales ABC classes are computed from unless told otherwise
+	DefaultABCWindowDays = 90
+	// abcClassAShare and abcClassBShare are the cumulative shares of the sales value items of class A, then B, make up
+	abcClassAShare = 0.8
+	abcClassBShare = 0.95
+)
+
+// ABCClass ranks items by the value they sold for. A items are the few making up most of the sales value, and are
+// counted most often, C items the many making up little of it
+type ABCClass string
+
+const (
+	ABCClassA ABCClass = "A"
+	ABCClassB ABCClass = "B"
+	ABCClassC ABCClass = "C"
+)
+
+func (c ABCClass) IsValid() bool {
+	switch c {
+	case ABCClassA, ABCClassB, ABCClassC:
+		return true
+	}
+	return false
+}
+
+// ItemClass is the ABC class of an item, along with the value it sold for that put it there
+type ItemClass struct {
+	ItemID string   `json:"item_id"`
+	Class  ABCClass `json:"class"`
+	// SalesValue is the units sold times the price of the item, in DefaultCurrency
+	SalesValue decimal.Decimal `json:"sales_value"`
+	// Share is the part of the sales value of every item the item and those ranked above it make up
+	Share float64 `json:"share"`
+}
+
+// ClassifyABC ranks items by their sales value, highest first. Items make up class A until they account for 80% of the
+// total value, then class B until 95%, and class C after. Items that sold nothing are class C
+func ClassifyABC(salesValues map[string]decimal.Decimal) []ItemClass {
+	classes := make([]ItemClass, 0, len(salesValues))
+	total := decimal.Zero
+	for itemID, value := range salesValues {
+		classes = append(classes, ItemClass{ItemID: itemID, SalesValue: value})
+		total = total.Add(value)
+	}
+	sort.Slice(classes, func(a, b int) bool {
+		if order := classes[a].SalesValue.Cmp(classes[b].SalesValue); order != 0 {
+			return order > 0
+		}
+		return classes[a].ItemID < classes[b].ItemID
+	})
+
+	cumulative := decimal.Zero
+	for index, class := range classes {
+		// the item that crosses a threshold still belongs to the class below it
+		var share float64
+		if total.Sign() > 0 {
+			share, _ = cumulative.Div(total).Float64()
+		}
+		cumulative = cumulative.Add(class.SalesValue)
+		switch {
+		case class.SalesValue.Sign() <= 0:
+			classes[index].Class = ABCClassC
+		case share < abcClassAShare:
+			classes[index].Class = ABCClassA
+		case share < abcClassBShare:
+			classes[index].Class = ABCClassB
+		default:
+			classes[index].Class = ABCClassC
+		}
+		if total.Sign() > 0 {
+			classes[index].Share, _ = cumulative.Div(total).Float64()
+		}
+	}
+	return classes
+}
+
+// CycleCountStatus is the stage a cycle count is at. Counts are open until they are approved, which posts their
+// variances, or cancelled
+type CycleCountStatus string
+
+const (
+	CycleCountOpen      CycleCountStatus = "open"
+	CycleCountApproved  CycleCountStatus = "approved"
+	CycleCountCancelled CycleCountStatus = "cancelled"
+)
+
+func (s CycleCountStatus) IsValid() bool {
+	switch s {
+	case CycleCountOpen, CycleCountApproved, CycleCountCancelled:
+		return true
+	}
+	return false
+}
+
+// CycleCount is a session counting the stock of a list of inventories. The list is generated from a location, an ABC
+// class and the filters of the inventory when the count is created
+type CycleCount struct {
+	ID string `json:"id"`
+	// LocationID and ABCClass narrow down what was counted. Empty ones matched everything
+	LocationID string   `json:"location_id,omitempty"`
+	ABCClass   ABCClass `json:"abc_class,omitempty"`
+	// Blind counts hide the expected quantities and variances until they are approved, so counters aren't swayed
+	Blind       bool             `json:"blind"`
+	Status      CycleCountStatus `json:"status"`
+	Lines       []CycleCountLine `json:"lines"`
+	CreatedBy   string           `json:"created_by"`
+	ApprovedBy  string           `json:"approved_by,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	ApprovedAt  *time.Time       `json:"approved_at"`
+	CancelledAt *time.Time       `json:"cancelled_at"`
+}
+
+// CycleCountLine is the count of one inventory
+type CycleCountLine struct {
+	InventoryID string `json:"inventory_id"`
+	ItemID      string `json:"item_id"`
+	LocationID  string `json:"location_id"`
+	// Expected is the quantity on hand when the line was last counted, or when the count was created until then
+	Expected *int `json:"expected,omitempty"`
+	Counted  *int `json:"counted"`
+	// Variance is Counted less Expected, once the line is counted
+	Variance  *int       `json:"variance,omitempty"`
+	CountedBy string     `json:"counted_by,omitempty"`
+	CountedAt *time.Time `json:"counted_at,omitempty"`
+}
+
+// CycleCountEntry records counted quantities, one line per inventory counted
+type CycleCountEntry struct {
+	Lines []CycleCountEntryLine `json:"lines"`
+}
+
+type CycleCountEntryLine struct {
+	InventoryID string `json:"inventory_id"`
+	Counted     int    `json:"counted"`
+}
+
+// CycleCountFilter narrows down cycle counts. Empty fields match everything
+type CycleCountFilter struct {
+	Status     CycleCountStatus
+	LocationID string
+}
+
+// VarianceGrouping is what count variances are summed by
+type VarianceGrouping string
+
+const (
+	VarianceByItem    VarianceGrouping = "item"
+	VarianceByCounter VarianceGrouping = "counter"
+)
+
+func (g VarianceGrouping) IsValid() bool {
+	return g == VarianceByItem || g == VarianceByCounter
+}
+
+// CountVariance sums the variances approved cycle counts posted, for an item or for a counter
+type CountVariance struct {
+	ItemID  string `json:"item_id,omitempty"`
+	Counter string `json:"counter,omitempty"`
+	// Lines is how many counted lines the variances are summed over
+	Lines int `json:"lines"`
+	// Variance is the net units found, negative when more went missing than turned up
+	Variance int `json:"variance"`
+	// Shrinkage is the units counted short and Overage the units counted over
+	Shrinkage int `json:"shrinkage"`
+	Overage   int `json:"overage"`
+	// Value is Variance at the current prices of the items, in DefaultCurrency. Items priced in a currency with no
+	// conversion rate are left out of it
+	Value decimal.Decimal `json:"value"`
+}
+
+type CycleCountUseCase interface {
+	// GetAll returns cycle counts newest first
+	GetAll(ctx context.Context, count int, offset int, filter CycleCountFilter) ([]CycleCount, error)
+	GetOne(ctx context.Context, id string) (*CycleCount, error)
+	// Create lists the inventory matching filter, at cycleCount.LocationID and of items of cycleCount.ABCClass if set,
+	// to be counted
+	Create(ctx context.Context, cycleCount *CycleCount, filter InventorySpecification) (*CycleCount, error)
+	// Count records the counted quantities of lines of an open count, replacing earlier counts of them
+	Count(ctx context.Context, id string, entry CycleCountEntry) (*CycleCount, error)
+	// Approve posts the variance of every line as a cycle count adjustment. Every line must have been counted
+	Approve(ctx context.Context, id string) (*CycleCount, error)
+	Cancel(ctx context.Context, id string) (*CycleCount, error)
+	// Classify ranks the items that sold over the last days by ABC class. Items left out are class C
+	Classify(ctx context.Context, days int) ([]ItemClass, error)
+	// Variances sums the variances of the counts approved between from and to, by item or by counter
+	Variances(ctx context.Context, from time.Time, to time.Time, by VarianceGrouping) ([]CountVariance, error)
+}
+
+type CycleCountRepository interface {
+	// GetAll returns cycle counts newest first
+	GetAll(ctx context.Context, count int, offset int, filter CycleCountFilter) ([]CycleCount, error)
+	GetOne(ctx context.Context, id string) (*CycleCount, error)
+	// GetOneForUpdate is GetOne that also locks the cycle count until the surrounding transaction ends
+	GetOneForUpdate(ctx context.Context, id string) (*CycleCount, error)
+	Save(ctx context.Context, cycleCount *CycleCount) (*CycleCount, error)
+	// Edit saves the status of the count and its lines
+	Edit(ctx context.Context, cycleCount *CycleCount) (*CycleCount, error)
+	// Variances sums the variances of the lines of counts approved between from and to, by item or by counter
+	Variances(ctx context.Context, from time.Time, to time.Time, by VarianceGrouping) ([]CountVariance, error)
+}
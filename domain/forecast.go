@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+const (
+	// DefaultForecastWindowDays is how many days of sales forecasts look back unless told otherwise
+	DefaultForecastWindowDays = 28
+	// DefaultForecastAlpha is the smoothing factor of forecasts unless told otherwise
+	DefaultForecastAlpha = 0.3
+	// DefaultCoverDays is how many days of demand suggested reorders cover unless told otherwise
+	DefaultCoverDays = 30
+)
+
+// ForecastOptions tune a forecast. Zero values take the defaults
+type ForecastOptions struct {
+	// WindowDays is how many days of sales consumption rates are computed from
+	WindowDays int
+	// Alpha is the smoothing factor of the exponential smoothing, above 0 and up to 1. Higher values follow the latest
+	// sales closer
+	Alpha float64
+	// CoverDays is how many days of demand a suggested reorder covers once it arrives
+	CoverDays int
+}
+
+// Sale is a number of units taken off hand as sold, as the movement ledger recorded it
+type Sale struct {
+	ItemID     string
+	LocationID string
+	Quantity   int
+	SoldAt     time.Time
+}
+
+// Forecast projects when the available stock of an item runs out at the rate it sold lately
+type Forecast struct {
+	ItemID string `json:"item_id"`
+	// LocationID is the location forecast, empty for the stock over all locations
+	LocationID string `json:"location_id,omitempty"`
+	Available  int    `json:"available"`
+	WindowDays int    `json:"window_days"`
+	// Sold is the units sold over the window
+	Sold int `json:"sold"`
+	// MovingAverage is the units sold per day over the window
+	MovingAverage float64 `json:"moving_average"`
+	// ExponentialSmoothing is the units sold per day, weighing recent days more. Stock is projected to run out at
+	// this rate
+	ExponentialSmoothing float64 `json:"exponential_smoothing"`
+	// DaysOfCover and StockoutDate are empty when the item doesn't sell
+	DaysOfCover  *float64   `json:"days_of_cover"`
+	StockoutDate *time.Time `json:"stockout_date"`
+	// SuggestedReorderQuantity covers the demand over the lead time of the preferred supplier and the cover days
+	// after, raised to the supplier's minimum order quantity
+	LeadTimeDays             int `json:"lead_time_days"`
+	SuggestedReorderQuantity int `json:"suggested_reorder_quantity"`
+}
+
+// ProjectForecast forecasts the available stock from the units sold on each day of the window, oldest first
+func ProjectForecast(dailySales []int, available int, alpha float64, now time.Time) Forecast {
+	forecast := Forecast{Available: available, WindowDays: len(dailySales)}
+	for day, sold := range dailySales {
+		forecast.Sold += sold
+		if day == 0 {
+			forecast.ExponentialSmoothing = float64(sold)
+			continue
+		}
+		forecast.ExponentialSmoothing = alpha*float64(sold) + (1-alpha)*forecast.ExponentialSmoothing
+	}
+	if len(dailySales) > 0 {
+		forecast.MovingAverage = float64(forecast.Sold) / float64(len(dailySales))
+	}
+
+	if forecast.ExponentialSmoothing > 0 {
+		daysOfCover := math.Max(float64(available), 0) / forecast.ExponentialSmoothing
+		stockoutDate := now.Add(time.Duration(daysOfCover * float64(24*time.Hour)))
+		forecast.DaysOfCover = &daysOfCover
+		forecast.StockoutDate = &stockoutDate
+	}
+	return forecast
+}
+
+// DailySales sums sales into the days of a window of the given length ending at now, oldest first. Days are the 24
+// hours before now, the 24 before that and so on
+func DailySales(sales []Sale, windowDays int, now time.Time) []int {
+	dailySales := make([]int, windowDays)
+	for _, sale := range sales {
+		daysAgo := int(now.Sub(sale.SoldAt) / (24 * time.Hour))
+		if sale.SoldAt.After(now) || daysAgo >= windowDays {
+			continue
+		}
+		dailySales[windowDays-1-daysAgo] += sale.Quantity
+	}
+	return dailySales
+}
+
+// StockoutBefore orders forecasts by stockout date, soonest first. Forecasts of items that don't sell come last
+func StockoutBefore(a *time.Time, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a != nil
+	}
+	return a.Before(*b)
+}
+
+type ForecastUseCase interface {
+	// GetForItem forecasts the stock of an item over all locations, or at one location if locationID isn't empty
+	GetForItem(ctx context.Context, itemID string, locationID string, options ForecastOptions) (*Forecast, error)
+	// GetAll forecasts the stock of every item over all locations, the soonest to run out first
+	GetAll(ctx context.Context, count int, offset int, options ForecastOptions) ([]Forecast, error)
+}
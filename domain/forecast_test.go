@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProjectForecast(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name          string
+		dailySales    []int
+		available     int
+		alpha         float64
+		movingAverage float64
+		smoothing     float64
+		// daysOfCover is -1 when the stock isn't projected to run out
+		daysOfCover float64
+	}{
+		{"no sales", []int{0, 0, 0}, 10, 0.3, 0, 0, -1},
+		{"steady sales", []int{2, 2, 2, 2}, 10, 0.3, 2, 2, 5},
+		{"recent days weigh more", []int{4, 0, 8}, 20, 0.5, 4, 5, 4},
+		{"old days weigh more", []int{8, 0, 2}, 20, 0.5, 10.0 / 3, 3, 20.0 / 3},
+		{"only the latest day", []int{8, 0, 4}, 20, 1, 4, 4, 5},
+		{"nothing available", []int{1, 1}, -3, 0.3, 1, 1, 0},
+		{"no window", nil, 10, 0.3, 0, 0, -1},
+	}
+	for _, c := range cases {
+		forecast := ProjectForecast(c.dailySales, c.available, c.alpha, now)
+		if !near(forecast.MovingAverage, c.movingAverage) || !near(forecast.ExponentialSmoothing, c.smoothing) {
+			t.Errorf("%s: moving average %v and smoothing %v, want %v and %v", c.name, forecast.MovingAverage,
+				forecast.ExponentialSmoothing, c.movingAverage, c.smoothing)
+		}
+		if c.daysOfCover < 0 {
+			if forecast.DaysOfCover != nil || forecast.StockoutDate != nil {
+				t.Errorf("%s: runs out in %v days, want it not to", c.name, *forecast.DaysOfCover)
+			}
+			continue
+		}
+		if forecast.DaysOfCover == nil {
+			t.Errorf("%s: doesn't run out, want it to in %v days", c.name, c.daysOfCover)
+			continue
+		}
+		if !near(*forecast.DaysOfCover, c.daysOfCover) {
+			t.Errorf("%s: days of cover %v, want %v", c.name, *forecast.DaysOfCover, c.daysOfCover)
+		}
+		stockout := now.Add(time.Duration(c.daysOfCover * float64(24*time.Hour)))
+		if forecast.StockoutDate == nil || !forecast.StockoutDate.Equal(stockout) {
+			t.Errorf("%s: runs out at %v, want %v", c.name, forecast.StockoutDate, stockout)
+		}
+	}
+}
+
+func TestDailySales(t *testing.T) {
+	now := time.Now()
+	sales := []Sale{
+		{Quantity: 1, SoldAt: now.Add(-time.Hour)},
+		{Quantity: 2, SoldAt: now.Add(-23 * time.Hour)},
+		{Quantity: 4, SoldAt: now.Add(-25 * time.Hour)},
+		{Quantity: 8, SoldAt: now.Add(-7*24*time.Hour + time.Minute)},
+		{Quantity: 16, SoldAt: now.Add(-7 * 24 * time.Hour)},
+		{Quantity: 32, SoldAt: now.Add(time.Hour)},
+	}
+	want := []int{8, 0, 0, 0, 0, 4, 3}
+	got := DailySales(sales, 7, now)
+	if len(got) != len(want) {
+		t.Fatalf("DailySales = %v, want %v", got, want)
+	}
+	for day := range want {
+		if got[day] != want[day] {
+			t.Fatalf("DailySales = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestStockoutBefore(t *testing.T) {
+	soon, late := time.Now(), time.Now().Add(time.Hour)
+	cases := []struct {
+		name string
+		a    *time.Time
+		b    *time.Time
+		want bool
+	}{
+		{"sooner", &soon, &late, true},
+		{"later", &late, &soon, false},
+		{"against one that doesn't sell", &late, nil, true},
+		{"one that doesn't sell", nil, &soon, false},
+		{"neither sells", nil, nil, false},
+	}
+	for _, c := range cases {
+		if got := StockoutBefore(c.a, c.b); got != c.want {
+			t.Errorf("%s: StockoutBefore = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+// near tells whether two rates agree to well within what a forecast reports
+func near(a float64, b float64) bool {
+	return a-b < 1e-9 && b-a < 1e-9
+}
@@ -2,29 +2,160 @@ package domain
 
 import (
 	"context"
+	"github.com/shopspring/decimal"
 	"time"
 )
 
+// InventoryItem is the stock of an item at one location. Quantity is what is on hand, of which Reserved is held by
+// reservations and Available can still be sold. Lots break down the part of it received in lots
 type InventoryItem struct {
-	ID        string    `json:"id"`
-	Item      Item      `json:"item"`
-	Quantity  int       `json:"quantity"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         string    `json:"id"`
+	Item       Item      `json:"item"`
+	LocationID string    `json:"location_id"`
+	Quantity   int       `json:"quantity"`
+	Reserved   int       `json:"reserved"`
+	Available  int       `json:"available"`
+	Lots       []Lot     `json:"lots,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	// Version is bumped on every change and serves as the ETag of the inventory
+	Version int `json:"version"`
+	// DaysOfCover and StockoutDate are projected when inventory is sorted by stockout, for inventory that sells
+	DaysOfCover  *float64   `json:"days_of_cover,omitempty"`
+	StockoutDate *time.Time `json:"stockout_date,omitempty"`
+	// InUnit expresses the quantities in another unit of the item when asked for
+	InUnit *UnitQuantities `json:"in_unit,omitempty"`
+}
+
+// InventorySort is the order inventory is listed in
+type InventorySort string
+
+const (
+	// InventorySortDefault lists inventory by ID
+	InventorySortDefault InventorySort = ""
+	// InventorySortStockout lists inventory by projected stockout date, soonest first
+	InventorySortStockout InventorySort = "stockout"
+)
+
+func (s InventorySort) IsValid() bool {
+	return s == InventorySortDefault || s == InventorySortStockout
+}
+
+// ItemStock is the stock of an item across all locations
+type ItemStock struct {
+	Item Item `json:"item"`
+	// Quantity, Reserved and Available are totals over all locations
+	Quantity  int             `json:"quantity"`
+	Reserved  int             `json:"reserved"`
+	Available int             `json:"available"`
+	Locations []LocationStock `json:"locations"`
+	// InUnit expresses the totals in another unit of the item when asked for, like the InUnit of every location
+	InUnit *UnitQuantities `json:"in_unit,omitempty"`
+}
+
+// LocationStock is the part of an ItemStock kept at one location
+type LocationStock struct {
+	InventoryID string          `json:"inventory_id"`
+	LocationID  string          `json:"location_id"`
+	Quantity    int             `json:"quantity"`
+	Reserved    int             `json:"reserved"`
+	Available   int             `json:"available"`
+	Lots        []Lot           `json:"lots,omitempty"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	Version     int             `json:"version"`
+	InUnit      *UnitQuantities `json:"in_unit,omitempty"`
+}
+
+// AdjustmentReason explains why a stock quantity changed
+type AdjustmentReason string
+
+const (
+	ReasonReceived   AdjustmentReason = "received"
+	ReasonSold       AdjustmentReason = "sold"
+	ReasonReturned   AdjustmentReason = "returned"
+	ReasonDamaged    AdjustmentReason = "damaged"
+	ReasonLost       AdjustmentReason = "lost"
+	ReasonCorrection AdjustmentReason = "correction"
+	// ReasonRemoved is recorded when an inventory is deleted along with its remaining stock
+	ReasonRemoved AdjustmentReason = "removed"
+	// ReasonTransferOut and ReasonTransferIn are recorded when a transfer ships and is received, and
+	// ReasonTransferCancelled when a cancelled transfer returns stock to its source
+	ReasonTransferOut       AdjustmentReason = "transfer_out"
+	ReasonTransferIn        AdjustmentReason = "transfer_in"
+	ReasonTransferCancelled AdjustmentReason = "transfer_cancelled"
+	// ReasonLostInTransit is recorded against no inventory when a transfer is received without some of what shipped
+	ReasonLostInTransit AdjustmentReason = "lost_in_transit"
+	// ReasonWrittenOff is recorded when returned units are written off on inspection, apart from sales
+	ReasonWrittenOff AdjustmentReason = "written_off"
+	// ReasonCycleCount is recorded when an approved cycle count posts the variance between counted and expected stock
+	ReasonCycleCount AdjustmentReason = "cycle_count"
+)
+
+// IsValid reports whether r can be given to an adjustment
+func (r AdjustmentReason) IsValid() bool {
+	switch r {
+	case ReasonReceived, ReasonSold, ReasonReturned, ReasonDamaged, ReasonLost, ReasonCorrection:
+		return true
+	}
+	return false
+}
+
+// IsTransfer reports whether r moves stock between locations, leaving what is owned unchanged
+func (r AdjustmentReason) IsTransfer() bool {
+	return r == ReasonTransferOut || r == ReasonTransferIn || r == ReasonTransferCancelled
+}
+
+// StockAdjustment changes a quantity by a signed delta rather than overwriting it, so concurrent adjustments compose
+type StockAdjustment struct {
+	// Delta is in Unit, the base unit of the item if empty
+	Delta  int              `json:"delta"`
+	Unit   string           `json:"unit,omitempty"`
+	Reason AdjustmentReason `json:"reason"`
+	// Serials names the units that arrive or leave, one per base unit moved, if the item is serialized
+	Serials []string `json:"serials,omitempty"`
+	// UnitCost is what each Unit arriving cost. Arrivals without one are costed at the average cost of the units of the
+	// item still on hand, or of its latest layer if none are
+	UnitCost *decimal.Decimal `json:"unit_cost,omitempty"`
 }
 
 type InventoryUseCase interface {
-	// GetInventoryForItem to test if an item has any stock in the inventory
-	GetInventoryForItem(ctx context.Context, itemID string) (*InventoryItem, error)
-	GetAll(ctx context.Context, count int, offset int, filter InventorySpecification) ([]InventoryItem, error)
+	// GetInventoryForItem to test if an item has any stock in the inventory, and where
+	GetInventoryForItem(ctx context.Context, itemID string) (*ItemStock, error)
+	// GetAll lists the inventory matching filter in the given order
+	GetAll(ctx context.Context, count int, offset int, filter InventorySpecification, sort InventorySort) ([]InventoryItem, error)
+	// UpdateInventoryItem creates the inventory of the item at item.LocationID, or overwrites it if it is still at
+	// item.Version. A Version of 0 overwrites whatever the version, and an empty LocationID means the default location
 	UpdateInventoryItem(ctx context.Context, item *InventoryItem) (*InventoryItem, error)
-	DeleteItem(ctx context.Context, id string) error
+	// AdjustQuantity applies adjustment to the inventory with the given id atomically, provided it is still at version.
+	// A version of 0 skips the check
+	AdjustQuantity(ctx context.Context, id string, adjustment StockAdjustment, version int) (*InventoryItem, error)
+	// MoveStock applies adjustment to the stock of the item at the location on behalf of another feature, recording it
+	// in the ledger. Inventory is created when stock first arrives at a location. Serialized items must name the serials
+	// that move, one per unit of the delta. It joins the transaction of ctx if there is one
+	MoveStock(ctx context.Context, itemID string, locationID string, adjustment StockAdjustment) (*InventoryItem, error)
+	// WriteOffInTransit writes off quantity units of the item that left a location in transit and never arrived, taking
+	// their cost off the books. No location holds them, so the movement names no inventory. It joins the transaction
+	// of ctx if there is one
+	WriteOffInTransit(ctx context.Context, itemID string, quantity int) error
+	// DeleteItem removes the inventory if it is still at version, or whatever its version if that is 0
+	DeleteItem(ctx context.Context, id string, version int) error
+	// GetMovements returns the ledger of quantity changes of an inventory, oldest first
+	GetMovements(ctx context.Context, id string, count int, offset int, filter MovementFilter) ([]StockMovement, error)
 }
 
 type InventoryRepository interface {
-	GetInventoryForItem(ctx context.Context, itemID string) (*InventoryItem, error)
+	// GetInventoryForItem returns the inventory of the item at every location holding it
+	GetInventoryForItem(ctx context.Context, itemID string) ([]InventoryItem, error)
+	GetInventoryAtLocation(ctx context.Context, itemID string, locationID string) (*InventoryItem, error)
 	GetAll(ctx context.Context, count int, offset int, filter InventorySpecification) ([]InventoryItem, error)
 	GetByID(ctx context.Context, id string) (*InventoryItem, error)
+	// GetByIDForUpdate is GetByID that also locks the inventory until the surrounding transaction ends
+	GetByIDForUpdate(ctx context.Context, id string) (*InventoryItem, error)
 	Save(ctx context.Context, item *InventoryItem) (*InventoryItem, error)
+	// Edit overwrites the inventory only if it is still at item.Version and bumps the version. It fails with a failed
+	// precondition otherwise
 	Edit(ctx context.Context, item *InventoryItem) (*InventoryItem, error)
+	// AdjustQuantity adds delta to the quantity in a single statement. It fails with a conflict if the quantity would
+	// become negative
+	AdjustQuantity(ctx context.Context, id string, delta int, updatedAt time.Time) (*InventoryItem, error)
 	DeleteItem(ctx context.Context, id string) error
 }
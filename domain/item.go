@@ -2,30 +2,80 @@ package domain
 
 import (
 	"context"
+	"github.com/shopspring/decimal"
 	"time"
 )
 
+// DefaultBaseUnit is the base unit of items that don't name one
+const DefaultBaseUnit = "each"
+
 type Item struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Price       float64   `json:"price"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// SKU is the merchant's own code for the item, unique among items if given
+	SKU string `json:"sku"`
+	// Barcodes are the codes the item is labelled with, each belonging to one item only
+	Barcodes []Barcode `json:"barcodes,omitempty"`
+	// BaseUnit is the unit quantities of the item are kept in, DefaultBaseUnit if left empty
+	BaseUnit string `json:"base_unit"`
+	// Units are the other units the item is counted in, which quantities given in them are converted from
+	Units []UnitConversion `json:"units,omitempty"`
+	// Price is an exact amount in Currency, DefaultCurrency if left empty
+	Price    decimal.Decimal `json:"price"`
+	Currency string          `json:"currency"`
+	// Prices is the price list of the item in currencies other than its own
+	Prices []ItemPrice `json:"prices,omitempty"`
+	// Serialized items keep a serial for every unit, which each stock movement must name
+	Serialized bool `json:"serialized"`
+	// CostingMethod is the order the cost layers of the item are consumed in, DefaultCostingMethod if left empty
+	CostingMethod CostingMethod `json:"costing_method"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	// Version is bumped on every change and serves as the ETag of the item
+	Version int `json:"version"`
+}
+
+// PriceIn returns the price of the item in currency, its own price if that is its currency or the one its price list
+// gives. It reports false if the item has no price in currency
+func (i Item) PriceIn(currency string) (decimal.Decimal, bool) {
+	if i.Currency == currency {
+		return i.Price, true
+	}
+	for _, price := range i.Prices {
+		if price.Currency == currency {
+			return price.Amount, true
+		}
+	}
+	return decimal.Decimal{}, false
 }
 
 type ItemUseCase interface {
 	GetAll(ctx context.Context, count int, offset int, filter Specification) ([]Item, error)
 	GetOne(ctx context.Context, id string) (*Item, error)
+	// GetBySKU returns the item with the given SKU
+	GetBySKU(ctx context.Context, sku string) (*Item, error)
+	// GetByBarcode returns the item labelled with the given code, or with the UPC-A or EAN-13 code it reads as
+	GetByBarcode(ctx context.Context, code string) (*Item, error)
 	Create(ctx context.Context, item *Item) (*Item, error)
+	// Update overwrites the item if it is still at item.Version, or whatever its version if that is 0. Whether it is
+	// serialized, and its base unit, can only change while none of it is in stock. An empty SKU, base unit, costing
+	// method or currency keeps the current one, and a nil price list, barcode list or unit list the current list
 	Update(ctx context.Context, item *Item) (*Item, error)
-	Delete(ctx context.Context, id string) error
+	// Delete removes the item if it is still at version, or whatever its version if that is 0
+	Delete(ctx context.Context, id string, version int) error
 }
 
 type ItemRepository interface {
 	GetAll(ctx context.Context, count int, offset int, filter Specification) ([]Item, error)
 	GetOne(ctx context.Context, id string) (*Item, error)
+	// GetBySKU and GetByBarcode return an empty item if none has the SKU or the barcode
+	GetBySKU(ctx context.Context, sku string) (*Item, error)
+	GetByBarcode(ctx context.Context, code string) (*Item, error)
 	Save(ctx context.Context, item *Item) (*Item, error)
+	// Edit overwrites the item only if it is still at item.Version and bumps the version. It fails with a failed
+	// precondition otherwise
 	Edit(ctx context.Context, item *Item) (*Item, error)
-	Delete(ctx context.Context, id string) error
+	// Delete removes the item only if it is still at version, failing with a failed precondition otherwise
+	Delete(ctx context.Context, id string, version int) error
 }
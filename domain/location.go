@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// DefaultLocationID is the location created with the schema. Inventory that doesn't name a location is kept there
+	DefaultLocationID = "default"
+	// QuarantineLocationID is the quarantine location created with the schema. Returns are quarantined there unless
+	// another quarantine location is named
+	QuarantineLocationID = "quarantine"
+)
+
+// LocationType tells what the stock at a location can be used for
+type LocationType string
+
+const (
+	// LocationStorage holds stock that can be sold
+	LocationStorage LocationType = "storage"
+	// LocationQuarantine holds stock that is on hand but mustn't be sold, e.g. returns awaiting repair
+	LocationQuarantine LocationType = "quarantine"
+)
+
+func (t LocationType) IsValid() bool {
+	return t == LocationStorage || t == LocationQuarantine
+}
+
+// Location is a place stock is kept in, e.g. a warehouse
+type Location struct {
+	ID        string       `json:"id"`
+	Name      string       `json:"name"`
+	Address   string       `json:"address"`
+	Type      LocationType `json:"type"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+type LocationUseCase interface {
+	GetAll(ctx context.Context, count int, offset int) ([]Location, error)
+	GetOne(ctx context.Context, id string) (*Location, error)
+	Create(ctx context.Context, location *Location) (*Location, error)
+	Update(ctx context.Context, location *Location) (*Location, error)
+	// Delete removes a location that holds no inventory
+	Delete(ctx context.Context, id string) error
+}
+
+type LocationRepository interface {
+	GetAll(ctx context.Context, count int, offset int) ([]Location, error)
+	GetOne(ctx context.Context, id string) (*Location, error)
+	Save(ctx context.Context, location *Location) (*Location, error)
+	Edit(ctx context.Context, location *Location) (*Location, error)
+	Delete(ctx context.Context, id string) error
+}
@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultExpiringDays is how far ahead the expiring lots report looks unless told otherwise
+const DefaultExpiringDays = 30
+
+// Lot is the part of an inventory received in one batch, with the dates it was made and expires. Stock received
+// without a lot is kept by the inventory outside any lot, so the lots of an inventory never hold more than its quantity.
+// A lot received in another Unit of the item is kept in its base unit
+type Lot struct {
+	ID             string     `json:"id"`
+	InventoryID    string     `json:"inventory_id"`
+	ItemID         string     `json:"item_id"`
+	LocationID     string     `json:"location_id"`
+	LotNumber      string     `json:"lot_number"`
+	Quantity       int        `json:"quantity"`
+	Unit           string     `json:"unit,omitempty"`
+	ManufacturedAt *time.Time `json:"manufactured_at"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	// Blocked lots, recalled ones for instance, stay on hand but can't be allocated
+	Blocked     bool      `json:"blocked"`
+	BlockReason string    `json:"block_reason,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ExpiresBefore orders lots first-expiry-first-out. Lots that don't expire come last
+func ExpiresBefore(a Lot, b Lot) bool {
+	if a.ExpiresAt == nil || b.ExpiresAt == nil {
+		return a.ExpiresAt != nil
+	}
+	return a.ExpiresAt.Before(*b.ExpiresAt)
+}
+
+// LotFilter narrows down lots. Empty fields match every lot
+type LotFilter struct {
+	ItemID     string
+	LocationID string
+	LotNumber  string
+}
+
+// LotBlock is the reason a lot is blocked for
+type LotBlock struct {
+	Reason string `json:"reason"`
+}
+
+type LotUseCase interface {
+	GetAll(ctx context.Context, count int, offset int, filter LotFilter) ([]Lot, error)
+	GetOne(ctx context.Context, id string) (*Lot, error)
+	// Receive puts lot.Quantity of the item on hand at lot.LocationID in the lot, which is created unless the
+	// inventory already holds a lot of that number
+	Receive(ctx context.Context, lot *Lot) (*Lot, error)
+	// Adjust applies adjustment to the lot, and to its inventory along with it
+	Adjust(ctx context.Context, id string, adjustment StockAdjustment) (*Lot, error)
+	Block(ctx context.Context, id string, block LotBlock) (*Lot, error)
+	Unblock(ctx context.Context, id string) (*Lot, error)
+	// Expiring returns the lots with stock that expire within the given number of days, or have already, soonest
+	// first
+	Expiring(ctx context.Context, days int, count int, offset int) ([]Lot, error)
+}
+
+type LotRepository interface {
+	GetAll(ctx context.Context, count int, offset int, filter LotFilter) ([]Lot, error)
+	// GetForInventory returns the lots of every given inventory
+	GetForInventory(ctx context.Context, inventoryIDs []string) ([]Lot, error)
+	GetOne(ctx context.Context, id string) (*Lot, error)
+	// GetOneForUpdate is GetOne that also locks the lot until the surrounding transaction ends
+	GetOneForUpdate(ctx context.Context, id string) (*Lot, error)
+	// GetByNumber returns the lot of the inventory with the given number, or an empty one if there is none
+	GetByNumber(ctx context.Context, inventoryID string, lotNumber string) (*Lot, error)
+	// GetExpiring returns the lots with stock that expire before the given time, soonest first
+	GetExpiring(ctx context.Context, before time.Time, count int, offset int) ([]Lot, error)
+	Save(ctx context.Context, lot *Lot) (*Lot, error)
+	// Edit saves the quantity and block of the lot
+	Edit(ctx context.Context, lot *Lot) (*Lot, error)
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// PurchaseOrderStatus is the stage a purchase order is at. Orders are drafted, sent to the supplier, then received
+// through any number of receipts. Closing an order settles it, whatever is still outstanding won't be received
+type PurchaseOrderStatus string
+
+const (
+	PurchaseOrderDraft             PurchaseOrderStatus = "draft"
+	PurchaseOrderSent              PurchaseOrderStatus = "sent"
+	PurchaseOrderPartiallyReceived PurchaseOrderStatus = "partially_received"
+	PurchaseOrderReceived          PurchaseOrderStatus = "received"
+	PurchaseOrderClosed            PurchaseOrderStatus = "closed"
+)
+
+// PurchaseOrder buys stock of one or more items from a supplier, to be received at a location
+type PurchaseOrder struct {
+	ID         string              `json:"id"`
+	SupplierID string              `json:"supplier_id"`
+	LocationID string              `json:"location_id"`
+	Status     PurchaseOrderStatus `json:"status"`
+	Lines      []PurchaseOrderLine `json:"lines"`
+	// Receipts are only returned for a single purchase order
+	Receipts   []PurchaseOrderReceipt `json:"receipts,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+	SentAt     *time.Time             `json:"sent_at"`
+	ReceivedAt *time.Time             `json:"received_at"`
+	ClosedAt   *time.Time             `json:"closed_at"`
+}
+
+// PurchaseOrderLine is the quantity of one item ordered, and the price agreed for each unit. A line ordered in another
+// unit of the item, such as cases, is kept in its base unit
+type PurchaseOrderLine struct {
+	ItemID   string `json:"item_id"`
+	Quantity int    `json:"quantity"`
+	Unit     string `json:"unit,omitempty"`
+	// UnitCost is taken from the supplier's catalogue when a draft leaves it out
+	UnitCost *decimal.Decimal `json:"unit_cost"`
+	Received int              `json:"received"`
+	// Outstanding is what was ordered and hasn't been received yet, nothing once the order is closed
+	Outstanding int `json:"outstanding"`
+}
+
+// PurchaseOrderReceipt records a delivery against a purchase order
+type PurchaseOrderReceipt struct {
+	ID         string                     `json:"id"`
+	Lines      []PurchaseOrderReceiptLine `json:"lines"`
+	Actor      string                     `json:"actor"`
+	ReceivedAt time.Time                  `json:"received_at"`
+}
+
+// PurchaseOrderReceiptLine is the quantity of one item received. Like order lines, it is kept in the base unit of the
+// item whatever unit it is received in
+type PurchaseOrderReceiptLine struct {
+	ItemID   string `json:"item_id"`
+	Quantity int    `json:"quantity"`
+	Unit     string `json:"unit,omitempty"`
+	// Serials names the units received if the item is serialized. They are kept with the serials, not the receipt
+	Serials []string `json:"serials,omitempty"`
+}
+
+type PurchaseOrderUseCase interface {
+	// GetAll returns purchase orders newest first. Empty supplierID and status match every order
+	GetAll(ctx context.Context, count int, offset int, supplierID string, status PurchaseOrderStatus) ([]PurchaseOrder, error)
+	GetOne(ctx context.Context, id string) (*PurchaseOrder, error)
+	Create(ctx context.Context, order *PurchaseOrder) (*PurchaseOrder, error)
+	// Update replaces the supplier, location and lines of a draft
+	Update(ctx context.Context, order *PurchaseOrder) (*PurchaseOrder, error)
+	Send(ctx context.Context, id string) (*PurchaseOrder, error)
+	// Receive puts the delivered stock into the location of the order and records the receipt against it
+	Receive(ctx context.Context, id string, receipt PurchaseOrderReceipt) (*PurchaseOrder, error)
+	Close(ctx context.Context, id string) (*PurchaseOrder, error)
+}
+
+type PurchaseOrderRepository interface {
+	GetAll(ctx context.Context, count int, offset int, supplierID string, status PurchaseOrderStatus) ([]PurchaseOrder, error)
+	// GetOne returns the purchase order along with its receipts
+	GetOne(ctx context.Context, id string) (*PurchaseOrder, error)
+	// GetOneForUpdate is GetOne that also locks the purchase order until the surrounding transaction ends
+	GetOneForUpdate(ctx context.Context, id string) (*PurchaseOrder, error)
+	Save(ctx context.Context, order *PurchaseOrder) (*PurchaseOrder, error)
+	// Edit saves the order and replaces its lines
+	Edit(ctx context.Context, order *PurchaseOrder) (*PurchaseOrder, error)
+	SaveReceipt(ctx context.Context, orderID string, receipt *PurchaseOrderReceipt) (*PurchaseOrderReceipt, error)
+}
@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ReorderPolicy tells when an item runs low and how much of it to order then. An alert opens once the available
+// stock falls to ReorderPoint or below
+type ReorderPolicy struct {
+	ID     string `json:"id"`
+	ItemID string `json:"item_id"`
+	// LocationID is the location whose stock is watched. Empty watches the stock of the item over all locations
+	LocationID      string `json:"location_id,omitempty"`
+	ReorderPoint    int    `json:"reorder_point"`
+	ReorderQuantity int    `json:"reorder_quantity"`
+	// DraftPurchaseOrder drafts a purchase order of ReorderQuantity from the preferred supplier of the item whenever
+	// an alert opens
+	DraftPurchaseOrder bool      `json:"draft_purchase_order"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// AlertStatus is the stage a low-stock alert is at. Alerts that aren't resolved are open, acknowledged or not
+type AlertStatus string
+
+const (
+	AlertOpen         AlertStatus = "open"
+	AlertAcknowledged AlertStatus = "acknowledged"
+	AlertResolved     AlertStatus = "resolved"
+)
+
+// StockAlert is raised when the stock a reorder policy watches runs low. A policy has at most one alert that isn't
+// resolved, which resolves by itself once the stock is above the reorder point again
+type StockAlert struct {
+	ID         string `json:"id"`
+	PolicyID   string `json:"policy_id"`
+	ItemID     string `json:"item_id"`
+	LocationID string `json:"location_id,omitempty"`
+	// ReorderPoint and Available are the reorder point of the policy and the available stock when the alert opened
+	ReorderPoint int         `json:"reorder_point"`
+	Available    int         `json:"available"`
+	Status       AlertStatus `json:"status"`
+	// PurchaseOrderID is the purchase order drafted for the alert, if any
+	PurchaseOrderID string     `json:"purchase_order_id,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	AcknowledgedAt  *time.Time `json:"acknowledged_at"`
+	ResolvedAt      *time.Time `json:"resolved_at"`
+}
+
+// StockWatcher is told about items whose stock changed, so their reorder policies can be evaluated. It mustn't
+// block, and the change may not be committed yet when it's told
+type StockWatcher interface {
+	StockChanged(itemID string)
+}
+
+type ReorderPolicyUseCase interface {
+	// GetAll returns reorder policies. Empty itemID and locationID match every policy
+	GetAll(ctx context.Context, count int, offset int, itemID string, locationID string) ([]ReorderPolicy, error)
+	GetOne(ctx context.Context, id string) (*ReorderPolicy, error)
+	Create(ctx context.Context, policy *ReorderPolicy) (*ReorderPolicy, error)
+	// Update changes the reorder point and quantity of a policy. Its item and location stay
+	Update(ctx context.Context, policy *ReorderPolicy) (*ReorderPolicy, error)
+	// Delete removes a policy along with its alerts
+	Delete(ctx context.Context, id string) error
+}
+
+type ReorderPolicyRepository interface {
+	GetAll(ctx context.Context, count int, offset int, itemID string, locationID string) ([]ReorderPolicy, error)
+	GetForItem(ctx context.Context, itemID string) ([]ReorderPolicy, error)
+	// ItemIDs returns the items that have reorder policies
+	ItemIDs(ctx context.Context) ([]string, error)
+	GetOne(ctx context.Context, id string) (*ReorderPolicy, error)
+	Save(ctx context.Context, policy *ReorderPolicy) (*ReorderPolicy, error)
+	Edit(ctx context.Context, policy *ReorderPolicy) (*ReorderPolicy, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type StockAlertUseCase interface {
+	// GetAll returns alerts newest first. Empty itemID and status match every alert
+	GetAll(ctx context.Context, count int, offset int, itemID string, status AlertStatus) ([]StockAlert, error)
+	GetOne(ctx context.Context, id string) (*StockAlert, error)
+	Acknowledge(ctx context.Context, id string) (*StockAlert, error)
+	Resolve(ctx context.Context, id string) (*StockAlert, error)
+	// Evaluate compares the stock of the item to its reorder policies, opening alerts for the policies it crossed
+	// and resolving those it recovered from
+	Evaluate(ctx context.Context, itemID string) error
+	// EvaluateAll evaluates every item that has reorder policies
+	EvaluateAll(ctx context.Context) error
+}
+
+type StockAlertRepository interface {
+	GetAll(ctx context.Context, count int, offset int, itemID string, status AlertStatus) ([]StockAlert, error)
+	GetOne(ctx context.Context, id string) (*StockAlert, error)
+	// GetOneForUpdate is GetOne that also locks the alert until the surrounding transaction ends
+	GetOneForUpdate(ctx context.Context, id string) (*StockAlert, error)
+	// GetUnresolved returns the alert of the policy that isn't resolved, or an empty one if there is none
+	GetUnresolved(ctx context.Context, policyID string) (*StockAlert, error)
+	Save(ctx context.Context, alert *StockAlert) (*StockAlert, error)
+	// Edit saves the status of the alert
+	Edit(ctx context.Context, alert *StockAlert) (*StockAlert, error)
+}
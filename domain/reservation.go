@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ReservationStatus is the stage a reservation is at. Only active reservations hold stock, and only until they expire
+type ReservationStatus string
+
+const (
+	ReservationActive    ReservationStatus = "active"
+	ReservationConfirmed ReservationStatus = "confirmed"
+	ReservationReleased  ReservationStatus = "released"
+	ReservationExpired   ReservationStatus = "expired"
+)
+
+// Reservation holds units of an item at a location for an owner, e.g. a checkout, so they can't be sold to anyone
+// else. It lowers the available quantity but leaves the quantity on hand alone until it's confirmed. A quantity reserved
+// in another Unit of the item is kept in its base unit
+type Reservation struct {
+	ID          string            `json:"id"`
+	ItemID      string            `json:"item_id"`
+	LocationID  string            `json:"location_id"`
+	InventoryID string            `json:"inventory_id"`
+	Quantity    int               `json:"quantity"`
+	Unit        string            `json:"unit,omitempty"`
+	Owner       string            `json:"owner"`
+	Status      ReservationStatus `json:"status"`
+	// TTLSeconds is how long a new reservation holds the units for
+	TTLSeconds int `json:"ttl_seconds,omitempty"`
+	// ExpiresAt is when the reservation stops holding the units. Allocations don't expire
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Expired tells whether the reservation has run out at the given time, whatever its status
+func (r Reservation) Expired(at time.Time) bool {
+	return r.ExpiresAt != nil && !r.ExpiresAt.After(at)
+}
+
+// ReservationConfirmation names the units sold when a reservation of a serialized item is confirmed
+type ReservationConfirmation struct {
+	Serials []string `json:"serials"`
+}
+
+// ReservationFilter narrows down reservations. Empty fields match everything
+type ReservationFilter struct {
+	Owner  string
+	Status ReservationStatus
+}
+
+type ReservationUseCase interface {
+	GetAll(ctx context.Context, count int, offset int, filter ReservationFilter) ([]Reservation, error)
+	GetOne(ctx context.Context, id string) (*Reservation, error)
+	// Reserve holds stock if enough of it is available. It joins the transaction of ctx if there is one
+	Reserve(ctx context.Context, reservation *Reservation) (*Reservation, error)
+	// Allocate is Reserve for holds that last until they are confirmed or released, such as sales order lines
+	Allocate(ctx context.Context, reservation *Reservation) (*Reservation, error)
+	// Confirm takes the reserved units out of stock as sold. It joins the transaction of ctx if there is one
+	Confirm(ctx context.Context, id string, confirmation ReservationConfirmation) (*Reservation, error)
+	// Release gives the reserved units back to the available stock. It joins the transaction of ctx if there is one
+	Release(ctx context.Context, id string) (*Reservation, error)
+	// ExpireReservations releases every active reservation past its expiry and returns how many there were
+	ExpireReservations(ctx context.Context) (int64, error)
+}
+
+type ReservationRepository interface {
+	// GetAll returns reservations newest first
+	GetAll(ctx context.Context, count int, offset int, filter ReservationFilter) ([]Reservation, error)
+	GetOne(ctx context.Context, id string) (*Reservation, error)
+	// GetOneForUpdate is GetOne that also locks the reservation until the surrounding transaction ends
+	GetOneForUpdate(ctx context.Context, id string) (*Reservation, error)
+	Save(ctx context.Context, reservation *Reservation) (*Reservation, error)
+	// Edit saves the status of the reservation
+	Edit(ctx context.Context, reservation *Reservation) (*Reservation, error)
+	// Reserved sums the active reservations of each inventory that haven't expired at the given time. Inventory
+	// nothing is reserved of is left out
+	Reserved(ctx context.Context, inventoryIDs []string, at time.Time) (map[string]int, error)
+	// Expire marks the active reservations that expired at the given time as expired and returns how many there were
+	Expire(ctx context.Context, at time.Time) (int64, error)
+}
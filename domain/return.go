@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ReturnStatus is the stage a customer return is at. Returns await inspection when they are received, which decides
+// what becomes of the stock
+type ReturnStatus string
+
+const (
+	ReturnPending     ReturnStatus = "pending"
+	ReturnRestocked   ReturnStatus = "restocked"
+	ReturnQuarantined ReturnStatus = "quarantined"
+	ReturnWrittenOff  ReturnStatus = "written_off"
+)
+
+// ReturnCondition is the state returned units arrive in
+type ReturnCondition string
+
+const (
+	ConditionNew       ReturnCondition = "new"
+	ConditionOpened    ReturnCondition = "opened"
+	ConditionUsed      ReturnCondition = "used"
+	ConditionDamaged   ReturnCondition = "damaged"
+	ConditionDefective ReturnCondition = "defective"
+)
+
+func (c ReturnCondition) IsValid() bool {
+	switch c {
+	case ConditionNew, ConditionOpened, ConditionUsed, ConditionDamaged, ConditionDefective:
+		return true
+	}
+	return false
+}
+
+// ReturnDisposition is what inspection decides to do with returned units
+type ReturnDisposition string
+
+const (
+	// DispositionRestock puts the units back into a storage location to be sold again
+	DispositionRestock ReturnDisposition = "restock"
+	// DispositionQuarantine keeps the units apart in a quarantine location, e.g. until they are repaired
+	DispositionQuarantine ReturnDisposition = "quarantine"
+	// DispositionWriteOff records the units as lost value. They are booked into a quarantine location and straight
+	// out again, so the ledger shows both
+	DispositionWriteOff ReturnDisposition = "write_off"
+)
+
+// Return is a return merchandise authorization, units of an item a customer sent back, optionally for a sales order. A
+// quantity returned in another Unit of the item is kept in its base unit
+type Return struct {
+	ID           string          `json:"id"`
+	ItemID       string          `json:"item_id"`
+	SalesOrderID string          `json:"sales_order_id,omitempty"`
+	Quantity     int             `json:"quantity"`
+	Unit         string          `json:"unit,omitempty"`
+	Reason       string          `json:"reason"`
+	Condition    ReturnCondition `json:"condition"`
+	Status       ReturnStatus    `json:"status"`
+	// LocationID is where the units went on inspection
+	LocationID  string     `json:"location_id,omitempty"`
+	Notes       string     `json:"notes"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	InspectedAt *time.Time `json:"inspected_at"`
+}
+
+// ReturnInspection decides what becomes of returned units. LocationID defaults to the default location for restocks
+// and to the quarantine location otherwise. Condition and Notes replace those recorded on receipt if given
+type ReturnInspection struct {
+	Disposition ReturnDisposition `json:"disposition"`
+	LocationID  string            `json:"location_id"`
+	Condition   ReturnCondition   `json:"condition"`
+	Notes       string            `json:"notes"`
+	// Serials names the units sent back if the item is serialized
+	Serials []string `json:"serials,omitempty"`
+}
+
+// ReturnFilter narrows down returns. Empty fields match everything
+type ReturnFilter struct {
+	ItemID       string
+	SalesOrderID string
+	Status       ReturnStatus
+}
+
+// WriteOff sums the units of an item written off on inspection of returns
+type WriteOff struct {
+	ItemID   string `json:"item_id"`
+	Quantity int    `json:"quantity"`
+	Returns  int    `json:"returns"`
+}
+
+type ReturnUseCase interface {
+	GetAll(ctx context.Context, count int, offset int, filter ReturnFilter) ([]Return, error)
+	GetOne(ctx context.Context, id string) (*Return, error)
+	// Create records a return awaiting inspection. Returns for a sales order can't exceed what it shipped of the item
+	Create(ctx context.Context, customerReturn *Return) (*Return, error)
+	// Inspect disposes of the returned units, moving stock accordingly
+	Inspect(ctx context.Context, id string, inspection ReturnInspection) (*Return, error)
+	// WriteOffs sums the returns written off between from and to, by item
+	WriteOffs(ctx context.Context, from time.Time, to time.Time) ([]WriteOff, error)
+}
+
+type ReturnRepository interface {
+	// GetAll returns returns newest first
+	GetAll(ctx context.Context, count int, offset int, filter ReturnFilter) ([]Return, error)
+	GetOne(ctx context.Context, id string) (*Return, error)
+	// GetOneForUpdate is GetOne that also locks the return until the surrounding transaction ends
+	GetOneForUpdate(ctx context.Context, id string) (*Return, error)
+	Save(ctx context.Context, customerReturn *Return) (*Return, error)
+	// Edit saves the outcome of the inspection of the return
+	Edit(ctx context.Context, customerReturn *Return) (*Return, error)
+	// Returned sums the units of the item returned for the sales order
+	Returned(ctx context.Context, salesOrderID string, itemID string) (int, error)
+	// WriteOffs sums the returns written off in [from, to) by item, ordered by item
+	WriteOffs(ctx context.Context, from time.Time, to time.Time) ([]WriteOff, error)
+}
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// SalesOrderStatus is the stage a sales order is at. Orders are allocated stock when they are placed, then picked,
+// packed and shipped in turn. Orders that haven't shipped can be cancelled
+type SalesOrderStatus string
+
+const (
+	SalesOrderAllocated SalesOrderStatus = "allocated"
+	SalesOrderPicked    SalesOrderStatus = "picked"
+	SalesOrderPacked    SalesOrderStatus = "packed"
+	SalesOrderShipped   SalesOrderStatus = "shipped"
+	SalesOrderCancelled SalesOrderStatus = "cancelled"
+)
+
+// SalesOrder sells stock of one or more items at a location to a customer
+type SalesOrder struct {
+	ID string `json:"id"`
+	// Customer is the reference the ordering system knows the customer by
+	Customer    string           `json:"customer"`
+	LocationID  string           `json:"location_id"`
+	Status      SalesOrderStatus `json:"status"`
+	Lines       []SalesOrderLine `json:"lines"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	ShippedAt   *time.Time       `json:"shipped_at"`
+	CancelledAt *time.Time       `json:"cancelled_at"`
+}
+
+// SalesOrderLine is the quantity of one item ordered, and the reservation allocating it. A line ordered in another unit
+// of the item is kept in its base unit
+type SalesOrderLine struct {
+	ItemID        string `json:"item_id"`
+	Quantity      int    `json:"quantity"`
+	Unit          string `json:"unit,omitempty"`
+	ReservationID string `json:"reservation_id"`
+}
+
+// SalesOrderShipment names the units shipped of the serialized items of an order, keyed by item ID
+type SalesOrderShipment struct {
+	Serials map[string][]string `json:"serials"`
+}
+
+type SalesOrderUseCase interface {
+	// GetAll returns sales orders newest first. Empty customer and status match every order
+	GetAll(ctx context.Context, count int, offset int, customer string, status SalesOrderStatus) ([]SalesOrder, error)
+	GetOne(ctx context.Context, id string) (*SalesOrder, error)
+	// Create places an order, allocating the stock of every line. It fails if any of it isn't available
+	Create(ctx context.Context, order *SalesOrder) (*SalesOrder, error)
+	Pick(ctx context.Context, id string) (*SalesOrder, error)
+	Pack(ctx context.Context, id string) (*SalesOrder, error)
+	// Ship takes the allocated stock out of the location as sold
+	Ship(ctx context.Context, id string, shipment SalesOrderShipment) (*SalesOrder, error)
+	// Cancel returns the allocated stock of an order that hasn't shipped
+	Cancel(ctx context.Context, id string) (*SalesOrder, error)
+}
+
+type SalesOrderRepository interface {
+	GetAll(ctx context.Context, count int, offset int, customer string, status SalesOrderStatus) ([]SalesOrder, error)
+	GetOne(ctx context.Context, id string) (*SalesOrder, error)
+	// GetOneForUpdate is GetOne that also locks the sales order until the surrounding transaction ends
+	GetOneForUpdate(ctx context.Context, id string) (*SalesOrder, error)
+	Save(ctx context.Context, order *SalesOrder) (*SalesOrder, error)
+	// Edit saves the status and timestamps of the order
+	Edit(ctx context.Context, order *SalesOrder) (*SalesOrder, error)
+}
@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// SerialStatus is the state a serialized unit is in. Units in stock or returned are on hand at their location
+type SerialStatus string
+
+const (
+	SerialInStock  SerialStatus = "in_stock"
+	SerialSold     SerialStatus = "sold"
+	SerialReturned SerialStatus = "returned"
+	SerialScrapped SerialStatus = "scrapped"
+)
+
+func (s SerialStatus) IsValid() bool {
+	switch s {
+	case SerialInStock, SerialSold, SerialReturned, SerialScrapped:
+		return true
+	}
+	return false
+}
+
+// IsOnHand reports whether units in status s count towards the quantity of the inventory at their location
+func (s SerialStatus) IsOnHand() bool {
+	return s == SerialInStock || s == SerialReturned
+}
+
+// SerialStatusAfter is the status a unit moved for reason is left in. Units that arrive are in stock, or returned if
+// a customer sent them back, and units that leave are sold or otherwise scrapped
+func SerialStatusAfter(reason AdjustmentReason, arrived bool) SerialStatus {
+	switch {
+	case arrived && reason == ReasonReturned:
+		return SerialReturned
+	case arrived:
+		return SerialInStock
+	case reason == ReasonSold:
+		return SerialSold
+	}
+	return SerialScrapped
+}
+
+// Serial is one unit of a serialized item. Serial numbers are unique per item
+type Serial struct {
+	ID           string       `json:"id"`
+	ItemID       string       `json:"item_id"`
+	SerialNumber string       `json:"serial_number"`
+	Status       SerialStatus `json:"status"`
+	// LocationID is where the unit is on hand, empty once it left
+	LocationID string    `json:"location_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	// History is every move of the unit, oldest first. It's only filled in when a single serial is looked up
+	History []SerialEvent `json:"history,omitempty"`
+}
+
+// SerialEvent records one move of a serialized unit, along with the ledger movement it was part of
+type SerialEvent struct {
+	ID         string           `json:"id"`
+	SerialID   string           `json:"serial_id"`
+	Status     SerialStatus     `json:"status"`
+	LocationID string           `json:"location_id"`
+	Reason     AdjustmentReason `json:"reason"`
+	MovementID string           `json:"movement_id"`
+	Actor      string           `json:"actor"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
+
+// SerialFilter narrows down serials. Empty fields match every serial
+type SerialFilter struct {
+	ItemID       string
+	SerialNumber string
+	Status       SerialStatus
+	LocationID   string
+}
+
+type SerialUseCase interface {
+	GetAll(ctx context.Context, count int, offset int, filter SerialFilter) ([]Serial, error)
+	// GetOne returns the serial along with its history
+	GetOne(ctx context.Context, id string) (*Serial, error)
+}
+
+type SerialRepository interface {
+	GetAll(ctx context.Context, count int, offset int, filter SerialFilter) ([]Serial, error)
+	GetOne(ctx context.Context, id string) (*Serial, error)
+	// GetByNumbersForUpdate returns the serials of the item with the given numbers that exist, locking them until the
+	// surrounding transaction ends
+	GetByNumbersForUpdate(ctx context.Context, itemID string, serialNumbers []string) ([]Serial, error)
+	// GetOnHand returns the serials of the item on hand at the location
+	GetOnHand(ctx context.Context, itemID string, locationID string) ([]Serial, error)
+	Save(ctx context.Context, serial *Serial) (*Serial, error)
+	// Edit saves the status and location of the serial
+	Edit(ctx context.Context, serial *Serial) (*Serial, error)
+	SaveEvent(ctx context.Context, event *SerialEvent) (*SerialEvent, error)
+	// GetHistory returns the events of the serial oldest first
+	GetHistory(ctx context.Context, serialID string) ([]SerialEvent, error)
+}
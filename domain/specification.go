@@ -1,10 +1,34 @@
 package domain
 
+// Dialect is the SQL flavour a specification is rendered for
+type Dialect int
+
+const (
+	Postgres Dialect = iota
+	SQLite
+)
+
+func (d Dialect) String() string {
+	if d == SQLite {
+		return "sqlite"
+	}
+	return "postgres"
+}
+
+// Specification is a filter over items. It can be rendered as a SQL predicate, which never contains user input
+// directly since every value is returned as a bind argument, or evaluated in memory with the same semantics.
 type Specification interface {
-	FilterQuery() string
+	// FilterQuery returns the predicate in the given dialect and its arguments in order. Placeholders are numbered
+	// starting at argIndex so the predicate can be embedded in a statement that already binds other arguments.
+	FilterQuery(dialect Dialect, argIndex int) (string, []interface{})
+	// IsSatisfiedBy reports whether item would be matched by FilterQuery
+	IsSatisfiedBy(item Item) bool
 }
 
+// InventorySpecification is a filter over inventory, rendered against inventory joined with its item
 type InventorySpecification interface {
-	Specification
-	ItemFilterQuery() string
+	FilterQuery(dialect Dialect, argIndex int) (string, []interface{})
+	ItemFilterQuery(dialect Dialect, argIndex int) (string, []interface{})
+	// IsSatisfiedBy reports whether inventory, with its Item filled in, would be matched by both queries
+	IsSatisfiedBy(inventory InventoryItem) bool
 }
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// StockMovement is an immutable ledger entry recording one change to an inventory quantity
+type StockMovement struct {
+	ID          string `json:"id"`
+	InventoryID string `json:"inventory_id"`
+	Delta       int    `json:"delta"`
+	// Quantity is the quantity of the inventory once Delta was applied
+	Quantity      int              `json:"quantity"`
+	Reason        AdjustmentReason `json:"reason"`
+	Actor         string           `json:"actor"`
+	CorrelationID string           `json:"correlation_id"`
+	// Cost is the value of the cost layer an arrival created, or the cost of goods of the units that left. Transfers
+	// between locations aren't costed
+	Cost      *decimal.Decimal `json:"cost,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// MovementFilter narrows down the movements of an inventory. Zero times leave that side of the range open
+type MovementFilter struct {
+	From time.Time
+	To   time.Time
+}
+
+type MovementRepository interface {
+	Save(ctx context.Context, movement *StockMovement) (*StockMovement, error)
+	// GetForInventory returns the movements of an inventory oldest first
+	GetForInventory(ctx context.Context, inventoryID string, count int, offset int, filter MovementFilter) ([]StockMovement, error)
+	// GetSales returns the units sold of the item since the given time, or of every item if itemID is empty. Sales of
+	// inventory deleted since are left out
+	GetSales(ctx context.Context, itemID string, since time.Time) ([]Sale, error)
+}
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// Supplier is a vendor stock is bought from through purchase orders
+type Supplier struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	ContactName string `json:"contact_name"`
+	Email       string `json:"email"`
+	Phone       string `json:"phone"`
+	// LeadTimeDays is how long the supplier takes to deliver once an order is sent
+	LeadTimeDays int `json:"lead_time_days"`
+	// MinimumOrderQuantity is the fewest units of an item the supplier accepts an order for
+	MinimumOrderQuantity int `json:"minimum_order_quantity"`
+	// Currency is the ISO 4217 code of the currency the supplier's prices are in
+	Currency  string    `json:"currency"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SupplierItem links an item to a supplier it can be bought from, at the supplier's SKU and price. Of the suppliers of
+// an item, one can be preferred for buying it
+type SupplierItem struct {
+	SupplierID  string          `json:"supplier_id"`
+	ItemID      string          `json:"item_id"`
+	SupplierSKU string          `json:"supplier_sku"`
+	UnitCost    decimal.Decimal `json:"unit_cost"`
+	Preferred   bool            `json:"preferred"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+type SupplierUseCase interface {
+	GetAll(ctx context.Context, count int, offset int) ([]Supplier, error)
+	GetOne(ctx context.Context, id string) (*Supplier, error)
+	Create(ctx context.Context, supplier *Supplier) (*Supplier, error)
+	Update(ctx context.Context, supplier *Supplier) (*Supplier, error)
+	// Delete removes a supplier no purchase order was made with, along with its catalogue
+	Delete(ctx context.Context, id string) error
+}
+
+type SupplierRepository interface {
+	GetAll(ctx context.Context, count int, offset int) ([]Supplier, error)
+	GetOne(ctx context.Context, id string) (*Supplier, error)
+	Save(ctx context.Context, supplier *Supplier) (*Supplier, error)
+	Edit(ctx context.Context, supplier *Supplier) (*Supplier, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type SupplierItemUseCase interface {
+	GetForSupplier(ctx context.Context, supplierID string, count int, offset int) ([]SupplierItem, error)
+	// GetForItem returns the suppliers of an item, the preferred one first
+	GetForItem(ctx context.Context, itemID string) ([]SupplierItem, error)
+	GetOne(ctx context.Context, supplierID string, itemID string) (*SupplierItem, error)
+	// Create links an item to a supplier. Preferring the supplier stops preferring any other for the item
+	Create(ctx context.Context, supplierItem *SupplierItem) (*SupplierItem, error)
+	Update(ctx context.Context, supplierItem *SupplierItem) (*SupplierItem, error)
+	Delete(ctx context.Context, supplierID string, itemID string) error
+}
+
+type SupplierItemRepository interface {
+	GetForSupplier(ctx context.Context, supplierID string, count int, offset int) ([]SupplierItem, error)
+	GetForItem(ctx context.Context, itemID string) ([]SupplierItem, error)
+	GetOne(ctx context.Context, supplierID string, itemID string) (*SupplierItem, error)
+	Save(ctx context.Context, supplierItem *SupplierItem) (*SupplierItem, error)
+	Edit(ctx context.Context, supplierItem *SupplierItem) (*SupplierItem, error)
+	Delete(ctx context.Context, supplierID string, itemID string) error
+	// ClearPreferred stops preferring any supplier of the item
+	ClearPreferred(ctx context.Context, itemID string) error
+}
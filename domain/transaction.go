@@ -0,0 +1,9 @@
+package domain
+
+import "context"
+
+// Transactor runs fn in a transaction. Repository calls made with the context fn receives join that transaction, so
+// they commit or roll back together. Calls nested in an existing transaction join it rather than starting another
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// TransferStatus is the stage a transfer is at. Transfers go from created to shipped, then to received through any
+// number of partial receipts. Transfers that haven't been received yet can be cancelled
+type TransferStatus string
+
+const (
+	TransferCreated           TransferStatus = "created"
+	TransferShipped           TransferStatus = "shipped"
+	TransferPartiallyReceived TransferStatus = "partially_received"
+	TransferReceived          TransferStatus = "received"
+	TransferCancelled         TransferStatus = "cancelled"
+)
+
+// Transfer moves stock of one or more items from one location to another
+type Transfer struct {
+	ID                    string         `json:"id"`
+	SourceLocationID      string         `json:"source_location_id"`
+	DestinationLocationID string         `json:"destination_location_id"`
+	Status                TransferStatus `json:"status"`
+	Lines                 []TransferLine `json:"lines"`
+	CreatedAt             time.Time      `json:"created_at"`
+	UpdatedAt             time.Time      `json:"updated_at"`
+	ShippedAt             *time.Time     `json:"shipped_at"`
+	ReceivedAt            *time.Time     `json:"received_at"`
+}
+
+// TransferLine is the quantity of one item a transfer moves. A line given in another unit of the item is kept in its
+// base unit
+type TransferLine struct {
+	ItemID   string `json:"item_id"`
+	Quantity int    `json:"quantity"`
+	Unit     string `json:"unit,omitempty"`
+	// LotID is the lot at the source the line ships from. What arrives goes into the lot of the same number at the
+	// destination. Lines without one ship stock held outside any lot
+	LotID    string `json:"lot_id,omitempty"`
+	Shipped  int    `json:"shipped"`
+	Received int    `json:"received"`
+	// InTransit is what was shipped and has neither been received nor written off as a discrepancy
+	InTransit int `json:"in_transit"`
+	// Discrepancy is what was shipped but never received, set once the transfer is received. It's negative if more
+	// was received than shipped
+	Discrepancy int `json:"discrepancy"`
+}
+
+// TransferReceipt records what arrived at the destination of a transfer. Closing it completes the transfer, recording
+// whatever is still in transit as a discrepancy
+type TransferReceipt struct {
+	Lines []TransferReceiptLine `json:"lines"`
+	Close bool                  `json:"close"`
+}
+
+type TransferReceiptLine struct {
+	ItemID   string `json:"item_id"`
+	Quantity int    `json:"quantity"`
+	Unit     string `json:"unit,omitempty"`
+}
+
+type TransferUseCase interface {
+	GetAll(ctx context.Context, count int, offset int, status TransferStatus) ([]Transfer, error)
+	GetOne(ctx context.Context, id string) (*Transfer, error)
+	Create(ctx context.Context, transfer *Transfer) (*Transfer, error)
+	// Ship takes the stock of every line out of the source location
+	Ship(ctx context.Context, id string) (*Transfer, error)
+	// Receive puts the stock that arrived into the destination location
+	Receive(ctx context.Context, id string, receipt TransferReceipt) (*Transfer, error)
+	// Cancel abandons a transfer nothing was received for, returning shipped stock to the source location
+	Cancel(ctx context.Context, id string) (*Transfer, error)
+}
+
+type TransferRepository interface {
+	// GetAll returns transfers newest first. An empty status matches every status
+	GetAll(ctx context.Context, count int, offset int, status TransferStatus) ([]Transfer, error)
+	GetOne(ctx context.Context, id string) (*Transfer, error)
+	// GetOneForUpdate is GetOne that also locks the transfer until the surrounding transaction ends
+	GetOneForUpdate(ctx context.Context, id string) (*Transfer, error)
+	Save(ctx context.Context, transfer *Transfer) (*Transfer, error)
+	// Edit saves the status and timestamps of the transfer and the quantities of its lines
+	Edit(ctx context.Context, transfer *Transfer) (*Transfer, error)
+}
@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"github.com/shopspring/decimal"
+	"math"
+)
+
+// MaxQuantity is the largest quantity of an item, in its base unit, that can be kept. The database stores quantities
+// as 32-bit integers
+const MaxQuantity = math.MaxInt32
+
+// UnitConversion is a unit an item is counted in besides its base unit, such as a case or a pallet
+type UnitConversion struct {
+	Unit string `json:"unit"`
+	// Factor is how many base units one unit holds, e.g. 12 for a case of 12 eaches
+	Factor int `json:"factor"`
+}
+
+// UnitFactor returns how many base units one unit of the item holds. An empty unit is the base unit. It reports false
+// if the item isn't counted in unit
+func (i Item) UnitFactor(unit string) (int, bool) {
+	if unit == "" || unit == i.BaseUnit {
+		return 1, true
+	}
+	for _, conversion := range i.Units {
+		if conversion.Unit == unit {
+			return conversion.Factor, true
+		}
+	}
+	return 0, false
+}
+
+// ScaleQuantity converts quantity units, each holding factor base units, to base units. It reports false if the result
+// would be beyond MaxQuantity either way
+func ScaleQuantity(quantity int, factor int) (int, bool) {
+	if factor > 1 && (quantity > MaxQuantity/factor || quantity < -MaxQuantity/factor) {
+		return 0, false
+	}
+	return quantity * factor, true
+}
+
+// UnitPlaces is the number of decimal places quantities expressed in another unit than the base unit are rounded to
+const UnitPlaces = 6
+
+// UnitQuantities are quantities of stock expressed in a unit other than the base unit of its item. They can be
+// fractions of the unit, rounded to UnitPlaces
+type UnitQuantities struct {
+	Unit      string          `json:"unit"`
+	Quantity  decimal.Decimal `json:"quantity"`
+	Reserved  decimal.Decimal `json:"reserved"`
+	Available decimal.Decimal `json:"available"`
+}
+
+// InUnit expresses quantities of the item, in its base unit, in unit. It reports false if the item isn't counted in
+// unit
+func (i Item) InUnit(unit string, quantity, reserved, available int) (*UnitQuantities, bool) {
+	factor, ok := i.UnitFactor(unit)
+	if !ok {
+		return nil, false
+	}
+	if unit == "" {
+		unit = i.BaseUnit
+	}
+	divisor := decimal.NewFromInt(int64(factor))
+	return &UnitQuantities{Unit: unit,
+		Quantity:  decimal.NewFromInt(int64(quantity)).DivRound(divisor, UnitPlaces),
+		Reserved:  decimal.NewFromInt(int64(reserved)).DivRound(divisor, UnitPlaces),
+		Available: decimal.NewFromInt(int64(available)).DivRound(divisor, UnitPlaces)}, true
+}
+
+// ToBaseUnit converts an adjustment given in another unit of the item to its base unit, along with its unit cost. It
+// reports whether the item is counted in the unit of the adjustment, and whether the delta fits in MaxQuantity once
+// converted. The adjustment is left alone unless both hold
+func (i Item) ToBaseUnit(adjustment *StockAdjustment) (counted bool, fits bool) {
+	factor, ok := i.UnitFactor(adjustment.Unit)
+	if !ok {
+		return false, false
+	}
+	delta, ok := ScaleQuantity(adjustment.Delta, factor)
+	if !ok {
+		return true, false
+	}
+	adjustment.Delta = delta
+	if adjustment.UnitCost != nil {
+		unitCost := adjustment.UnitCost.DivRound(decimal.NewFromInt(int64(factor)), CostPlaces)
+		adjustment.UnitCost = &unitCost
+	}
+	adjustment.Unit = ""
+	return true, true
+}
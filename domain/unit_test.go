@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestScaleQuantity(t *testing.T) {
+	cases := []struct {
+		name     string
+		quantity int
+		factor   int
+		want     int
+		ok       bool
+	}{
+		{"base unit", 5, 1, 5, true},
+		{"cases", 3, 12, 36, true},
+		{"taken out", -3, 12, -36, true},
+		{"up to the limit", MaxQuantity / 12, 12, MaxQuantity / 12 * 12, true},
+		{"past the limit", MaxQuantity/12 + 1, 12, 0, false},
+		{"past the limit taken out", -(MaxQuantity/12 + 1), 12, 0, false},
+	}
+	for _, c := range cases {
+		scaled, ok := ScaleQuantity(c.quantity, c.factor)
+		if ok != c.ok || scaled != c.want {
+			t.Errorf("%s: ScaleQuantity(%d, %d) = %d, %v, want %d, %v", c.name, c.quantity, c.factor, scaled, ok,
+				c.want, c.ok)
+		}
+	}
+}
+
+func TestInUnit(t *testing.T) {
+	item := Item{BaseUnit: DefaultBaseUnit, Units: []UnitConversion{{Unit: "pack", Factor: 3}}}
+
+	// a third of a pack is rounded to UnitPlaces
+	quantities, ok := item.InUnit("pack", 7, 3, 4)
+	if !ok {
+		t.Fatal("InUnit reported the item isn't counted in packs")
+	}
+	want := UnitQuantities{Unit: "pack", Quantity: decimal.RequireFromString("2.333333"),
+		Reserved: decimal.NewFromInt(1), Available: decimal.RequireFromString("1.333333")}
+	if quantities.Unit != want.Unit || !quantities.Quantity.Equal(want.Quantity) ||
+		!quantities.Reserved.Equal(want.Reserved) || !quantities.Available.Equal(want.Available) {
+		t.Errorf("InUnit = %+v, want %+v", quantities, want)
+	}
+	if _, ok = item.InUnit("crate", 7, 3, 4); ok {
+		t.Error("InUnit reported the item is counted in crates")
+	}
+}
@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// CostingMethod is the order the cost layers of an item are consumed in when stock leaves
+type CostingMethod string
+
+const (
+	// CostingFIFO consumes the oldest layers first
+	CostingFIFO CostingMethod = "fifo"
+	// CostingLIFO consumes the newest layers first
+	CostingLIFO CostingMethod = "lifo"
+	// CostingAverage pools the layers of an item, so every unit leaves at the weighted average cost of those on hand
+	CostingAverage CostingMethod = "average"
+	// DefaultCostingMethod is the costing method of items that don't pick one
+	DefaultCostingMethod = CostingFIFO
+)
+
+// CostPlaces is the number of decimal places costs are rounded to when they are divided, like the cost of a unit out of
+// that of a case or the share of a layer's value that leaves with some of its units. It is finer than the minor unit of
+// any currency, so what is rounded away never adds up to money
+const CostPlaces = 6
+
+func (m CostingMethod) IsValid() bool {
+	switch m {
+	case CostingFIFO, CostingLIFO, CostingAverage:
+		return true
+	}
+	return false
+}
+
+// CostLayer is a batch of units of an item received at the same unit cost. Stock leaving consumes layers according to
+// the costing method of the item until their Remaining units run out
+type CostLayer struct {
+	ID     string `json:"id"`
+	ItemID string `json:"item_id"`
+	// MovementID is the movement that received the layer. Layers opening the stock held before costing began have none
+	MovementID string          `json:"movement_id,omitempty"`
+	Quantity   int             `json:"quantity"`
+	UnitCost   decimal.Decimal `json:"unit_cost"`
+	Remaining  int             `json:"remaining"`
+	// RemainingValue is what the remaining units are carried at. Layers of average costed items absorb the older
+	// layers of the item, so it can differ from Remaining times UnitCost
+	RemainingValue decimal.Decimal `json:"remaining_value"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// CostConsumption records the units of a layer a movement took out and the cost of goods they left at
+type CostConsumption struct {
+	ID     string `json:"id"`
+	ItemID string `json:"item_id"`
+	// LayerID is empty for units that left with no layer left to cost them
+	LayerID    string          `json:"layer_id,omitempty"`
+	MovementID string          `json:"movement_id"`
+	Quantity   int             `json:"quantity"`
+	Cost       decimal.Decimal `json:"cost"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// ItemValuation is the stock of an item owned at a point in time and what it is carried at
+type ItemValuation struct {
+	ItemID   string          `json:"item_id"`
+	Quantity int             `json:"quantity"`
+	Value    decimal.Decimal `json:"value"`
+	// UnitCost is Value over Quantity
+	UnitCost decimal.Decimal `json:"unit_cost"`
+}
+
+// Valuation values all stock owned at a point in time, stock in transit between locations included
+type Valuation struct {
+	At       time.Time       `json:"at"`
+	Quantity int             `json:"quantity"`
+	Value    decimal.Decimal `json:"value"`
+	Items    []ItemValuation `json:"items"`
+}
+
+type ValuationUseCase interface {
+	// Valuation values the stock of every item at the given time
+	Valuation(ctx context.Context, at time.Time) (*Valuation, error)
+	// GetLayers returns the cost layers of an item newest first
+	GetLayers(ctx context.Context, itemID string, count int, offset int) ([]CostLayer, error)
+}
+
+type CostRepository interface {
+	// GetLayers returns the cost layers of an item newest first
+	GetLayers(ctx context.Context, itemID string, count int, offset int) ([]CostLayer, error)
+	// GetOpenLayersForUpdate returns the layers of an item with units remaining, oldest first, and locks them until the
+	// surrounding transaction ends
+	GetOpenLayersForUpdate(ctx context.Context, itemID string) ([]CostLayer, error)
+	SaveLayer(ctx context.Context, layer *CostLayer) (*CostLayer, error)
+	// EditLayer saves the remaining units and value of the layer
+	EditLayer(ctx context.Context, layer *CostLayer) (*CostLayer, error)
+	SaveConsumption(ctx context.Context, consumption *CostConsumption) (*CostConsumption, error)
+	// Valuation sums the layers received less the consumptions made up to the given time, by item. Items with nothing
+	// left are left out
+	Valuation(ctx context.Context, at time.Time) ([]ItemValuation, error)
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"net/http"
+	"strconv"
+)
+
+type ForecastHandler struct {
+	useCase domain.ForecastUseCase
+}
+
+func NewForecastHandler(useCase domain.ForecastUseCase) *ForecastHandler {
+	return &ForecastHandler{useCase: useCase}
+}
+
+func (h *ForecastHandler) GetForItem(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	options, restErr := parseOptions(c)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	location, _ := c.GetQuery("location")
+
+	ctx := c.Request.Context()
+	forecast, err := h.useCase.GetForItem(ctx, id, location, options)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, forecast)
+}
+
+func (h *ForecastHandler) GetAll(c *gin.Context) {
+	options, restErr := parseOptions(c)
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+
+	var count int64
+	if countQuery, ok := c.GetQuery("count"); ok {
+		count, _ = strconv.ParseInt(countQuery, 10, 64)
+	} else {
+		count = 20
+	}
+
+	var offset int64
+	if offsetQuery, ok := c.GetQuery("offset"); ok {
+		offset, _ = strconv.ParseInt(offsetQuery, 10, 64)
+	}
+
+	ctx := c.Request.Context()
+	forecasts, err := h.useCase.GetAll(ctx, int(count), int(offset), options)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, forecasts)
+}
+
+// parseOptions reads the window, alpha and cover_days query parameters. Those not given are left for the defaults
+func parseOptions(c *gin.Context) (domain.ForecastOptions, *errors.RestError) {
+	var options domain.ForecastOptions
+	var err error
+	if windowQuery, ok := c.GetQuery("window"); ok {
+		if options.WindowDays, err = strconv.Atoi(windowQuery); err != nil {
+			return options, errors.NewBadRequestError("invalid window")
+		}
+	}
+	if alphaQuery, ok := c.GetQuery("alpha"); ok {
+		if options.Alpha, err = strconv.ParseFloat(alphaQuery, 64); err != nil {
+			return options, errors.NewBadRequestError("invalid alpha")
+		}
+	}
+	if coverQuery, ok := c.GetQuery("cover_days"); ok {
+		if options.CoverDays, err = strconv.Atoi(coverQuery); err != nil {
+			return options, errors.NewBadRequestError("invalid cover_days")
+		}
+	}
+	return options, nil
+}
@@ -0,0 +1,204 @@
+package usecase
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/specification"
+	"github.com/nuzurie/shopify/utils/errors"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	maxWindowDays = 365
+	maxCoverDays  = 365
+	// maxForecastInventory is how much inventory the forecast report reads at most
+	maxForecastInventory = 10000
+)
+
+type forecastUseCase struct {
+	inventoryUseCase       domain.InventoryUseCase
+	movementRepository     domain.MovementRepository
+	supplierRepository     domain.SupplierRepository
+	supplierItemRepository domain.SupplierItemRepository
+	timeout                time.Duration
+}
+
+// NewForecastUseCase forecasts the available stock inventoryUseCase reports from the sales in movementRepository.
+// Reorders are suggested according to the lead time and minimum order quantity of the preferred supplier
+func NewForecastUseCase(inventoryUseCase domain.InventoryUseCase, movementRepository domain.MovementRepository,
+	supplierRepository domain.SupplierRepository, supplierItemRepository domain.SupplierItemRepository,
+	timeout time.Duration) domain.ForecastUseCase {
+	return &forecastUseCase{inventoryUseCase: inventoryUseCase, movementRepository: movementRepository,
+		supplierRepository: supplierRepository, supplierItemRepository: supplierItemRepository, timeout: timeout}
+}
+
+func (f *forecastUseCase) GetForItem(ctx context.Context, itemID string, locationID string,
+	options domain.ForecastOptions) (*domain.Forecast, error) {
+	c, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	if err := validate(&options); err != nil {
+		return nil, err
+	}
+
+	stock, err := f.inventoryUseCase.GetInventoryForItem(c, itemID)
+	if err != nil {
+		return nil, err
+	}
+	available := stock.Available
+	if locationID != "" {
+		found := false
+		for _, location := range stock.Locations {
+			if location.LocationID == locationID {
+				available, found = location.Available, true
+			}
+		}
+		if !found {
+			return nil, errors.NewNotFoundError("the location holds none of the item")
+		}
+	}
+
+	now := time.Now()
+	sales, err := f.movementRepository.GetSales(c, itemID, now.AddDate(0, 0, -options.WindowDays))
+	if err != nil {
+		return nil, err
+	}
+	if locationID != "" {
+		var atLocation []domain.Sale
+		for _, sale := range sales {
+			if sale.LocationID == locationID {
+				atLocation = append(atLocation, sale)
+			}
+		}
+		sales = atLocation
+	}
+
+	forecast := domain.ProjectForecast(domain.DailySales(sales, options.WindowDays, now), available, options.Alpha,
+		now)
+	forecast.ItemID = itemID
+	forecast.LocationID = locationID
+	if err = f.suggestReorder(c, &forecast, options); err != nil {
+		return nil, err
+	}
+	return &forecast, nil
+}
+
+func (f *forecastUseCase) GetAll(ctx context.Context, count int, offset int,
+	options domain.ForecastOptions) ([]domain.Forecast, error) {
+	c, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	if err := validate(&options); err != nil {
+		return nil, err
+	}
+
+	everything := specification.NewInventorySpecification(0, -1, "", "",
+		specification.NewItemSpecification("", "", nil, nil, ""))
+	inventoryItems, err := f.inventoryUseCase.GetAll(c, maxForecastInventory, 0, everything,
+		domain.InventorySortDefault)
+	if err != nil {
+		return nil, err
+	}
+	var itemIDs []string
+	available := map[string]int{}
+	for _, inventory := range inventoryItems {
+		if _, ok := available[inventory.Item.ID]; !ok {
+			itemIDs = append(itemIDs, inventory.Item.ID)
+		}
+		available[inventory.Item.ID] += inventory.Available
+	}
+
+	now := time.Now()
+	sales, err := f.movementRepository.GetSales(c, "", now.AddDate(0, 0, -options.WindowDays))
+	if err != nil {
+		return nil, err
+	}
+	salesOf := map[string][]domain.Sale{}
+	for _, sale := range sales {
+		salesOf[sale.ItemID] = append(salesOf[sale.ItemID], sale)
+	}
+
+	forecasts := make([]domain.Forecast, len(itemIDs))
+	for index, itemID := range itemIDs {
+		forecasts[index] = domain.ProjectForecast(domain.DailySales(salesOf[itemID], options.WindowDays, now),
+			available[itemID], options.Alpha, now)
+		forecasts[index].ItemID = itemID
+	}
+	// items come in the order of their inventory, so the order of items that never run out is stable
+	sort.SliceStable(forecasts, func(a, b int) bool {
+		return domain.StockoutBefore(forecasts[a].StockoutDate, forecasts[b].StockoutDate)
+	})
+
+	if offset > len(forecasts) {
+		offset = len(forecasts)
+	}
+	if end := offset + count; count >= 0 && end < len(forecasts) {
+		forecasts = forecasts[:end]
+	}
+	forecasts = forecasts[offset:]
+	if len(forecasts) == 0 {
+		return nil, errors.NewNotFoundError("no forecasts found")
+	}
+
+	for index := range forecasts {
+		if err = f.suggestReorder(c, &forecasts[index], options); err != nil {
+			return nil, err
+		}
+	}
+	return forecasts, nil
+}
+
+// suggestReorder sizes a reorder from the preferred supplier of the item so that the stock covers the demand over the
+// supplier's lead time and the cover days after. Items without a preferred supplier are taken to arrive straight away
+func (f *forecastUseCase) suggestReorder(c context.Context, forecast *domain.Forecast,
+	options domain.ForecastOptions) error {
+	minimumOrderQuantity := 1
+	supplierItems, err := f.supplierItemRepository.GetForItem(c, forecast.ItemID)
+	if err != nil {
+		return err
+	}
+	if len(supplierItems) > 0 && supplierItems[0].Preferred {
+		supplier, err := f.supplierRepository.GetOne(c, supplierItems[0].SupplierID)
+		if err != nil {
+			return err
+		}
+		forecast.LeadTimeDays = supplier.LeadTimeDays
+		minimumOrderQuantity = supplier.MinimumOrderQuantity
+	}
+
+	demand := forecast.ExponentialSmoothing * float64(forecast.LeadTimeDays+options.CoverDays)
+	shortfall := int(math.Ceil(demand)) - forecast.Available
+	forecast.SuggestedReorderQuantity = 0
+	if shortfall > 0 {
+		forecast.SuggestedReorderQuantity = shortfall
+		if shortfall < minimumOrderQuantity {
+			forecast.SuggestedReorderQuantity = minimumOrderQuantity
+		}
+	}
+	return nil
+}
+
+// validate checks the options of a forecast, defaulting those not given
+func validate(options *domain.ForecastOptions) error {
+	if options.WindowDays == 0 {
+		options.WindowDays = domain.DefaultForecastWindowDays
+	}
+	if options.Alpha == 0 {
+		options.Alpha = domain.DefaultForecastAlpha
+	}
+	if options.CoverDays == 0 {
+		options.CoverDays = domain.DefaultCoverDays
+	}
+	if options.WindowDays < 1 || options.WindowDays > maxWindowDays {
+		return errors.NewBadRequestError("invalid request. Window must be between 1 and 365 days")
+	}
+	if options.Alpha <= 0 || options.Alpha > 1 {
+		return errors.NewBadRequestError("invalid request. Alpha must be above 0 and up to 1")
+	}
+	if options.CoverDays < 1 || options.CoverDays > maxCoverDays {
+		return errors.NewBadRequestError("invalid request. Cover days must be between 1 and 365")
+	}
+	return nil
+}
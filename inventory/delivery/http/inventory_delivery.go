@@ -6,9 +6,13 @@ import (
 	"github.com/nuzurie/shopify/domain"
 	"github.com/nuzurie/shopify/specification"
 	"github.com/nuzurie/shopify/utils/errors"
+	"github.com/nuzurie/shopify/utils/etag"
+	"github.com/shopspring/decimal"
 	"net/http"
 	"reflect"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type InventoryHandler struct {
@@ -22,23 +26,21 @@ func NewInventoryHandler(useCase domain.InventoryUseCase) *InventoryHandler {
 func (h *InventoryHandler) GetAll(c *gin.Context) {
 	name, _ := c.GetQuery("name")
 	description, _ := c.GetQuery("description")
-	minPriceQuery, _ := c.GetQuery("min-price")
-	minPrice, err := strconv.ParseFloat(minPriceQuery, 64)
-	if err != nil {
-		minPrice = 0
+	var minPrice, maxPrice *decimal.Decimal
+	if minPriceQuery, ok := c.GetQuery("min-price"); ok {
+		if price, err := decimal.NewFromString(minPriceQuery); err == nil {
+			minPrice = &price
+		}
 	}
-	maxPriceQuery, ok := c.GetQuery("max-price")
-	var maxPrice float64
-	if ok {
-		maxPrice, err = strconv.ParseFloat(maxPriceQuery, 64)
-		if err != nil {
-			maxPrice = -1
+	if maxPriceQuery, ok := c.GetQuery("max-price"); ok {
+		if price, err := decimal.NewFromString(maxPriceQuery); err == nil {
+			maxPrice = &price
 		}
-	} else {
-		maxPrice = -1
 	}
+	currency, _ := c.GetQuery("currency")
 
-	itemSpec := specification.NewItemSpecification(name, description, minPrice, maxPrice)
+	itemSpec := specification.NewItemSpecification(name, description, minPrice, maxPrice,
+		strings.ToUpper(currency))
 
 	minQuantityQuery, _ := c.GetQuery("min-quantity")
 	minQuantity, err := strconv.ParseInt(minQuantityQuery, 10, 64)
@@ -56,7 +58,11 @@ func (h *InventoryHandler) GetAll(c *gin.Context) {
 		maxQuantity = -1
 	}
 
-	inventorySpec := specification.NewInventorySpecification(int(minQuantity), int(maxQuantity), itemSpec)
+	location, _ := c.GetQuery("location")
+	lot, _ := c.GetQuery("lot")
+	inventorySpec := specification.NewInventorySpecification(int(minQuantity), int(maxQuantity), location, lot,
+		itemSpec)
+	order, _ := c.GetQuery("sort")
 
 	var count int64
 	if countQuery, ok := c.GetQuery("count"); ok {
@@ -71,7 +77,7 @@ func (h *InventoryHandler) GetAll(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
-	items, err := h.useCase.GetAll(ctx, int(count), int(offset), inventorySpec)
+	items, err := h.useCase.GetAll(ctx, int(count), int(offset), inventorySpec, domain.InventorySort(order))
 	if err != nil {
 		switch v := err.(type) {
 		case *errors.RestError:
@@ -83,6 +89,15 @@ func (h *InventoryHandler) GetAll(c *gin.Context) {
 		}
 	}
 
+	// quantities are also given in the requested unit for the items counted in it
+	if unit, ok := c.GetQuery("unit"); ok && unit != "" {
+		for index := range items {
+			inventory := &items[index]
+			inventory.InUnit, _ = inventory.Item.InUnit(unit, inventory.Quantity, inventory.Reserved,
+				inventory.Available)
+		}
+	}
+
 	c.JSON(http.StatusOK, items)
 }
 
@@ -94,7 +109,7 @@ func (h *InventoryHandler) GetInventoryForItem(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
-	inventory, err := h.useCase.GetInventoryForItem(ctx, id)
+	stock, err := h.useCase.GetInventoryForItem(ctx, id)
 	if err != nil {
 		switch v := err.(type) {
 		case *errors.RestError:
@@ -106,7 +121,20 @@ func (h *InventoryHandler) GetInventoryForItem(c *gin.Context) {
 		}
 	}
 
-	c.JSON(http.StatusNoContent, inventory)
+	if unit, ok := c.GetQuery("unit"); ok && unit != "" {
+		if stock.InUnit, ok = stock.Item.InUnit(unit, stock.Quantity, stock.Reserved, stock.Available); !ok {
+			c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid request. The item isn't counted in "+
+				unit))
+			return
+		}
+		for index := range stock.Locations {
+			location := &stock.Locations[index]
+			location.InUnit, _ = stock.Item.InUnit(unit, location.Quantity, location.Reserved, location.Available)
+		}
+	}
+
+	// the ETag of each location's inventory is its version, listed in the body
+	c.JSON(http.StatusOK, stock)
 }
 
 func (h *InventoryHandler) CreateOrUpdate(c *gin.Context) {
@@ -117,6 +145,12 @@ func (h *InventoryHandler) CreateOrUpdate(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid inventory body"))
 		return
 	}
+	version, restErr := etag.Parse(c.GetHeader("If-Match"))
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+	inventory.Version = version
 
 	ctx := c.Request.Context()
 	createdInventory, err := h.useCase.UpdateInventoryItem(ctx, &inventory)
@@ -131,9 +165,110 @@ func (h *InventoryHandler) CreateOrUpdate(c *gin.Context) {
 		}
 	}
 
+	c.Header("ETag", etag.Format(createdInventory.Version))
 	c.JSON(http.StatusCreated, createdInventory)
 }
 
+func (h *InventoryHandler) Adjust(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	var adjustment domain.StockAdjustment
+	err := c.ShouldBindJSON(&adjustment)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid adjustment body"))
+		return
+	}
+	version, restErr := etag.Parse(c.GetHeader("If-Match"))
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx := c.Request.Context()
+	inventory, err := h.useCase.AdjustQuantity(ctx, id, adjustment, version)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	// an adjustment in another unit is answered in that unit as well
+	if adjustment.Unit != "" {
+		inventory.InUnit, _ = inventory.Item.InUnit(adjustment.Unit, inventory.Quantity, inventory.Reserved,
+			inventory.Available)
+	}
+
+	c.Header("ETag", etag.Format(inventory.Version))
+	c.JSON(http.StatusOK, inventory)
+}
+
+func (h *InventoryHandler) GetMovements(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	var filter domain.MovementFilter
+	var err error
+	if fromQuery, ok := c.GetQuery("from"); ok {
+		if filter.From, err = parseTime(fromQuery); err != nil {
+			c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid from date"))
+			return
+		}
+	}
+	if toQuery, ok := c.GetQuery("to"); ok {
+		if filter.To, err = parseTime(toQuery); err != nil {
+			c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid to date"))
+			return
+		}
+	}
+
+	var count int64
+	if countQuery, ok := c.GetQuery("count"); ok {
+		count, _ = strconv.ParseInt(countQuery, 10, 64)
+	} else {
+		count = 20
+	}
+
+	var offset int64
+	if offsetQuery, ok := c.GetQuery("offset"); ok {
+		offset, _ = strconv.ParseInt(offsetQuery, 10, 64)
+	}
+
+	ctx := c.Request.Context()
+	movements, err := h.useCase.GetMovements(ctx, id, int(count), int(offset), filter)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, movements)
+}
+
+// parseTime accepts RFC 3339 timestamps or plain dates, which are taken as midnight UTC
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
 func (h *InventoryHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
@@ -141,8 +276,14 @@ func (h *InventoryHandler) Delete(c *gin.Context) {
 		return
 	}
 
+	version, restErr := etag.Parse(c.GetHeader("If-Match"))
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+
 	ctx := c.Request.Context()
-	err := h.useCase.DeleteItem(ctx, id)
+	err := h.useCase.DeleteItem(ctx, id, version)
 	if err != nil {
 		switch v := err.(type) {
 		case *errors.RestError:
@@ -3,129 +3,136 @@ package repository
 import (
 	"context"
 	"fmt"
-	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/nuzurie/shopify/db"
 	"github.com/nuzurie/shopify/domain"
 	"github.com/nuzurie/shopify/utils/errors"
-	"log"
 	"time"
 )
 
 type inventoryRepository struct {
-	db *pgxpool.Pool
+	db db.DB
 }
 
 const (
-	createInventoryTable = `CREATE TABLE IF NOT EXISTS inventory (
-	id text PRIMARY KEY,
-	quantity int,
-	updated_at timestamp without time zone,
-	item_id text,
-	FOREIGN KEY (item_id)
-	REFERENCES item(id)
-	)`
-	getInventoryForItemID = `SELECT id, quantity, updated_at, item_id FROM public.inventory WHERE item_id=$1`
-	getAll                = `SELECT id, quantity, updated_at, item_id FROM public.inventory WHERE item_id IN (SELECT id 
-							 FROM public.item WHERE %s) AND %s LIMIT $1 OFFSET $2`
-	getByID = `SELECT id, quantity, updated_at, item_id FROM public.inventory WHERE id=$1`
-	save    = `INSERT INTO public.inventory (id, quantity, updated_at, item_id)
-			VALUES ($1, $2, $3, $4)`
-	update      = `UPDATE public.inventory SET quantity=$2, updated_at=$3 WHERE id=$1`
-	deleteForID = `DELETE FROM public.inventory WHERE id=$1`
+	getInventoryForItemID = `SELECT id, quantity, updated_at, item_id, version, location_id FROM inventory
+							 WHERE item_id=$1 ORDER BY location_id`
+	getInventoryAtLocation = `SELECT id, quantity, updated_at, item_id, version, location_id FROM inventory
+							 WHERE item_id=$1 AND location_id=$2`
+	getAll = `SELECT inventory.id, inventory.quantity, inventory.updated_at, inventory.item_id,
+							 inventory.version, inventory.location_id
+							 FROM inventory JOIN item ON item.id = inventory.item_id
+							 WHERE (%s) AND (%s) ORDER BY inventory.id LIMIT $%d OFFSET $%d`
+	getByID = `SELECT id, quantity, updated_at, item_id, version, location_id FROM inventory WHERE id=$1`
+	save    = `INSERT INTO inventory (id, quantity, updated_at, item_id, version, location_id)
+			VALUES ($1, $2, $3, $4, $5, $6)`
+	update = `UPDATE inventory SET quantity=$2, updated_at=$3, version=version+1 WHERE id=$1 AND version=$4`
+	adjust = `UPDATE inventory SET quantity=quantity+$2, updated_at=$3, version=version+1 WHERE id=$1 AND quantity+$2>=0
+			RETURNING id, quantity, updated_at, item_id, version, location_id`
+	deleteForID = `DELETE FROM inventory WHERE id=$1`
+	// SQLite has no row locks, but it only ever runs one transaction at a time
+	forUpdate = ` FOR UPDATE`
 )
 
-func NewInventoryRepository(db *pgxpool.Pool) (domain.InventoryRepository, error) {
-	log.Println("Creating item table")
-	tx, err := db.Begin(context.Background())
-	if err != nil {
-		return nil, err
-		//log.Fatalln("Unable to create the table. ", err.Error())
-	}
-	defer tx.Rollback(context.Background())
-
-	_, err = tx.Exec(context.Background(), createInventoryTable)
-	if err != nil {
-		return nil, err
-	}
-
-	err = tx.Commit(context.Background())
-	if err != nil {
-		return nil, err
-	}
-	return &inventoryRepository{db: db}, nil
+// NewInventoryRepository stores inventory in a SQL database, Postgres or SQLite. The schema must be migrated
+func NewInventoryRepository(database db.DB) domain.InventoryRepository {
+	return &inventoryRepository{db: database}
 }
 
-func (i *inventoryRepository) GetInventoryForItem(ctx context.Context, itemID string) (*domain.InventoryItem, error) {
+func (i *inventoryRepository) GetInventoryForItem(ctx context.Context, itemID string) ([]domain.InventoryItem, error) {
 	rows, err := i.db.Query(ctx, getInventoryForItemID, itemID)
 	if err != nil {
-		err = errors.NewInternalServerError(err.Error())
-		return nil, err
+		return nil, errors.NewInternalServerError(err.Error())
 	}
 	defer rows.Close()
 
-	var inventory domain.InventoryItem
+	var inventoryItems []domain.InventoryItem
 	for rows.Next() {
-		value, err := rows.Values()
+		var inventory domain.InventoryItem
+		err = rows.Scan(&inventory.ID, &inventory.Quantity, &inventory.UpdatedAt, &inventory.Item.ID,
+			&inventory.Version, &inventory.LocationID)
 		if err != nil {
-			err = errors.NewInternalServerError(err.Error())
-			return nil, err
+			return nil, errors.NewInternalServerError(err.Error())
 		}
 
-		inventory.ID = value[0].(string)
-		inventory.Quantity = int(value[1].(int32))
-		inventory.UpdatedAt = value[2].(time.Time)
-		inventory.Item.ID = value[3].(string)
+		inventoryItems = append(inventoryItems, inventory)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return inventoryItems, nil
+}
+
+func (i *inventoryRepository) GetInventoryAtLocation(ctx context.Context, itemID string,
+	locationID string) (*domain.InventoryItem, error) {
+	var inventory domain.InventoryItem
+	err := i.db.QueryRow(ctx, getInventoryAtLocation, itemID, locationID).
+		Scan(&inventory.ID, &inventory.Quantity, &inventory.UpdatedAt, &inventory.Item.ID,
+			&inventory.Version, &inventory.LocationID)
+	if err != nil && err != db.ErrNoRows {
+		err = errors.NewInternalServerError(err.Error())
+		return nil, err
 	}
 
 	return &inventory, nil
 }
 
 func (i *inventoryRepository) GetByID(ctx context.Context, id string) (*domain.InventoryItem, error) {
-	rows, err := i.db.Query(ctx, getByID, id)
-	if err != nil {
+	var inventory domain.InventoryItem
+	err := i.db.QueryRow(ctx, getByID, id).
+		Scan(&inventory.ID, &inventory.Quantity, &inventory.UpdatedAt, &inventory.Item.ID,
+			&inventory.Version, &inventory.LocationID)
+	if err != nil && err != db.ErrNoRows {
 		err = errors.NewInternalServerError(err.Error())
 		return nil, err
 	}
-	defer rows.Close()
 
-	var inventory domain.InventoryItem
-	for rows.Next() {
-		value, err := rows.Values()
-		if err != nil {
-			err = errors.NewInternalServerError(err.Error())
-			return nil, err
-		}
+	return &inventory, nil
+}
 
-		inventory.ID = value[0].(string)
-		inventory.Quantity = int(value[1].(int32))
-		inventory.UpdatedAt = value[2].(time.Time)
-		inventory.Item.ID = value[3].(string)
+func (i *inventoryRepository) GetByIDForUpdate(ctx context.Context, id string) (*domain.InventoryItem, error) {
+	query := getByID
+	if i.db.Dialect() == domain.Postgres {
+		query += forUpdate
+	}
+
+	var inventory domain.InventoryItem
+	err := i.db.QueryRow(ctx, query, id).
+		Scan(&inventory.ID, &inventory.Quantity, &inventory.UpdatedAt, &inventory.Item.ID,
+			&inventory.Version, &inventory.LocationID)
+	if err != nil && err != db.ErrNoRows {
+		err = errors.NewInternalServerError(err.Error())
+		return nil, err
 	}
 
 	return &inventory, nil
 }
 
 func (i *inventoryRepository) GetAll(ctx context.Context, count int, offset int, filter domain.InventorySpecification) ([]domain.InventoryItem, error) {
-	rows, err := i.db.Query(ctx, fmt.Sprintf(getAll, filter.ItemFilterQuery(), filter.FilterQuery()), count, offset)
-	defer rows.Close()
+	itemQuery, args := filter.ItemFilterQuery(i.db.Dialect(), 1)
+	inventoryQuery, inventoryArgs := filter.FilterQuery(i.db.Dialect(), len(args)+1)
+	args = append(args, inventoryArgs...)
+	query := fmt.Sprintf(getAll, itemQuery, inventoryQuery, len(args)+1, len(args)+2)
+	rows, err := i.db.Query(ctx, query, append(args, count, offset)...)
 	if err != nil {
 		return nil, errors.NewInternalServerError(err.Error())
 	}
+	defer rows.Close()
 
 	var inventoryItems []domain.InventoryItem
 	for rows.Next() {
-		value, err := rows.Values()
+		var inventory domain.InventoryItem
+		err = rows.Scan(&inventory.ID, &inventory.Quantity, &inventory.UpdatedAt, &inventory.Item.ID,
+			&inventory.Version, &inventory.LocationID)
 		if err != nil {
 			return nil, errors.NewInternalServerError(err.Error())
 		}
 
-		var inventory domain.InventoryItem
-		inventory.ID = value[0].(string)
-		inventory.Quantity = int(value[1].(int32))
-		inventory.UpdatedAt = value[2].(time.Time)
-		inventory.Item.ID = value[3].(string)
-
 		inventoryItems = append(inventoryItems, inventory)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
 
 	return inventoryItems, nil
 }
@@ -137,8 +144,15 @@ func (i *inventoryRepository) Save(ctx context.Context, inventoryItem *domain.In
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, save, inventoryItem.ID, inventoryItem.Quantity, inventoryItem.UpdatedAt, inventoryItem.Item.ID)
+	_, err = tx.Exec(ctx, save, inventoryItem.ID, inventoryItem.Quantity, inventoryItem.UpdatedAt, inventoryItem.Item.ID,
+		inventoryItem.Version, inventoryItem.LocationID)
 	if err != nil {
+		if db.IsForeignKeyViolation(err) {
+			return nil, errors.NewBadRequestError("no item or location with such ID exists")
+		}
+		if db.IsUniqueViolation(err) {
+			return nil, errors.NewConflictError("the item already has inventory at the location")
+		}
 		return nil, errors.NewInternalServerError(err.Error())
 	}
 
@@ -156,10 +170,15 @@ func (i *inventoryRepository) Edit(ctx context.Context, inventoryItem *domain.In
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, update, inventoryItem.ID, inventoryItem.Quantity, inventoryItem.UpdatedAt)
+	updated, err := tx.Exec(ctx, update, inventoryItem.ID, inventoryItem.Quantity, inventoryItem.UpdatedAt,
+		inventoryItem.Version)
 	if err != nil {
 		return nil, errors.NewInternalServerError(err.Error())
 	}
+	if updated == 0 {
+		return nil, errors.NewPreconditionFailedError("inventory has been modified or deleted since it was read")
+	}
+	inventoryItem.Version++
 
 	err = tx.Commit(ctx)
 	if err != nil {
@@ -168,6 +187,33 @@ func (i *inventoryRepository) Edit(ctx context.Context, inventoryItem *domain.In
 	return inventoryItem, nil
 }
 
+func (i *inventoryRepository) AdjustQuantity(ctx context.Context, id string, delta int, updatedAt time.Time) (*domain.InventoryItem, error) {
+	var inventory domain.InventoryItem
+	err := i.db.QueryRow(ctx, adjust, id, delta, updatedAt).
+		Scan(&inventory.ID, &inventory.Quantity, &inventory.UpdatedAt, &inventory.Item.ID,
+			&inventory.Version, &inventory.LocationID)
+	if err == db.ErrNoRows {
+		return nil, i.adjustmentRejected(ctx, id)
+	}
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return &inventory, nil
+}
+
+// adjustmentRejected explains why an adjustment matched no row
+func (i *inventoryRepository) adjustmentRejected(ctx context.Context, id string) error {
+	existing, err := i.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing.ID == "" {
+		return errors.NewNotFoundError("no such inventory found")
+	}
+	return errors.NewConflictError("insufficient stock. Quantity can't be less than 0")
+}
+
 func (i *inventoryRepository) DeleteItem(ctx context.Context, id string) error {
 	tx, err := i.db.Begin(ctx)
 	if err != nil {
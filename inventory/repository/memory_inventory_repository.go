@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"sort"
+	"time"
+)
+
+type memoryInventoryRepository struct {
+	store *memory.Store
+}
+
+// NewMemoryInventoryRepository keeps inventory in store instead of a database. It's meant for tests and demos, and
+// store must be shared with the item repository so inventory can only reference existing items
+func NewMemoryInventoryRepository(store *memory.Store) domain.InventoryRepository {
+	return &memoryInventoryRepository{store: store}
+}
+
+func (i *memoryInventoryRepository) GetInventoryForItem(ctx context.Context, itemID string) ([]domain.InventoryItem, error) {
+	defer i.store.Read(ctx)()
+
+	var inventoryItems []domain.InventoryItem
+	for _, inventory := range i.store.Inventory {
+		if inventory.Item.ID == itemID {
+			inventoryItems = append(inventoryItems, inventory)
+		}
+	}
+	sort.Slice(inventoryItems, func(a, b int) bool {
+		return inventoryItems[a].LocationID < inventoryItems[b].LocationID
+	})
+	return inventoryItems, nil
+}
+
+func (i *memoryInventoryRepository) GetInventoryAtLocation(ctx context.Context, itemID string,
+	locationID string) (*domain.InventoryItem, error) {
+	defer i.store.Read(ctx)()
+
+	var inventory domain.InventoryItem
+	for _, existing := range i.store.Inventory {
+		if existing.Item.ID == itemID && existing.LocationID == locationID {
+			inventory = existing
+		}
+	}
+	return &inventory, nil
+}
+
+func (i *memoryInventoryRepository) GetAll(ctx context.Context, count int, offset int, filter domain.InventorySpecification) ([]domain.InventoryItem, error) {
+	defer i.store.Read(ctx)()
+
+	var inventoryItems []domain.InventoryItem
+	for _, inventory := range i.store.Inventory {
+		joined := inventory
+		joined.Item = i.store.Items[inventory.Item.ID]
+		for _, lot := range i.store.Lots {
+			if lot.InventoryID == inventory.ID {
+				joined.Lots = append(joined.Lots, lot)
+			}
+		}
+		if filter.IsSatisfiedBy(joined) {
+			inventoryItems = append(inventoryItems, inventory)
+		}
+	}
+	sort.Slice(inventoryItems, func(a, b int) bool {
+		return inventoryItems[a].ID < inventoryItems[b].ID
+	})
+
+	start, end := memory.Page(len(inventoryItems), count, offset)
+	return inventoryItems[start:end], nil
+}
+
+func (i *memoryInventoryRepository) GetByID(ctx context.Context, id string) (*domain.InventoryItem, error) {
+	defer i.store.Read(ctx)()
+
+	inventory := i.store.Inventory[id]
+	return &inventory, nil
+}
+
+// GetByIDForUpdate needs no lock of its own, transactions hold the whole store
+func (i *memoryInventoryRepository) GetByIDForUpdate(ctx context.Context, id string) (*domain.InventoryItem, error) {
+	return i.GetByID(ctx, id)
+}
+
+func (i *memoryInventoryRepository) Save(ctx context.Context, inventoryItem *domain.InventoryItem) (*domain.InventoryItem, error) {
+	defer i.store.Write(ctx)()
+
+	if _, ok := i.store.Items[inventoryItem.Item.ID]; !ok {
+		return nil, errors.NewBadRequestError("no item or location with such ID exists")
+	}
+	if _, ok := i.store.Locations[inventoryItem.LocationID]; !ok {
+		return nil, errors.NewBadRequestError("no item or location with such ID exists")
+	}
+	if _, ok := i.store.Inventory[inventoryItem.ID]; ok {
+		return nil, errors.NewConflictError("inventory already exists")
+	}
+	for _, existing := range i.store.Inventory {
+		if existing.Item.ID == inventoryItem.Item.ID && existing.LocationID == inventoryItem.LocationID {
+			return nil, errors.NewConflictError("the item already has inventory at the location")
+		}
+	}
+
+	inventory := *inventoryItem
+	inventory.Item = domain.Item{ID: inventoryItem.Item.ID}
+	i.store.Inventory[inventory.ID] = inventory
+	return inventoryItem, nil
+}
+
+func (i *memoryInventoryRepository) Edit(ctx context.Context, inventoryItem *domain.InventoryItem) (*domain.InventoryItem, error) {
+	defer i.store.Write(ctx)()
+
+	existing, ok := i.store.Inventory[inventoryItem.ID]
+	if !ok || existing.Version != inventoryItem.Version {
+		return nil, errors.NewPreconditionFailedError("inventory has been modified or deleted since it was read")
+	}
+	existing.Quantity = inventoryItem.Quantity
+	existing.UpdatedAt = inventoryItem.UpdatedAt
+	existing.Version++
+	i.store.Inventory[inventoryItem.ID] = existing
+
+	inventoryItem.Version = existing.Version
+	return inventoryItem, nil
+}
+
+func (i *memoryInventoryRepository) AdjustQuantity(ctx context.Context, id string, delta int, updatedAt time.Time) (*domain.InventoryItem, error) {
+	defer i.store.Write(ctx)()
+
+	inventory, ok := i.store.Inventory[id]
+	if !ok {
+		return nil, errors.NewNotFoundError("no such inventory found")
+	}
+	if inventory.Quantity+delta < 0 {
+		return nil, errors.NewConflictError("insufficient stock. Quantity can't be less than 0")
+	}
+
+	inventory.Quantity += delta
+	inventory.UpdatedAt = updatedAt
+	inventory.Version++
+	i.store.Inventory[id] = inventory
+	return &inventory, nil
+}
+
+func (i *memoryInventoryRepository) DeleteItem(ctx context.Context, id string) error {
+	defer i.store.Write(ctx)()
+
+	for lotID, lot := range i.store.Lots {
+		if lot.InventoryID == id {
+			delete(i.store.Lots, lotID)
+		}
+	}
+	delete(i.store.Inventory, id)
+	return nil
+}
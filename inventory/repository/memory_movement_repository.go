@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"time"
+)
+
+type memoryMovementRepository struct {
+	store *memory.Store
+}
+
+// NewMemoryMovementRepository keeps the stock movement ledger in store instead of a database
+func NewMemoryMovementRepository(store *memory.Store) domain.MovementRepository {
+	return &memoryMovementRepository{store: store}
+}
+
+func (m *memoryMovementRepository) Save(ctx context.Context, movement *domain.StockMovement) (*domain.StockMovement, error) {
+	defer m.store.Write(ctx)()
+
+	m.store.Movements = append(m.store.Movements, *movement)
+	return movement, nil
+}
+
+// GetForInventory relies on movements being appended in the order they happen
+func (m *memoryMovementRepository) GetForInventory(ctx context.Context, inventoryID string, count int, offset int,
+	filter domain.MovementFilter) ([]domain.StockMovement, error) {
+	defer m.store.Read(ctx)()
+
+	var movements []domain.StockMovement
+	for _, movement := range m.store.Movements {
+		if movement.InventoryID != inventoryID {
+			continue
+		}
+		if !filter.From.IsZero() && movement.CreatedAt.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !movement.CreatedAt.Before(filter.To) {
+			continue
+		}
+		movements = append(movements, movement)
+	}
+
+	start, end := memory.Page(len(movements), count, offset)
+	return movements[start:end], nil
+}
+
+func (m *memoryMovementRepository) GetSales(ctx context.Context, itemID string,
+	since time.Time) ([]domain.Sale, error) {
+	defer m.store.Read(ctx)()
+
+	var sales []domain.Sale
+	for _, movement := range m.store.Movements {
+		if movement.Reason != domain.ReasonSold || movement.CreatedAt.Before(since) {
+			continue
+		}
+		inventory, ok := m.store.Inventory[movement.InventoryID]
+		if !ok || (itemID != "" && inventory.Item.ID != itemID) {
+			continue
+		}
+		sales = append(sales, domain.Sale{ItemID: inventory.Item.ID, LocationID: inventory.LocationID,
+			Quantity: -movement.Delta, SoldAt: movement.CreatedAt})
+	}
+	return sales, nil
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/nuzurie/shopify/db"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"github.com/shopspring/decimal"
+	"strings"
+	"time"
+)
+
+type movementRepository struct {
+	db db.DB
+}
+
+const (
+	saveMovement = `INSERT INTO inventory_movement (id, inventory_id, delta, quantity, reason, actor, correlation_id, cost,
+			created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	getMovementsForInventory = `SELECT id, inventory_id, delta, quantity, reason, actor, correlation_id, cost, created_at
+			FROM inventory_movement WHERE %s ORDER BY created_at, id LIMIT $%d OFFSET $%d`
+	getSales = `SELECT inventory.item_id, inventory.location_id, -inventory_movement.delta, inventory_movement.created_at
+			FROM inventory_movement JOIN inventory ON inventory.id = inventory_movement.inventory_id
+			WHERE inventory_movement.reason='sold' AND inventory_movement.created_at>=$1`
+	getItemSales = getSales + ` AND inventory.item_id=$2`
+)
+
+// NewMovementRepository stores the stock movement ledger in a SQL database, Postgres or SQLite
+func NewMovementRepository(database db.DB) domain.MovementRepository {
+	return &movementRepository{db: database}
+}
+
+func (m *movementRepository) Save(ctx context.Context, movement *domain.StockMovement) (*domain.StockMovement, error) {
+	_, err := m.db.Exec(ctx, saveMovement, movement.ID, movement.InventoryID, movement.Delta, movement.Quantity,
+		movement.Reason, movement.Actor, movement.CorrelationID, movement.Cost, movement.CreatedAt)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return movement, nil
+}
+
+func (m *movementRepository) GetForInventory(ctx context.Context, inventoryID string, count int, offset int,
+	filter domain.MovementFilter) ([]domain.StockMovement, error) {
+	conditions := []string{"inventory_id=$1"}
+	args := []interface{}{inventoryID}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		conditions = append(conditions, fmt.Sprintf("created_at>=$%d", len(args)))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		conditions = append(conditions, fmt.Sprintf("created_at<$%d", len(args)))
+	}
+
+	query := fmt.Sprintf(getMovementsForInventory, strings.Join(conditions, " AND "), len(args)+1, len(args)+2)
+	rows, err := m.db.Query(ctx, query, append(args, count, offset)...)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var movements []domain.StockMovement
+	for rows.Next() {
+		var movement domain.StockMovement
+		var cost decimal.NullDecimal
+		err = rows.Scan(&movement.ID, &movement.InventoryID, &movement.Delta, &movement.Quantity, &movement.Reason,
+			&movement.Actor, &movement.CorrelationID, &cost, &movement.CreatedAt)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+		if cost.Valid {
+			movement.Cost = &cost.Decimal
+		}
+
+		movements = append(movements, movement)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return movements, nil
+}
+
+func (m *movementRepository) GetSales(ctx context.Context, itemID string, since time.Time) ([]domain.Sale, error) {
+	query, args := getSales, []interface{}{since}
+	if itemID != "" {
+		query, args = getItemSales, append(args, itemID)
+	}
+
+	rows, err := m.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var sales []domain.Sale
+	for rows.Next() {
+		var sale domain.Sale
+		if err = rows.Scan(&sale.ItemID, &sale.LocationID, &sale.Quantity, &sale.SoldAt); err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		sales = append(sales, sale)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return sales, nil
+}
@@ -4,28 +4,63 @@ import (
 	"context"
 	"github.com/google/uuid"
 	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/item/validator"
+	"github.com/nuzurie/shopify/utils/audit"
 	"github.com/nuzurie/shopify/utils/errors"
+	"github.com/shopspring/decimal"
 	"golang.org/x/sync/errgroup"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// maxSortedInventory is how much inventory is sorted by stockout at most. Inventory past it isn't listed
+const maxSortedInventory = 10000
+
 type inventoryUseCase struct {
-	itemRepository      domain.ItemRepository
-	inventoryRepository domain.InventoryRepository
-	timeout             time.Duration
+	itemRepository        domain.ItemRepository
+	inventoryRepository   domain.InventoryRepository
+	movementRepository    domain.MovementRepository
+	reservationRepository domain.ReservationRepository
+	locationRepository    domain.LocationRepository
+	lotRepository         domain.LotRepository
+	serialRepository      domain.SerialRepository
+	costRepository        domain.CostRepository
+	watcher               domain.StockWatcher
+	transactor            domain.Transactor
+	timeout               time.Duration
 }
 
-func NewInventoryUseCase(itemRepository domain.ItemRepository,
-	inventoryRepository domain.InventoryRepository, timeout time.Duration) domain.InventoryUseCase {
-	return &inventoryUseCase{itemRepository: itemRepository, inventoryRepository: inventoryRepository, timeout: timeout}
+// NewInventoryUseCase records every quantity change in the movement ledger, in the same transaction as the change.
+// The reservations in reservationRepository are reported as reserved stock, and stock at the quarantine locations of
+// locationRepository or in blocked lots of lotRepository as unavailable. Stock taken out without naming a lot comes out
+// of the lots first-expiry-first-out. The units of serialized items moving are tracked in serialRepository, and what
+// they cost in the layers of costRepository. watcher is told about the item of every quantity change
+func NewInventoryUseCase(itemRepository domain.ItemRepository, inventoryRepository domain.InventoryRepository,
+	movementRepository domain.MovementRepository, reservationRepository domain.ReservationRepository,
+	locationRepository domain.LocationRepository, lotRepository domain.LotRepository,
+	serialRepository domain.SerialRepository, costRepository domain.CostRepository, watcher domain.StockWatcher,
+	transactor domain.Transactor, timeout time.Duration) domain.InventoryUseCase {
+	return &inventoryUseCase{itemRepository: itemRepository, inventoryRepository: inventoryRepository,
+		movementRepository: movementRepository, reservationRepository: reservationRepository,
+		locationRepository: locationRepository, lotRepository: lotRepository, serialRepository: serialRepository,
+		costRepository: costRepository, watcher: watcher, transactor: transactor, timeout: timeout}
 }
 
-func (i *inventoryUseCase) GetAll(ctx context.Context, count int, offset int,
-	filter domain.InventorySpecification) ([]domain.InventoryItem, error) {
+func (i *inventoryUseCase) GetAll(ctx context.Context, count int, offset int, filter domain.InventorySpecification,
+	order domain.InventorySort) ([]domain.InventoryItem, error) {
 	c, cancel := context.WithTimeout(ctx, i.timeout)
 	defer cancel()
 
+	if !order.IsValid() {
+		return nil, errors.NewBadRequestError("invalid request. Unknown sort " + string(order))
+	}
+	if order == domain.InventorySortStockout {
+		return i.getAllByStockout(c, count, offset, filter)
+	}
+
 	inventoryItems, err := i.inventoryRepository.GetAll(c, count, offset, filter)
 	if err != nil {
 		return nil, err
@@ -33,10 +68,111 @@ func (i *inventoryUseCase) GetAll(ctx context.Context, count int, offset int,
 	if len(inventoryItems) == 0 {
 		return nil, errors.NewNotFoundError("no items found matching the specification")
 	}
+	if err = i.fillAvailability(c, inventoryItems); err != nil {
+		return nil, err
+	}
+
+	return i.fillItemDetails(c, inventoryItems)
+}
+
+// getAllByStockout projects when every inventory matching filter runs out at the rate it sold over the default
+// forecast window, and pages through them soonest first
+func (i *inventoryUseCase) getAllByStockout(c context.Context, count int, offset int,
+	filter domain.InventorySpecification) ([]domain.InventoryItem, error) {
+	inventoryItems, err := i.inventoryRepository.GetAll(c, maxSortedInventory, 0, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(inventoryItems) == 0 {
+		return nil, errors.NewNotFoundError("no items found matching the specification")
+	}
+	if err = i.fillAvailability(c, inventoryItems); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sales, err := i.movementRepository.GetSales(c, "", now.AddDate(0, 0, -domain.DefaultForecastWindowDays))
+	if err != nil {
+		return nil, err
+	}
+	salesAt := map[string][]domain.Sale{}
+	for _, sale := range sales {
+		salesAt[sale.ItemID+"/"+sale.LocationID] = append(salesAt[sale.ItemID+"/"+sale.LocationID], sale)
+	}
+
+	for index, inventory := range inventoryItems {
+		dailySales := domain.DailySales(salesAt[inventory.Item.ID+"/"+inventory.LocationID],
+			domain.DefaultForecastWindowDays, now)
+		forecast := domain.ProjectForecast(dailySales, inventory.Available, domain.DefaultForecastAlpha, now)
+		inventoryItems[index].DaysOfCover = forecast.DaysOfCover
+		inventoryItems[index].StockoutDate = forecast.StockoutDate
+	}
+	sort.SliceStable(inventoryItems, func(a, b int) bool {
+		return domain.StockoutBefore(inventoryItems[a].StockoutDate, inventoryItems[b].StockoutDate)
+	})
+
+	if offset > len(inventoryItems) {
+		offset = len(inventoryItems)
+	}
+	if end := offset + count; count >= 0 && end < len(inventoryItems) {
+		inventoryItems = inventoryItems[:end]
+	}
+	inventoryItems = inventoryItems[offset:]
+	if len(inventoryItems) == 0 {
+		return nil, errors.NewNotFoundError("no items found matching the specification")
+	}
 
 	return i.fillItemDetails(c, inventoryItems)
 }
 
+// fillAvailability sets how much of each inventory is reserved and how much is left to sell, along with the lots still
+// holding stock. Available is negative when stock held by reservations was lost or blocked since, and nothing at
+// quarantine locations is available
+func (i *inventoryUseCase) fillAvailability(c context.Context, inventoryItems []domain.InventoryItem) error {
+	inventoryIDs := make([]string, len(inventoryItems))
+	quarantined := map[string]bool{}
+	for index, inventory := range inventoryItems {
+		inventoryIDs[index] = inventory.ID
+		if _, ok := quarantined[inventory.LocationID]; ok {
+			continue
+		}
+		location, err := i.locationRepository.GetOne(c, inventory.LocationID)
+		if err != nil {
+			return err
+		}
+		quarantined[inventory.LocationID] = location.Type == domain.LocationQuarantine
+	}
+	reserved, err := i.reservationRepository.Reserved(c, inventoryIDs, time.Now())
+	if err != nil {
+		return err
+	}
+	lots, err := i.lotRepository.GetForInventory(c, inventoryIDs)
+	if err != nil {
+		return err
+	}
+	lotsOf := map[string][]domain.Lot{}
+	blocked := map[string]int{}
+	for _, lot := range lots {
+		if lot.Quantity == 0 {
+			continue
+		}
+		lotsOf[lot.InventoryID] = append(lotsOf[lot.InventoryID], lot)
+		if lot.Blocked {
+			blocked[lot.InventoryID] += lot.Quantity
+		}
+	}
+
+	for index, inventory := range inventoryItems {
+		inventoryItems[index].Reserved = reserved[inventory.ID]
+		inventoryItems[index].Available = inventory.Quantity - reserved[inventory.ID] - blocked[inventory.ID]
+		inventoryItems[index].Lots = lotsOf[inventory.ID]
+		if quarantined[inventory.LocationID] {
+			inventoryItems[index].Available = 0
+		}
+	}
+	return nil
+}
+
 func (i *inventoryUseCase) fillItemDetails(c context.Context, inventoryItems []domain.InventoryItem) ([]domain.InventoryItem, error) {
 	group, ctx := errgroup.WithContext(c)
 
@@ -69,7 +205,7 @@ func (i *inventoryUseCase) fillItemDetails(c context.Context, inventoryItems []d
 	}()
 
 	for item := range itemChan {
-		if item != (domain.Item{}) {
+		if item.ID != "" {
 			inventoryMap[item.ID] = item
 		}
 	}
@@ -86,35 +222,77 @@ func (i *inventoryUseCase) fillItemDetails(c context.Context, inventoryItems []d
 	return inventoryItems, nil
 }
 
-func (i *inventoryUseCase) GetInventoryForItem(ctx context.Context, itemID string) (*domain.InventoryItem, error) {
+func (i *inventoryUseCase) GetInventoryForItem(ctx context.Context, itemID string) (*domain.ItemStock, error) {
 	c, cancel := context.WithTimeout(ctx, i.timeout)
 	defer cancel()
 
-	inventoryItem, err := i.inventoryRepository.GetInventoryForItem(c, itemID)
+	inventoryItems, err := i.inventoryRepository.GetInventoryForItem(c, itemID)
 	if err != nil {
 		return nil, err
 	}
-	if inventoryItem == nil || inventoryItem.ID == "" {
+	if len(inventoryItems) == 0 {
 		return nil, errors.NewNotFoundError("no item found matching the specification")
 	}
+	if err = i.fillAvailability(c, inventoryItems); err != nil {
+		return nil, err
+	}
 
-	item, err := i.itemRepository.GetOne(c, inventoryItem.Item.ID)
+	stock := domain.ItemStock{Item: domain.Item{ID: itemID}}
+	item, err := i.itemRepository.GetOne(c, itemID)
 	if err != nil {
 		return nil, err
 	}
 	if item == nil {
 		log.Println("error getting the item for inventory")
 	} else {
-		inventoryItem.Item = *item
+		stock.Item = *item
 	}
 
-	return inventoryItem, nil
+	for _, inventory := range inventoryItems {
+		stock.Quantity += inventory.Quantity
+		stock.Reserved += inventory.Reserved
+		stock.Available += inventory.Available
+		stock.Locations = append(stock.Locations, domain.LocationStock{
+			InventoryID: inventory.ID,
+			LocationID:  inventory.LocationID,
+			Quantity:    inventory.Quantity,
+			Reserved:    inventory.Reserved,
+			Available:   inventory.Available,
+			Lots:        inventory.Lots,
+			UpdatedAt:   inventory.UpdatedAt,
+			Version:     inventory.Version,
+		})
+	}
+
+	return &stock, nil
 }
 
 func (i *inventoryUseCase) UpdateInventoryItem(ctx context.Context, inventory *domain.InventoryItem) (*domain.InventoryItem, error) {
 	c, cancel := context.WithTimeout(ctx, i.timeout)
 	defer cancel()
 
+	if inventory.Quantity < 0 {
+		return nil, errors.NewBadRequestError("invalid request. Quantity can't be less than 0")
+	}
+
+	var updated *domain.InventoryItem
+	err := i.transactor.WithinTransaction(c, func(c context.Context) error {
+		var err error
+		updated, err = i.updateInventoryItem(c, inventory)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	updatedItems := []domain.InventoryItem{*updated}
+	if err = i.fillAvailability(c, updatedItems); err != nil {
+		return nil, err
+	}
+	return &updatedItems[0], nil
+}
+
+func (i *inventoryUseCase) updateInventoryItem(c context.Context, inventory *domain.InventoryItem) (*domain.InventoryItem, error) {
 	// no inventory already exists
 	if inventory.ID == "" {
 		var existingItem *domain.Item
@@ -133,35 +311,78 @@ func (i *inventoryUseCase) UpdateInventoryItem(ctx context.Context, inventory *d
 		if existingItem == nil || existingItem.ID == "" {
 			// create an item
 			inventory.Item.ID = uuid.NewString()
+			if inventory.Item.CostingMethod == "" {
+				inventory.Item.CostingMethod = domain.DefaultCostingMethod
+			}
+			if !inventory.Item.CostingMethod.IsValid() {
+				return nil, errors.NewBadRequestError("invalid request. Unknown costing method " +
+					string(inventory.Item.CostingMethod))
+			}
+			if inventory.Item.Currency == "" {
+				inventory.Item.Currency = domain.DefaultCurrency
+			}
+			if err = validator.Prices(&inventory.Item); err != nil {
+				return nil, err
+			}
+			if strings.TrimSpace(inventory.Item.BaseUnit) == "" {
+				inventory.Item.BaseUnit = domain.DefaultBaseUnit
+			}
+			if err = validator.Identifiers(c, i.itemRepository, &inventory.Item); err != nil {
+				return nil, err
+			}
+			if err = validator.Units(&inventory.Item); err != nil {
+				return nil, err
+			}
 			inventory.Item.CreatedAt = time.Now()
+			inventory.Item.Version = 1
 			_, err = i.itemRepository.Save(c, &inventory.Item)
 			if err != nil {
 				return nil, err
 			}
 		}
+		if inventory.LocationID == "" {
+			inventory.LocationID = domain.DefaultLocationID
+		}
+
+		// this allows us to increase the number if item already exists at the location instead of creating another
+		// entry for the same item
+		inv, err := i.inventoryRepository.GetInventoryAtLocation(c, inventory.Item.ID, inventory.LocationID)
+		if err != nil {
+			return nil, err
+		}
+		if (*inv).ID == "" {
+			if inventory.Version != 0 {
+				return nil, errors.NewPreconditionFailedError("no inventory exists for the item")
+			}
+			inventory.ID = uuid.NewString()
+			inventory.UpdatedAt = time.Now()
+			inventory.Version = 1
+			created, err := i.inventoryRepository.Save(c, inventory)
+			if err != nil {
+				return nil, err
+			}
+			return created, i.recordMovement(c, created,
+				domain.StockAdjustment{Delta: created.Quantity, Reason: domain.ReasonReceived})
+		}
+		inventory.ID = inv.ID
 	}
 
-	// this allows us to increase the number if item already exists in inventory instead of creating another
-	// entry for the same item
-	inv, err := i.inventoryRepository.GetInventoryForItem(c, inventory.Item.ID)
+	// the ledger records the difference, so the quantity it's computed from must not change under us
+	current, err := i.inventoryRepository.GetByIDForUpdate(c, inventory.ID)
 	if err != nil {
 		return nil, err
 	}
-	if inventory.ID == "" {
-		if (*inv).ID == "" {
-			inventory.ID = uuid.NewString()
-			return i.inventoryRepository.Save(c, inventory)
-		} else {
-			inventory.ID = inv.ID
-		}
+	if current.ID == "" {
+		return nil, errors.NewNotFoundError("no such inventory found")
 	}
-	// ensure we aren't trying to change the item. why must this ever happen?
-	if inv.ID != inventory.ID {
-		log.Println("error", inv.ID, inventory.ID)
-		return nil, errors.NewBadRequestError("invalid request. Can't change the item while updating")
+	// ensure we aren't trying to change the item or location. why must this ever happen?
+	if current.Item.ID != inventory.Item.ID || (inventory.LocationID != "" && current.LocationID != inventory.LocationID) {
+		log.Println("error", current.ID, inventory.ID)
+		return nil, errors.NewBadRequestError("invalid request. Can't change the item or location while updating")
 	}
-	if inventory.Quantity < 0 {
-		return nil, errors.NewBadRequestError("invalid request. Quantity can't be less than 0")
+	inventory.LocationID = current.LocationID
+	if inventory.Version == 0 {
+		inventory.Version = current.Version
 	}
 	inventory.UpdatedAt = time.Now()
 	var updated *domain.InventoryItem
@@ -170,27 +391,580 @@ func (i *inventoryUseCase) UpdateInventoryItem(ctx context.Context, inventory *d
 		return nil, err
 	}
 
-	return updated, nil
+	return updated, i.recordMovement(c, updated,
+		domain.StockAdjustment{Delta: updated.Quantity - current.Quantity, Reason: domain.ReasonCorrection})
 }
 
-func (i *inventoryUseCase) DeleteItem(ctx context.Context, id string) error {
+func (i *inventoryUseCase) AdjustQuantity(ctx context.Context, id string, adjustment domain.StockAdjustment,
+	version int) (*domain.InventoryItem, error) {
 	c, cancel := context.WithTimeout(ctx, i.timeout)
 	defer cancel()
 
-	inventory, err := i.inventoryRepository.GetByID(c, id)
+	if adjustment.Delta == 0 {
+		return nil, errors.NewBadRequestError("invalid request. Delta can't be 0")
+	}
+	if !adjustment.Reason.IsValid() {
+		return nil, errors.NewBadRequestError("invalid request. Unknown reason " + string(adjustment.Reason))
+	}
+
+	var inventory *domain.InventoryItem
+	err := i.transactor.WithinTransaction(c, func(c context.Context) error {
+		if adjustment.Unit != "" {
+			current, err := i.inventoryRepository.GetByID(c, id)
+			if err != nil {
+				return err
+			}
+			if current.ID == "" {
+				return errors.NewNotFoundError("no such inventory found")
+			}
+			if err = i.toBaseUnit(c, current.Item.ID, &adjustment); err != nil {
+				return err
+			}
+		}
+		if version != 0 {
+			current, err := i.inventoryRepository.GetByIDForUpdate(c, id)
+			if err != nil {
+				return err
+			}
+			if current.ID == "" {
+				return errors.NewNotFoundError("no such inventory found")
+			}
+			if current.Version != version {
+				return errors.NewPreconditionFailedError("inventory has been modified since it was read")
+			}
+		}
+
+		var err error
+		inventory, err = i.inventoryRepository.AdjustQuantity(c, id, adjustment.Delta, time.Now())
+		if err != nil {
+			return err
+		}
+		return i.recordMovement(c, inventory, adjustment)
+	})
 	if err != nil {
-		log.Println(err)
-		return errors.NewInternalServerError(err.Error())
+		return nil, err
 	}
-	if inventory == nil || inventory.ID == "" {
-		return errors.NewNotFoundError("no such item found")
+	log.Println("adjusted inventory", id, "by", adjustment.Delta, "for", adjustment.Reason)
+
+	item, err := i.itemRepository.GetOne(c, inventory.Item.ID)
+	if err != nil {
+		return nil, err
 	}
-	err = i.inventoryRepository.DeleteItem(c, inventory.ID)
+	inventory.Item = *item
+
+	adjusted := []domain.InventoryItem{*inventory}
+	if err = i.fillAvailability(c, adjusted); err != nil {
+		return nil, err
+	}
+	return &adjusted[0], nil
+}
+
+func (i *inventoryUseCase) MoveStock(ctx context.Context, itemID string, locationID string,
+	adjustment domain.StockAdjustment) (*domain.InventoryItem, error) {
+	c, cancel := context.WithTimeout(ctx, i.timeout)
+	defer cancel()
+
+	var inventory *domain.InventoryItem
+	err := i.transactor.WithinTransaction(c, func(c context.Context) error {
+		if err := i.toBaseUnit(c, itemID, &adjustment); err != nil {
+			return err
+		}
+		existing, err := i.inventoryRepository.GetInventoryAtLocation(c, itemID, locationID)
+		if err != nil {
+			return err
+		}
+
+		if existing.ID == "" {
+			if adjustment.Delta < 0 {
+				return errors.NewConflictError("insufficient stock. The location holds none of item " + itemID)
+			}
+			inventory = &domain.InventoryItem{ID: uuid.NewString(), Item: domain.Item{ID: itemID},
+				LocationID: locationID, Quantity: adjustment.Delta, UpdatedAt: time.Now(), Version: 1}
+			if _, err = i.inventoryRepository.Save(c, inventory); err != nil {
+				return err
+			}
+		} else {
+			inventory, err = i.inventoryRepository.AdjustQuantity(c, existing.ID, adjustment.Delta, time.Now())
+			if err != nil {
+				return err
+			}
+		}
+		return i.recordMovement(c, inventory, adjustment)
+	})
 	if err != nil {
-		log.Println(err.Error())
-		return errors.NewInternalServerError(err.Error())
+		return nil, err
 	}
 
-	// this is a design choice. Perhaps a bit iffy. In real life, it'd depend on what the client wants
-	return i.itemRepository.Delete(c, inventory.Item.ID)
+	return inventory, nil
+}
+
+func (i *inventoryUseCase) WriteOffInTransit(ctx context.Context, itemID string, quantity int) error {
+	c, cancel := context.WithTimeout(ctx, i.timeout)
+	defer cancel()
+
+	if quantity <= 0 {
+		return nil
+	}
+	return i.transactor.WithinTransaction(c, func(c context.Context) error {
+		item, err := i.itemRepository.GetOne(c, itemID)
+		if err != nil {
+			return err
+		}
+		if item.ID == "" {
+			return errors.NewNotFoundError("no such item found")
+		}
+		movement := &domain.StockMovement{
+			ID:            uuid.NewString(),
+			Delta:         -quantity,
+			Reason:        domain.ReasonLostInTransit,
+			Actor:         audit.Actor(c),
+			CorrelationID: audit.CorrelationID(c),
+			CreatedAt:     time.Now(),
+		}
+		if movement.Cost, err = i.postCost(c, item, movement, nil); err != nil {
+			return err
+		}
+		_, err = i.movementRepository.Save(c, movement)
+		return err
+	})
+}
+
+// toBaseUnit converts an adjustment given in another unit of the item to its base unit
+func (i *inventoryUseCase) toBaseUnit(c context.Context, itemID string, adjustment *domain.StockAdjustment) error {
+	if adjustment.Unit == "" {
+		return nil
+	}
+	item, err := i.itemRepository.GetOne(c, itemID)
+	if err != nil {
+		return err
+	}
+	if item.ID == "" {
+		return errors.NewBadRequestError("invalid request. Item " + itemID + " isn't counted in " + adjustment.Unit)
+	}
+	counted, fits := item.ToBaseUnit(adjustment)
+	if !counted {
+		return errors.NewBadRequestError("invalid request. Item " + itemID + " isn't counted in " + adjustment.Unit)
+	}
+	if !fits {
+		return errors.NewBadRequestError("invalid request. Delta is too large for item " + itemID)
+	}
+	return nil
+}
+
+func (i *inventoryUseCase) DeleteItem(ctx context.Context, id string, version int) error {
+	c, cancel := context.WithTimeout(ctx, i.timeout)
+	defer cancel()
+
+	return i.transactor.WithinTransaction(c, func(c context.Context) error {
+		inventory, err := i.inventoryRepository.GetByIDForUpdate(c, id)
+		if err != nil {
+			log.Println(err)
+			return errors.NewInternalServerError(err.Error())
+		}
+		if inventory == nil || inventory.ID == "" {
+			return errors.NewNotFoundError("no such item found")
+		}
+		if version != 0 && inventory.Version != version {
+			return errors.NewPreconditionFailedError("inventory has been modified since it was read")
+		}
+		// the units of a serialized item go along with its inventory
+		onHand, err := i.serialRepository.GetOnHand(c, inventory.Item.ID, inventory.LocationID)
+		if err != nil {
+			return err
+		}
+		serials := make([]string, len(onHand))
+		for index, serial := range onHand {
+			serials[index] = serial.SerialNumber
+		}
+		err = i.inventoryRepository.DeleteItem(c, inventory.ID)
+		if err != nil {
+			log.Println(err.Error())
+			return errors.NewInternalServerError(err.Error())
+		}
+		removed := *inventory
+		removed.Quantity = 0
+		err = i.recordMovement(c, &removed,
+			domain.StockAdjustment{Delta: -inventory.Quantity, Reason: domain.ReasonRemoved, Serials: serials})
+		if err != nil {
+			return err
+		}
+
+		// this is a design choice. Perhaps a bit iffy. In real life, it'd depend on what the client wants. The item
+		// goes once no location holds it anymore
+		remaining, err := i.inventoryRepository.GetInventoryForItem(c, inventory.Item.ID)
+		if err != nil {
+			return err
+		}
+		if len(remaining) > 0 {
+			return nil
+		}
+		item, err := i.itemRepository.GetOne(c, inventory.Item.ID)
+		if err != nil || item.ID == "" {
+			return err
+		}
+		return i.itemRepository.Delete(c, item.ID, item.Version)
+	})
+}
+
+func (i *inventoryUseCase) GetMovements(ctx context.Context, id string, count int, offset int,
+	filter domain.MovementFilter) ([]domain.StockMovement, error) {
+	c, cancel := context.WithTimeout(ctx, i.timeout)
+	defer cancel()
+
+	movements, err := i.movementRepository.GetForInventory(c, id, count, offset, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(movements) == 0 {
+		return nil, errors.NewNotFoundError("no movements found for the inventory")
+	}
+
+	return movements, nil
+}
+
+// recordMovement appends adjustment of inventory to the ledger, costing it along the way. It must run in the
+// transaction that made the change, and inventory must hold the resulting quantity. The serials of adjustment are the
+// units that moved, if the item is serialized
+func (i *inventoryUseCase) recordMovement(c context.Context, inventory *domain.InventoryItem,
+	adjustment domain.StockAdjustment) error {
+	if adjustment.Delta == 0 {
+		if len(adjustment.Serials) > 0 {
+			return errors.NewBadRequestError("invalid request. No units moved, so no serials can be given")
+		}
+		return nil
+	}
+	if adjustment.UnitCost != nil {
+		if adjustment.Delta < 0 {
+			return errors.NewBadRequestError("invalid request. Only units arriving can be given a unit cost")
+		}
+		if adjustment.UnitCost.IsNegative() {
+			return errors.NewBadRequestError("invalid request. Unit cost can't be less than 0")
+		}
+	}
+
+	item, err := i.itemRepository.GetOne(c, inventory.Item.ID)
+	if err != nil {
+		return err
+	}
+	movement := &domain.StockMovement{
+		ID:            uuid.NewString(),
+		InventoryID:   inventory.ID,
+		Delta:         adjustment.Delta,
+		Quantity:      inventory.Quantity,
+		Reason:        adjustment.Reason,
+		Actor:         audit.Actor(c),
+		CorrelationID: audit.CorrelationID(c),
+		CreatedAt:     time.Now(),
+	}
+	// the ledger can't be changed once written, so the movement is costed first
+	if movement.Cost, err = i.postCost(c, item, movement, adjustment.UnitCost); err != nil {
+		return err
+	}
+	if movement, err = i.movementRepository.Save(c, movement); err != nil {
+		return err
+	}
+	if err = i.moveSerials(c, item, inventory, movement, adjustment.Serials); err != nil {
+		return err
+	}
+	if adjustment.Delta < 0 {
+		if err = i.consumeLots(c, inventory); err != nil {
+			return err
+		}
+	}
+
+	i.watcher.StockChanged(inventory.Item.ID)
+	return nil
+}
+
+// postCost creates a cost layer for the units that arrived with movement, or consumes the layers of item for those
+// that left, in the order of its costing method. It returns the value of the layer created or the cost of goods of the
+// units that left. Transfers aren't costed, since what is owned stays the same
+func (i *inventoryUseCase) postCost(c context.Context, item *domain.Item, movement *domain.StockMovement,
+	unitCost *decimal.Decimal) (*decimal.Decimal, error) {
+	if movement.Reason.IsTransfer() {
+		return nil, nil
+	}
+	layers, err := i.costRepository.GetOpenLayersForUpdate(c, item.ID)
+	if err != nil {
+		return nil, err
+	}
+	method := item.CostingMethod
+	if method == "" {
+		method = domain.DefaultCostingMethod
+	}
+
+	if movement.Delta > 0 {
+		layer := domain.CostLayer{ID: uuid.NewString(), ItemID: item.ID, MovementID: movement.ID,
+			Quantity: movement.Delta, Remaining: movement.Delta, CreatedAt: movement.CreatedAt,
+			UpdatedAt: movement.CreatedAt}
+		if unitCost != nil {
+			layer.UnitCost = *unitCost
+		} else if layer.UnitCost, err = i.currentUnitCost(c, item.ID, layers); err != nil {
+			return nil, err
+		}
+		layer.RemainingValue = layer.UnitCost.Mul(decimal.NewFromInt(int64(layer.Quantity)))
+		cost := layer.RemainingValue
+
+		if method == domain.CostingAverage {
+			// the new layer absorbs the open ones, so every unit on hand is carried at their weighted average cost
+			if err = i.poolLayers(c, &layer, layers, movement.CreatedAt); err != nil {
+				return nil, err
+			}
+		}
+		if _, err = i.costRepository.SaveLayer(c, &layer); err != nil {
+			return nil, err
+		}
+		return &cost, nil
+	}
+
+	switch method {
+	case domain.CostingLIFO:
+		for left, right := 0, len(layers)-1; left < right; left, right = left+1, right-1 {
+			layers[left], layers[right] = layers[right], layers[left]
+		}
+	case domain.CostingAverage:
+		// layers left open while the item was costed some other way are pooled before any units leave
+		if len(layers) > 1 {
+			newest := layers[len(layers)-1]
+			if err = i.poolLayers(c, &newest, layers[:len(layers)-1], movement.CreatedAt); err != nil {
+				return nil, err
+			}
+			layers = []domain.CostLayer{newest}
+		}
+	}
+
+	units := -movement.Delta
+	var cost decimal.Decimal
+	for _, layer := range layers {
+		if units == 0 {
+			break
+		}
+		consumed := layer.Remaining
+		if consumed > units {
+			consumed = units
+		}
+		value := layer.RemainingValue
+		if consumed < layer.Remaining {
+			value = layer.RemainingValue.Mul(decimal.NewFromInt(int64(consumed))).
+				DivRound(decimal.NewFromInt(int64(layer.Remaining)), domain.CostPlaces)
+		}
+		layer.Remaining -= consumed
+		layer.RemainingValue = layer.RemainingValue.Sub(value)
+		layer.UpdatedAt = movement.CreatedAt
+		if _, err = i.costRepository.EditLayer(c, &layer); err != nil {
+			return nil, err
+		}
+		if err = i.saveConsumption(c, item.ID, layer.ID, movement, consumed, value); err != nil {
+			return nil, err
+		}
+		cost = cost.Add(value)
+		units -= consumed
+	}
+	if units > 0 {
+		// more left than the layers hold, which only stock that was never received can do. It has no cost
+		if err = i.saveConsumption(c, item.ID, "", movement, units, decimal.Zero); err != nil {
+			return nil, err
+		}
+	}
+	return &cost, nil
+}
+
+// poolLayers moves the remaining units and value of layers into into, which is saved by the caller
+func (i *inventoryUseCase) poolLayers(c context.Context, into *domain.CostLayer, layers []domain.CostLayer,
+	updatedAt time.Time) error {
+	for _, layer := range layers {
+		into.Remaining += layer.Remaining
+		into.RemainingValue = into.RemainingValue.Add(layer.RemainingValue)
+		layer.Remaining = 0
+		layer.RemainingValue = decimal.Zero
+		layer.UpdatedAt = updatedAt
+		if _, err := i.costRepository.EditLayer(c, &layer); err != nil {
+			return err
+		}
+	}
+	into.UpdatedAt = updatedAt
+	return nil
+}
+
+// currentUnitCost is what units arriving without a cost of their own are costed at: the average cost of the open
+// layers of the item, or the unit cost of its latest layer if none are open, or nothing if it has never had any
+func (i *inventoryUseCase) currentUnitCost(c context.Context, itemID string,
+	layers []domain.CostLayer) (decimal.Decimal, error) {
+	var remaining int
+	var value decimal.Decimal
+	for _, layer := range layers {
+		remaining += layer.Remaining
+		value = value.Add(layer.RemainingValue)
+	}
+	if remaining > 0 {
+		return value.DivRound(decimal.NewFromInt(int64(remaining)), domain.CostPlaces), nil
+	}
+
+	latest, err := i.costRepository.GetLayers(c, itemID, 1, 0)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if len(latest) == 0 {
+		return decimal.Zero, nil
+	}
+	return latest[0].UnitCost, nil
+}
+
+func (i *inventoryUseCase) saveConsumption(c context.Context, itemID string, layerID string,
+	movement *domain.StockMovement, quantity int, cost decimal.Decimal) error {
+	_, err := i.costRepository.SaveConsumption(c, &domain.CostConsumption{
+		ID:         uuid.NewString(),
+		ItemID:     itemID,
+		LayerID:    layerID,
+		MovementID: movement.ID,
+		Quantity:   quantity,
+		Cost:       cost,
+		CreatedAt:  movement.CreatedAt,
+	})
+	return err
+}
+
+// consumeLots takes the lots of inventory down to the quantity left when stock went out without naming a lot. The
+// stock held outside any lot goes first, then the lots first-expiry-first-out. Blocked lots are never taken, since
+// nothing should be allocated from them, so stock they alone hold can only leave by naming the lot
+func (i *inventoryUseCase) consumeLots(c context.Context, inventory *domain.InventoryItem) error {
+	lots, err := i.lotRepository.GetForInventory(c, []string{inventory.ID})
+	if err != nil {
+		return err
+	}
+	excess := -inventory.Quantity
+	for _, lot := range lots {
+		excess += lot.Quantity
+	}
+	if excess <= 0 {
+		return nil
+	}
+
+	sort.SliceStable(lots, func(a, b int) bool {
+		return domain.ExpiresBefore(lots[a], lots[b])
+	})
+	now := time.Now()
+	for index := 0; index < len(lots) && excess > 0; index++ {
+		lot := lots[index]
+		if lot.Quantity == 0 || lot.Blocked {
+			continue
+		}
+		consumed := lot.Quantity
+		if consumed > excess {
+			consumed = excess
+		}
+		lot.Quantity -= consumed
+		lot.UpdatedAt = now
+		if _, err = i.lotRepository.Edit(c, &lot); err != nil {
+			return err
+		}
+		excess -= consumed
+	}
+	if excess > 0 {
+		return errors.NewConflictError("insufficient stock. " + strconv.Itoa(excess) + " of the units of item " +
+			inventory.Item.ID + " are only held in blocked lots, which must be named to take stock out of them")
+	}
+	return nil
+}
+
+// moveSerials moves the serials of the units of item that arrived at or left inventory with movement, creating those
+// that arrive for the first time. Serialized items must name a serial for every unit moved, and the units on hand must
+// then agree with the quantity of inventory
+func (i *inventoryUseCase) moveSerials(c context.Context, item *domain.Item, inventory *domain.InventoryItem,
+	movement *domain.StockMovement, serials []string) error {
+	if !item.Serialized {
+		if len(serials) > 0 {
+			return errors.NewBadRequestError("invalid request. Item " + item.ID + " isn't serialized")
+		}
+		return nil
+	}
+
+	units := movement.Delta
+	if units < 0 {
+		units = -units
+	}
+	if len(serials) != units {
+		return errors.NewBadRequestError("invalid request. Item " + item.ID +
+			" is serialized, so it needs a serial for each of the " + strconv.Itoa(units) + " units moved")
+	}
+	serialNumbers := make([]string, len(serials))
+	seen := map[string]bool{}
+	for index, serialNumber := range serials {
+		serialNumber = strings.TrimSpace(serialNumber)
+		if serialNumber == "" {
+			return errors.NewBadRequestError("invalid request. Serials can't be empty")
+		}
+		if seen[serialNumber] {
+			return errors.NewBadRequestError("invalid request. Serial " + serialNumber + " is given more than once")
+		}
+		seen[serialNumber] = true
+		serialNumbers[index] = serialNumber
+	}
+
+	existing, err := i.serialRepository.GetByNumbersForUpdate(c, item.ID, serialNumbers)
+	if err != nil {
+		return err
+	}
+	known := map[string]domain.Serial{}
+	for _, serial := range existing {
+		known[serial.SerialNumber] = serial
+	}
+
+	arrived := movement.Delta > 0
+	status := domain.SerialStatusAfter(movement.Reason, arrived)
+	for _, serialNumber := range serialNumbers {
+		serial, ok := known[serialNumber]
+		switch {
+		case arrived && ok && serial.Status.IsOnHand():
+			return errors.NewConflictError("serial " + serialNumber + " is already on hand at location " +
+				serial.LocationID)
+		case !arrived && (!ok || !serial.Status.IsOnHand() || serial.LocationID != inventory.LocationID):
+			return errors.NewConflictError("serial " + serialNumber + " isn't on hand at location " +
+				inventory.LocationID)
+		}
+
+		serial.Status = status
+		serial.LocationID = ""
+		if arrived {
+			serial.LocationID = inventory.LocationID
+		}
+		serial.UpdatedAt = movement.CreatedAt
+		if ok {
+			_, err = i.serialRepository.Edit(c, &serial)
+		} else {
+			serial.ID = uuid.NewString()
+			serial.ItemID = item.ID
+			serial.SerialNumber = serialNumber
+			serial.CreatedAt = movement.CreatedAt
+			_, err = i.serialRepository.Save(c, &serial)
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = i.serialRepository.SaveEvent(c, &domain.SerialEvent{
+			ID:         uuid.NewString(),
+			SerialID:   serial.ID,
+			Status:     status,
+			LocationID: inventory.LocationID,
+			Reason:     movement.Reason,
+			MovementID: movement.ID,
+			Actor:      movement.Actor,
+			CreatedAt:  movement.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	onHand, err := i.serialRepository.GetOnHand(c, item.ID, inventory.LocationID)
+	if err != nil {
+		return err
+	}
+	if len(onHand) != inventory.Quantity {
+		return errors.NewConflictError("the " + strconv.Itoa(len(onHand)) + " serials of item " + item.ID +
+			" on hand at location " + inventory.LocationID + " don't agree with its quantity of " +
+			strconv.Itoa(inventory.Quantity))
+	}
+	return nil
 }
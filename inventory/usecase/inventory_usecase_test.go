@@ -0,0 +1,401 @@
+package usecase
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/inventory/repository"
+	repository2 "github.com/nuzurie/shopify/item/repository"
+	repository3 "github.com/nuzurie/shopify/location/repository"
+	repository4 "github.com/nuzurie/shopify/lot/repository"
+	repository5 "github.com/nuzurie/shopify/reservation/repository"
+	repository6 "github.com/nuzurie/shopify/serial/repository"
+	"github.com/nuzurie/shopify/specification"
+	"github.com/nuzurie/shopify/utils/errors"
+	repository7 "github.com/nuzurie/shopify/valuation/repository"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUpdateInventoryItem(t *testing.T) {
+	store := memory.NewStore()
+	store.Locations["shelf"] = domain.Location{ID: "shelf", Name: "Shelf"}
+	inventoryUseCase := newInventoryUseCase(store)
+	ctx := context.Background()
+	inventory := stock(t, inventoryUseCase, domain.Item{Name: "hat"}, 10)
+
+	cases := []struct {
+		name      string
+		inventory domain.InventoryItem
+		want      int
+		quantity  int
+	}{
+		{"existing item", domain.InventoryItem{Item: domain.Item{ID: inventory.Item.ID}, Quantity: 12}, 0, 12},
+		{"by id", domain.InventoryItem{ID: inventory.ID, Item: domain.Item{ID: inventory.Item.ID}, Quantity: 8}, 0, 8},
+		{"unknown item", domain.InventoryItem{Item: domain.Item{ID: "missing"}, Quantity: 1}, http.StatusBadRequest, 8},
+		{"negative quantity", domain.InventoryItem{Item: domain.Item{ID: inventory.Item.ID}, Quantity: -1},
+			http.StatusBadRequest, 8},
+	}
+	for _, c := range cases {
+		updated := c.inventory
+		_, err := inventoryUseCase.UpdateInventoryItem(ctx, &updated)
+		if got := statusOf(err); got != c.want {
+			t.Errorf("%s: UpdateInventoryItem failed with %d (%v), want %d", c.name, got, err, c.want)
+			continue
+		}
+		current, err := inventoryUseCase.GetInventoryForItem(ctx, inventory.Item.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(current.Locations) != 1 || current.Locations[0].InventoryID != inventory.ID ||
+			current.Quantity != c.quantity {
+			t.Errorf("%s: stock %+v, want %s holding %d", c.name, current, inventory.ID, c.quantity)
+		}
+	}
+
+	// stock at another location is kept apart and counts towards the total
+	shelf, err := inventoryUseCase.UpdateInventoryItem(ctx, &domain.InventoryItem{Item: domain.Item{ID: inventory.Item.ID},
+		LocationID: "shelf", Quantity: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := inventoryUseCase.GetInventoryForItem(ctx, inventory.Item.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shelf.ID == inventory.ID || len(current.Locations) != 2 || current.Quantity != 10 {
+		t.Errorf("stock %+v, want 8 at %s and 2 at %s", current, inventory.ID, shelf.ID)
+	}
+}
+
+func TestGetAllAndDelete(t *testing.T) {
+	inventoryUseCase := newInventoryUseCase(memory.NewStore())
+	ctx := context.Background()
+	hats := stock(t, inventoryUseCase, domain.Item{Name: "hat", Price: decimal.New(499, -2)}, 10)
+	stock(t, inventoryUseCase, domain.Item{Name: "scarf", Price: decimal.New(999, -2)}, 3)
+
+	cases := []struct {
+		name   string
+		filter domain.InventorySpecification
+		want   int
+		found  int
+	}{
+		{"all", specification.NewInventorySpecification(0, -1, "", "", specification.And()), 0, 2},
+		{"by quantity", specification.NewInventorySpecification(5, -1, "", "", specification.And()), 0, 1},
+		{"by location", specification.NewInventorySpecification(0, -1, domain.DefaultLocationID, "",
+			specification.And()), 0, 2},
+		{"at another location", specification.NewInventorySpecification(0, -1, "shelf", "", specification.And()),
+			http.StatusNotFound, 0},
+		{"by item", specification.NewInventorySpecification(0, -1, "", "",
+			specification.LessThan(specification.Price, 5)), 0, 1},
+		{"none", specification.NewInventorySpecification(20, -1, "", "", specification.And()), http.StatusNotFound, 0},
+	}
+	for _, c := range cases {
+		inventoryItems, err := inventoryUseCase.GetAll(ctx, 10, 0, c.filter, domain.InventorySortDefault)
+		if got := statusOf(err); got != c.want {
+			t.Errorf("%s: GetAll failed with %d (%v), want %d", c.name, got, err, c.want)
+			continue
+		}
+		if len(inventoryItems) != c.found {
+			t.Errorf("%s: found %d, want %d", c.name, len(inventoryItems), c.found)
+		}
+		for _, inventory := range inventoryItems {
+			if inventory.Item.Name == "" {
+				t.Errorf("%s: inventory %s came without the details of its item", c.name, inventory.ID)
+			}
+		}
+	}
+
+	// the item goes along with its inventory
+	if err := inventoryUseCase.DeleteItem(ctx, hats.ID, 2); statusOf(err) != http.StatusPreconditionFailed {
+		t.Errorf("DeleteItem at a stale version failed with %v, want %d", err, http.StatusPreconditionFailed)
+	}
+	if err := inventoryUseCase.DeleteItem(ctx, hats.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inventoryUseCase.GetInventoryForItem(ctx, hats.Item.ID); statusOf(err) != http.StatusNotFound {
+		t.Errorf("GetInventoryForItem of a deleted item failed with %v, want %d", err, http.StatusNotFound)
+	}
+	if err := inventoryUseCase.DeleteItem(ctx, hats.ID, 0); statusOf(err) != http.StatusNotFound {
+		t.Errorf("DeleteItem of deleted inventory failed with %v, want %d", err, http.StatusNotFound)
+	}
+}
+
+func TestAdjustQuantity(t *testing.T) {
+	inventoryUseCase := newInventoryUseCase(memory.NewStore())
+	ctx := context.Background()
+	inventory := stock(t, inventoryUseCase, domain.Item{Name: "hat"}, 10)
+
+	cases := []struct {
+		name       string
+		id         string
+		adjustment domain.StockAdjustment
+		version    int
+		want       int
+		quantity   int
+	}{
+		{"sold", inventory.ID, domain.StockAdjustment{Delta: -3, Reason: domain.ReasonSold}, 0, 0, 7},
+		{"received at the current version", inventory.ID,
+			domain.StockAdjustment{Delta: 5, Reason: domain.ReasonReceived}, 2, 0, 12},
+		{"stale version", inventory.ID, domain.StockAdjustment{Delta: 1, Reason: domain.ReasonReceived}, 2,
+			http.StatusPreconditionFailed, 12},
+		{"no delta", inventory.ID, domain.StockAdjustment{Reason: domain.ReasonSold}, 0, http.StatusBadRequest, 12},
+		{"unknown reason", inventory.ID, domain.StockAdjustment{Delta: -1, Reason: "stolen"}, 0,
+			http.StatusBadRequest, 12},
+		{"insufficient stock", inventory.ID, domain.StockAdjustment{Delta: -13, Reason: domain.ReasonSold}, 0,
+			http.StatusConflict, 12},
+		{"all of it", inventory.ID, domain.StockAdjustment{Delta: -12, Reason: domain.ReasonDamaged}, 0, 0, 0},
+		{"unknown inventory", "missing", domain.StockAdjustment{Delta: 1, Reason: domain.ReasonReceived}, 0,
+			http.StatusNotFound, 0},
+	}
+	movements := 1
+	for _, c := range cases {
+		adjusted, err := inventoryUseCase.AdjustQuantity(ctx, c.id, c.adjustment, c.version)
+		if got := statusOf(err); got != c.want {
+			t.Errorf("%s: AdjustQuantity failed with %d (%v), want %d", c.name, got, err, c.want)
+			continue
+		}
+		if err == nil {
+			movements++
+			if adjusted.Quantity != c.quantity || adjusted.Item.Name != "hat" {
+				t.Errorf("%s: %d of %q left, want %d hats", c.name, adjusted.Quantity, adjusted.Item.Name, c.quantity)
+			}
+		}
+	}
+
+	// only the adjustments that succeeded reach the ledger, each with the quantity it left
+	ledger, err := inventoryUseCase.GetMovements(ctx, inventory.ID, 100, 0, domain.MovementFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ledger) != movements {
+		t.Fatalf("%d movements recorded, want %d", len(ledger), movements)
+	}
+	quantity := 0
+	for _, movement := range ledger {
+		quantity += movement.Delta
+		if movement.Quantity != quantity {
+			t.Errorf("movement %+v leaves %d, want %d", movement, movement.Quantity, quantity)
+		}
+	}
+}
+
+func TestAdjustQuantityConcurrently(t *testing.T) {
+	cases := []struct {
+		name     string
+		stock    int
+		sales    int
+		quantity int
+	}{
+		{"enough stock", 20, 20, 0},
+		{"insufficient stock", 10, 25, 0},
+		{"some left", 30, 12, 18},
+	}
+	for _, c := range cases {
+		inventoryUseCase := newInventoryUseCase(memory.NewStore())
+		ctx := context.Background()
+		inventory := stock(t, inventoryUseCase, domain.Item{Name: "hat"}, c.stock)
+
+		var wait sync.WaitGroup
+		var mu sync.Mutex
+		sold, conflicts := 0, 0
+		for n := 0; n < c.sales; n++ {
+			wait.Add(1)
+			go func() {
+				defer wait.Done()
+				_, err := inventoryUseCase.AdjustQuantity(ctx, inventory.ID,
+					domain.StockAdjustment{Delta: -1, Reason: domain.ReasonSold}, 0)
+				mu.Lock()
+				defer mu.Unlock()
+				switch statusOf(err) {
+				case 0:
+					sold++
+				case http.StatusConflict:
+					conflicts++
+				default:
+					t.Errorf("%s: AdjustQuantity failed with %v", c.name, err)
+				}
+			}()
+		}
+		wait.Wait()
+
+		if sold != c.stock-c.quantity || conflicts != c.sales-sold {
+			t.Errorf("%s: %d sold and %d short of stock, want %d and %d", c.name, sold, conflicts,
+				c.stock-c.quantity, c.sales-c.stock+c.quantity)
+		}
+		current, err := inventoryUseCase.GetInventoryForItem(ctx, inventory.Item.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if current.Quantity != c.quantity {
+			t.Errorf("%s: %d left, want %d", c.name, current.Quantity, c.quantity)
+		}
+		ledger, err := inventoryUseCase.GetMovements(ctx, inventory.ID, 100, 0, domain.MovementFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ledger) != sold+1 {
+			t.Errorf("%s: %d movements recorded, want %d", c.name, len(ledger), sold+1)
+		}
+	}
+}
+
+func TestAdjustQuantityInOtherUnits(t *testing.T) {
+	inventoryUseCase := newInventoryUseCase(memory.NewStore())
+	ctx := context.Background()
+	inventory := stock(t, inventoryUseCase, domain.Item{Name: "pen",
+		Units: []domain.UnitConversion{{Unit: "pack", Factor: 3}, {Unit: "box", Factor: 12}}}, 0)
+	unitCost := func(cost string) *decimal.Decimal {
+		value := decimal.RequireFromString(cost)
+		return &value
+	}
+
+	cases := []struct {
+		name       string
+		adjustment domain.StockAdjustment
+		want       int
+		delta      int
+		cost       string
+	}{
+		{"boxes", domain.StockAdjustment{Delta: 2, Unit: "box", Reason: domain.ReasonReceived,
+			UnitCost: unitCost("6")}, 0, 24, "12"},
+		{"packs costed to the unit", domain.StockAdjustment{Delta: 1, Unit: "pack", Reason: domain.ReasonReceived,
+			UnitCost: unitCost("10")}, 0, 3, "9.999999"},
+		{"base unit", domain.StockAdjustment{Delta: -2, Reason: domain.ReasonSold}, 0, -2, "1"},
+		{"base unit by name", domain.StockAdjustment{Delta: -1, Unit: "each", Reason: domain.ReasonSold}, 0, -1,
+			"0.5"},
+		{"more boxes than in stock", domain.StockAdjustment{Delta: -3, Unit: "box", Reason: domain.ReasonSold},
+			http.StatusConflict, 0, ""},
+		{"unknown unit", domain.StockAdjustment{Delta: 1, Unit: "crate", Reason: domain.ReasonReceived},
+			http.StatusBadRequest, 0, ""},
+		{"more boxes than can be kept", domain.StockAdjustment{Delta: domain.MaxQuantity/12 + 1, Unit: "box",
+			Reason: domain.ReasonReceived}, http.StatusBadRequest, 0, ""},
+	}
+	for _, c := range cases {
+		_, err := inventoryUseCase.AdjustQuantity(ctx, inventory.ID, c.adjustment, 0)
+		if got := statusOf(err); got != c.want {
+			t.Errorf("%s: AdjustQuantity failed with %d (%v), want %d", c.name, got, err, c.want)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		ledger, err := inventoryUseCase.GetMovements(ctx, inventory.ID, 100, 0, domain.MovementFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		movement := ledger[len(ledger)-1]
+		cost := decimal.RequireFromString(c.cost)
+		if movement.Delta != c.delta || movement.Cost == nil || !movement.Cost.Equal(cost) {
+			t.Errorf("%s: moved %d costing %v, want %d costing %s", c.name, movement.Delta, movement.Cost, c.delta,
+				c.cost)
+		}
+	}
+}
+
+// ignoreStockChanges is a StockWatcher for tests that don't evaluate reorder points
+type ignoreStockChanges struct{}
+
+func (ignoreStockChanges) StockChanged(string) {}
+
+func TestAdjustSerialized(t *testing.T) {
+	store := memory.NewStore()
+	inventoryUseCase := newInventoryUseCase(store)
+	ctx := context.Background()
+	if _, err := repository2.NewMemoryItemRepository(store).Save(ctx, &domain.Item{ID: "phone", Name: "phone",
+		Serialized: true, Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+	inventory, err := inventoryUseCase.MoveStock(ctx, "phone", domain.DefaultLocationID,
+		domain.StockAdjustment{Delta: 2, Reason: domain.ReasonReceived, Serials: []string{"A", "B"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hat := stock(t, inventoryUseCase, domain.Item{Name: "hat"}, 1)
+
+	cases := []struct {
+		name       string
+		id         string
+		adjustment domain.StockAdjustment
+		want       int
+		onHand     int
+	}{
+		{"fewer serials than units", inventory.ID,
+			domain.StockAdjustment{Delta: 2, Reason: domain.ReasonReceived, Serials: []string{"C"}},
+			http.StatusBadRequest, 2},
+		{"more serials than units", inventory.ID,
+			domain.StockAdjustment{Delta: -1, Reason: domain.ReasonSold, Serials: []string{"A", "B"}},
+			http.StatusBadRequest, 2},
+		{"no serials", inventory.ID, domain.StockAdjustment{Delta: -1, Reason: domain.ReasonSold},
+			http.StatusBadRequest, 2},
+		{"the same serial twice", inventory.ID,
+			domain.StockAdjustment{Delta: 2, Reason: domain.ReasonReceived, Serials: []string{"C", "C"}},
+			http.StatusBadRequest, 2},
+		{"a serial already on hand", inventory.ID,
+			domain.StockAdjustment{Delta: 1, Reason: domain.ReasonReceived, Serials: []string{"A"}},
+			http.StatusConflict, 2},
+		{"a serial not on hand", inventory.ID,
+			domain.StockAdjustment{Delta: -1, Reason: domain.ReasonSold, Serials: []string{"C"}},
+			http.StatusConflict, 2},
+		{"sold", inventory.ID, domain.StockAdjustment{Delta: -1, Reason: domain.ReasonSold, Serials: []string{"A"}},
+			0, 1},
+		{"received", inventory.ID,
+			domain.StockAdjustment{Delta: 2, Reason: domain.ReasonReceived, Serials: []string{"A", "C"}}, 0, 3},
+		{"serials of an item that isn't serialized", hat.ID,
+			domain.StockAdjustment{Delta: 1, Reason: domain.ReasonReceived, Serials: []string{"D"}},
+			http.StatusBadRequest, 3},
+	}
+	serialRepository := repository6.NewMemorySerialRepository(store)
+	for _, c := range cases {
+		_, err := inventoryUseCase.AdjustQuantity(ctx, c.id, c.adjustment, 0)
+		if got := statusOf(err); got != c.want {
+			t.Errorf("%s: AdjustQuantity failed with %d (%v), want %d", c.name, got, err, c.want)
+		}
+		current, err := inventoryUseCase.GetInventoryForItem(ctx, "phone")
+		if err != nil {
+			t.Fatal(err)
+		}
+		onHand, err := serialRepository.GetOnHand(ctx, "phone", domain.DefaultLocationID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if current.Quantity != c.onHand || len(onHand) != c.onHand {
+			t.Errorf("%s: %d phones and %d serials on hand, want %d", c.name, current.Quantity, len(onHand),
+				c.onHand)
+		}
+	}
+}
+
+func newInventoryUseCase(store *memory.Store) domain.InventoryUseCase {
+	return NewInventoryUseCase(repository2.NewMemoryItemRepository(store),
+		repository.NewMemoryInventoryRepository(store), repository.NewMemoryMovementRepository(store),
+		repository5.NewMemoryReservationRepository(store), repository3.NewMemoryLocationRepository(store),
+		repository4.NewMemoryLotRepository(store), repository6.NewMemorySerialRepository(store),
+		repository7.NewMemoryCostRepository(store), ignoreStockChanges{}, store, time.Second)
+}
+
+// stock creates item along with quantity of it
+func stock(t *testing.T, inventoryUseCase domain.InventoryUseCase, item domain.Item,
+	quantity int) *domain.InventoryItem {
+	inventory, err := inventoryUseCase.UpdateInventoryItem(context.Background(),
+		&domain.InventoryItem{Item: item, Quantity: quantity})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inventory
+}
+
+// statusOf is the status code of err, or 0 if there is none
+func statusOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	if restError, ok := err.(*errors.RestError); ok {
+		return restError.Code
+	}
+	return http.StatusInternalServerError
+}
@@ -5,9 +5,12 @@ import (
 	"github.com/nuzurie/shopify/domain"
 	"github.com/nuzurie/shopify/specification"
 	"github.com/nuzurie/shopify/utils/errors"
+	"github.com/nuzurie/shopify/utils/etag"
+	"github.com/shopspring/decimal"
 	"net/http"
 	"reflect"
 	"strconv"
+	"strings"
 )
 
 type ItemHandler struct {
@@ -21,21 +24,18 @@ func NewItemHandler(useCase domain.ItemUseCase) *ItemHandler {
 func (h *ItemHandler) GetAll(c *gin.Context) {
 	name, _ := c.GetQuery("name")
 	description, _ := c.GetQuery("description")
-	minPriceQuery, _ := c.GetQuery("min-price")
-	minPrice, err := strconv.ParseFloat(minPriceQuery, 64)
-	if err != nil {
-		minPrice = 0
-	}
-	maxPriceQuery, ok := c.GetQuery("max-price")
-	var maxPrice float64
-	if ok {
-		maxPrice, err = strconv.ParseFloat(maxPriceQuery, 64)
-		if err != nil {
-			maxPrice = -1
+	var minPrice, maxPrice *decimal.Decimal
+	if minPriceQuery, ok := c.GetQuery("min-price"); ok {
+		if price, err := decimal.NewFromString(minPriceQuery); err == nil {
+			minPrice = &price
+		}
+	}
+	if maxPriceQuery, ok := c.GetQuery("max-price"); ok {
+		if price, err := decimal.NewFromString(maxPriceQuery); err == nil {
+			maxPrice = &price
 		}
-	} else {
-		maxPrice = -1
 	}
+	currency, _ := c.GetQuery("currency")
 
 	var count int64
 	if countQuery, ok := c.GetQuery("count"); ok {
@@ -49,7 +49,8 @@ func (h *ItemHandler) GetAll(c *gin.Context) {
 		offset, _ = strconv.ParseInt(offsetQuery, 10, 64)
 	}
 
-	spec := specification.NewItemSpecification(name, description, minPrice, maxPrice)
+	spec := specification.NewItemSpecification(name, description, minPrice, maxPrice,
+		strings.ToUpper(currency))
 
 	ctx := c.Request.Context()
 	items, err := h.useCase.GetAll(ctx, int(count), int(offset), spec)
@@ -67,6 +68,78 @@ func (h *ItemHandler) GetAll(c *gin.Context) {
 	c.JSON(http.StatusOK, items)
 }
 
+func (h *ItemHandler) GetOne(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	item, err := h.useCase.GetOne(ctx, id)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.Header("ETag", etag.Format(item.Version))
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *ItemHandler) GetBySKU(c *gin.Context) {
+	sku := c.Param("sku")
+	if sku == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("sku not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	item, err := h.useCase.GetBySKU(ctx, sku)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.Header("ETag", etag.Format(item.Version))
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *ItemHandler) GetByBarcode(c *gin.Context) {
+	code := c.Param("code")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("barcode not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	item, err := h.useCase.GetByBarcode(ctx, code)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.Header("ETag", etag.Format(item.Version))
+	c.JSON(http.StatusOK, item)
+}
+
 func (h *ItemHandler) Create(c *gin.Context) {
 	var item domain.Item
 	err := c.ShouldBind(&item)
@@ -88,6 +161,7 @@ func (h *ItemHandler) Create(c *gin.Context) {
 		}
 	}
 
+	c.Header("ETag", etag.Format(createdItem.Version))
 	c.JSON(http.StatusCreated, createdItem)
 }
 
@@ -104,8 +178,14 @@ func (h *ItemHandler) Update(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid review body"))
 		return
 	}
+	version, restErr := etag.Parse(c.GetHeader("If-Match"))
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
 
 	item.ID = id
+	item.Version = version
 	ctx := c.Request.Context()
 	updated, err := h.useCase.Update(ctx, &item)
 	if err != nil {
@@ -119,6 +199,7 @@ func (h *ItemHandler) Update(c *gin.Context) {
 		}
 	}
 
+	c.Header("ETag", etag.Format(updated.Version))
 	c.JSON(http.StatusOK, updated)
 }
 
@@ -129,8 +210,14 @@ func (h *ItemHandler) Delete(c *gin.Context) {
 		return
 	}
 
+	version, restErr := etag.Parse(c.GetHeader("If-Match"))
+	if restErr != nil {
+		c.JSON(restErr.Code, restErr)
+		return
+	}
+
 	ctx := c.Request.Context()
-	err := h.useCase.Delete(ctx, id)
+	err := h.useCase.Delete(ctx, id, version)
 	if err != nil {
 		switch v := err.(type) {
 		case *errors.RestError:
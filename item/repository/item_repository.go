@@ -3,111 +3,181 @@ package repository
 import (
 	"context"
 	"fmt"
-	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/nuzurie/shopify/db"
 	"github.com/nuzurie/shopify/domain"
 	"github.com/nuzurie/shopify/utils/errors"
-	"log"
 	"strings"
-	"time"
 )
 
 type itemRepository struct {
-	db *pgxpool.Pool
+	db db.DB
 }
 
 const (
-	createItemTable = `CREATE TABLE IF NOT EXISTS item (
-	id text PRIMARY KEY,
-	name text NOT NULL,
-	description text,
-	price float,
-	created_at timestamp without time zone,
-	updated_at timestamp without time zone
-	);`
-	getByID = `SELECT id, name, description, price, created_at, updated_at FROM public.item WHERE id=$1`
-	getAll  = `SELECT id, name, description, price, created_at, updated_at FROM public.item WHERE %s LIMIT $1 OFFSET $2`
-	save    = `INSERT INTO public.item(id, name, description, price, created_at, updated_at) 
-			VALUES ($1, $2, $3, $4, $5, $6)`
-	update = `UPDATE public.item
-	SET name=$2, description=$3, price=$4, updated_at=$5
-	WHERE id=$1;`
-	deleteByID = `DELETE FROM public.item WHERE id=$1`
+	columns = `id, name, description, sku, base_unit, price, currency, serialized, costing_method, created_at, updated_at,
+			version`
+	getByID      = `SELECT ` + columns + ` FROM item WHERE id=$1`
+	getBySKU     = `SELECT ` + columns + ` FROM item WHERE sku=$1 AND sku<>''`
+	getByBarcode = `SELECT ` + columns + ` FROM item WHERE id=(SELECT item_id FROM item_barcode WHERE code=$1)`
+	getAll       = `SELECT ` + columns + ` FROM item WHERE %s LIMIT $%d OFFSET $%d`
+	save         = `INSERT INTO item(id, name, description, sku, base_unit, price, currency, serialized, costing_method,
+			created_at, updated_at, version) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	update = `UPDATE item
+	SET name=$2, description=$3, sku=$4, base_unit=$5, price=$6, currency=$7, serialized=$8, costing_method=$9,
+	updated_at=$10, version=version+1
+	WHERE id=$1 AND version=$11;`
+	deleteByID     = `DELETE FROM item WHERE id=$1 AND version=$2`
+	getPrices      = `SELECT item_id, currency, amount FROM item_price WHERE item_id IN (%s) ORDER BY currency`
+	savePrice      = `INSERT INTO item_price(item_id, currency, amount) VALUES ($1, $2, $3)`
+	deletePrices   = `DELETE FROM item_price WHERE item_id=$1`
+	getBarcodes    = `SELECT item_id, code, symbology FROM item_barcode WHERE item_id IN (%s) ORDER BY code`
+	saveBarcode    = `INSERT INTO item_barcode(code, item_id, symbology) VALUES ($1, $2, $3)`
+	deleteBarcodes = `DELETE FROM item_barcode WHERE item_id=$1`
+	getUnits       = `SELECT item_id, unit, factor FROM item_unit WHERE item_id IN (%s) ORDER BY factor, unit`
+	saveUnit       = `INSERT INTO item_unit(item_id, unit, factor) VALUES ($1, $2, $3)`
+	deleteUnits    = `DELETE FROM item_unit WHERE item_id=$1`
 )
 
-func NewItemRepository(db *pgxpool.Pool) (domain.ItemRepository, error) {
-	log.Println("Creating item table")
-	tx, err := db.Begin(context.Background())
-	if err != nil {
-		return nil, err
-		//log.Fatalln("Unable to create the table. ", err.Error())
-	}
-	defer tx.Rollback(context.Background())
-
-	_, err = tx.Exec(context.Background(), createItemTable)
-	if err != nil {
-		return nil, err
-	}
-
-	err = tx.Commit(context.Background())
-	if err != nil {
-		return nil, err
-	}
-	return &itemRepository{db: db}, nil
+// NewItemRepository stores items in a SQL database, Postgres or SQLite. The schema must be migrated
+func NewItemRepository(database db.DB) domain.ItemRepository {
+	return &itemRepository{db: database}
 }
 
 func (i itemRepository) GetAll(ctx context.Context, count int, offset int, filter domain.Specification) ([]domain.Item, error) {
-	rows, err := i.db.Query(ctx, fmt.Sprintf(getAll, filter.FilterQuery()), count, offset)
-	defer rows.Close()
+	filterQuery, args := filter.FilterQuery(i.db.Dialect(), 1)
+	query := fmt.Sprintf(getAll, filterQuery, len(args)+1, len(args)+2)
+	rows, err := i.db.Query(ctx, query, append(args, count, offset)...)
 	if err != nil {
 		return nil, errors.NewInternalServerError(err.Error())
 	}
+	defer rows.Close()
 
 	var items []domain.Item
 	for rows.Next() {
-		value, err := rows.Values()
+		var item domain.Item
+		err = rows.Scan(&item.ID, &item.Name, &item.Description, &item.SKU, &item.BaseUnit, &item.Price,
+			&item.Currency, &item.Serialized, &item.CostingMethod, &item.CreatedAt, &item.UpdatedAt, &item.Version)
 		if err != nil {
 			return nil, errors.NewInternalServerError(err.Error())
 		}
 
-		var item domain.Item
-		item.ID = value[0].(string)
-		item.Name = value[1].(string)
-		item.Description = value[2].(string)
-		item.Price = value[3].(float64)
-		item.CreatedAt = value[4].(time.Time)
-		item.UpdatedAt = value[5].(time.Time)
-
 		items = append(items, item)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	rows.Close()
 
+	if err = i.getDetails(ctx, items); err != nil {
+		return nil, err
+	}
 	return items, nil
 }
 
 func (i itemRepository) GetOne(ctx context.Context, id string) (*domain.Item, error) {
-	rows, err := i.db.Query(ctx, getByID, id)
+	return i.getOne(ctx, getByID, id)
+}
+
+func (i itemRepository) GetBySKU(ctx context.Context, sku string) (*domain.Item, error) {
+	return i.getOne(ctx, getBySKU, sku)
+}
+
+func (i itemRepository) GetByBarcode(ctx context.Context, code string) (*domain.Item, error) {
+	return i.getOne(ctx, getByBarcode, code)
+}
+
+func (i itemRepository) getOne(ctx context.Context, query string, arg string) (*domain.Item, error) {
+	var item domain.Item
+	err := i.db.QueryRow(ctx, query, arg).
+		Scan(&item.ID, &item.Name, &item.Description, &item.SKU, &item.BaseUnit, &item.Price, &item.Currency,
+			&item.Serialized, &item.CostingMethod, &item.CreatedAt, &item.UpdatedAt, &item.Version)
+	if err == db.ErrNoRows {
+		return &domain.Item{}, nil
+	}
 	if err != nil {
 		err = errors.NewInternalServerError(err.Error())
 		return nil, err
 	}
-	defer rows.Close()
 
-	var item domain.Item
-	for rows.Next() {
-		value, err := rows.Values()
-		if err != nil {
-			err = errors.NewInternalServerError(err.Error())
-			return nil, err
+	items := []domain.Item{item}
+	if err = i.getDetails(ctx, items); err != nil {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+// getDetails loads the price lists, the barcodes and the units of the items, one query each for all of them
+func (i itemRepository) getDetails(ctx context.Context, items []domain.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	indexes := map[string]int{}
+	args := make([]interface{}, len(items))
+	placeholders := make([]string, len(items))
+	for index, item := range items {
+		indexes[item.ID] = index
+		args[index] = item.ID
+		placeholders[index] = fmt.Sprintf("$%d", index+1)
+	}
+	in := strings.Join(placeholders, ", ")
+
+	err := i.queryDetails(ctx, fmt.Sprintf(getPrices, in), args, func(rows db.Rows) error {
+		var itemID string
+		var price domain.ItemPrice
+		if err := rows.Scan(&itemID, &price.Currency, &price.Amount); err != nil {
+			return err
 		}
+		item := &items[indexes[itemID]]
+		item.Prices = append(item.Prices, price)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = i.queryDetails(ctx, fmt.Sprintf(getBarcodes, in), args, func(rows db.Rows) error {
+		var itemID string
+		var barcode domain.Barcode
+		if err := rows.Scan(&itemID, &barcode.Code, &barcode.Symbology); err != nil {
+			return err
+		}
+		item := &items[indexes[itemID]]
+		item.Barcodes = append(item.Barcodes, barcode)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return i.queryDetails(ctx, fmt.Sprintf(getUnits, in), args, func(rows db.Rows) error {
+		var itemID string
+		var unit domain.UnitConversion
+		if err := rows.Scan(&itemID, &unit.Unit, &unit.Factor); err != nil {
+			return err
+		}
+		item := &items[indexes[itemID]]
+		item.Units = append(item.Units, unit)
+		return nil
+	})
+}
 
-		item.ID = value[0].(string)
-		item.Name = value[1].(string)
-		item.Description = value[2].(string)
-		item.Price = value[3].(float64)
-		item.CreatedAt = value[4].(time.Time)
-		item.UpdatedAt = value[5].(time.Time)
+// queryDetails runs a query for the details of items, handing every row to scan
+func (i itemRepository) queryDetails(ctx context.Context, query string, args []interface{},
+	scan func(rows db.Rows) error) error {
+	rows, err := i.db.Query(ctx, query, args...)
+	if err != nil {
+		return errors.NewInternalServerError(err.Error())
 	}
+	defer rows.Close()
 
-	return &item, nil
+	for rows.Next() {
+		if err = scan(rows); err != nil {
+			return errors.NewInternalServerError(err.Error())
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
 }
 
 func (i itemRepository) Save(ctx context.Context, item *domain.Item) (*domain.Item, error) {
@@ -117,10 +187,17 @@ func (i itemRepository) Save(ctx context.Context, item *domain.Item) (*domain.It
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, save, item.ID, item.Name, item.Description, item.Price, item.CreatedAt, item.UpdatedAt)
+	_, err = tx.Exec(ctx, save, item.ID, item.Name, item.Description, item.SKU, item.BaseUnit, item.Price,
+		item.Currency, item.Serialized, item.CostingMethod, item.CreatedAt, item.UpdatedAt, item.Version)
 	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, errors.NewConflictError("the SKU already belongs to another item")
+		}
 		return nil, errors.NewInternalServerError(err.Error())
 	}
+	if err = saveDetails(ctx, tx, item); err != nil {
+		return nil, err
+	}
 
 	err = tx.Commit(ctx)
 	if err != nil {
@@ -136,10 +213,30 @@ func (i itemRepository) Edit(ctx context.Context, item *domain.Item) (*domain.It
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, update, item.ID, item.Name, item.Description, item.Price, item.UpdatedAt)
+	updated, err := tx.Exec(ctx, update, item.ID, item.Name, item.Description, item.SKU, item.BaseUnit, item.Price,
+		item.Currency, item.Serialized, item.CostingMethod, item.UpdatedAt, item.Version)
 	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, errors.NewConflictError("the SKU already belongs to another item")
+		}
 		return nil, errors.NewInternalServerError(err.Error())
 	}
+	if updated == 0 {
+		return nil, errors.NewPreconditionFailedError("item has been modified or deleted since it was read")
+	}
+	if _, err = tx.Exec(ctx, deletePrices, item.ID); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	if _, err = tx.Exec(ctx, deleteBarcodes, item.ID); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	if _, err = tx.Exec(ctx, deleteUnits, item.ID); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	if err = saveDetails(ctx, tx, item); err != nil {
+		return nil, err
+	}
+	item.Version++
 
 	err = tx.Commit(ctx)
 	if err != nil {
@@ -148,23 +245,46 @@ func (i itemRepository) Edit(ctx context.Context, item *domain.Item) (*domain.It
 	return item, nil
 }
 
-func (i itemRepository) Delete(ctx context.Context, id string) error {
+// saveDetails saves the price list, the barcodes and the units of the item
+func saveDetails(ctx context.Context, tx db.Tx, item *domain.Item) error {
+	for _, price := range item.Prices {
+		if _, err := tx.Exec(ctx, savePrice, item.ID, price.Currency, price.Amount); err != nil {
+			return errors.NewInternalServerError(err.Error())
+		}
+	}
+	for _, barcode := range item.Barcodes {
+		if _, err := tx.Exec(ctx, saveBarcode, barcode.Code, item.ID, barcode.Symbology); err != nil {
+			if db.IsUniqueViolation(err) {
+				return errors.NewConflictError("barcode " + barcode.Code + " already belongs to another item")
+			}
+			return errors.NewInternalServerError(err.Error())
+		}
+	}
+	for _, unit := range item.Units {
+		if _, err := tx.Exec(ctx, saveUnit, item.ID, unit.Unit, unit.Factor); err != nil {
+			return errors.NewInternalServerError(err.Error())
+		}
+	}
+	return nil
+}
+
+func (i itemRepository) Delete(ctx context.Context, id string, version int) error {
 	tx, err := i.db.Begin(ctx)
 	if err != nil {
 		return errors.NewInternalServerError(err.Error())
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, deleteByID, id)
+	deleted, err := tx.Exec(ctx, deleteByID, id, version)
 	if err != nil {
-		//if pgerr, ok := err.(pgx.PgError); ok {
-		//	fmt.Println(pgerr)
-		//}
-		if strings.Contains(err.Error(), "inventory_item_id_fkey") {
+		if db.IsForeignKeyViolation(err) {
 			return errors.NewBadRequestError("can't delete item while in inventory. Remove inventory first")
 		}
 		return errors.NewInternalServerError(err.Error())
 	}
+	if deleted == 0 {
+		return errors.NewPreconditionFailedError("item has been modified or deleted since it was read")
+	}
 
 	err = tx.Commit(ctx)
 	if err != nil {
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"sort"
+)
+
+type memoryItemRepository struct {
+	store *memory.Store
+}
+
+// NewMemoryItemRepository keeps items in store instead of a database. It's meant for tests and demos
+func NewMemoryItemRepository(store *memory.Store) domain.ItemRepository {
+	return &memoryItemRepository{store: store}
+}
+
+func (i *memoryItemRepository) GetAll(ctx context.Context, count int, offset int, filter domain.Specification) ([]domain.Item, error) {
+	defer i.store.Read(ctx)()
+
+	var items []domain.Item
+	for _, item := range i.store.Items {
+		if filter.IsSatisfiedBy(item) {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(a, b int) bool {
+		return items[a].ID < items[b].ID
+	})
+
+	start, end := memory.Page(len(items), count, offset)
+	return items[start:end], nil
+}
+
+func (i *memoryItemRepository) GetOne(ctx context.Context, id string) (*domain.Item, error) {
+	defer i.store.Read(ctx)()
+
+	item := i.store.Items[id]
+	return &item, nil
+}
+
+func (i *memoryItemRepository) GetBySKU(ctx context.Context, sku string) (*domain.Item, error) {
+	defer i.store.Read(ctx)()
+
+	for _, item := range i.store.Items {
+		if sku != "" && item.SKU == sku {
+			return &item, nil
+		}
+	}
+	return &domain.Item{}, nil
+}
+
+func (i *memoryItemRepository) GetByBarcode(ctx context.Context, code string) (*domain.Item, error) {
+	defer i.store.Read(ctx)()
+
+	for _, item := range i.store.Items {
+		for _, barcode := range item.Barcodes {
+			if barcode.Code == code {
+				return &item, nil
+			}
+		}
+	}
+	return &domain.Item{}, nil
+}
+
+func (i *memoryItemRepository) Save(ctx context.Context, item *domain.Item) (*domain.Item, error) {
+	defer i.store.Write(ctx)()
+
+	if _, ok := i.store.Items[item.ID]; ok {
+		return nil, errors.NewConflictError("item already exists")
+	}
+	if err := i.checkUnique(item); err != nil {
+		return nil, err
+	}
+	saved := *item
+	saved.Prices = append([]domain.ItemPrice(nil), item.Prices...)
+	saved.Barcodes = append([]domain.Barcode(nil), item.Barcodes...)
+	saved.Units = append([]domain.UnitConversion(nil), item.Units...)
+	i.store.Items[item.ID] = saved
+	return item, nil
+}
+
+func (i *memoryItemRepository) Edit(ctx context.Context, item *domain.Item) (*domain.Item, error) {
+	defer i.store.Write(ctx)()
+
+	existing, ok := i.store.Items[item.ID]
+	if !ok || existing.Version != item.Version {
+		return nil, errors.NewPreconditionFailedError("item has been modified or deleted since it was read")
+	}
+	if err := i.checkUnique(item); err != nil {
+		return nil, err
+	}
+	existing.Name = item.Name
+	existing.Description = item.Description
+	existing.SKU = item.SKU
+	existing.BaseUnit = item.BaseUnit
+	existing.Barcodes = append([]domain.Barcode(nil), item.Barcodes...)
+	existing.Units = append([]domain.UnitConversion(nil), item.Units...)
+	existing.Price = item.Price
+	existing.Currency = item.Currency
+	existing.Prices = append([]domain.ItemPrice(nil), item.Prices...)
+	existing.Serialized = item.Serialized
+	existing.CostingMethod = item.CostingMethod
+	existing.UpdatedAt = item.UpdatedAt
+	existing.Version++
+	i.store.Items[item.ID] = existing
+
+	item.Version = existing.Version
+	return item, nil
+}
+
+func (i *memoryItemRepository) Delete(ctx context.Context, id string, version int) error {
+	defer i.store.Write(ctx)()
+
+	if existing, ok := i.store.Items[id]; !ok || existing.Version != version {
+		return errors.NewPreconditionFailedError("item has been modified or deleted since it was read")
+	}
+	for _, inventory := range i.store.Inventory {
+		if inventory.Item.ID == id {
+			return errors.NewBadRequestError("can't delete item while in inventory. Remove inventory first")
+		}
+	}
+	for key, supplierItem := range i.store.SupplierItems {
+		if supplierItem.ItemID == id {
+			delete(i.store.SupplierItems, key)
+		}
+	}
+	i.store.DeleteReorderPolicies(func(policy domain.ReorderPolicy) bool {
+		return policy.ItemID == id
+	})
+	i.store.DeleteSerials(func(serial domain.Serial) bool {
+		return serial.ItemID == id
+	})
+	delete(i.store.Items, id)
+	return nil
+}
+
+// checkUnique fails with a conflict if another item has the SKU or one of the barcodes of item, as the unique
+// constraints of the database would
+func (i *memoryItemRepository) checkUnique(item *domain.Item) error {
+	for _, other := range i.store.Items {
+		if other.ID == item.ID {
+			continue
+		}
+		if item.SKU != "" && other.SKU == item.SKU {
+			return errors.NewConflictError("the SKU already belongs to another item")
+		}
+		for _, barcode := range item.Barcodes {
+			for _, otherBarcode := range other.Barcodes {
+				if barcode.Code == otherBarcode.Code {
+					return errors.NewConflictError("barcode " + barcode.Code + " already belongs to another item")
+				}
+			}
+		}
+	}
+	return nil
+}
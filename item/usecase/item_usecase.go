@@ -5,19 +5,25 @@ import (
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/item/validator"
 	"github.com/nuzurie/shopify/utils/errors"
 	"log"
 	"reflect"
+	"strings"
 	"time"
 )
 
 type itemUseCase struct {
-	itemRepository domain.ItemRepository
-	timeout        time.Duration
+	itemRepository      domain.ItemRepository
+	inventoryRepository domain.InventoryRepository
+	timeout             time.Duration
 }
 
-func NewItemUseCase(repository domain.ItemRepository, timeout time.Duration) domain.ItemUseCase {
-	return &itemUseCase{itemRepository: repository, timeout: timeout}
+// NewItemUseCase checks inventoryRepository before an item becomes serialized or stops being so, since the stock it
+// already holds has no serials
+func NewItemUseCase(repository domain.ItemRepository, inventoryRepository domain.InventoryRepository,
+	timeout time.Duration) domain.ItemUseCase {
+	return &itemUseCase{itemRepository: repository, inventoryRepository: inventoryRepository, timeout: timeout}
 }
 
 func (i *itemUseCase) GetAll(ctx context.Context, count int, offset int, filter domain.Specification) ([]domain.Item, error) {
@@ -50,12 +56,68 @@ func (i *itemUseCase) GetOne(ctx context.Context, id string) (*domain.Item, erro
 	return item, nil
 }
 
+func (i *itemUseCase) GetBySKU(ctx context.Context, sku string) (*domain.Item, error) {
+	c, cancel := context.WithTimeout(ctx, i.timeout)
+	defer cancel()
+
+	item, err := i.itemRepository.GetBySKU(c, sku)
+	if err != nil {
+		return nil, err
+	}
+	if item.ID == "" {
+		return nil, errors.NewNotFoundError("no item found with the SKU")
+	}
+
+	return item, nil
+}
+
+// GetByBarcode also finds items labelled with the UPC-A code an EAN-13 code reads as, and the other way round
+func (i *itemUseCase) GetByBarcode(ctx context.Context, code string) (*domain.Item, error) {
+	c, cancel := context.WithTimeout(ctx, i.timeout)
+	defer cancel()
+
+	for _, equivalent := range domain.EquivalentCodes(code) {
+		item, err := i.itemRepository.GetByBarcode(c, equivalent)
+		if err != nil {
+			return nil, err
+		}
+		if item.ID != "" {
+			return item, nil
+		}
+	}
+
+	return nil, errors.NewNotFoundError("no item found with the barcode")
+}
+
 func (i *itemUseCase) Create(ctx context.Context, item *domain.Item) (*domain.Item, error) {
 	c, cancel := context.WithTimeout(ctx, i.timeout)
 	defer cancel()
 
+	if item.CostingMethod == "" {
+		item.CostingMethod = domain.DefaultCostingMethod
+	}
+	if !item.CostingMethod.IsValid() {
+		return nil, errors.NewBadRequestError("invalid request. Unknown costing method " + string(item.CostingMethod))
+	}
+	if item.Currency == "" {
+		item.Currency = domain.DefaultCurrency
+	}
+	if err := validator.Prices(item); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(item.BaseUnit) == "" {
+		item.BaseUnit = domain.DefaultBaseUnit
+	}
+	if err := validator.Identifiers(c, i.itemRepository, item); err != nil {
+		return nil, err
+	}
+	if err := validator.Units(item); err != nil {
+		return nil, err
+	}
+
 	item.ID = uuid.NewString()
 	item.CreatedAt = time.Now()
+	item.Version = 1
 	createdItem, err := i.itemRepository.Save(c, item)
 	if err != nil {
 		log.Println(fmt.Sprintf("Failed to create item %s at %s", item.ID, time.Now()))
@@ -76,6 +138,59 @@ func (i *itemUseCase) Update(ctx context.Context, item *domain.Item) (*domain.It
 	if reflect.DeepEqual(existingItem, &domain.Item{}) {
 		return nil, errors.NewBadRequestError("no such item exists")
 	}
+	if item.Version == 0 {
+		item.Version = existingItem.Version
+	}
+	// the costing method is kept unless another is given. Changing it only changes how later stock is costed
+	if item.CostingMethod == "" {
+		item.CostingMethod = existingItem.CostingMethod
+	}
+	if !item.CostingMethod.IsValid() {
+		return nil, errors.NewBadRequestError("invalid request. Unknown costing method " + string(item.CostingMethod))
+	}
+	if item.Currency == "" {
+		item.Currency = existingItem.Currency
+	}
+	if item.Prices == nil {
+		item.Prices = existingItem.Prices
+	}
+	if err := validator.Prices(item); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(item.SKU) == "" {
+		item.SKU = existingItem.SKU
+	}
+	if strings.TrimSpace(item.BaseUnit) == "" {
+		item.BaseUnit = existingItem.BaseUnit
+	}
+	if item.Barcodes == nil {
+		item.Barcodes = existingItem.Barcodes
+	}
+	if item.Units == nil {
+		item.Units = existingItem.Units
+	}
+	if err := validator.Identifiers(c, i.itemRepository, item); err != nil {
+		return nil, err
+	}
+	if err := validator.Units(item); err != nil {
+		return nil, err
+	}
+	// the stock of the item is counted in its base unit, so it can only change along with whether it is serialized
+	if item.Serialized != existingItem.Serialized || item.BaseUnit != existingItem.BaseUnit {
+		inventoryItems, err := i.inventoryRepository.GetInventoryForItem(c, item.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, inventory := range inventoryItems {
+			if inventory.Quantity == 0 {
+				continue
+			}
+			if item.Serialized != existingItem.Serialized {
+				return nil, errors.NewConflictError("can't change whether an item is serialized while it is in stock")
+			}
+			return nil, errors.NewConflictError("can't change the base unit of an item while it is in stock")
+		}
+	}
 
 	item.UpdatedAt = time.Now()
 	var updated *domain.Item
@@ -87,7 +202,7 @@ func (i *itemUseCase) Update(ctx context.Context, item *domain.Item) (*domain.It
 	return updated, err
 }
 
-func (i itemUseCase) Delete(ctx context.Context, id string) error {
+func (i itemUseCase) Delete(ctx context.Context, id string, version int) error {
 	c, cancel := context.WithTimeout(ctx, i.timeout)
 	defer cancel()
 
@@ -98,6 +213,9 @@ func (i itemUseCase) Delete(ctx context.Context, id string) error {
 	if reflect.DeepEqual(existingItem, &domain.Item{}) {
 		return errors.NewBadRequestError("no such item exists")
 	}
+	if version == 0 {
+		version = existingItem.Version
+	}
 
-	return i.itemRepository.Delete(ctx, id)
+	return i.itemRepository.Delete(c, id, version)
 }
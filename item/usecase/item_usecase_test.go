@@ -0,0 +1,236 @@
+package usecase
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	repository2 "github.com/nuzurie/shopify/inventory/repository"
+	"github.com/nuzurie/shopify/item/repository"
+	"github.com/nuzurie/shopify/specification"
+	"github.com/nuzurie/shopify/utils/errors"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCreate(t *testing.T) {
+	itemUseCase, _ := newItemUseCase()
+	ctx := context.Background()
+	if _, err := itemUseCase.Create(ctx, &domain.Item{Name: "hat", SKU: "HAT-1",
+		Barcodes: []domain.Barcode{{Code: "036000291452"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		item domain.Item
+		want int
+	}{
+		{"defaults", domain.Item{Name: "scarf"}, 0},
+		{"price list", domain.Item{Name: "scarf", Currency: "usd",
+			Prices: []domain.ItemPrice{{Currency: "eur", Amount: decimal.NewFromInt(4)}}}, 0},
+		{"unknown costing method", domain.Item{Name: "scarf", CostingMethod: "random"}, http.StatusBadRequest},
+		{"unknown currency", domain.Item{Name: "scarf", Currency: "dollars"}, http.StatusBadRequest},
+		{"negative price", domain.Item{Name: "scarf", Price: decimal.NewFromInt(-1)}, http.StatusBadRequest},
+		{"own currency in price list", domain.Item{Name: "scarf",
+			Prices: []domain.ItemPrice{{Currency: "usd", Amount: decimal.NewFromInt(4)}}}, http.StatusBadRequest},
+		{"SKU taken", domain.Item{Name: "scarf", SKU: " HAT-1 "}, http.StatusConflict},
+		{"equivalent barcode taken", domain.Item{Name: "scarf",
+			Barcodes: []domain.Barcode{{Code: "0036000291452"}}}, http.StatusConflict},
+		{"invalid barcode", domain.Item{Name: "scarf", Barcodes: []domain.Barcode{{Code: "036000291453"}}},
+			http.StatusBadRequest},
+		{"base unit listed", domain.Item{Name: "scarf", Units: []domain.UnitConversion{{Unit: "each", Factor: 2}}},
+			http.StatusBadRequest},
+		{"unit listed twice", domain.Item{Name: "scarf",
+			Units: []domain.UnitConversion{{Unit: "box", Factor: 2}, {Unit: "box", Factor: 3}}}, http.StatusBadRequest},
+		{"zero factor", domain.Item{Name: "scarf", Units: []domain.UnitConversion{{Unit: "box"}}},
+			http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		item := c.item
+		created, err := itemUseCase.Create(ctx, &item)
+		if got := statusOf(err); got != c.want {
+			t.Errorf("%s: Create failed with %d (%v), want %d", c.name, got, err, c.want)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if created.Version != 1 || created.BaseUnit != domain.DefaultBaseUnit ||
+			created.CostingMethod != domain.DefaultCostingMethod || created.Currency != domain.DefaultCurrency {
+			t.Errorf("%s: Create = %+v, want the defaults at version 1", c.name, created)
+		}
+		for _, price := range created.Prices {
+			if price.Currency != "EUR" {
+				t.Errorf("%s: price list currency %q, want it upper cased", c.name, price.Currency)
+			}
+		}
+	}
+}
+
+func TestGetAndUpdate(t *testing.T) {
+	itemUseCase, _ := newItemUseCase()
+	ctx := context.Background()
+	created, err := itemUseCase.Create(ctx, &domain.Item{Name: "hat", Price: decimal.New(499, -2), SKU: "HAT-1",
+		Barcodes: []domain.Barcode{{Code: "036000291452"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = itemUseCase.Create(ctx, &domain.Item{Name: "scarf", Price: decimal.New(999, -2)}); err != nil {
+		t.Fatal(err)
+	}
+
+	lookups := []struct {
+		name   string
+		lookup func() ([]domain.Item, error)
+		want   int
+		found  int
+	}{
+		{"by id", func() ([]domain.Item, error) {
+			item, err := itemUseCase.GetOne(ctx, created.ID)
+			if err != nil {
+				return nil, err
+			}
+			return []domain.Item{*item}, nil
+		}, 0, 1},
+		{"unknown id", func() ([]domain.Item, error) {
+			_, err := itemUseCase.GetOne(ctx, "missing")
+			return nil, err
+		}, http.StatusBadRequest, 0},
+		{"by SKU", func() ([]domain.Item, error) {
+			item, err := itemUseCase.GetBySKU(ctx, "HAT-1")
+			if err != nil {
+				return nil, err
+			}
+			return []domain.Item{*item}, nil
+		}, 0, 1},
+		{"unknown SKU", func() ([]domain.Item, error) {
+			_, err := itemUseCase.GetBySKU(ctx, "HAT-2")
+			return nil, err
+		}, http.StatusNotFound, 0},
+		{"by equivalent EAN-13", func() ([]domain.Item, error) {
+			item, err := itemUseCase.GetByBarcode(ctx, "0036000291452")
+			if err != nil {
+				return nil, err
+			}
+			return []domain.Item{*item}, nil
+		}, 0, 1},
+		{"all", func() ([]domain.Item, error) {
+			return itemUseCase.GetAll(ctx, 10, 0, specification.And())
+		}, 0, 2},
+		{"matching", func() ([]domain.Item, error) {
+			return itemUseCase.GetAll(ctx, 10, 0, specification.Contains(specification.Name, "HAT"))
+		}, 0, 1},
+		{"past the last page", func() ([]domain.Item, error) {
+			return itemUseCase.GetAll(ctx, 10, 2, specification.And())
+		}, http.StatusNotFound, 0},
+	}
+	for _, l := range lookups {
+		items, err := l.lookup()
+		if got := statusOf(err); got != l.want {
+			t.Errorf("%s: failed with %d (%v), want %d", l.name, got, err, l.want)
+			continue
+		}
+		if len(items) != l.found {
+			t.Errorf("%s: found %d items, want %d", l.name, len(items), l.found)
+		}
+	}
+
+	updates := []struct {
+		name string
+		item domain.Item
+		want int
+	}{
+		{"at the current version", domain.Item{ID: created.ID, Name: "beret", Version: 1}, 0},
+		{"stale version", domain.Item{ID: created.ID, Name: "beanie", Version: 1}, http.StatusPreconditionFailed},
+		{"any version", domain.Item{ID: created.ID, Name: "cap", Price: decimal.New(399, -2)}, 0},
+		{"unknown item", domain.Item{ID: "missing", Name: "beret"}, http.StatusBadRequest},
+	}
+	for _, u := range updates {
+		item := u.item
+		updated, err := itemUseCase.Update(ctx, &item)
+		if got := statusOf(err); got != u.want {
+			t.Errorf("%s: Update failed with %d (%v), want %d", u.name, got, err, u.want)
+			continue
+		}
+		if err == nil && (updated.SKU != "HAT-1" || len(updated.Barcodes) != 1) {
+			t.Errorf("%s: Update = %+v, want the SKU and barcodes kept", u.name, updated)
+		}
+	}
+	item, err := itemUseCase.GetOne(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Name != "cap" || !item.Price.Equal(decimal.New(399, -2)) {
+		t.Errorf("GetOne = %+v after the update, want a cap at 3.99", item)
+	}
+}
+
+func TestUpdateAndDeleteInStock(t *testing.T) {
+	itemUseCase, store := newItemUseCase()
+	ctx := context.Background()
+	created, err := itemUseCase.Create(ctx, &domain.Item{Name: "hat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inventoryRepository := repository2.NewMemoryInventoryRepository(store)
+	if _, err = inventoryRepository.Save(ctx, &domain.InventoryItem{ID: "inv", Item: *created,
+		LocationID: domain.DefaultLocationID, Quantity: 3, UpdatedAt: time.Now(), Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		item domain.Item
+		want int
+	}{
+		{"serialize", domain.Item{ID: created.ID, Name: "hat", Serialized: true}, http.StatusConflict},
+		{"change base unit", domain.Item{ID: created.ID, Name: "hat", BaseUnit: "pair"}, http.StatusConflict},
+		{"rename", domain.Item{ID: created.ID, Name: "cap"}, 0},
+	}
+	for _, c := range cases {
+		item := c.item
+		if _, err := itemUseCase.Update(ctx, &item); statusOf(err) != c.want {
+			t.Errorf("%s: Update failed with %d (%v), want %d", c.name, statusOf(err), err, c.want)
+		}
+	}
+
+	if err = itemUseCase.Delete(ctx, created.ID, 1); statusOf(err) != http.StatusPreconditionFailed {
+		t.Errorf("Delete at a stale version failed with %v, want %d", err, http.StatusPreconditionFailed)
+	}
+	if err = itemUseCase.Delete(ctx, created.ID, 0); statusOf(err) != http.StatusBadRequest {
+		t.Errorf("Delete while in stock failed with %v, want %d", err, http.StatusBadRequest)
+	}
+	if err = inventoryRepository.DeleteItem(ctx, "inv"); err != nil {
+		t.Fatal(err)
+	}
+	// the version is checked again as the item is deleted, in case it changed after it was read
+	err = repository.NewMemoryItemRepository(store).Delete(ctx, created.ID, 1)
+	if statusOf(err) != http.StatusPreconditionFailed {
+		t.Errorf("Delete of a stale item failed with %v, want %d", err, http.StatusPreconditionFailed)
+	}
+	if err = itemUseCase.Delete(ctx, created.ID, 2); err != nil {
+		t.Errorf("Delete failed with %v", err)
+	}
+	if err = itemUseCase.Delete(ctx, created.ID, 0); statusOf(err) != http.StatusBadRequest {
+		t.Errorf("Delete of a deleted item failed with %v, want %d", err, http.StatusBadRequest)
+	}
+}
+
+func newItemUseCase() (domain.ItemUseCase, *memory.Store) {
+	store := memory.NewStore()
+	return NewItemUseCase(repository.NewMemoryItemRepository(store), repository2.NewMemoryInventoryRepository(store),
+		time.Second), store
+}
+
+// statusOf is the status code of err, or 0 if there is none
+func statusOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	if restError, ok := err.(*errors.RestError); ok {
+		return restError.Code
+	}
+	return http.StatusInternalServerError
+}
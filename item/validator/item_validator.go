@@ -0,0 +1,121 @@
+// Package validator checks items before they are saved, for every use case that creates or changes them
+package validator
+
+import (
+	"context"
+	"fmt"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"strings"
+)
+
+// maxSKULength is the longest SKU an item can have
+const maxSKULength = 64
+
+// Prices checks the price of the item and its price list, which can't list its own currency or a currency twice.
+// Currencies are upper cased
+func Prices(item *domain.Item) error {
+	item.Currency = strings.ToUpper(strings.TrimSpace(item.Currency))
+	if !domain.IsCurrencyCode(item.Currency) {
+		return errors.NewBadRequestError("invalid request. Currency must be an ISO 4217 code like " +
+			domain.DefaultCurrency)
+	}
+	if item.Price.IsNegative() {
+		return errors.NewBadRequestError("invalid request. Price can't be less than 0")
+	}
+
+	currencies := map[string]bool{item.Currency: true}
+	for index := range item.Prices {
+		price := &item.Prices[index]
+		price.Currency = strings.ToUpper(strings.TrimSpace(price.Currency))
+		if !domain.IsCurrencyCode(price.Currency) {
+			return errors.NewBadRequestError("invalid request. Currency must be an ISO 4217 code like " +
+				domain.DefaultCurrency)
+		}
+		if currencies[price.Currency] {
+			return errors.NewBadRequestError("invalid request. The price list has more than one price in " +
+				price.Currency)
+		}
+		if price.Amount.IsNegative() {
+			return errors.NewBadRequestError("invalid request. Price can't be less than 0")
+		}
+		currencies[price.Currency] = true
+	}
+	return nil
+}
+
+// Units checks the units the item is counted in besides its base unit. Each holds a whole number of base units
+func Units(item *domain.Item) error {
+	units := map[string]bool{}
+	for index := range item.Units {
+		conversion := &item.Units[index]
+		conversion.Unit = strings.TrimSpace(conversion.Unit)
+		if conversion.Unit == "" {
+			return errors.NewBadRequestError("invalid request. Unit is required")
+		}
+		if conversion.Unit == item.BaseUnit {
+			return errors.NewBadRequestError("invalid request. Unit " + conversion.Unit + " is the base unit")
+		}
+		if units[conversion.Unit] {
+			return errors.NewBadRequestError("invalid request. Unit " + conversion.Unit + " is listed twice")
+		}
+		if conversion.Factor <= 0 {
+			return errors.NewBadRequestError("invalid request. Factor must be more than 0")
+		}
+		units[conversion.Unit] = true
+	}
+	return nil
+}
+
+// Identifiers checks the SKU, base unit and barcodes of the item, inferring the symbology of barcodes that
+// don't name one. The SKU and barcodes must not belong to another item
+func Identifiers(c context.Context, repository domain.ItemRepository, item *domain.Item) error {
+	item.SKU = strings.TrimSpace(item.SKU)
+	item.BaseUnit = strings.TrimSpace(item.BaseUnit)
+	if len(item.SKU) > maxSKULength {
+		return errors.NewBadRequestError(fmt.Sprintf("invalid request. SKU can't be longer than %d characters",
+			maxSKULength))
+	}
+	if item.SKU != "" {
+		other, err := repository.GetBySKU(c, item.SKU)
+		if err != nil {
+			return err
+		}
+		if other.ID != "" && other.ID != item.ID {
+			return errors.NewConflictError("the SKU already belongs to another item")
+		}
+	}
+
+	codes := map[string]bool{}
+	for index := range item.Barcodes {
+		barcode := &item.Barcodes[index]
+		barcode.Code = strings.TrimSpace(barcode.Code)
+		if barcode.Symbology == "" {
+			barcode.Symbology = domain.InferSymbology(barcode.Code)
+		}
+		if !barcode.Symbology.IsValid() {
+			return errors.NewBadRequestError("invalid request. Unknown symbology " + string(barcode.Symbology))
+		}
+		if !barcode.IsValid() {
+			return errors.NewBadRequestError(fmt.Sprintf("invalid request. %s isn't a valid %s barcode",
+				barcode.Code, barcode.Symbology))
+		}
+
+		// a UPC-A code and the EAN-13 code it reads as label the same item, so they can't belong to different ones
+		for _, code := range domain.EquivalentCodes(barcode.Code) {
+			if codes[code] {
+				return errors.NewBadRequestError("invalid request. Barcode " + barcode.Code + " is listed twice")
+			}
+			codes[code] = true
+
+			other, err := repository.GetByBarcode(c, code)
+			if err != nil {
+				return err
+			}
+			if other.ID != "" && other.ID != item.ID {
+				return errors.NewConflictError("barcode " + barcode.Code + " already belongs to another item")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"net/http"
+	"strconv"
+)
+
+type LocationHandler struct {
+	useCase domain.LocationUseCase
+}
+
+func NewLocationHandler(useCase domain.LocationUseCase) *LocationHandler {
+	return &LocationHandler{useCase: useCase}
+}
+
+func (h *LocationHandler) GetAll(c *gin.Context) {
+	var count int64
+	if countQuery, ok := c.GetQuery("count"); ok {
+		count, _ = strconv.ParseInt(countQuery, 10, 64)
+	} else {
+		count = 20
+	}
+
+	var offset int64
+	if offsetQuery, ok := c.GetQuery("offset"); ok {
+		offset, _ = strconv.ParseInt(offsetQuery, 10, 64)
+	}
+
+	ctx := c.Request.Context()
+	locations, err := h.useCase.GetAll(ctx, int(count), int(offset))
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, locations)
+}
+
+func (h *LocationHandler) GetOne(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	location, err := h.useCase.GetOne(ctx, id)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, location)
+}
+
+func (h *LocationHandler) Create(c *gin.Context) {
+	var location domain.Location
+	if err := c.ShouldBindJSON(&location); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid location body"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	created, err := h.useCase.Create(ctx, &location)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *LocationHandler) Update(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	var location domain.Location
+	if err := c.ShouldBindJSON(&location); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid location body"))
+		return
+	}
+
+	location.ID = id
+	ctx := c.Request.Context()
+	updated, err := h.useCase.Update(ctx, &location)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+func (h *LocationHandler) Delete(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	err := h.useCase.Delete(ctx, id)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "deleted successfully"})
+}
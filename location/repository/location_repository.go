@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+)
+
+type locationRepository struct {
+	db db.DB
+}
+
+const (
+	getByID = `SELECT id, name, address, type, created_at, updated_at FROM location WHERE id=$1`
+	getAll  = `SELECT id, name, address, type, created_at, updated_at FROM location ORDER BY name, id
+			LIMIT $1 OFFSET $2`
+	save       = `INSERT INTO location (id, name, address, type, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	update     = `UPDATE location SET name=$2, address=$3, type=$4, updated_at=$5 WHERE id=$1`
+	deleteByID = `DELETE FROM location WHERE id=$1`
+)
+
+// NewLocationRepository stores locations in a SQL database, Postgres or SQLite. The schema must be migrated
+func NewLocationRepository(database db.DB) domain.LocationRepository {
+	return &locationRepository{db: database}
+}
+
+func (l *locationRepository) GetAll(ctx context.Context, count int, offset int) ([]domain.Location, error) {
+	rows, err := l.db.Query(ctx, getAll, count, offset)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var locations []domain.Location
+	for rows.Next() {
+		var location domain.Location
+		err = rows.Scan(&location.ID, &location.Name, &location.Address, &location.Type, &location.CreatedAt,
+			&location.UpdatedAt)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		locations = append(locations, location)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return locations, nil
+}
+
+func (l *locationRepository) GetOne(ctx context.Context, id string) (*domain.Location, error) {
+	var location domain.Location
+	err := l.db.QueryRow(ctx, getByID, id).
+		Scan(&location.ID, &location.Name, &location.Address, &location.Type, &location.CreatedAt, &location.UpdatedAt)
+	if err != nil && err != db.ErrNoRows {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return &location, nil
+}
+
+func (l *locationRepository) Save(ctx context.Context, location *domain.Location) (*domain.Location, error) {
+	_, err := l.db.Exec(ctx, save, location.ID, location.Name, location.Address, location.Type, location.CreatedAt,
+		location.UpdatedAt)
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, errors.NewConflictError("location already exists")
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return location, nil
+}
+
+func (l *locationRepository) Edit(ctx context.Context, location *domain.Location) (*domain.Location, error) {
+	_, err := l.db.Exec(ctx, update, location.ID, location.Name, location.Address, location.Type, location.UpdatedAt)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return location, nil
+}
+
+func (l *locationRepository) Delete(ctx context.Context, id string) error {
+	_, err := l.db.Exec(ctx, deleteByID, id)
+	if err != nil {
+		if db.IsForeignKeyViolation(err) {
+			return errors.NewConflictError("can't delete location while inventory, transfers or orders reference it")
+		}
+		return errors.NewInternalServerError(err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"sort"
+)
+
+type memoryLocationRepository struct {
+	store *memory.Store
+}
+
+// NewMemoryLocationRepository keeps locations in store instead of a database. store must be shared with the inventory
+// repository so locations holding inventory can't be deleted
+func NewMemoryLocationRepository(store *memory.Store) domain.LocationRepository {
+	return &memoryLocationRepository{store: store}
+}
+
+func (l *memoryLocationRepository) GetAll(ctx context.Context, count int, offset int) ([]domain.Location, error) {
+	defer l.store.Read(ctx)()
+
+	var locations []domain.Location
+	for _, location := range l.store.Locations {
+		locations = append(locations, location)
+	}
+	sort.Slice(locations, func(a, b int) bool {
+		if locations[a].Name != locations[b].Name {
+			return locations[a].Name < locations[b].Name
+		}
+		return locations[a].ID < locations[b].ID
+	})
+
+	start, end := memory.Page(len(locations), count, offset)
+	return locations[start:end], nil
+}
+
+func (l *memoryLocationRepository) GetOne(ctx context.Context, id string) (*domain.Location, error) {
+	defer l.store.Read(ctx)()
+
+	location := l.store.Locations[id]
+	return &location, nil
+}
+
+func (l *memoryLocationRepository) Save(ctx context.Context, location *domain.Location) (*domain.Location, error) {
+	defer l.store.Write(ctx)()
+
+	if _, ok := l.store.Locations[location.ID]; ok {
+		return nil, errors.NewConflictError("location already exists")
+	}
+	l.store.Locations[location.ID] = *location
+	return location, nil
+}
+
+func (l *memoryLocationRepository) Edit(ctx context.Context, location *domain.Location) (*domain.Location, error) {
+	defer l.store.Write(ctx)()
+
+	if existing, ok := l.store.Locations[location.ID]; ok {
+		existing.Name = location.Name
+		existing.Address = location.Address
+		existing.Type = location.Type
+		existing.UpdatedAt = location.UpdatedAt
+		l.store.Locations[location.ID] = existing
+	}
+	return location, nil
+}
+
+func (l *memoryLocationRepository) Delete(ctx context.Context, id string) error {
+	defer l.store.Write(ctx)()
+
+	for _, inventory := range l.store.Inventory {
+		if inventory.LocationID == id {
+			return errors.NewConflictError("can't delete location while inventory, transfers or orders reference it")
+		}
+	}
+	for _, transfer := range l.store.Transfers {
+		if transfer.SourceLocationID == id || transfer.DestinationLocationID == id {
+			return errors.NewConflictError("can't delete location while inventory, transfers or orders reference it")
+		}
+	}
+	for _, order := range l.store.PurchaseOrders {
+		if order.LocationID == id {
+			return errors.NewConflictError("can't delete location while inventory, transfers or orders reference it")
+		}
+	}
+	for _, order := range l.store.SalesOrders {
+		if order.LocationID == id {
+			return errors.NewConflictError("can't delete location while inventory, transfers or orders reference it")
+		}
+	}
+	for _, customerReturn := range l.store.Returns {
+		if customerReturn.LocationID == id {
+			return errors.NewConflictError("can't delete location while inventory, transfers or orders reference it")
+		}
+	}
+	l.store.DeleteReorderPolicies(func(policy domain.ReorderPolicy) bool {
+		return policy.LocationID == id
+	})
+	delete(l.store.Locations, id)
+	return nil
+}
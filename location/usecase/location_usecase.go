@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"strings"
+	"time"
+)
+
+type locationUseCase struct {
+	locationRepository domain.LocationRepository
+	timeout            time.Duration
+}
+
+func NewLocationUseCase(repository domain.LocationRepository, timeout time.Duration) domain.LocationUseCase {
+	return &locationUseCase{locationRepository: repository, timeout: timeout}
+}
+
+func (l *locationUseCase) GetAll(ctx context.Context, count int, offset int) ([]domain.Location, error) {
+	c, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	locations, err := l.locationRepository.GetAll(c, count, offset)
+	if err != nil {
+		return nil, err
+	}
+	if len(locations) == 0 {
+		return nil, errors.NewNotFoundError("no locations found")
+	}
+
+	return locations, nil
+}
+
+func (l *locationUseCase) GetOne(ctx context.Context, id string) (*domain.Location, error) {
+	c, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	location, err := l.locationRepository.GetOne(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if location.ID == "" {
+		return nil, errors.NewNotFoundError("no such location exists")
+	}
+
+	return location, nil
+}
+
+func (l *locationUseCase) Create(ctx context.Context, location *domain.Location) (*domain.Location, error) {
+	c, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	if err := validate(location); err != nil {
+		return nil, err
+	}
+
+	location.ID = uuid.NewString()
+	location.CreatedAt = time.Now()
+	location.UpdatedAt = location.CreatedAt
+	return l.locationRepository.Save(c, location)
+}
+
+func (l *locationUseCase) Update(ctx context.Context, location *domain.Location) (*domain.Location, error) {
+	c, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	existing, err := l.locationRepository.GetOne(c, location.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing.ID == "" {
+		return nil, errors.NewNotFoundError("no such location exists")
+	}
+
+	// a location keeps its type unless another one is given
+	if location.Type == "" {
+		location.Type = existing.Type
+	}
+	if err = validate(location); err != nil {
+		return nil, err
+	}
+	if isBuiltIn(location.ID) && location.Type != existing.Type {
+		return nil, errors.NewBadRequestError("the type of the " + location.ID + " location can't be changed")
+	}
+
+	location.CreatedAt = existing.CreatedAt
+	location.UpdatedAt = time.Now()
+	return l.locationRepository.Edit(c, location)
+}
+
+func (l *locationUseCase) Delete(ctx context.Context, id string) error {
+	c, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	// stock that doesn't name a location falls back to the built-in ones, so they must always exist
+	if isBuiltIn(id) {
+		return errors.NewBadRequestError("the " + id + " location can't be deleted")
+	}
+
+	existing, err := l.locationRepository.GetOne(c, id)
+	if err != nil {
+		return err
+	}
+	if existing.ID == "" {
+		return errors.NewNotFoundError("no such location exists")
+	}
+
+	return l.locationRepository.Delete(c, id)
+}
+
+// validate checks the name and type of a location, defaulting the type to storage
+func validate(location *domain.Location) error {
+	if strings.TrimSpace(location.Name) == "" {
+		return errors.NewBadRequestError("invalid request. Location name can't be empty")
+	}
+	if location.Type == "" {
+		location.Type = domain.LocationStorage
+	}
+	if !location.Type.IsValid() {
+		return errors.NewBadRequestError("invalid request. Location type must be storage or quarantine")
+	}
+	return nil
+}
+
+func isBuiltIn(id string) bool {
+	return id == domain.DefaultLocationID || id == domain.QuarantineLocationID
+}
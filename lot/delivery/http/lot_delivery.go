@@ -0,0 +1,216 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"net/http"
+	"strconv"
+)
+
+type LotHandler struct {
+	useCase domain.LotUseCase
+}
+
+func NewLotHandler(useCase domain.LotUseCase) *LotHandler {
+	return &LotHandler{useCase: useCase}
+}
+
+func (h *LotHandler) GetAll(c *gin.Context) {
+	var filter domain.LotFilter
+	filter.ItemID, _ = c.GetQuery("item")
+	filter.LocationID, _ = c.GetQuery("location")
+	filter.LotNumber, _ = c.GetQuery("lot_number")
+
+	var count int64
+	if countQuery, ok := c.GetQuery("count"); ok {
+		count, _ = strconv.ParseInt(countQuery, 10, 64)
+	} else {
+		count = 20
+	}
+
+	var offset int64
+	if offsetQuery, ok := c.GetQuery("offset"); ok {
+		offset, _ = strconv.ParseInt(offsetQuery, 10, 64)
+	}
+
+	ctx := c.Request.Context()
+	lots, err := h.useCase.GetAll(ctx, int(count), int(offset), filter)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, lots)
+}
+
+func (h *LotHandler) GetOne(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	lot, err := h.useCase.GetOne(ctx, id)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, lot)
+}
+
+func (h *LotHandler) Receive(c *gin.Context) {
+	var lot domain.Lot
+	if err := c.ShouldBindJSON(&lot); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid lot body"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	received, err := h.useCase.Receive(ctx, &lot)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, received)
+}
+
+func (h *LotHandler) Adjust(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	var adjustment domain.StockAdjustment
+	if err := c.ShouldBindJSON(&adjustment); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid adjustment body"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	lot, err := h.useCase.Adjust(ctx, id, adjustment)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, lot)
+}
+
+func (h *LotHandler) Block(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	var block domain.LotBlock
+	if err := c.ShouldBindJSON(&block); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid block body"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	lot, err := h.useCase.Block(ctx, id, block)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, lot)
+}
+
+func (h *LotHandler) Unblock(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	lot, err := h.useCase.Unblock(ctx, id)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, lot)
+}
+
+func (h *LotHandler) Expiring(c *gin.Context) {
+	days := domain.DefaultExpiringDays
+	if daysQuery, ok := c.GetQuery("days"); ok {
+		var err error
+		if days, err = strconv.Atoi(daysQuery); err != nil {
+			c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid days"))
+			return
+		}
+	}
+
+	var count int64
+	if countQuery, ok := c.GetQuery("count"); ok {
+		count, _ = strconv.ParseInt(countQuery, 10, 64)
+	} else {
+		count = 20
+	}
+
+	var offset int64
+	if offsetQuery, ok := c.GetQuery("offset"); ok {
+		offset, _ = strconv.ParseInt(offsetQuery, 10, 64)
+	}
+
+	ctx := c.Request.Context()
+	lots, err := h.useCase.Expiring(ctx, days, int(count), int(offset))
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, lots)
+}
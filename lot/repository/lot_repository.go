@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/nuzurie/shopify/db"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"strings"
+	"time"
+)
+
+type lotRepository struct {
+	db db.DB
+}
+
+const (
+	// lots are read along with the item and location of their inventory
+	selectLot = `SELECT lot.id, lot.inventory_id, inventory.item_id, inventory.location_id, lot.lot_number,
+			lot.quantity, lot.manufactured_at, lot.expires_at, lot.blocked, lot.block_reason, lot.created_at,
+			lot.updated_at FROM lot JOIN inventory ON inventory.id = lot.inventory_id`
+	getAll          = selectLot + ` WHERE %s ORDER BY lot.lot_number, lot.id LIMIT $%d OFFSET $%d`
+	getForInventory = selectLot + ` WHERE lot.inventory_id IN (%s) ORDER BY lot.id`
+	getByID         = selectLot + ` WHERE lot.id=$1`
+	getByNumber     = selectLot + ` WHERE lot.inventory_id=$1 AND lot.lot_number=$2`
+	getExpiring     = selectLot + ` WHERE lot.quantity > 0 AND lot.expires_at < $1
+			ORDER BY lot.expires_at, lot.id LIMIT $2 OFFSET $3`
+	save = `INSERT INTO lot (id, inventory_id, lot_number, quantity, manufactured_at, expires_at, blocked, block_reason,
+			created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	update = `UPDATE lot SET quantity=$2, blocked=$3, block_reason=$4, updated_at=$5 WHERE id=$1`
+	// SQLite has no row locks, but it only ever runs one transaction at a time
+	forUpdate = ` FOR UPDATE OF lot`
+)
+
+// NewLotRepository stores lots in a SQL database, Postgres or SQLite. The schema must be migrated
+func NewLotRepository(database db.DB) domain.LotRepository {
+	return &lotRepository{db: database}
+}
+
+func (l *lotRepository) GetAll(ctx context.Context, count int, offset int, filter domain.LotFilter) ([]domain.Lot, error) {
+	conditions := []string{"1=1"}
+	var args []interface{}
+	if filter.ItemID != "" {
+		args = append(args, filter.ItemID)
+		conditions = append(conditions, fmt.Sprintf("inventory.item_id=$%d", len(args)))
+	}
+	if filter.LocationID != "" {
+		args = append(args, filter.LocationID)
+		conditions = append(conditions, fmt.Sprintf("inventory.location_id=$%d", len(args)))
+	}
+	if filter.LotNumber != "" {
+		args = append(args, filter.LotNumber)
+		conditions = append(conditions, fmt.Sprintf("lot.lot_number=$%d", len(args)))
+	}
+
+	query := fmt.Sprintf(getAll, strings.Join(conditions, " AND "), len(args)+1, len(args)+2)
+	return l.query(ctx, query, append(args, count, offset)...)
+}
+
+func (l *lotRepository) GetForInventory(ctx context.Context, inventoryIDs []string) ([]domain.Lot, error) {
+	if len(inventoryIDs) == 0 {
+		return nil, nil
+	}
+
+	args := make([]interface{}, len(inventoryIDs))
+	placeholders := make([]string, len(inventoryIDs))
+	for index, inventoryID := range inventoryIDs {
+		args[index] = inventoryID
+		placeholders[index] = fmt.Sprintf("$%d", index+1)
+	}
+	return l.query(ctx, fmt.Sprintf(getForInventory, strings.Join(placeholders, ", ")), args...)
+}
+
+func (l *lotRepository) GetExpiring(ctx context.Context, before time.Time, count int,
+	offset int) ([]domain.Lot, error) {
+	return l.query(ctx, getExpiring, before, count, offset)
+}
+
+func (l *lotRepository) query(ctx context.Context, query string, args ...interface{}) ([]domain.Lot, error) {
+	rows, err := l.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var lots []domain.Lot
+	for rows.Next() {
+		lot, err := scan(rows)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		lots = append(lots, *lot)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return lots, nil
+}
+
+func (l *lotRepository) GetOne(ctx context.Context, id string) (*domain.Lot, error) {
+	return l.getOne(ctx, getByID, id)
+}
+
+func (l *lotRepository) GetOneForUpdate(ctx context.Context, id string) (*domain.Lot, error) {
+	query := getByID
+	if l.db.Dialect() == domain.Postgres {
+		query += forUpdate
+	}
+	return l.getOne(ctx, query, id)
+}
+
+func (l *lotRepository) GetByNumber(ctx context.Context, inventoryID string, lotNumber string) (*domain.Lot, error) {
+	return l.getOne(ctx, getByNumber, inventoryID, lotNumber)
+}
+
+func (l *lotRepository) getOne(ctx context.Context, query string, args ...interface{}) (*domain.Lot, error) {
+	lot, err := scan(l.db.QueryRow(ctx, query, args...))
+	if err == db.ErrNoRows {
+		return &domain.Lot{}, nil
+	}
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return lot, nil
+}
+
+func scan(row db.Row) (*domain.Lot, error) {
+	var lot domain.Lot
+	err := row.Scan(&lot.ID, &lot.InventoryID, &lot.ItemID, &lot.LocationID, &lot.LotNumber, &lot.Quantity,
+		&lot.ManufacturedAt, &lot.ExpiresAt, &lot.Blocked, &lot.BlockReason, &lot.CreatedAt, &lot.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lot, nil
+}
+
+func (l *lotRepository) Save(ctx context.Context, lot *domain.Lot) (*domain.Lot, error) {
+	_, err := l.db.Exec(ctx, save, lot.ID, lot.InventoryID, lot.LotNumber, lot.Quantity, lot.ManufacturedAt,
+		lot.ExpiresAt, lot.Blocked, lot.BlockReason, lot.CreatedAt, lot.UpdatedAt)
+	if err != nil {
+		if db.IsForeignKeyViolation(err) {
+			return nil, errors.NewBadRequestError("no inventory with such ID exists")
+		}
+		if db.IsUniqueViolation(err) {
+			return nil, errors.NewConflictError("the inventory already holds lot " + lot.LotNumber)
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return lot, nil
+}
+
+func (l *lotRepository) Edit(ctx context.Context, lot *domain.Lot) (*domain.Lot, error) {
+	_, err := l.db.Exec(ctx, update, lot.ID, lot.Quantity, lot.Blocked, lot.BlockReason, lot.UpdatedAt)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return lot, nil
+}
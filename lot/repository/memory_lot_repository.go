@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"sort"
+	"time"
+)
+
+type memoryLotRepository struct {
+	store *memory.Store
+}
+
+// NewMemoryLotRepository keeps lots in store instead of a database. store must be shared with the inventory repository
+// so lots go along with their inventory
+func NewMemoryLotRepository(store *memory.Store) domain.LotRepository {
+	return &memoryLotRepository{store: store}
+}
+
+func (l *memoryLotRepository) GetAll(ctx context.Context, count int, offset int,
+	filter domain.LotFilter) ([]domain.Lot, error) {
+	defer l.store.Read(ctx)()
+
+	var lots []domain.Lot
+	for _, lot := range l.store.Lots {
+		if (filter.ItemID == "" || lot.ItemID == filter.ItemID) &&
+			(filter.LocationID == "" || lot.LocationID == filter.LocationID) &&
+			(filter.LotNumber == "" || lot.LotNumber == filter.LotNumber) {
+			lots = append(lots, lot)
+		}
+	}
+	sort.Slice(lots, func(a, b int) bool {
+		if lots[a].LotNumber != lots[b].LotNumber {
+			return lots[a].LotNumber < lots[b].LotNumber
+		}
+		return lots[a].ID < lots[b].ID
+	})
+
+	start, end := memory.Page(len(lots), count, offset)
+	return lots[start:end], nil
+}
+
+func (l *memoryLotRepository) GetForInventory(ctx context.Context, inventoryIDs []string) ([]domain.Lot, error) {
+	defer l.store.Read(ctx)()
+
+	wanted := map[string]bool{}
+	for _, inventoryID := range inventoryIDs {
+		wanted[inventoryID] = true
+	}
+	var lots []domain.Lot
+	for _, lot := range l.store.Lots {
+		if wanted[lot.InventoryID] {
+			lots = append(lots, lot)
+		}
+	}
+	sort.Slice(lots, func(a, b int) bool {
+		return lots[a].ID < lots[b].ID
+	})
+	return lots, nil
+}
+
+func (l *memoryLotRepository) GetOne(ctx context.Context, id string) (*domain.Lot, error) {
+	defer l.store.Read(ctx)()
+
+	lot := l.store.Lots[id]
+	return &lot, nil
+}
+
+// GetOneForUpdate needs no lock of its own, transactions hold the whole store
+func (l *memoryLotRepository) GetOneForUpdate(ctx context.Context, id string) (*domain.Lot, error) {
+	return l.GetOne(ctx, id)
+}
+
+func (l *memoryLotRepository) GetByNumber(ctx context.Context, inventoryID string,
+	lotNumber string) (*domain.Lot, error) {
+	defer l.store.Read(ctx)()
+
+	for _, lot := range l.store.Lots {
+		if lot.InventoryID == inventoryID && lot.LotNumber == lotNumber {
+			return &lot, nil
+		}
+	}
+	return &domain.Lot{}, nil
+}
+
+func (l *memoryLotRepository) GetExpiring(ctx context.Context, before time.Time, count int,
+	offset int) ([]domain.Lot, error) {
+	defer l.store.Read(ctx)()
+
+	var lots []domain.Lot
+	for _, lot := range l.store.Lots {
+		if lot.Quantity > 0 && lot.ExpiresAt != nil && lot.ExpiresAt.Before(before) {
+			lots = append(lots, lot)
+		}
+	}
+	sort.Slice(lots, func(a, b int) bool {
+		if !lots[a].ExpiresAt.Equal(*lots[b].ExpiresAt) {
+			return lots[a].ExpiresAt.Before(*lots[b].ExpiresAt)
+		}
+		return lots[a].ID < lots[b].ID
+	})
+
+	start, end := memory.Page(len(lots), count, offset)
+	return lots[start:end], nil
+}
+
+func (l *memoryLotRepository) Save(ctx context.Context, lot *domain.Lot) (*domain.Lot, error) {
+	defer l.store.Write(ctx)()
+
+	inventory, ok := l.store.Inventory[lot.InventoryID]
+	if !ok {
+		return nil, errors.NewBadRequestError("no inventory with such ID exists")
+	}
+	for _, existing := range l.store.Lots {
+		if existing.InventoryID == lot.InventoryID && existing.LotNumber == lot.LotNumber {
+			return nil, errors.NewConflictError("the inventory already holds lot " + lot.LotNumber)
+		}
+	}
+	if _, ok := l.store.Lots[lot.ID]; ok {
+		return nil, errors.NewConflictError("lot already exists")
+	}
+	saved := *lot
+	saved.ItemID = inventory.Item.ID
+	saved.LocationID = inventory.LocationID
+	l.store.Lots[lot.ID] = saved
+	return lot, nil
+}
+
+func (l *memoryLotRepository) Edit(ctx context.Context, lot *domain.Lot) (*domain.Lot, error) {
+	defer l.store.Write(ctx)()
+
+	if existing, ok := l.store.Lots[lot.ID]; ok {
+		existing.Quantity = lot.Quantity
+		existing.Blocked = lot.Blocked
+		existing.BlockReason = lot.BlockReason
+		existing.UpdatedAt = lot.UpdatedAt
+		l.store.Lots[lot.ID] = existing
+	}
+	return lot, nil
+}
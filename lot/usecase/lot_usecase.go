@@ -0,0 +1,291 @@
+package usecase
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type lotUseCase struct {
+	lotRepository       domain.LotRepository
+	itemRepository      domain.ItemRepository
+	locationRepository  domain.LocationRepository
+	inventoryRepository domain.InventoryRepository
+	inventoryUseCase    domain.InventoryUseCase
+	watcher             domain.StockWatcher
+	transactor          domain.Transactor
+	timeout             time.Duration
+}
+
+// NewLotUseCase moves the stock of lots through inventoryUseCase, in the same transaction as the change to the lot.
+// Lots are changed with their inventory locked in inventoryRepository, as stock taken out of the inventory changes
+// them too. watcher is told about the item whenever a lot is blocked or unblocked
+func NewLotUseCase(lotRepository domain.LotRepository, itemRepository domain.ItemRepository,
+	locationRepository domain.LocationRepository, inventoryRepository domain.InventoryRepository,
+	inventoryUseCase domain.InventoryUseCase, watcher domain.StockWatcher, transactor domain.Transactor,
+	timeout time.Duration) domain.LotUseCase {
+	return &lotUseCase{lotRepository: lotRepository, itemRepository: itemRepository,
+		locationRepository: locationRepository, inventoryRepository: inventoryRepository,
+		inventoryUseCase: inventoryUseCase, watcher: watcher, transactor: transactor, timeout: timeout}
+}
+
+func (l *lotUseCase) GetAll(ctx context.Context, count int, offset int, filter domain.LotFilter) ([]domain.Lot, error) {
+	c, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	lots, err := l.lotRepository.GetAll(c, count, offset, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(lots) == 0 {
+		return nil, errors.NewNotFoundError("no lots found")
+	}
+
+	return lots, nil
+}
+
+func (l *lotUseCase) GetOne(ctx context.Context, id string) (*domain.Lot, error) {
+	c, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	lot, err := l.lotRepository.GetOne(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if lot.ID == "" {
+		return nil, errors.NewNotFoundError("no such lot found")
+	}
+
+	return lot, nil
+}
+
+func (l *lotUseCase) Receive(ctx context.Context, lot *domain.Lot) (*domain.Lot, error) {
+	c, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	lot.LotNumber = strings.TrimSpace(lot.LotNumber)
+	if lot.LotNumber == "" {
+		return nil, errors.NewBadRequestError("invalid request. Lot number is required")
+	}
+	if lot.Quantity <= 0 {
+		return nil, errors.NewBadRequestError("invalid request. Quantity must be more than 0")
+	}
+	// the database keeps timestamps without their zone
+	if lot.ManufacturedAt != nil {
+		manufacturedAt := lot.ManufacturedAt.UTC()
+		lot.ManufacturedAt = &manufacturedAt
+	}
+	if lot.ExpiresAt != nil {
+		expiresAt := lot.ExpiresAt.UTC()
+		lot.ExpiresAt = &expiresAt
+	}
+	if lot.ManufacturedAt != nil && lot.ExpiresAt != nil && !lot.ExpiresAt.After(*lot.ManufacturedAt) {
+		return nil, errors.NewBadRequestError("invalid request. A lot must expire after it was manufactured")
+	}
+	if lot.LocationID == "" {
+		lot.LocationID = domain.DefaultLocationID
+	}
+
+	item, err := l.itemRepository.GetOne(c, lot.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	if item.ID == "" {
+		return nil, errors.NewBadRequestError("no item with such ID exists")
+	}
+	factor, ok := item.UnitFactor(lot.Unit)
+	if !ok {
+		return nil, errors.NewBadRequestError("invalid request. Item " + lot.ItemID + " isn't counted in " + lot.Unit)
+	}
+	if lot.Quantity, ok = domain.ScaleQuantity(lot.Quantity, factor); !ok {
+		return nil, errors.NewBadRequestError("invalid request. Quantity is too large for item " + lot.ItemID)
+	}
+	lot.Unit = ""
+	location, err := l.locationRepository.GetOne(c, lot.LocationID)
+	if err != nil {
+		return nil, err
+	}
+	if location.ID == "" {
+		return nil, errors.NewBadRequestError("no location with such ID exists")
+	}
+
+	var received *domain.Lot
+	err = l.transactor.WithinTransaction(c, func(c context.Context) error {
+		inventory, err := l.inventoryUseCase.MoveStock(c, lot.ItemID, lot.LocationID,
+			domain.StockAdjustment{Delta: lot.Quantity, Reason: domain.ReasonReceived})
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		received, err = l.lotRepository.GetByNumber(c, inventory.ID, lot.LotNumber)
+		if err != nil {
+			return err
+		}
+		if received.ID != "" {
+			if !sameTime(received.ManufacturedAt, lot.ManufacturedAt) || !sameTime(received.ExpiresAt, lot.ExpiresAt) {
+				return errors.NewConflictError("lot " + lot.LotNumber + " was received before with other dates")
+			}
+			received.Quantity += lot.Quantity
+			received.UpdatedAt = now
+			_, err = l.lotRepository.Edit(c, received)
+			return err
+		}
+
+		lot.ID = uuid.NewString()
+		lot.InventoryID = inventory.ID
+		lot.Blocked = false
+		lot.BlockReason = ""
+		lot.CreatedAt = now
+		lot.UpdatedAt = now
+		received, err = l.lotRepository.Save(c, lot)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return received, nil
+}
+
+// sameTime tells whether the dates of a lot received again match those it was received with. Dates left out match
+func sameTime(existing *time.Time, received *time.Time) bool {
+	return received == nil || existing != nil && existing.Equal(*received)
+}
+
+func (l *lotUseCase) Adjust(ctx context.Context, id string, adjustment domain.StockAdjustment) (*domain.Lot, error) {
+	c, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	if adjustment.Delta == 0 {
+		return nil, errors.NewBadRequestError("invalid request. Delta can't be 0")
+	}
+	if !adjustment.Reason.IsValid() {
+		return nil, errors.NewBadRequestError("invalid request. Unknown reason " + string(adjustment.Reason))
+	}
+
+	var lot *domain.Lot
+	err := l.transactor.WithinTransaction(c, func(c context.Context) error {
+		var err error
+		lot, err = l.getForUpdate(c, id)
+		if err != nil {
+			return err
+		}
+		if adjustment.Unit != "" {
+			item, err := l.itemRepository.GetOne(c, lot.ItemID)
+			if err != nil {
+				return err
+			}
+			counted, fits := item.ToBaseUnit(&adjustment)
+			if !counted {
+				return errors.NewBadRequestError("invalid request. Item " + lot.ItemID + " isn't counted in " +
+					adjustment.Unit)
+			}
+			if !fits {
+				return errors.NewBadRequestError("invalid request. Delta is too large for item " + lot.ItemID)
+			}
+		}
+		if lot.Quantity+adjustment.Delta < 0 {
+			return errors.NewConflictError("insufficient stock. Lot " + lot.LotNumber + " holds only " +
+				strconv.Itoa(lot.Quantity))
+		}
+
+		// the lot changes first, so the stock taken out of the inventory isn't taken out of its other lots as well
+		lot.Quantity += adjustment.Delta
+		lot.UpdatedAt = time.Now()
+		if _, err = l.lotRepository.Edit(c, lot); err != nil {
+			return err
+		}
+		_, err = l.inventoryUseCase.MoveStock(c, lot.ItemID, lot.LocationID, adjustment)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return lot, nil
+}
+
+func (l *lotUseCase) Block(ctx context.Context, id string, block domain.LotBlock) (*domain.Lot, error) {
+	reason := strings.TrimSpace(block.Reason)
+	if reason == "" {
+		return nil, errors.NewBadRequestError("invalid request. Reason is required")
+	}
+
+	return l.setBlocked(ctx, id, true, reason)
+}
+
+func (l *lotUseCase) Unblock(ctx context.Context, id string) (*domain.Lot, error) {
+	return l.setBlocked(ctx, id, false, "")
+}
+
+func (l *lotUseCase) setBlocked(ctx context.Context, id string, blocked bool, reason string) (*domain.Lot, error) {
+	c, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	var lot *domain.Lot
+	err := l.transactor.WithinTransaction(c, func(c context.Context) error {
+		var err error
+		lot, err = l.getForUpdate(c, id)
+		if err != nil {
+			return err
+		}
+		if lot.Blocked == blocked {
+			if blocked {
+				return errors.NewConflictError("lot " + lot.LotNumber + " is already blocked")
+			}
+			return errors.NewConflictError("lot " + lot.LotNumber + " isn't blocked")
+		}
+
+		lot.Blocked = blocked
+		lot.BlockReason = reason
+		lot.UpdatedAt = time.Now()
+		_, err = l.lotRepository.Edit(c, lot)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	l.watcher.StockChanged(lot.ItemID)
+	return lot, nil
+}
+
+// getForUpdate locks the inventory of the lot and then the lot, in the order stock changes lock them
+func (l *lotUseCase) getForUpdate(c context.Context, id string) (*domain.Lot, error) {
+	lot, err := l.lotRepository.GetOne(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if lot.ID == "" {
+		return nil, errors.NewNotFoundError("no such lot found")
+	}
+	if _, err = l.inventoryRepository.GetByIDForUpdate(c, lot.InventoryID); err != nil {
+		return nil, err
+	}
+
+	return l.lotRepository.GetOneForUpdate(c, id)
+}
+
+func (l *lotUseCase) Expiring(ctx context.Context, days int, count int, offset int) ([]domain.Lot, error) {
+	c, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	if days < 0 {
+		return nil, errors.NewBadRequestError("invalid request. Days can't be less than 0")
+	}
+
+	lots, err := l.lotRepository.GetExpiring(c, time.Now().AddDate(0, 0, days), count, offset)
+	if err != nil {
+		return nil, err
+	}
+	if len(lots) == 0 {
+		return nil, errors.NewNotFoundError("no expiring lots found")
+	}
+
+	return lots, nil
+}
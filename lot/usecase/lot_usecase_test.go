@@ -0,0 +1,158 @@
+package usecase
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/inventory/repository"
+	usecase2 "github.com/nuzurie/shopify/inventory/usecase"
+	repository2 "github.com/nuzurie/shopify/item/repository"
+	repository3 "github.com/nuzurie/shopify/location/repository"
+	repository4 "github.com/nuzurie/shopify/lot/repository"
+	repository5 "github.com/nuzurie/shopify/reservation/repository"
+	repository6 "github.com/nuzurie/shopify/serial/repository"
+	"github.com/nuzurie/shopify/utils/errors"
+	repository7 "github.com/nuzurie/shopify/valuation/repository"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestConsumeFirstExpiryFirstOut(t *testing.T) {
+	lotUseCase, inventoryUseCase := newLotUseCase(t)
+	ctx := context.Background()
+	now := time.Now()
+	inDays := func(days int) *time.Time {
+		at := now.AddDate(0, 0, days)
+		return &at
+	}
+
+	received := map[string]*domain.Lot{}
+	for _, lot := range []domain.Lot{
+		{LotNumber: "late", ExpiresAt: inDays(10)},
+		{LotNumber: "soon", ExpiresAt: inDays(3)},
+		{LotNumber: "never"},
+		{LotNumber: "recalled", ExpiresAt: inDays(1)},
+	} {
+		lot.ItemID = "milk"
+		lot.Quantity = 4
+		lot, err := lotUseCase.Receive(ctx, &lot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		received[lot.LotNumber] = lot
+	}
+	if _, err := lotUseCase.Block(ctx, received["recalled"].ID, domain.LotBlock{Reason: "recall"}); err != nil {
+		t.Fatal(err)
+	}
+	inventoryID := received["late"].InventoryID
+
+	// 5 units were on hand before any lot was received, so they go before the lots do
+	steps := []struct {
+		name   string
+		sold   int
+		status int
+		want   map[string]int
+	}{
+		{"outside any lot", 3, 0, map[string]int{"late": 4, "soon": 4, "never": 4, "recalled": 4}},
+		{"rest of those outside any lot, then the lot expiring first", 4, 0,
+			map[string]int{"late": 4, "soon": 2, "never": 4, "recalled": 4}},
+		{"across lots", 5, 0, map[string]int{"late": 1, "soon": 0, "never": 4, "recalled": 4}},
+		{"lots that don't expire after those that do", 2, 0,
+			map[string]int{"late": 0, "soon": 0, "never": 3, "recalled": 4}},
+		{"not out of blocked lots", 5, http.StatusConflict,
+			map[string]int{"late": 0, "soon": 0, "never": 3, "recalled": 4}},
+		{"the rest of the lots that aren't blocked", 3, 0,
+			map[string]int{"late": 0, "soon": 0, "never": 0, "recalled": 4}},
+	}
+	for _, step := range steps {
+		_, err := inventoryUseCase.AdjustQuantity(ctx, inventoryID,
+			domain.StockAdjustment{Delta: -step.sold, Reason: domain.ReasonSold}, 0)
+		if statusOf(err) != step.status {
+			t.Fatalf("%s: AdjustQuantity failed with %d (%v), want %d", step.name, statusOf(err), err, step.status)
+		}
+		lots, err := lotUseCase.GetAll(ctx, 10, 0, domain.LotFilter{ItemID: "milk"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, lot := range lots {
+			if lot.Quantity != step.want[lot.LotNumber] {
+				t.Errorf("%s: lot %s holds %d, want %d", step.name, lot.LotNumber, lot.Quantity,
+					step.want[lot.LotNumber])
+			}
+		}
+	}
+}
+
+func TestAdjustLot(t *testing.T) {
+	lotUseCase, _ := newLotUseCase(t)
+	ctx := context.Background()
+	soon := time.Now().AddDate(0, 0, 1)
+	first, err := lotUseCase.Receive(ctx, &domain.Lot{ItemID: "milk", LotNumber: "first", Quantity: 4, ExpiresAt: &soon})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = lotUseCase.Receive(ctx, &domain.Lot{ItemID: "milk", LotNumber: "second", Quantity: 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	// stock taken out of a lot named comes out of that lot only, whatever its expiry
+	second, err := lotUseCase.GetAll(ctx, 1, 0, domain.LotFilter{LotNumber: "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	adjusted, err := lotUseCase.Adjust(ctx, second[0].ID, domain.StockAdjustment{Delta: -3, Reason: domain.ReasonSold})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if adjusted.Quantity != 1 {
+		t.Errorf("lot second holds %d, want 1", adjusted.Quantity)
+	}
+	if first, err = lotUseCase.GetOne(ctx, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if first.Quantity != 4 {
+		t.Errorf("lot first holds %d, want 4", first.Quantity)
+	}
+}
+
+// ignoreStockChanges is a StockWatcher for tests that don't evaluate reorder points
+type ignoreStockChanges struct{}
+
+func (ignoreStockChanges) StockChanged(string) {}
+
+// newLotUseCase works on a store holding 5 units of milk at the default location, outside any lot
+func newLotUseCase(t *testing.T) (domain.LotUseCase, domain.InventoryUseCase) {
+	store := memory.NewStore()
+	itemRepository := repository2.NewMemoryItemRepository(store)
+	inventoryRepository := repository.NewMemoryInventoryRepository(store)
+	locationRepository := repository3.NewMemoryLocationRepository(store)
+	lotRepository := repository4.NewMemoryLotRepository(store)
+	inventoryUseCase := usecase2.NewInventoryUseCase(itemRepository, inventoryRepository,
+		repository.NewMemoryMovementRepository(store), repository5.NewMemoryReservationRepository(store),
+		locationRepository, lotRepository, repository6.NewMemorySerialRepository(store),
+		repository7.NewMemoryCostRepository(store), ignoreStockChanges{}, store, time.Second)
+
+	ctx := context.Background()
+	if _, err := itemRepository.Save(ctx, &domain.Item{ID: "milk", Name: "milk", Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inventoryUseCase.MoveStock(ctx, "milk", domain.DefaultLocationID,
+		domain.StockAdjustment{Delta: 5, Reason: domain.ReasonReceived}); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewLotUseCase(lotRepository, itemRepository, locationRepository, inventoryRepository, inventoryUseCase,
+		ignoreStockChanges{}, store, time.Second), inventoryUseCase
+}
+
+// statusOf is the status code of err, or 0 if there is none
+func statusOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	if restError, ok := err.(*errors.RestError); ok {
+		return restError.Code
+	}
+	return http.StatusInternalServerError
+}
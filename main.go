@@ -2,8 +2,13 @@ package main
 
 import (
 	"github.com/nuzurie/shopify/app"
+	"os"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		app.Migrate(os.Args[2:])
+		return
+	}
 	app.Start()
 }
@@ -0,0 +1,200 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"net/http"
+	"strconv"
+)
+
+type PurchaseOrderHandler struct {
+	useCase domain.PurchaseOrderUseCase
+}
+
+func NewPurchaseOrderHandler(useCase domain.PurchaseOrderUseCase) *PurchaseOrderHandler {
+	return &PurchaseOrderHandler{useCase: useCase}
+}
+
+func (h *PurchaseOrderHandler) GetAll(c *gin.Context) {
+	supplierID, _ := c.GetQuery("supplier")
+	status, _ := c.GetQuery("status")
+
+	var count int64
+	if countQuery, ok := c.GetQuery("count"); ok {
+		count, _ = strconv.ParseInt(countQuery, 10, 64)
+	} else {
+		count = 20
+	}
+
+	var offset int64
+	if offsetQuery, ok := c.GetQuery("offset"); ok {
+		offset, _ = strconv.ParseInt(offsetQuery, 10, 64)
+	}
+
+	ctx := c.Request.Context()
+	orders, err := h.useCase.GetAll(ctx, int(count), int(offset), supplierID, domain.PurchaseOrderStatus(status))
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
+
+func (h *PurchaseOrderHandler) GetOne(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	order, err := h.useCase.GetOne(ctx, id)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+func (h *PurchaseOrderHandler) Create(c *gin.Context) {
+	var order domain.PurchaseOrder
+	if err := c.ShouldBindJSON(&order); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid purchase order body"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	created, err := h.useCase.Create(ctx, &order)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *PurchaseOrderHandler) Update(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	var order domain.PurchaseOrder
+	if err := c.ShouldBindJSON(&order); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid purchase order body"))
+		return
+	}
+
+	order.ID = id
+	ctx := c.Request.Context()
+	updated, err := h.useCase.Update(ctx, &order)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+func (h *PurchaseOrderHandler) Send(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	order, err := h.useCase.Send(ctx, id)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+func (h *PurchaseOrderHandler) Receive(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	var receipt domain.PurchaseOrderReceipt
+	if err := c.ShouldBindJSON(&receipt); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid receipt body"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	order, err := h.useCase.Receive(ctx, id, receipt)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+func (h *PurchaseOrderHandler) Close(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	order, err := h.useCase.Close(ctx, id)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, order)
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"sort"
+)
+
+type memoryPurchaseOrderRepository struct {
+	store *memory.Store
+}
+
+// NewMemoryPurchaseOrderRepository keeps purchase orders in store instead of a database. store must be shared with
+// the supplier and location repositories so orders can only reference existing suppliers and locations
+func NewMemoryPurchaseOrderRepository(store *memory.Store) domain.PurchaseOrderRepository {
+	return &memoryPurchaseOrderRepository{store: store}
+}
+
+func (p *memoryPurchaseOrderRepository) GetAll(ctx context.Context, count int, offset int, supplierID string,
+	status domain.PurchaseOrderStatus) ([]domain.PurchaseOrder, error) {
+	defer p.store.Read(ctx)()
+
+	var orders []domain.PurchaseOrder
+	for _, order := range p.store.PurchaseOrders {
+		if (supplierID == "" || order.SupplierID == supplierID) && (status == "" || order.Status == status) {
+			order = copyPurchaseOrder(order)
+			order.Receipts = nil
+			orders = append(orders, order)
+		}
+	}
+	sort.Slice(orders, func(a, b int) bool {
+		if !orders[a].CreatedAt.Equal(orders[b].CreatedAt) {
+			return orders[a].CreatedAt.After(orders[b].CreatedAt)
+		}
+		return orders[a].ID < orders[b].ID
+	})
+
+	start, end := memory.Page(len(orders), count, offset)
+	return orders[start:end], nil
+}
+
+func (p *memoryPurchaseOrderRepository) GetOne(ctx context.Context, id string) (*domain.PurchaseOrder, error) {
+	defer p.store.Read(ctx)()
+
+	order := copyPurchaseOrder(p.store.PurchaseOrders[id])
+	return &order, nil
+}
+
+// GetOneForUpdate needs no lock of its own, transactions hold the whole store
+func (p *memoryPurchaseOrderRepository) GetOneForUpdate(ctx context.Context, id string) (*domain.PurchaseOrder, error) {
+	return p.GetOne(ctx, id)
+}
+
+func (p *memoryPurchaseOrderRepository) Save(ctx context.Context, order *domain.PurchaseOrder) (*domain.PurchaseOrder, error) {
+	defer p.store.Write(ctx)()
+
+	if !p.referencesExist(order) {
+		return nil, errors.NewBadRequestError("no supplier or location with such ID exists")
+	}
+	if _, ok := p.store.PurchaseOrders[order.ID]; ok {
+		return nil, errors.NewConflictError("purchase order already exists")
+	}
+	saved := copyPurchaseOrder(*order)
+	saved.Receipts = nil
+	p.store.PurchaseOrders[order.ID] = saved
+	return order, nil
+}
+
+func (p *memoryPurchaseOrderRepository) Edit(ctx context.Context, order *domain.PurchaseOrder) (*domain.PurchaseOrder, error) {
+	defer p.store.Write(ctx)()
+
+	existing, ok := p.store.PurchaseOrders[order.ID]
+	if !ok {
+		return order, nil
+	}
+	if !p.referencesExist(order) {
+		return nil, errors.NewBadRequestError("no supplier or location with such ID exists")
+	}
+	// receipts are only ever added through SaveReceipt
+	saved := copyPurchaseOrder(*order)
+	saved.Receipts = existing.Receipts
+	p.store.PurchaseOrders[order.ID] = saved
+	return order, nil
+}
+
+func (p *memoryPurchaseOrderRepository) SaveReceipt(ctx context.Context, orderID string,
+	receipt *domain.PurchaseOrderReceipt) (*domain.PurchaseOrderReceipt, error) {
+	defer p.store.Write(ctx)()
+
+	order, ok := p.store.PurchaseOrders[orderID]
+	if !ok {
+		return nil, errors.NewNotFoundError("no such purchase order found")
+	}
+	order = copyPurchaseOrder(order)
+	saved := *receipt
+	saved.Lines = append([]domain.PurchaseOrderReceiptLine(nil), receipt.Lines...)
+	order.Receipts = append(order.Receipts, saved)
+	p.store.PurchaseOrders[orderID] = order
+	return receipt, nil
+}
+
+func (p *memoryPurchaseOrderRepository) referencesExist(order *domain.PurchaseOrder) bool {
+	_, supplierExists := p.store.Suppliers[order.SupplierID]
+	_, locationExists := p.store.Locations[order.LocationID]
+	return supplierExists && locationExists
+}
+
+// copyPurchaseOrder keeps the lines and receipts held by the store from being changed through the orders handed out
+func copyPurchaseOrder(order domain.PurchaseOrder) domain.PurchaseOrder {
+	order.Lines = append([]domain.PurchaseOrderLine(nil), order.Lines...)
+	order.Receipts = append([]domain.PurchaseOrderReceipt(nil), order.Receipts...)
+	return order
+}
@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/nuzurie/shopify/db"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"github.com/shopspring/decimal"
+	"strings"
+)
+
+type purchaseOrderRepository struct {
+	db db.DB
+}
+
+const (
+	getAll = `SELECT id, supplier_id, location_id, status, created_at, updated_at, sent_at, received_at, closed_at
+			FROM purchase_order WHERE %s ORDER BY created_at DESC, id LIMIT $%d OFFSET $%d`
+	getByID = `SELECT id, supplier_id, location_id, status, created_at, updated_at, sent_at, received_at, closed_at
+			FROM purchase_order WHERE id=$1`
+	getLines = `SELECT item_id, quantity, unit_cost, received FROM purchase_order_line WHERE purchase_order_id=$1
+			ORDER BY item_id`
+	getReceipts = `SELECT id, actor, received_at FROM purchase_order_receipt WHERE purchase_order_id=$1
+			ORDER BY received_at, id`
+	getReceiptLines = `SELECT item_id, quantity FROM purchase_order_receipt_line WHERE receipt_id=$1 ORDER BY item_id`
+	save            = `INSERT INTO purchase_order (id, supplier_id, location_id, status, created_at, updated_at, sent_at,
+			received_at, closed_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	saveLine = `INSERT INTO purchase_order_line (purchase_order_id, item_id, quantity, unit_cost, received)
+			VALUES ($1, $2, $3, $4, $5)`
+	saveReceipt = `INSERT INTO purchase_order_receipt (id, purchase_order_id, actor, received_at)
+			VALUES ($1, $2, $3, $4)`
+	saveReceiptLine = `INSERT INTO purchase_order_receipt_line (receipt_id, item_id, quantity) VALUES ($1, $2, $3)`
+	update          = `UPDATE purchase_order SET supplier_id=$2, location_id=$3, status=$4, updated_at=$5, sent_at=$6,
+			received_at=$7, closed_at=$8 WHERE id=$1`
+	deleteLines = `DELETE FROM purchase_order_line WHERE purchase_order_id=$1`
+	// SQLite has no row locks, but it only ever runs one transaction at a time
+	forUpdate = ` FOR UPDATE`
+)
+
+// NewPurchaseOrderRepository stores purchase orders in a SQL database, Postgres or SQLite. The schema must be migrated
+func NewPurchaseOrderRepository(database db.DB) domain.PurchaseOrderRepository {
+	return &purchaseOrderRepository{db: database}
+}
+
+func (p *purchaseOrderRepository) GetAll(ctx context.Context, count int, offset int, supplierID string,
+	status domain.PurchaseOrderStatus) ([]domain.PurchaseOrder, error) {
+	conditions, args := []string{"1=1"}, []interface{}{}
+	if supplierID != "" {
+		args = append(args, supplierID)
+		conditions = append(conditions, fmt.Sprintf("supplier_id=$%d", len(args)))
+	}
+	if status != "" {
+		args = append(args, status)
+		conditions = append(conditions, fmt.Sprintf("status=$%d", len(args)))
+	}
+	query := fmt.Sprintf(getAll, strings.Join(conditions, " AND "), len(args)+1, len(args)+2)
+	rows, err := p.db.Query(ctx, query, append(args, count, offset)...)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var orders []domain.PurchaseOrder
+	for rows.Next() {
+		var order domain.PurchaseOrder
+		err = rows.Scan(&order.ID, &order.SupplierID, &order.LocationID, &order.Status, &order.CreatedAt,
+			&order.UpdatedAt, &order.SentAt, &order.ReceivedAt, &order.ClosedAt)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		orders = append(orders, order)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	rows.Close()
+
+	for index := range orders {
+		if orders[index].Lines, err = p.getLines(ctx, orders[index].ID); err != nil {
+			return nil, err
+		}
+	}
+	return orders, nil
+}
+
+func (p *purchaseOrderRepository) GetOne(ctx context.Context, id string) (*domain.PurchaseOrder, error) {
+	return p.getOne(ctx, getByID, id)
+}
+
+func (p *purchaseOrderRepository) GetOneForUpdate(ctx context.Context, id string) (*domain.PurchaseOrder, error) {
+	query := getByID
+	if p.db.Dialect() == domain.Postgres {
+		query += forUpdate
+	}
+	return p.getOne(ctx, query, id)
+}
+
+func (p *purchaseOrderRepository) getOne(ctx context.Context, query string, id string) (*domain.PurchaseOrder, error) {
+	var order domain.PurchaseOrder
+	err := p.db.QueryRow(ctx, query, id).
+		Scan(&order.ID, &order.SupplierID, &order.LocationID, &order.Status, &order.CreatedAt, &order.UpdatedAt,
+			&order.SentAt, &order.ReceivedAt, &order.ClosedAt)
+	if err == db.ErrNoRows {
+		return &order, nil
+	}
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	if order.Lines, err = p.getLines(ctx, order.ID); err != nil {
+		return nil, err
+	}
+	if order.Receipts, err = p.getReceipts(ctx, order.ID); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (p *purchaseOrderRepository) getLines(ctx context.Context, orderID string) ([]domain.PurchaseOrderLine, error) {
+	rows, err := p.db.Query(ctx, getLines, orderID)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var lines []domain.PurchaseOrderLine
+	for rows.Next() {
+		var line domain.PurchaseOrderLine
+		var unitCost decimal.Decimal
+		err = rows.Scan(&line.ItemID, &line.Quantity, &unitCost, &line.Received)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+		line.UnitCost = &unitCost
+
+		lines = append(lines, line)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return lines, nil
+}
+
+func (p *purchaseOrderRepository) getReceipts(ctx context.Context, orderID string) ([]domain.PurchaseOrderReceipt, error) {
+	rows, err := p.db.Query(ctx, getReceipts, orderID)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var receipts []domain.PurchaseOrderReceipt
+	for rows.Next() {
+		var receipt domain.PurchaseOrderReceipt
+		err = rows.Scan(&receipt.ID, &receipt.Actor, &receipt.ReceivedAt)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		receipts = append(receipts, receipt)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	rows.Close()
+
+	for index := range receipts {
+		if receipts[index].Lines, err = p.getReceiptLines(ctx, receipts[index].ID); err != nil {
+			return nil, err
+		}
+	}
+	return receipts, nil
+}
+
+func (p *purchaseOrderRepository) getReceiptLines(ctx context.Context,
+	receiptID string) ([]domain.PurchaseOrderReceiptLine, error) {
+	rows, err := p.db.Query(ctx, getReceiptLines, receiptID)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var lines []domain.PurchaseOrderReceiptLine
+	for rows.Next() {
+		var line domain.PurchaseOrderReceiptLine
+		if err = rows.Scan(&line.ItemID, &line.Quantity); err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		lines = append(lines, line)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return lines, nil
+}
+
+func (p *purchaseOrderRepository) Save(ctx context.Context, order *domain.PurchaseOrder) (*domain.PurchaseOrder, error) {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, save, order.ID, order.SupplierID, order.LocationID, order.Status, order.CreatedAt,
+		order.UpdatedAt, order.SentAt, order.ReceivedAt, order.ClosedAt)
+	if err != nil {
+		if db.IsForeignKeyViolation(err) {
+			return nil, errors.NewBadRequestError("no supplier or location with such ID exists")
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	if err = saveLines(ctx, tx, order); err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return order, nil
+}
+
+func (p *purchaseOrderRepository) Edit(ctx context.Context, order *domain.PurchaseOrder) (*domain.PurchaseOrder, error) {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, update, order.ID, order.SupplierID, order.LocationID, order.Status, order.UpdatedAt,
+		order.SentAt, order.ReceivedAt, order.ClosedAt)
+	if err != nil {
+		if db.IsForeignKeyViolation(err) {
+			return nil, errors.NewBadRequestError("no supplier or location with such ID exists")
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	if _, err = tx.Exec(ctx, deleteLines, order.ID); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	if err = saveLines(ctx, tx, order); err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return order, nil
+}
+
+func saveLines(ctx context.Context, tx db.Tx, order *domain.PurchaseOrder) error {
+	for _, line := range order.Lines {
+		_, err := tx.Exec(ctx, saveLine, order.ID, line.ItemID, line.Quantity, line.UnitCost, line.Received)
+		if err != nil {
+			return errors.NewInternalServerError(err.Error())
+		}
+	}
+	return nil
+}
+
+func (p *purchaseOrderRepository) SaveReceipt(ctx context.Context, orderID string,
+	receipt *domain.PurchaseOrderReceipt) (*domain.PurchaseOrderReceipt, error) {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, saveReceipt, receipt.ID, orderID, receipt.Actor, receipt.ReceivedAt)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	for _, line := range receipt.Lines {
+		if _, err = tx.Exec(ctx, saveReceiptLine, receipt.ID, line.ItemID, line.Quantity); err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return receipt, nil
+}
@@ -0,0 +1,361 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/audit"
+	"github.com/nuzurie/shopify/utils/errors"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type purchaseOrderUseCase struct {
+	purchaseOrderRepository domain.PurchaseOrderRepository
+	itemRepository          domain.ItemRepository
+	supplierRepository      domain.SupplierRepository
+	supplierItemRepository  domain.SupplierItemRepository
+	rateRepository          domain.CurrencyRateRepository
+	inventoryUseCase        domain.InventoryUseCase
+	transactor              domain.Transactor
+	timeout                 time.Duration
+}
+
+// NewPurchaseOrderUseCase receives stock through inventoryUseCase, in the same transaction as the receipt. Lines
+// without a unit cost are priced from the supplier's catalogue in supplierItemRepository. Unit costs are in the
+// currency of the supplier in supplierRepository, and received stock is costed in DefaultCurrency at the rates in
+// rateRepository
+func NewPurchaseOrderUseCase(purchaseOrderRepository domain.PurchaseOrderRepository,
+	itemRepository domain.ItemRepository, supplierRepository domain.SupplierRepository,
+	supplierItemRepository domain.SupplierItemRepository, rateRepository domain.CurrencyRateRepository,
+	inventoryUseCase domain.InventoryUseCase, transactor domain.Transactor, timeout time.Duration) domain.PurchaseOrderUseCase {
+	return &purchaseOrderUseCase{purchaseOrderRepository: purchaseOrderRepository, itemRepository: itemRepository,
+		supplierRepository: supplierRepository, supplierItemRepository: supplierItemRepository,
+		rateRepository: rateRepository, inventoryUseCase: inventoryUseCase, transactor: transactor, timeout: timeout}
+}
+
+func (p *purchaseOrderUseCase) GetAll(ctx context.Context, count int, offset int, supplierID string,
+	status domain.PurchaseOrderStatus) ([]domain.PurchaseOrder, error) {
+	c, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	orders, err := p.purchaseOrderRepository.GetAll(c, count, offset, supplierID, status)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, errors.NewNotFoundError("no purchase orders found")
+	}
+
+	for index := range orders {
+		fillOutstanding(&orders[index])
+	}
+	return orders, nil
+}
+
+func (p *purchaseOrderUseCase) GetOne(ctx context.Context, id string) (*domain.PurchaseOrder, error) {
+	c, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	order, err := p.purchaseOrderRepository.GetOne(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.ID == "" {
+		return nil, errors.NewNotFoundError("no such purchase order found")
+	}
+
+	fillOutstanding(order)
+	return order, nil
+}
+
+func (p *purchaseOrderUseCase) Create(ctx context.Context, order *domain.PurchaseOrder) (*domain.PurchaseOrder, error) {
+	c, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	if err := p.validate(c, order); err != nil {
+		return nil, err
+	}
+
+	order.ID = uuid.NewString()
+	order.Status = domain.PurchaseOrderDraft
+	order.Receipts = nil
+	order.CreatedAt = time.Now()
+	order.UpdatedAt = order.CreatedAt
+	order.SentAt = nil
+	order.ReceivedAt = nil
+	order.ClosedAt = nil
+	if _, err := p.purchaseOrderRepository.Save(c, order); err != nil {
+		return nil, err
+	}
+
+	fillOutstanding(order)
+	return order, nil
+}
+
+func (p *purchaseOrderUseCase) Update(ctx context.Context, order *domain.PurchaseOrder) (*domain.PurchaseOrder, error) {
+	return p.update(ctx, order.ID, func(c context.Context, existing *domain.PurchaseOrder) error {
+		if existing.Status != domain.PurchaseOrderDraft {
+			return errors.NewConflictError("only draft purchase orders can be changed, this one is " +
+				string(existing.Status))
+		}
+		if err := p.validate(c, order); err != nil {
+			return err
+		}
+
+		existing.SupplierID = order.SupplierID
+		existing.LocationID = order.LocationID
+		existing.Lines = order.Lines
+		return nil
+	})
+}
+
+func (p *purchaseOrderUseCase) Send(ctx context.Context, id string) (*domain.PurchaseOrder, error) {
+	return p.update(ctx, id, func(c context.Context, order *domain.PurchaseOrder) error {
+		if order.Status != domain.PurchaseOrderDraft {
+			return errors.NewConflictError("only draft purchase orders can be sent, this one is " + string(order.Status))
+		}
+
+		now := time.Now()
+		order.Status = domain.PurchaseOrderSent
+		order.SentAt = &now
+		return nil
+	})
+}
+
+func (p *purchaseOrderUseCase) Receive(ctx context.Context, id string,
+	receipt domain.PurchaseOrderReceipt) (*domain.PurchaseOrder, error) {
+	if len(receipt.Lines) == 0 {
+		return nil, errors.NewBadRequestError("invalid request. A receipt needs at least one line")
+	}
+
+	return p.update(ctx, id, func(c context.Context, order *domain.PurchaseOrder) error {
+		if order.Status != domain.PurchaseOrderSent && order.Status != domain.PurchaseOrderPartiallyReceived {
+			return errors.NewConflictError("only sent purchase orders can be received, this one is " +
+				string(order.Status))
+		}
+
+		currency, rates, err := p.rates(c, order.SupplierID)
+		if err != nil {
+			return err
+		}
+
+		lines := map[string]int{}
+		for index, line := range order.Lines {
+			lines[line.ItemID] = index
+		}
+		seen := map[string]bool{}
+		for receivedIndex, received := range receipt.Lines {
+			index, ok := lines[received.ItemID]
+			if !ok {
+				return errors.NewBadRequestError("invalid request. Item " + received.ItemID + " isn't on the purchase order")
+			}
+			if seen[received.ItemID] {
+				return errors.NewBadRequestError("invalid request. Item " + received.ItemID + " is on more than one line")
+			}
+			seen[received.ItemID] = true
+			if received.Quantity <= 0 {
+				return errors.NewBadRequestError("invalid request. Quantity must be more than 0")
+			}
+			if received.Unit != "" {
+				factor, err := p.unitFactor(c, received.ItemID, received.Unit)
+				if err != nil {
+					return err
+				}
+				var ok bool
+				if received.Quantity, ok = domain.ScaleQuantity(received.Quantity, factor); !ok {
+					return errors.NewBadRequestError("invalid request. Quantity is too large for item " +
+						received.ItemID)
+				}
+				received.Unit = ""
+				receipt.Lines[receivedIndex] = received
+			}
+			line := order.Lines[index]
+			if received.Quantity > line.Quantity-line.Received {
+				return errors.NewConflictError(fmt.Sprintf("can't receive %d of item %s, only %d outstanding",
+					received.Quantity, received.ItemID, line.Quantity-line.Received))
+			}
+
+			// the layer the receipt creates is costed at what the order paid, in the currency stock is valued in
+			unitCost, _ := rates.ConvertCost(*line.UnitCost, currency, domain.DefaultCurrency)
+			_, err := p.inventoryUseCase.MoveStock(c, received.ItemID, order.LocationID, domain.StockAdjustment{
+				Delta: received.Quantity, Reason: domain.ReasonReceived, Serials: received.Serials,
+				UnitCost: &unitCost})
+			if err != nil {
+				return err
+			}
+			order.Lines[index].Received += received.Quantity
+		}
+
+		receipt.ID = uuid.NewString()
+		receipt.Actor = audit.Actor(c)
+		receipt.ReceivedAt = time.Now()
+		if _, err := p.purchaseOrderRepository.SaveReceipt(c, order.ID, &receipt); err != nil {
+			return err
+		}
+		order.Receipts = append(order.Receipts, receipt)
+
+		order.Status = domain.PurchaseOrderReceived
+		for _, line := range order.Lines {
+			if line.Received < line.Quantity {
+				order.Status = domain.PurchaseOrderPartiallyReceived
+			}
+		}
+		if order.Status == domain.PurchaseOrderReceived {
+			order.ReceivedAt = &receipt.ReceivedAt
+		}
+		return nil
+	})
+}
+
+func (p *purchaseOrderUseCase) Close(ctx context.Context, id string) (*domain.PurchaseOrder, error) {
+	return p.update(ctx, id, func(c context.Context, order *domain.PurchaseOrder) error {
+		if order.Status != domain.PurchaseOrderSent && order.Status != domain.PurchaseOrderPartiallyReceived {
+			return errors.NewConflictError("only sent purchase orders can be closed, this one is " +
+				string(order.Status))
+		}
+
+		now := time.Now()
+		order.Status = domain.PurchaseOrderClosed
+		order.ClosedAt = &now
+		return nil
+	})
+}
+
+// validate checks the supplier, location and lines of a draft, defaulting the location and the unit costs
+func (p *purchaseOrderUseCase) validate(c context.Context, order *domain.PurchaseOrder) error {
+	if order.SupplierID == "" {
+		return errors.NewBadRequestError("invalid request. Supplier is required")
+	}
+	if order.LocationID == "" {
+		order.LocationID = domain.DefaultLocationID
+	}
+	if len(order.Lines) == 0 {
+		return errors.NewBadRequestError("invalid request. A purchase order needs at least one line")
+	}
+
+	seen := map[string]bool{}
+	for index, line := range order.Lines {
+		if line.Quantity <= 0 {
+			return errors.NewBadRequestError("invalid request. Quantity must be more than 0")
+		}
+		if line.UnitCost != nil && line.UnitCost.IsNegative() {
+			return errors.NewBadRequestError("invalid request. Unit cost can't be negative")
+		}
+		if seen[line.ItemID] {
+			return errors.NewBadRequestError("invalid request. Item " + line.ItemID + " is on more than one line")
+		}
+		seen[line.ItemID] = true
+
+		factor, err := p.unitFactor(c, line.ItemID, line.Unit)
+		if err != nil {
+			return err
+		}
+		// unit costs from the supplier's catalogue are already per base unit
+		var ok bool
+		if line.Quantity, ok = domain.ScaleQuantity(line.Quantity, factor); !ok {
+			return errors.NewBadRequestError("invalid request. Quantity is too large for item " + line.ItemID)
+		}
+		unitCost := decimal.Zero
+		if line.UnitCost != nil {
+			unitCost = line.UnitCost.DivRound(decimal.NewFromInt(int64(factor)), domain.CostPlaces)
+		} else {
+			supplierItem, err := p.supplierItemRepository.GetOne(c, order.SupplierID, line.ItemID)
+			if err != nil {
+				return err
+			}
+			if supplierItem.ItemID == "" {
+				return errors.NewBadRequestError("invalid request. Item " + line.ItemID +
+					" has no unit cost and isn't in the supplier's catalogue")
+			}
+			unitCost = supplierItem.UnitCost
+		}
+		line.UnitCost = &unitCost
+		order.Lines[index] = domain.PurchaseOrderLine{ItemID: line.ItemID, Quantity: line.Quantity,
+			UnitCost: line.UnitCost}
+	}
+	return nil
+}
+
+// rates returns the currency of the supplier along with the conversion rates, which must have one for it
+func (p *purchaseOrderUseCase) rates(c context.Context, supplierID string) (string, domain.Rates, error) {
+	supplier, err := p.supplierRepository.GetOne(c, supplierID)
+	if err != nil {
+		return "", nil, err
+	}
+	currency := supplier.Currency
+	if currency == "" {
+		currency = domain.DefaultCurrency
+	}
+	rates, err := p.rateRepository.GetAll(c)
+	if err != nil {
+		return "", nil, err
+	}
+	converted := domain.NewRates(rates)
+	if _, ok := converted[currency]; !ok {
+		return "", nil, errors.NewConflictError("no conversion rate for " + currency +
+			", so the stock received can't be costed")
+	}
+	return currency, converted, nil
+}
+
+// unitFactor returns how many base units of the item one unit holds, checking the item exists
+func (p *purchaseOrderUseCase) unitFactor(c context.Context, itemID string, unit string) (int, error) {
+	item, err := p.itemRepository.GetOne(c, itemID)
+	if err != nil {
+		return 0, err
+	}
+	if item.ID == "" {
+		return 0, errors.NewBadRequestError("no item with ID " + itemID + " exists")
+	}
+	factor, ok := item.UnitFactor(unit)
+	if !ok {
+		return 0, errors.NewBadRequestError("invalid request. Item " + itemID + " isn't counted in " + unit)
+	}
+	return factor, nil
+}
+
+// update applies change to the purchase order with the given id and saves it, in one transaction with the stock it
+// receives
+func (p *purchaseOrderUseCase) update(ctx context.Context, id string,
+	change func(c context.Context, order *domain.PurchaseOrder) error) (*domain.PurchaseOrder, error) {
+	c, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var order *domain.PurchaseOrder
+	err := p.transactor.WithinTransaction(c, func(c context.Context) error {
+		var err error
+		order, err = p.purchaseOrderRepository.GetOneForUpdate(c, id)
+		if err != nil {
+			return err
+		}
+		if order.ID == "" {
+			return errors.NewNotFoundError("no such purchase order found")
+		}
+
+		if err = change(c, order); err != nil {
+			return err
+		}
+		order.UpdatedAt = time.Now()
+		_, err = p.purchaseOrderRepository.Edit(c, order)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fillOutstanding(order)
+	return order, nil
+}
+
+// fillOutstanding works out what the supplier still has to deliver. Nothing is once the order is closed
+func fillOutstanding(order *domain.PurchaseOrder) {
+	for index, line := range order.Lines {
+		order.Lines[index].Outstanding = 0
+		open := order.Status == domain.PurchaseOrderSent || order.Status == domain.PurchaseOrderPartiallyReceived
+		if open && line.Quantity > line.Received {
+			order.Lines[index].Outstanding = line.Quantity - line.Received
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package usecase
+
+import (
+	"context"
+	repository10 "github.com/nuzurie/shopify/currency/repository"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/inventory/repository"
+	usecase2 "github.com/nuzurie/shopify/inventory/usecase"
+	repository2 "github.com/nuzurie/shopify/item/repository"
+	repository6 "github.com/nuzurie/shopify/location/repository"
+	repository8 "github.com/nuzurie/shopify/lot/repository"
+	repository3 "github.com/nuzurie/shopify/purchaseorder/repository"
+	repository5 "github.com/nuzurie/shopify/reservation/repository"
+	repository7 "github.com/nuzurie/shopify/serial/repository"
+	repository4 "github.com/nuzurie/shopify/supplier/repository"
+	"github.com/nuzurie/shopify/utils/errors"
+	repository9 "github.com/nuzurie/shopify/valuation/repository"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestReceive(t *testing.T) {
+	cases := []struct {
+		name        string
+		receipts    []int
+		close       bool
+		want        int
+		status      domain.PurchaseOrderStatus
+		received    int
+		outstanding int
+	}{
+		{"in full", []int{10}, false, 0, domain.PurchaseOrderReceived, 10, 0},
+		{"in parts", []int{4, 6}, false, 0, domain.PurchaseOrderReceived, 10, 0},
+		{"short", []int{4}, false, 0, domain.PurchaseOrderPartiallyReceived, 4, 6},
+		{"short and closed", []int{4}, true, 0, domain.PurchaseOrderClosed, 4, 0},
+		{"more than ordered", []int{11}, false, http.StatusConflict, domain.PurchaseOrderSent, 0, 10},
+		{"more than outstanding", []int{4, 7}, false, http.StatusConflict, domain.PurchaseOrderPartiallyReceived, 4,
+			6},
+	}
+	for _, c := range cases {
+		purchaseOrderUseCase, inventoryUseCase := newPurchaseOrderUseCase(t)
+		ctx := context.Background()
+		order := send(t, purchaseOrderUseCase, 10)
+
+		var err error
+		for _, quantity := range c.receipts {
+			_, err = purchaseOrderUseCase.Receive(ctx, order.ID, domain.PurchaseOrderReceipt{
+				Lines: []domain.PurchaseOrderReceiptLine{{ItemID: "hat", Quantity: quantity}}})
+			if err != nil {
+				break
+			}
+		}
+		if got := statusOf(err); got != c.want {
+			t.Errorf("%s: Receive failed with %d (%v), want %d", c.name, got, err, c.want)
+		}
+		if c.close {
+			if _, err = purchaseOrderUseCase.Close(ctx, order.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if order, err = purchaseOrderUseCase.GetOne(ctx, order.ID); err != nil {
+			t.Fatal(err)
+		}
+		line := order.Lines[0]
+		if order.Status != c.status || line.Received != c.received || line.Outstanding != c.outstanding {
+			t.Errorf("%s: %s with line %+v, want %s with %d received and %d outstanding", c.name, order.Status, line,
+				c.status, c.received, c.outstanding)
+		}
+		stock, err := inventoryUseCase.GetInventoryForItem(ctx, "hat")
+		if statusOf(err) == http.StatusNotFound {
+			stock = &domain.ItemStock{}
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if stock.Quantity != c.received {
+			t.Errorf("%s: %d in stock, want %d", c.name, stock.Quantity, c.received)
+		}
+	}
+}
+
+func TestPurchaseOrderStatus(t *testing.T) {
+	purchaseOrderUseCase, _ := newPurchaseOrderUseCase(t)
+	ctx := context.Background()
+	draft, err := purchaseOrderUseCase.Create(ctx, &domain.PurchaseOrder{SupplierID: "acme",
+		Lines: []domain.PurchaseOrderLine{{ItemID: "hat", Quantity: 10, UnitCost: cost("2")}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := send(t, purchaseOrderUseCase, 10)
+
+	cases := []struct {
+		name   string
+		change func() (*domain.PurchaseOrder, error)
+		want   int
+	}{
+		{"receive a draft", func() (*domain.PurchaseOrder, error) {
+			return purchaseOrderUseCase.Receive(ctx, draft.ID, domain.PurchaseOrderReceipt{
+				Lines: []domain.PurchaseOrderReceiptLine{{ItemID: "hat", Quantity: 1}}})
+		}, http.StatusConflict},
+		{"receive an item not on the order", func() (*domain.PurchaseOrder, error) {
+			return purchaseOrderUseCase.Receive(ctx, sent.ID, domain.PurchaseOrderReceipt{
+				Lines: []domain.PurchaseOrderReceiptLine{{ItemID: "scarf", Quantity: 1}}})
+		}, http.StatusBadRequest},
+		{"empty receipt", func() (*domain.PurchaseOrder, error) {
+			return purchaseOrderUseCase.Receive(ctx, sent.ID, domain.PurchaseOrderReceipt{})
+		}, http.StatusBadRequest},
+		{"change once sent", func() (*domain.PurchaseOrder, error) {
+			return purchaseOrderUseCase.Update(ctx, &domain.PurchaseOrder{ID: sent.ID, SupplierID: "acme",
+				Lines: []domain.PurchaseOrderLine{{ItemID: "hat", Quantity: 5}}})
+		}, http.StatusConflict},
+		{"send twice", func() (*domain.PurchaseOrder, error) {
+			return purchaseOrderUseCase.Send(ctx, sent.ID)
+		}, http.StatusConflict},
+		{"close a draft", func() (*domain.PurchaseOrder, error) {
+			return purchaseOrderUseCase.Close(ctx, draft.ID)
+		}, http.StatusConflict},
+		{"close once received", func() (*domain.PurchaseOrder, error) {
+			received := send(t, purchaseOrderUseCase, 1)
+			if _, err := purchaseOrderUseCase.Receive(ctx, received.ID, domain.PurchaseOrderReceipt{
+				Lines: []domain.PurchaseOrderReceiptLine{{ItemID: "hat", Quantity: 1}}}); err != nil {
+				t.Fatal(err)
+			}
+			return purchaseOrderUseCase.Close(ctx, received.ID)
+		}, http.StatusConflict},
+		{"close", func() (*domain.PurchaseOrder, error) {
+			return purchaseOrderUseCase.Close(ctx, sent.ID)
+		}, 0},
+		{"close twice", func() (*domain.PurchaseOrder, error) {
+			return purchaseOrderUseCase.Close(ctx, sent.ID)
+		}, http.StatusConflict},
+		{"unknown purchase order", func() (*domain.PurchaseOrder, error) {
+			return purchaseOrderUseCase.Send(ctx, "missing")
+		}, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		if _, err := c.change(); statusOf(err) != c.want {
+			t.Errorf("%s: failed with %d (%v), want %d", c.name, statusOf(err), err, c.want)
+		}
+	}
+}
+
+func TestCatalogueCost(t *testing.T) {
+	purchaseOrderUseCase, _ := newPurchaseOrderUseCase(t)
+	cases := []struct {
+		name       string
+		supplierID string
+		// unitCost is the cost agreed on the order, none if empty
+		unitCost string
+		want     int
+		cost     string
+	}{
+		{"agreed on the order", "acme", "2.5", 0, "2.5"},
+		{"free on the order", "acme", "0", 0, "0"},
+		{"from the catalogue", "acme", "", 0, "3"},
+		{"not in the catalogue", "euro", "", http.StatusBadRequest, ""},
+	}
+	for _, c := range cases {
+		line := domain.PurchaseOrderLine{ItemID: "hat", Quantity: 1}
+		if c.unitCost != "" {
+			line.UnitCost = cost(c.unitCost)
+		}
+		order, err := purchaseOrderUseCase.Create(context.Background(), &domain.PurchaseOrder{
+			SupplierID: c.supplierID, Lines: []domain.PurchaseOrderLine{line}})
+		if got := statusOf(err); got != c.want {
+			t.Errorf("%s: Create failed with %d (%v), want %d", c.name, got, err, c.want)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !order.Lines[0].UnitCost.Equal(decimal.RequireFromString(c.cost)) {
+			t.Errorf("%s: costs %s, want %s", c.name, order.Lines[0].UnitCost, c.cost)
+		}
+	}
+}
+
+func TestReceiveInSupplierCurrency(t *testing.T) {
+	cases := []struct {
+		name       string
+		supplierID string
+		want       int
+		cost       string
+	}{
+		{"in the default currency", "acme", 0, "4"},
+		{"converted at the rate of the supplier's currency", "euro", 0, "4.4"},
+		{"in a currency without a rate", "pound", http.StatusConflict, ""},
+	}
+	for _, c := range cases {
+		purchaseOrderUseCase, inventoryUseCase := newPurchaseOrderUseCase(t)
+		ctx := context.Background()
+		order, err := purchaseOrderUseCase.Create(ctx, &domain.PurchaseOrder{SupplierID: c.supplierID,
+			Lines: []domain.PurchaseOrderLine{{ItemID: "hat", Quantity: 2, UnitCost: cost("2")}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = purchaseOrderUseCase.Send(ctx, order.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = purchaseOrderUseCase.Receive(ctx, order.ID, domain.PurchaseOrderReceipt{
+			Lines: []domain.PurchaseOrderReceiptLine{{ItemID: "hat", Quantity: 2}}})
+		if got := statusOf(err); got != c.want {
+			t.Errorf("%s: Receive failed with %d (%v), want %d", c.name, got, err, c.want)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		stock, err := inventoryUseCase.GetInventoryForItem(ctx, "hat")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ledger, err := inventoryUseCase.GetMovements(ctx, stock.Locations[0].InventoryID, 10, 0,
+			domain.MovementFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cost := ledger[0].Cost; cost == nil || !cost.Equal(decimal.RequireFromString(c.cost)) {
+			t.Errorf("%s: received at a cost of %v, want %s", c.name, cost, c.cost)
+		}
+	}
+}
+
+// send sends a purchase order for quantity hats
+func send(t *testing.T, purchaseOrderUseCase domain.PurchaseOrderUseCase, quantity int) *domain.PurchaseOrder {
+	ctx := context.Background()
+	order, err := purchaseOrderUseCase.Create(ctx, &domain.PurchaseOrder{SupplierID: "acme",
+		Lines: []domain.PurchaseOrderLine{{ItemID: "hat", Quantity: quantity, UnitCost: cost("2")}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order, err = purchaseOrderUseCase.Send(ctx, order.ID); err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+// cost is a unit cost for an order line
+func cost(value string) *decimal.Decimal {
+	unitCost := decimal.RequireFromString(value)
+	return &unitCost
+}
+
+// ignoreStockChanges is a StockWatcher for tests that don't evaluate reorder points
+type ignoreStockChanges struct{}
+
+func (ignoreStockChanges) StockChanged(string) {}
+
+// newPurchaseOrderUseCase works on a store with a hat that isn't in stock and a supplier of it, selling it for 3. Two
+// more suppliers charge in euros, which are worth 1.1 dollars, and in pounds, which have no rate
+func newPurchaseOrderUseCase(t *testing.T) (domain.PurchaseOrderUseCase, domain.InventoryUseCase) {
+	store := memory.NewStore()
+	store.Suppliers["acme"] = domain.Supplier{ID: "acme", Name: "Acme"}
+	store.Suppliers["euro"] = domain.Supplier{ID: "euro", Name: "Euro", Currency: "EUR"}
+	store.Suppliers["pound"] = domain.Supplier{ID: "pound", Name: "Pound", Currency: "GBP"}
+	store.CurrencyRates["EUR"] = domain.CurrencyRate{Currency: "EUR", Rate: decimal.RequireFromString("1.1")}
+	store.SupplierItems[memory.SupplierItemKey("acme", "hat")] = domain.SupplierItem{SupplierID: "acme", ItemID: "hat",
+		UnitCost: decimal.NewFromInt(3)}
+	itemRepository := repository2.NewMemoryItemRepository(store)
+	if _, err := itemRepository.Save(context.Background(), &domain.Item{ID: "hat", Name: "hat",
+		Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	inventoryUseCase := usecase2.NewInventoryUseCase(itemRepository, repository.NewMemoryInventoryRepository(store),
+		repository.NewMemoryMovementRepository(store), repository5.NewMemoryReservationRepository(store),
+		repository6.NewMemoryLocationRepository(store), repository8.NewMemoryLotRepository(store),
+		repository7.NewMemorySerialRepository(store), repository9.NewMemoryCostRepository(store), ignoreStockChanges{},
+		store, time.Second)
+	return NewPurchaseOrderUseCase(repository3.NewMemoryPurchaseOrderRepository(store), itemRepository,
+		repository4.NewMemorySupplierRepository(store), repository4.NewMemorySupplierItemRepository(store),
+		repository10.NewMemoryCurrencyRateRepository(store), inventoryUseCase, store, time.Second), inventoryUseCase
+}
+
+// statusOf is the status code of err, or 0 if there is none
+func statusOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	if restError, ok := err.(*errors.RestError); ok {
+		return restError.Code
+	}
+	return http.StatusInternalServerError
+}
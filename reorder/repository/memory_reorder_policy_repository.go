@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"sort"
+)
+
+type memoryReorderPolicyRepository struct {
+	store *memory.Store
+}
+
+// NewMemoryReorderPolicyRepository keeps reorder policies in store instead of a database. store must be shared with
+// the item and location repositories so policies go along with what they watch
+func NewMemoryReorderPolicyRepository(store *memory.Store) domain.ReorderPolicyRepository {
+	return &memoryReorderPolicyRepository{store: store}
+}
+
+func (r *memoryReorderPolicyRepository) GetAll(ctx context.Context, count int, offset int, itemID string,
+	locationID string) ([]domain.ReorderPolicy, error) {
+	defer r.store.Read(ctx)()
+
+	var policies []domain.ReorderPolicy
+	for _, policy := range r.store.ReorderPolicies {
+		if (itemID == "" || policy.ItemID == itemID) && (locationID == "" || policy.LocationID == locationID) {
+			policies = append(policies, policy)
+		}
+	}
+	sortPolicies(policies)
+
+	start, end := memory.Page(len(policies), count, offset)
+	return policies[start:end], nil
+}
+
+func (r *memoryReorderPolicyRepository) GetForItem(ctx context.Context,
+	itemID string) ([]domain.ReorderPolicy, error) {
+	defer r.store.Read(ctx)()
+
+	var policies []domain.ReorderPolicy
+	for _, policy := range r.store.ReorderPolicies {
+		if policy.ItemID == itemID {
+			policies = append(policies, policy)
+		}
+	}
+	sortPolicies(policies)
+	return policies, nil
+}
+
+func sortPolicies(policies []domain.ReorderPolicy) {
+	sort.Slice(policies, func(a, b int) bool {
+		if policies[a].ItemID != policies[b].ItemID {
+			return policies[a].ItemID < policies[b].ItemID
+		}
+		if policies[a].LocationID != policies[b].LocationID {
+			return policies[a].LocationID < policies[b].LocationID
+		}
+		return policies[a].ID < policies[b].ID
+	})
+}
+
+func (r *memoryReorderPolicyRepository) ItemIDs(ctx context.Context) ([]string, error) {
+	defer r.store.Read(ctx)()
+
+	seen := map[string]bool{}
+	var itemIDs []string
+	for _, policy := range r.store.ReorderPolicies {
+		if !seen[policy.ItemID] {
+			seen[policy.ItemID] = true
+			itemIDs = append(itemIDs, policy.ItemID)
+		}
+	}
+	sort.Strings(itemIDs)
+	return itemIDs, nil
+}
+
+func (r *memoryReorderPolicyRepository) GetOne(ctx context.Context, id string) (*domain.ReorderPolicy, error) {
+	defer r.store.Read(ctx)()
+
+	policy := r.store.ReorderPolicies[id]
+	return &policy, nil
+}
+
+func (r *memoryReorderPolicyRepository) Save(ctx context.Context,
+	policy *domain.ReorderPolicy) (*domain.ReorderPolicy, error) {
+	defer r.store.Write(ctx)()
+
+	if _, ok := r.store.Items[policy.ItemID]; !ok {
+		return nil, errors.NewBadRequestError("no item or location with such ID exists")
+	}
+	if _, ok := r.store.Locations[policy.LocationID]; policy.LocationID != "" && !ok {
+		return nil, errors.NewBadRequestError("no item or location with such ID exists")
+	}
+	for _, existing := range r.store.ReorderPolicies {
+		if existing.ID == policy.ID || (existing.ItemID == policy.ItemID && existing.LocationID == policy.LocationID) {
+			return nil, errors.NewConflictError("the item already has a reorder policy for the location")
+		}
+	}
+	r.store.ReorderPolicies[policy.ID] = *policy
+	return policy, nil
+}
+
+func (r *memoryReorderPolicyRepository) Edit(ctx context.Context,
+	policy *domain.ReorderPolicy) (*domain.ReorderPolicy, error) {
+	defer r.store.Write(ctx)()
+
+	if existing, ok := r.store.ReorderPolicies[policy.ID]; ok {
+		existing.ReorderPoint = policy.ReorderPoint
+		existing.ReorderQuantity = policy.ReorderQuantity
+		existing.DraftPurchaseOrder = policy.DraftPurchaseOrder
+		existing.UpdatedAt = policy.UpdatedAt
+		r.store.ReorderPolicies[policy.ID] = existing
+	}
+	return policy, nil
+}
+
+func (r *memoryReorderPolicyRepository) Delete(ctx context.Context, id string) error {
+	defer r.store.Write(ctx)()
+
+	r.store.DeleteReorderPolicies(func(policy domain.ReorderPolicy) bool {
+		return policy.ID == id
+	})
+	return nil
+}
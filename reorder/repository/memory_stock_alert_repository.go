@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"sort"
+)
+
+type memoryStockAlertRepository struct {
+	store *memory.Store
+}
+
+// NewMemoryStockAlertRepository keeps low-stock alerts in store instead of a database. store must be shared with the
+// reorder policy repository so alerts go along with their policy
+func NewMemoryStockAlertRepository(store *memory.Store) domain.StockAlertRepository {
+	return &memoryStockAlertRepository{store: store}
+}
+
+func (s *memoryStockAlertRepository) GetAll(ctx context.Context, count int, offset int, itemID string,
+	status domain.AlertStatus) ([]domain.StockAlert, error) {
+	defer s.store.Read(ctx)()
+
+	var alerts []domain.StockAlert
+	for _, alert := range s.store.StockAlerts {
+		if (itemID == "" || alert.ItemID == itemID) && (status == "" || alert.Status == status) {
+			alerts = append(alerts, alert)
+		}
+	}
+	sort.Slice(alerts, func(a, b int) bool {
+		if !alerts[a].CreatedAt.Equal(alerts[b].CreatedAt) {
+			return alerts[a].CreatedAt.After(alerts[b].CreatedAt)
+		}
+		return alerts[a].ID < alerts[b].ID
+	})
+
+	start, end := memory.Page(len(alerts), count, offset)
+	return alerts[start:end], nil
+}
+
+func (s *memoryStockAlertRepository) GetOne(ctx context.Context, id string) (*domain.StockAlert, error) {
+	defer s.store.Read(ctx)()
+
+	alert := s.store.StockAlerts[id]
+	return &alert, nil
+}
+
+// GetOneForUpdate needs no lock of its own, transactions hold the whole store
+func (s *memoryStockAlertRepository) GetOneForUpdate(ctx context.Context, id string) (*domain.StockAlert, error) {
+	return s.GetOne(ctx, id)
+}
+
+func (s *memoryStockAlertRepository) GetUnresolved(ctx context.Context, policyID string) (*domain.StockAlert, error) {
+	defer s.store.Read(ctx)()
+
+	for _, alert := range s.store.StockAlerts {
+		if alert.PolicyID == policyID && alert.Status != domain.AlertResolved {
+			return &alert, nil
+		}
+	}
+	return &domain.StockAlert{}, nil
+}
+
+func (s *memoryStockAlertRepository) Save(ctx context.Context, alert *domain.StockAlert) (*domain.StockAlert, error) {
+	defer s.store.Write(ctx)()
+
+	for _, existing := range s.store.StockAlerts {
+		if existing.PolicyID == alert.PolicyID && existing.Status != domain.AlertResolved {
+			return nil, errors.NewConflictError("the reorder policy already has an alert that isn't resolved")
+		}
+	}
+	if _, ok := s.store.StockAlerts[alert.ID]; ok {
+		return nil, errors.NewConflictError("alert already exists")
+	}
+	s.store.StockAlerts[alert.ID] = *alert
+	return alert, nil
+}
+
+func (s *memoryStockAlertRepository) Edit(ctx context.Context, alert *domain.StockAlert) (*domain.StockAlert, error) {
+	defer s.store.Write(ctx)()
+
+	if existing, ok := s.store.StockAlerts[alert.ID]; ok {
+		existing.Status = alert.Status
+		existing.UpdatedAt = alert.UpdatedAt
+		existing.AcknowledgedAt = alert.AcknowledgedAt
+		existing.ResolvedAt = alert.ResolvedAt
+		s.store.StockAlerts[alert.ID] = existing
+	}
+	return alert, nil
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/nuzurie/shopify/db"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"strings"
+)
+
+type reorderPolicyRepository struct {
+	db db.DB
+}
+
+const (
+	getPolicies = `SELECT id, item_id, location_id, reorder_point, reorder_quantity, draft_purchase_order, created_at,
+			updated_at FROM reorder_policy WHERE %s ORDER BY item_id, location_id, id LIMIT $%d OFFSET $%d`
+	getItemPolicies = `SELECT id, item_id, location_id, reorder_point, reorder_quantity, draft_purchase_order,
+			created_at, updated_at FROM reorder_policy WHERE item_id=$1 ORDER BY location_id, id`
+	getPolicy = `SELECT id, item_id, location_id, reorder_point, reorder_quantity, draft_purchase_order, created_at,
+			updated_at FROM reorder_policy WHERE id=$1`
+	getPolicyItemIDs = `SELECT DISTINCT item_id FROM reorder_policy ORDER BY item_id`
+	savePolicy       = `INSERT INTO reorder_policy (id, item_id, location_id, reorder_point, reorder_quantity,
+			draft_purchase_order, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	updatePolicy = `UPDATE reorder_policy SET reorder_point=$2, reorder_quantity=$3, draft_purchase_order=$4,
+			updated_at=$5 WHERE id=$1`
+	deletePolicy = `DELETE FROM reorder_policy WHERE id=$1`
+)
+
+// NewReorderPolicyRepository stores reorder policies in a SQL database, Postgres or SQLite. The schema must be migrated
+func NewReorderPolicyRepository(database db.DB) domain.ReorderPolicyRepository {
+	return &reorderPolicyRepository{db: database}
+}
+
+func (r *reorderPolicyRepository) GetAll(ctx context.Context, count int, offset int, itemID string,
+	locationID string) ([]domain.ReorderPolicy, error) {
+	conditions := []string{"1=1"}
+	var args []interface{}
+	if itemID != "" {
+		args = append(args, itemID)
+		conditions = append(conditions, fmt.Sprintf("item_id=$%d", len(args)))
+	}
+	if locationID != "" {
+		args = append(args, locationID)
+		conditions = append(conditions, fmt.Sprintf("location_id=$%d", len(args)))
+	}
+
+	query := fmt.Sprintf(getPolicies, strings.Join(conditions, " AND "), len(args)+1, len(args)+2)
+	return r.query(ctx, query, append(args, count, offset)...)
+}
+
+func (r *reorderPolicyRepository) GetForItem(ctx context.Context, itemID string) ([]domain.ReorderPolicy, error) {
+	return r.query(ctx, getItemPolicies, itemID)
+}
+
+func (r *reorderPolicyRepository) query(ctx context.Context, query string,
+	args ...interface{}) ([]domain.ReorderPolicy, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var policies []domain.ReorderPolicy
+	for rows.Next() {
+		policy, err := scanPolicy(rows)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		policies = append(policies, *policy)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return policies, nil
+}
+
+func (r *reorderPolicyRepository) ItemIDs(ctx context.Context) ([]string, error) {
+	rows, err := r.db.Query(ctx, getPolicyItemIDs)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var itemIDs []string
+	for rows.Next() {
+		var itemID string
+		if err = rows.Scan(&itemID); err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		itemIDs = append(itemIDs, itemID)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return itemIDs, nil
+}
+
+func (r *reorderPolicyRepository) GetOne(ctx context.Context, id string) (*domain.ReorderPolicy, error) {
+	policy, err := scanPolicy(r.db.QueryRow(ctx, getPolicy, id))
+	if err == db.ErrNoRows {
+		return &domain.ReorderPolicy{}, nil
+	}
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return policy, nil
+}
+
+// scanPolicy reads a reorder policy, whose location is NULL when it watches every location
+func scanPolicy(row db.Row) (*domain.ReorderPolicy, error) {
+	var policy domain.ReorderPolicy
+	var locationID *string
+	err := row.Scan(&policy.ID, &policy.ItemID, &locationID, &policy.ReorderPoint, &policy.ReorderQuantity,
+		&policy.DraftPurchaseOrder, &policy.CreatedAt, &policy.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if locationID != nil {
+		policy.LocationID = *locationID
+	}
+	return &policy, nil
+}
+
+func (r *reorderPolicyRepository) Save(ctx context.Context, policy *domain.ReorderPolicy) (*domain.ReorderPolicy, error) {
+	_, err := r.db.Exec(ctx, savePolicy, policy.ID, policy.ItemID, nullable(policy.LocationID), policy.ReorderPoint,
+		policy.ReorderQuantity, policy.DraftPurchaseOrder, policy.CreatedAt, policy.UpdatedAt)
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, errors.NewConflictError("the item already has a reorder policy for the location")
+		}
+		if db.IsForeignKeyViolation(err) {
+			return nil, errors.NewBadRequestError("no item or location with such ID exists")
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return policy, nil
+}
+
+func (r *reorderPolicyRepository) Edit(ctx context.Context, policy *domain.ReorderPolicy) (*domain.ReorderPolicy, error) {
+	_, err := r.db.Exec(ctx, updatePolicy, policy.ID, policy.ReorderPoint, policy.ReorderQuantity,
+		policy.DraftPurchaseOrder, policy.UpdatedAt)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return policy, nil
+}
+
+func (r *reorderPolicyRepository) Delete(ctx context.Context, id string) error {
+	if _, err := r.db.Exec(ctx, deletePolicy, id); err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+// nullable stores empty references as NULL, so they aren't taken for IDs the foreign keys must find
+func nullable(id string) interface{} {
+	if id == "" {
+		return nil
+	}
+	return id
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/nuzurie/shopify/db"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"strings"
+)
+
+type stockAlertRepository struct {
+	db db.DB
+}
+
+const (
+	getAlerts = `SELECT id, policy_id, item_id, location_id, reorder_point, available, status, purchase_order_id,
+			created_at, updated_at, acknowledged_at, resolved_at FROM stock_alert WHERE %s
+			ORDER BY created_at DESC, id LIMIT $%d OFFSET $%d`
+	getAlert = `SELECT id, policy_id, item_id, location_id, reorder_point, available, status, purchase_order_id,
+			created_at, updated_at, acknowledged_at, resolved_at FROM stock_alert WHERE id=$1`
+	getUnresolvedAlert = `SELECT id, policy_id, item_id, location_id, reorder_point, available, status,
+			purchase_order_id, created_at, updated_at, acknowledged_at, resolved_at FROM stock_alert
+			WHERE policy_id=$1 AND status<>'resolved'`
+	saveAlert = `INSERT INTO stock_alert (id, policy_id, item_id, location_id, reorder_point, available, status,
+			purchase_order_id, created_at, updated_at, acknowledged_at, resolved_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	updateAlert = `UPDATE stock_alert SET status=$2, updated_at=$3, acknowledged_at=$4, resolved_at=$5 WHERE id=$1`
+	// SQLite has no row locks, but it only ever runs one transaction at a time
+	forUpdate = ` FOR UPDATE`
+)
+
+// NewStockAlertRepository stores low-stock alerts in a SQL database, Postgres or SQLite. The schema must be migrated
+func NewStockAlertRepository(database db.DB) domain.StockAlertRepository {
+	return &stockAlertRepository{db: database}
+}
+
+func (s *stockAlertRepository) GetAll(ctx context.Context, count int, offset int, itemID string,
+	status domain.AlertStatus) ([]domain.StockAlert, error) {
+	conditions := []string{"1=1"}
+	var args []interface{}
+	if itemID != "" {
+		args = append(args, itemID)
+		conditions = append(conditions, fmt.Sprintf("item_id=$%d", len(args)))
+	}
+	if status != "" {
+		args = append(args, status)
+		conditions = append(conditions, fmt.Sprintf("status=$%d", len(args)))
+	}
+
+	query := fmt.Sprintf(getAlerts, strings.Join(conditions, " AND "), len(args)+1, len(args)+2)
+	rows, err := s.db.Query(ctx, query, append(args, count, offset)...)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var alerts []domain.StockAlert
+	for rows.Next() {
+		alert, err := scanAlert(rows)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		alerts = append(alerts, *alert)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return alerts, nil
+}
+
+func (s *stockAlertRepository) GetOne(ctx context.Context, id string) (*domain.StockAlert, error) {
+	return s.getOne(ctx, getAlert, id)
+}
+
+func (s *stockAlertRepository) GetOneForUpdate(ctx context.Context, id string) (*domain.StockAlert, error) {
+	query := getAlert
+	if s.db.Dialect() == domain.Postgres {
+		query += forUpdate
+	}
+	return s.getOne(ctx, query, id)
+}
+
+func (s *stockAlertRepository) GetUnresolved(ctx context.Context, policyID string) (*domain.StockAlert, error) {
+	return s.getOne(ctx, getUnresolvedAlert, policyID)
+}
+
+func (s *stockAlertRepository) getOne(ctx context.Context, query string, arg string) (*domain.StockAlert, error) {
+	alert, err := scanAlert(s.db.QueryRow(ctx, query, arg))
+	if err == db.ErrNoRows {
+		return &domain.StockAlert{}, nil
+	}
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return alert, nil
+}
+
+// scanAlert reads a stock alert, whose location and purchase order may be NULL
+func scanAlert(row db.Row) (*domain.StockAlert, error) {
+	var alert domain.StockAlert
+	var locationID, purchaseOrderID *string
+	err := row.Scan(&alert.ID, &alert.PolicyID, &alert.ItemID, &locationID, &alert.ReorderPoint, &alert.Available,
+		&alert.Status, &purchaseOrderID, &alert.CreatedAt, &alert.UpdatedAt, &alert.AcknowledgedAt, &alert.ResolvedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if locationID != nil {
+		alert.LocationID = *locationID
+	}
+	if purchaseOrderID != nil {
+		alert.PurchaseOrderID = *purchaseOrderID
+	}
+	return &alert, nil
+}
+
+func (s *stockAlertRepository) Save(ctx context.Context, alert *domain.StockAlert) (*domain.StockAlert, error) {
+	_, err := s.db.Exec(ctx, saveAlert, alert.ID, alert.PolicyID, alert.ItemID, nullable(alert.LocationID),
+		alert.ReorderPoint, alert.Available, alert.Status, nullable(alert.PurchaseOrderID), alert.CreatedAt,
+		alert.UpdatedAt, alert.AcknowledgedAt, alert.ResolvedAt)
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, errors.NewConflictError("the reorder policy already has an alert that isn't resolved")
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return alert, nil
+}
+
+func (s *stockAlertRepository) Edit(ctx context.Context, alert *domain.StockAlert) (*domain.StockAlert, error) {
+	_, err := s.db.Exec(ctx, updateAlert, alert.ID, alert.Status, alert.UpdatedAt, alert.AcknowledgedAt,
+		alert.ResolvedAt)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return alert, nil
+}
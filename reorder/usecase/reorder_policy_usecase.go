@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"time"
+)
+
+type reorderPolicyUseCase struct {
+	reorderPolicyRepository domain.ReorderPolicyRepository
+	itemRepository          domain.ItemRepository
+	locationRepository      domain.LocationRepository
+	watcher                 domain.StockWatcher
+	timeout                 time.Duration
+}
+
+// NewReorderPolicyUseCase manages reorder policies. watcher is told about the item of every policy saved, so it's
+// evaluated against the new reorder point straight away
+func NewReorderPolicyUseCase(reorderPolicyRepository domain.ReorderPolicyRepository,
+	itemRepository domain.ItemRepository, locationRepository domain.LocationRepository, watcher domain.StockWatcher,
+	timeout time.Duration) domain.ReorderPolicyUseCase {
+	return &reorderPolicyUseCase{reorderPolicyRepository: reorderPolicyRepository, itemRepository: itemRepository,
+		locationRepository: locationRepository, watcher: watcher, timeout: timeout}
+}
+
+func (r *reorderPolicyUseCase) GetAll(ctx context.Context, count int, offset int, itemID string,
+	locationID string) ([]domain.ReorderPolicy, error) {
+	c, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	policies, err := r.reorderPolicyRepository.GetAll(c, count, offset, itemID, locationID)
+	if err != nil {
+		return nil, err
+	}
+	if len(policies) == 0 {
+		return nil, errors.NewNotFoundError("no reorder policies found")
+	}
+
+	return policies, nil
+}
+
+func (r *reorderPolicyUseCase) GetOne(ctx context.Context, id string) (*domain.ReorderPolicy, error) {
+	c, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	policy, err := r.reorderPolicyRepository.GetOne(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if policy.ID == "" {
+		return nil, errors.NewNotFoundError("no such reorder policy exists")
+	}
+
+	return policy, nil
+}
+
+func (r *reorderPolicyUseCase) Create(ctx context.Context, policy *domain.ReorderPolicy) (*domain.ReorderPolicy, error) {
+	c, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if err := validate(policy); err != nil {
+		return nil, err
+	}
+
+	item, err := r.itemRepository.GetOne(c, policy.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	if item.ID == "" {
+		return nil, errors.NewBadRequestError("no item with such ID exists")
+	}
+	if policy.LocationID != "" {
+		location, err := r.locationRepository.GetOne(c, policy.LocationID)
+		if err != nil {
+			return nil, err
+		}
+		if location.ID == "" {
+			return nil, errors.NewBadRequestError("no location with such ID exists")
+		}
+	}
+
+	policy.ID = uuid.NewString()
+	policy.CreatedAt = time.Now()
+	policy.UpdatedAt = policy.CreatedAt
+	if _, err = r.reorderPolicyRepository.Save(c, policy); err != nil {
+		return nil, err
+	}
+
+	r.watcher.StockChanged(policy.ItemID)
+	return policy, nil
+}
+
+func (r *reorderPolicyUseCase) Update(ctx context.Context, policy *domain.ReorderPolicy) (*domain.ReorderPolicy, error) {
+	c, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if err := validate(policy); err != nil {
+		return nil, err
+	}
+
+	existing, err := r.reorderPolicyRepository.GetOne(c, policy.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing.ID == "" {
+		return nil, errors.NewNotFoundError("no such reorder policy exists")
+	}
+
+	policy.ItemID = existing.ItemID
+	policy.LocationID = existing.LocationID
+	policy.CreatedAt = existing.CreatedAt
+	policy.UpdatedAt = time.Now()
+	if _, err = r.reorderPolicyRepository.Edit(c, policy); err != nil {
+		return nil, err
+	}
+
+	r.watcher.StockChanged(policy.ItemID)
+	return policy, nil
+}
+
+func (r *reorderPolicyUseCase) Delete(ctx context.Context, id string) error {
+	c, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	existing, err := r.reorderPolicyRepository.GetOne(c, id)
+	if err != nil {
+		return err
+	}
+	if existing.ID == "" {
+		return errors.NewNotFoundError("no such reorder policy exists")
+	}
+
+	return r.reorderPolicyRepository.Delete(c, id)
+}
+
+// validate checks the reorder point and quantity of a policy
+func validate(policy *domain.ReorderPolicy) error {
+	if policy.ReorderPoint < 0 {
+		return errors.NewBadRequestError("invalid request. Reorder point can't be negative")
+	}
+	if policy.ReorderQuantity <= 0 {
+		return errors.NewBadRequestError("invalid request. Reorder quantity must be more than 0")
+	}
+	return nil
+}
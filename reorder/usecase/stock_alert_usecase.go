@@ -0,0 +1,243 @@
+package usecase
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"log"
+	"time"
+)
+
+type stockAlertUseCase struct {
+	stockAlertRepository    domain.StockAlertRepository
+	reorderPolicyRepository domain.ReorderPolicyRepository
+	supplierRepository      domain.SupplierRepository
+	supplierItemRepository  domain.SupplierItemRepository
+	inventoryUseCase        domain.InventoryUseCase
+	purchaseOrderUseCase    domain.PurchaseOrderUseCase
+	transactor              domain.Transactor
+	timeout                 time.Duration
+}
+
+// NewStockAlertUseCase raises alerts when the available stock reported by inventoryUseCase runs low. Policies that
+// ask for it get a purchase order drafted through purchaseOrderUseCase from the preferred supplier of the item
+func NewStockAlertUseCase(stockAlertRepository domain.StockAlertRepository,
+	reorderPolicyRepository domain.ReorderPolicyRepository, supplierRepository domain.SupplierRepository,
+	supplierItemRepository domain.SupplierItemRepository, inventoryUseCase domain.InventoryUseCase,
+	purchaseOrderUseCase domain.PurchaseOrderUseCase, transactor domain.Transactor,
+	timeout time.Duration) domain.StockAlertUseCase {
+	return &stockAlertUseCase{stockAlertRepository: stockAlertRepository,
+		reorderPolicyRepository: reorderPolicyRepository, supplierRepository: supplierRepository,
+		supplierItemRepository: supplierItemRepository, inventoryUseCase: inventoryUseCase,
+		purchaseOrderUseCase: purchaseOrderUseCase, transactor: transactor, timeout: timeout}
+}
+
+func (s *stockAlertUseCase) GetAll(ctx context.Context, count int, offset int, itemID string,
+	status domain.AlertStatus) ([]domain.StockAlert, error) {
+	c, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	alerts, err := s.stockAlertRepository.GetAll(c, count, offset, itemID, status)
+	if err != nil {
+		return nil, err
+	}
+	if len(alerts) == 0 {
+		return nil, errors.NewNotFoundError("no alerts found")
+	}
+
+	return alerts, nil
+}
+
+func (s *stockAlertUseCase) GetOne(ctx context.Context, id string) (*domain.StockAlert, error) {
+	c, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	alert, err := s.stockAlertRepository.GetOne(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if alert.ID == "" {
+		return nil, errors.NewNotFoundError("no such alert found")
+	}
+
+	return alert, nil
+}
+
+func (s *stockAlertUseCase) Acknowledge(ctx context.Context, id string) (*domain.StockAlert, error) {
+	return s.update(ctx, id, func(alert *domain.StockAlert) error {
+		if alert.Status != domain.AlertOpen {
+			return errors.NewConflictError("only open alerts can be acknowledged, this one is " + string(alert.Status))
+		}
+
+		now := time.Now()
+		alert.Status = domain.AlertAcknowledged
+		alert.AcknowledgedAt = &now
+		return nil
+	})
+}
+
+func (s *stockAlertUseCase) Resolve(ctx context.Context, id string) (*domain.StockAlert, error) {
+	return s.update(ctx, id, func(alert *domain.StockAlert) error {
+		if alert.Status == domain.AlertResolved {
+			return errors.NewConflictError("the alert is already resolved")
+		}
+
+		resolve(alert)
+		return nil
+	})
+}
+
+// update applies change to the alert with the given id and saves it
+func (s *stockAlertUseCase) update(ctx context.Context, id string,
+	change func(alert *domain.StockAlert) error) (*domain.StockAlert, error) {
+	c, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var alert *domain.StockAlert
+	err := s.transactor.WithinTransaction(c, func(c context.Context) error {
+		var err error
+		alert, err = s.stockAlertRepository.GetOneForUpdate(c, id)
+		if err != nil {
+			return err
+		}
+		if alert.ID == "" {
+			return errors.NewNotFoundError("no such alert found")
+		}
+
+		if err = change(alert); err != nil {
+			return err
+		}
+		alert.UpdatedAt = time.Now()
+		_, err = s.stockAlertRepository.Edit(c, alert)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return alert, nil
+}
+
+func (s *stockAlertUseCase) Evaluate(ctx context.Context, itemID string) error {
+	c, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	policies, err := s.reorderPolicyRepository.GetForItem(c, itemID)
+	if err != nil {
+		return err
+	}
+	if len(policies) == 0 {
+		return nil
+	}
+
+	stock, err := s.inventoryUseCase.GetInventoryForItem(c, itemID)
+	if errors.IsNotFound(err) {
+		// no location holds the item anymore
+		stock, err = &domain.ItemStock{}, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, policy := range policies {
+		available := stock.Available
+		if policy.LocationID != "" {
+			available = 0
+			for _, location := range stock.Locations {
+				if location.LocationID == policy.LocationID {
+					available = location.Available
+				}
+			}
+		}
+
+		if err = s.evaluate(c, policy, available); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evaluate opens an alert for a policy whose stock is at its reorder point or below, unless one is already open, and
+// resolves the alert of a policy whose stock recovered
+func (s *stockAlertUseCase) evaluate(c context.Context, policy domain.ReorderPolicy, available int) error {
+	return s.transactor.WithinTransaction(c, func(c context.Context) error {
+		alert, err := s.stockAlertRepository.GetUnresolved(c, policy.ID)
+		if err != nil {
+			return err
+		}
+
+		low := available <= policy.ReorderPoint
+		switch {
+		case low && alert.ID == "":
+			now := time.Now()
+			alert = &domain.StockAlert{ID: uuid.NewString(), PolicyID: policy.ID, ItemID: policy.ItemID,
+				LocationID: policy.LocationID, ReorderPoint: policy.ReorderPoint, Available: available,
+				Status: domain.AlertOpen, CreatedAt: now, UpdatedAt: now}
+			if policy.DraftPurchaseOrder {
+				if alert.PurchaseOrderID, err = s.draftPurchaseOrder(c, policy); err != nil {
+					return err
+				}
+			}
+			log.Println("item", policy.ItemID, "is low on stock,", available, "available")
+			_, err = s.stockAlertRepository.Save(c, alert)
+			return err
+		case !low && alert.ID != "":
+			resolve(alert)
+			alert.UpdatedAt = time.Now()
+			_, err = s.stockAlertRepository.Edit(c, alert)
+			return err
+		}
+		return nil
+	})
+}
+
+// draftPurchaseOrder drafts a purchase order of the reorder quantity of a policy from the preferred supplier of the
+// item, at least as much as the supplier's minimum order quantity. Nothing is drafted for items with no preferred
+// supplier
+func (s *stockAlertUseCase) draftPurchaseOrder(c context.Context, policy domain.ReorderPolicy) (string, error) {
+	supplierItems, err := s.supplierItemRepository.GetForItem(c, policy.ItemID)
+	if err != nil {
+		return "", err
+	}
+	if len(supplierItems) == 0 || !supplierItems[0].Preferred {
+		log.Println("no purchase order drafted for item", policy.ItemID, "as it has no preferred supplier")
+		return "", nil
+	}
+
+	supplier, err := s.supplierRepository.GetOne(c, supplierItems[0].SupplierID)
+	if err != nil {
+		return "", err
+	}
+	quantity := policy.ReorderQuantity
+	if quantity < supplier.MinimumOrderQuantity {
+		quantity = supplier.MinimumOrderQuantity
+	}
+
+	order, err := s.purchaseOrderUseCase.Create(c, &domain.PurchaseOrder{SupplierID: supplier.ID,
+		LocationID: policy.LocationID, Lines: []domain.PurchaseOrderLine{{ItemID: policy.ItemID, Quantity: quantity}}})
+	if err != nil {
+		return "", err
+	}
+	return order.ID, nil
+}
+
+func (s *stockAlertUseCase) EvaluateAll(ctx context.Context) error {
+	itemIDs, err := s.reorderPolicyRepository.ItemIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, itemID := range itemIDs {
+		if err = s.Evaluate(ctx, itemID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func resolve(alert *domain.StockAlert) {
+	now := time.Now()
+	alert.Status = domain.AlertResolved
+	alert.ResolvedAt = &now
+}
@@ -0,0 +1,198 @@
+package usecase
+
+import (
+	"context"
+	repository11 "github.com/nuzurie/shopify/currency/repository"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/inventory/repository"
+	usecase2 "github.com/nuzurie/shopify/inventory/usecase"
+	repository2 "github.com/nuzurie/shopify/item/repository"
+	repository3 "github.com/nuzurie/shopify/location/repository"
+	repository4 "github.com/nuzurie/shopify/lot/repository"
+	repository7 "github.com/nuzurie/shopify/purchaseorder/repository"
+	usecase7 "github.com/nuzurie/shopify/purchaseorder/usecase"
+	repository10 "github.com/nuzurie/shopify/reorder/repository"
+	repository5 "github.com/nuzurie/shopify/reservation/repository"
+	repository8 "github.com/nuzurie/shopify/serial/repository"
+	repository6 "github.com/nuzurie/shopify/supplier/repository"
+	"github.com/nuzurie/shopify/utils/errors"
+	repository9 "github.com/nuzurie/shopify/valuation/repository"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	stockAlertUseCase, inventoryUseCase, store := newStockAlertUseCase(t)
+	ctx := context.Background()
+	// the hats are low at 5 or fewer, whether at the default location or over all locations
+	store.ReorderPolicies["here"] = domain.ReorderPolicy{ID: "here", ItemID: "hat",
+		LocationID: domain.DefaultLocationID, ReorderPoint: 5, ReorderQuantity: 20, DraftPurchaseOrder: true}
+	store.ReorderPolicies["everywhere"] = domain.ReorderPolicy{ID: "everywhere", ItemID: "hat", ReorderPoint: 5,
+		ReorderQuantity: 40}
+
+	steps := []struct {
+		name       string
+		delta      int
+		location   string
+		here       domain.AlertStatus
+		everywhere domain.AlertStatus
+	}{
+		{"above both reorder points", 0, domain.DefaultLocationID, "", ""},
+		{"one above the reorder point", -4, domain.DefaultLocationID, "", ""},
+		{"at the reorder point", -1, domain.DefaultLocationID, domain.AlertOpen, domain.AlertOpen},
+		{"lower still", -2, domain.DefaultLocationID, domain.AlertOpen, domain.AlertOpen},
+		{"recovered elsewhere", 6, "east", domain.AlertOpen, domain.AlertResolved},
+		{"recovered here", 3, domain.DefaultLocationID, domain.AlertResolved, domain.AlertResolved},
+	}
+	for _, step := range steps {
+		if step.delta != 0 {
+			if _, err := inventoryUseCase.MoveStock(ctx, "hat", step.location,
+				domain.StockAdjustment{Delta: step.delta, Reason: domain.ReasonCorrection}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := stockAlertUseCase.Evaluate(ctx, "hat"); err != nil {
+			t.Fatalf("%s: Evaluate failed with %v", step.name, err)
+		}
+
+		for policyID, want := range map[string]domain.AlertStatus{"here": step.here, "everywhere": step.everywhere} {
+			alerts := alertsOf(store, policyID)
+			if want == "" {
+				if len(alerts) != 0 {
+					t.Errorf("%s: policy %s has alerts %+v, want none", step.name, policyID, alerts)
+				}
+				continue
+			}
+			if len(alerts) != 1 || alerts[0].Status != want {
+				t.Errorf("%s: policy %s has alerts %+v, want one that is %s", step.name, policyID, alerts, want)
+			}
+		}
+	}
+
+	// the alert of the policy that asked for it comes with a draft order of at least the supplier's minimum
+	alert := alertsOf(store, "here")[0]
+	if alert.Available != 5 || alert.ReorderPoint != 5 {
+		t.Errorf("alert opened with %d available at a reorder point of %d, want 5 and 5", alert.Available,
+			alert.ReorderPoint)
+	}
+	order, ok := store.PurchaseOrders[alert.PurchaseOrderID]
+	if !ok || order.SupplierID != "acme" || order.Status != domain.PurchaseOrderDraft ||
+		order.Lines[0].Quantity != 30 {
+		t.Errorf("alert drafted %+v, want a draft order for 30 hats from acme", order)
+	}
+	if other := alertsOf(store, "everywhere")[0]; other.PurchaseOrderID != "" {
+		t.Errorf("alert of a policy that drafts no orders drafted %s", other.PurchaseOrderID)
+	}
+
+	// the stock is low again, which opens a new alert
+	if _, err := inventoryUseCase.MoveStock(ctx, "hat", domain.DefaultLocationID,
+		domain.StockAdjustment{Delta: -4, Reason: domain.ReasonSold}); err != nil {
+		t.Fatal(err)
+	}
+	if err := stockAlertUseCase.Evaluate(ctx, "hat"); err != nil {
+		t.Fatal(err)
+	}
+	if alerts := alertsOf(store, "here"); len(alerts) != 2 {
+		t.Errorf("policy has alerts %+v, want a resolved and an open one", alerts)
+	}
+}
+
+func TestAlertStatus(t *testing.T) {
+	stockAlertUseCase, _, store := newStockAlertUseCase(t)
+	ctx := context.Background()
+	store.StockAlerts["open"] = domain.StockAlert{ID: "open", ItemID: "hat", Status: domain.AlertOpen}
+
+	cases := []struct {
+		name   string
+		change func() (*domain.StockAlert, error)
+		want   int
+	}{
+		{"acknowledge", func() (*domain.StockAlert, error) {
+			return stockAlertUseCase.Acknowledge(ctx, "open")
+		}, 0},
+		{"acknowledge twice", func() (*domain.StockAlert, error) {
+			return stockAlertUseCase.Acknowledge(ctx, "open")
+		}, http.StatusConflict},
+		{"resolve", func() (*domain.StockAlert, error) {
+			return stockAlertUseCase.Resolve(ctx, "open")
+		}, 0},
+		{"resolve twice", func() (*domain.StockAlert, error) {
+			return stockAlertUseCase.Resolve(ctx, "open")
+		}, http.StatusConflict},
+		{"unknown alert", func() (*domain.StockAlert, error) {
+			return stockAlertUseCase.Resolve(ctx, "missing")
+		}, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		if _, err := c.change(); statusOf(err) != c.want {
+			t.Errorf("%s: failed with %d (%v), want %d", c.name, statusOf(err), err, c.want)
+		}
+	}
+}
+
+// alertsOf is the alerts of a policy, oldest first
+func alertsOf(store *memory.Store, policyID string) []domain.StockAlert {
+	var alerts []domain.StockAlert
+	for _, alert := range store.StockAlerts {
+		if alert.PolicyID == policyID {
+			alerts = append(alerts, alert)
+		}
+	}
+	if len(alerts) == 2 && alerts[1].CreatedAt.Before(alerts[0].CreatedAt) {
+		alerts[0], alerts[1] = alerts[1], alerts[0]
+	}
+	return alerts
+}
+
+// ignoreStockChanges is a StockWatcher for tests that evaluate reorder points themselves
+type ignoreStockChanges struct{}
+
+func (ignoreStockChanges) StockChanged(string) {}
+
+// newStockAlertUseCase works on a store holding 10 hats at the default location, whose preferred supplier takes
+// orders of 30 or more
+func newStockAlertUseCase(t *testing.T) (domain.StockAlertUseCase, domain.InventoryUseCase, *memory.Store) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	store.Locations["east"] = domain.Location{ID: "east", Name: "East", Type: domain.LocationStorage}
+	store.Suppliers["acme"] = domain.Supplier{ID: "acme", Name: "Acme", MinimumOrderQuantity: 30}
+	store.SupplierItems[memory.SupplierItemKey("acme", "hat")] = domain.SupplierItem{SupplierID: "acme", ItemID: "hat",
+		UnitCost: decimal.NewFromInt(2), Preferred: true}
+	itemRepository := repository2.NewMemoryItemRepository(store)
+	if _, err := itemRepository.Save(ctx, &domain.Item{ID: "hat", Name: "hat", Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	inventoryUseCase := usecase2.NewInventoryUseCase(itemRepository, repository.NewMemoryInventoryRepository(store),
+		repository.NewMemoryMovementRepository(store), repository5.NewMemoryReservationRepository(store),
+		repository3.NewMemoryLocationRepository(store), repository4.NewMemoryLotRepository(store),
+		repository8.NewMemorySerialRepository(store), repository9.NewMemoryCostRepository(store), ignoreStockChanges{},
+		store, time.Second)
+	if _, err := inventoryUseCase.MoveStock(ctx, "hat", domain.DefaultLocationID,
+		domain.StockAdjustment{Delta: 10, Reason: domain.ReasonReceived}); err != nil {
+		t.Fatal(err)
+	}
+
+	supplierRepository := repository6.NewMemorySupplierRepository(store)
+	supplierItemRepository := repository6.NewMemorySupplierItemRepository(store)
+	purchaseOrderUseCase := usecase7.NewPurchaseOrderUseCase(repository7.NewMemoryPurchaseOrderRepository(store),
+		itemRepository, supplierRepository, supplierItemRepository, repository11.NewMemoryCurrencyRateRepository(store),
+		inventoryUseCase, store, time.Second)
+	return NewStockAlertUseCase(repository10.NewMemoryStockAlertRepository(store),
+		repository10.NewMemoryReorderPolicyRepository(store), supplierRepository, supplierItemRepository,
+		inventoryUseCase, purchaseOrderUseCase, store, time.Second), inventoryUseCase, store
+}
+
+// statusOf is the status code of err, or 0 if there is none
+func statusOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	if restError, ok := err.(*errors.RestError); ok {
+		return restError.Code
+	}
+	return http.StatusInternalServerError
+}
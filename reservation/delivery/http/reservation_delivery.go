@@ -0,0 +1,151 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"net/http"
+	"strconv"
+)
+
+type ReservationHandler struct {
+	useCase domain.ReservationUseCase
+}
+
+func NewReservationHandler(useCase domain.ReservationUseCase) *ReservationHandler {
+	return &ReservationHandler{useCase: useCase}
+}
+
+func (h *ReservationHandler) GetAll(c *gin.Context) {
+	owner, _ := c.GetQuery("owner")
+	status, _ := c.GetQuery("status")
+
+	var count int64
+	if countQuery, ok := c.GetQuery("count"); ok {
+		count, _ = strconv.ParseInt(countQuery, 10, 64)
+	} else {
+		count = 20
+	}
+
+	var offset int64
+	if offsetQuery, ok := c.GetQuery("offset"); ok {
+		offset, _ = strconv.ParseInt(offsetQuery, 10, 64)
+	}
+
+	ctx := c.Request.Context()
+	filter := domain.ReservationFilter{Owner: owner, Status: domain.ReservationStatus(status)}
+	reservations, err := h.useCase.GetAll(ctx, int(count), int(offset), filter)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, reservations)
+}
+
+func (h *ReservationHandler) GetOne(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	reservation, err := h.useCase.GetOne(ctx, id)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+func (h *ReservationHandler) Reserve(c *gin.Context) {
+	var reservation domain.Reservation
+	if err := c.ShouldBindJSON(&reservation); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid reservation body"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	created, err := h.useCase.Reserve(ctx, &reservation)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *ReservationHandler) Confirm(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	// only reservations of serialized items need a body, naming the units sold
+	var confirmation domain.ReservationConfirmation
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&confirmation); err != nil {
+			c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid confirmation body"))
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	reservation, err := h.useCase.Confirm(ctx, id, confirmation)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+func (h *ReservationHandler) Release(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	reservation, err := h.useCase.Release(ctx, id)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
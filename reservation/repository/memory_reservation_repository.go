@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"sort"
+	"time"
+)
+
+type memoryReservationRepository struct {
+	store *memory.Store
+}
+
+// NewMemoryReservationRepository keeps reservations in store instead of a database
+func NewMemoryReservationRepository(store *memory.Store) domain.ReservationRepository {
+	return &memoryReservationRepository{store: store}
+}
+
+func (r *memoryReservationRepository) GetAll(ctx context.Context, count int, offset int,
+	filter domain.ReservationFilter) ([]domain.Reservation, error) {
+	defer r.store.Read(ctx)()
+
+	var reservations []domain.Reservation
+	for _, reservation := range r.store.Reservations {
+		if (filter.Owner == "" || reservation.Owner == filter.Owner) &&
+			(filter.Status == "" || reservation.Status == filter.Status) {
+			reservations = append(reservations, reservation)
+		}
+	}
+	sort.Slice(reservations, func(a, b int) bool {
+		if !reservations[a].CreatedAt.Equal(reservations[b].CreatedAt) {
+			return reservations[a].CreatedAt.After(reservations[b].CreatedAt)
+		}
+		return reservations[a].ID < reservations[b].ID
+	})
+
+	start, end := memory.Page(len(reservations), count, offset)
+	return reservations[start:end], nil
+}
+
+func (r *memoryReservationRepository) GetOne(ctx context.Context, id string) (*domain.Reservation, error) {
+	defer r.store.Read(ctx)()
+
+	reservation := r.store.Reservations[id]
+	return &reservation, nil
+}
+
+// GetOneForUpdate needs no lock of its own, transactions hold the whole store
+func (r *memoryReservationRepository) GetOneForUpdate(ctx context.Context, id string) (*domain.Reservation, error) {
+	return r.GetOne(ctx, id)
+}
+
+func (r *memoryReservationRepository) Save(ctx context.Context, reservation *domain.Reservation) (*domain.Reservation, error) {
+	defer r.store.Write(ctx)()
+
+	if _, ok := r.store.Reservations[reservation.ID]; ok {
+		return nil, errors.NewConflictError("reservation already exists")
+	}
+	r.store.Reservations[reservation.ID] = *reservation
+	return reservation, nil
+}
+
+func (r *memoryReservationRepository) Edit(ctx context.Context, reservation *domain.Reservation) (*domain.Reservation, error) {
+	defer r.store.Write(ctx)()
+
+	if existing, ok := r.store.Reservations[reservation.ID]; ok {
+		existing.Status = reservation.Status
+		existing.UpdatedAt = reservation.UpdatedAt
+		r.store.Reservations[reservation.ID] = existing
+	}
+	return reservation, nil
+}
+
+func (r *memoryReservationRepository) Reserved(ctx context.Context, inventoryIDs []string,
+	at time.Time) (map[string]int, error) {
+	defer r.store.Read(ctx)()
+
+	wanted := map[string]bool{}
+	for _, inventoryID := range inventoryIDs {
+		wanted[inventoryID] = true
+	}
+
+	reservedByInventory := map[string]int{}
+	for _, reservation := range r.store.Reservations {
+		if wanted[reservation.InventoryID] && reservation.Status == domain.ReservationActive &&
+			!reservation.Expired(at) {
+			reservedByInventory[reservation.InventoryID] += reservation.Quantity
+		}
+	}
+	return reservedByInventory, nil
+}
+
+func (r *memoryReservationRepository) Expire(ctx context.Context, at time.Time) (int64, error) {
+	defer r.store.Write(ctx)()
+
+	var expired int64
+	for id, reservation := range r.store.Reservations {
+		if reservation.Status == domain.ReservationActive && reservation.Expired(at) {
+			reservation.Status = domain.ReservationExpired
+			reservation.UpdatedAt = at
+			r.store.Reservations[id] = reservation
+			expired++
+		}
+	}
+	return expired, nil
+}
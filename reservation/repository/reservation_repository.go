@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/nuzurie/shopify/db"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"strings"
+	"time"
+)
+
+type reservationRepository struct {
+	db db.DB
+}
+
+const (
+	getAll = `SELECT id, item_id, location_id, inventory_id, quantity, owner, status, expires_at, created_at, updated_at
+			FROM reservation WHERE %s ORDER BY created_at DESC, id LIMIT $%d OFFSET $%d`
+	getByID = `SELECT id, item_id, location_id, inventory_id, quantity, owner, status, expires_at, created_at, updated_at
+			FROM reservation WHERE id=$1`
+	save = `INSERT INTO reservation (id, item_id, location_id, inventory_id, quantity, owner, status, expires_at,
+			created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	update   = `UPDATE reservation SET status=$2, updated_at=$3 WHERE id=$1`
+	reserved = `SELECT inventory_id, SUM(quantity) FROM reservation
+			WHERE status='active' AND (expires_at IS NULL OR expires_at>$1) AND inventory_id IN (%s)
+			GROUP BY inventory_id`
+	expire = `UPDATE reservation SET status='expired', updated_at=$1 WHERE status='active' AND expires_at<=$1`
+	// SQLite has no row locks, but it only ever runs one transaction at a time
+	forUpdate = ` FOR UPDATE`
+)
+
+// NewReservationRepository stores reservations in a SQL database, Postgres or SQLite. The schema must be migrated
+func NewReservationRepository(database db.DB) domain.ReservationRepository {
+	return &reservationRepository{db: database}
+}
+
+func (r *reservationRepository) GetAll(ctx context.Context, count int, offset int,
+	filter domain.ReservationFilter) ([]domain.Reservation, error) {
+	conditions := []string{"1=1"}
+	var args []interface{}
+	if filter.Owner != "" {
+		args = append(args, filter.Owner)
+		conditions = append(conditions, fmt.Sprintf("owner=$%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status=$%d", len(args)))
+	}
+
+	query := fmt.Sprintf(getAll, strings.Join(conditions, " AND "), len(args)+1, len(args)+2)
+	rows, err := r.db.Query(ctx, query, append(args, count, offset)...)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var reservations []domain.Reservation
+	for rows.Next() {
+		var reservation domain.Reservation
+		err = rows.Scan(&reservation.ID, &reservation.ItemID, &reservation.LocationID, &reservation.InventoryID,
+			&reservation.Quantity, &reservation.Owner, &reservation.Status, &reservation.ExpiresAt,
+			&reservation.CreatedAt, &reservation.UpdatedAt)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		reservations = append(reservations, reservation)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return reservations, nil
+}
+
+func (r *reservationRepository) GetOne(ctx context.Context, id string) (*domain.Reservation, error) {
+	return r.getOne(ctx, getByID, id)
+}
+
+func (r *reservationRepository) GetOneForUpdate(ctx context.Context, id string) (*domain.Reservation, error) {
+	query := getByID
+	if r.db.Dialect() == domain.Postgres {
+		query += forUpdate
+	}
+	return r.getOne(ctx, query, id)
+}
+
+func (r *reservationRepository) getOne(ctx context.Context, query string, id string) (*domain.Reservation, error) {
+	var reservation domain.Reservation
+	err := r.db.QueryRow(ctx, query, id).
+		Scan(&reservation.ID, &reservation.ItemID, &reservation.LocationID, &reservation.InventoryID,
+			&reservation.Quantity, &reservation.Owner, &reservation.Status, &reservation.ExpiresAt,
+			&reservation.CreatedAt, &reservation.UpdatedAt)
+	if err != nil && err != db.ErrNoRows {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return &reservation, nil
+}
+
+func (r *reservationRepository) Save(ctx context.Context, reservation *domain.Reservation) (*domain.Reservation, error) {
+	_, err := r.db.Exec(ctx, save, reservation.ID, reservation.ItemID, reservation.LocationID, reservation.InventoryID,
+		reservation.Quantity, reservation.Owner, reservation.Status, reservation.ExpiresAt, reservation.CreatedAt,
+		reservation.UpdatedAt)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return reservation, nil
+}
+
+func (r *reservationRepository) Edit(ctx context.Context, reservation *domain.Reservation) (*domain.Reservation, error) {
+	_, err := r.db.Exec(ctx, update, reservation.ID, reservation.Status, reservation.UpdatedAt)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return reservation, nil
+}
+
+func (r *reservationRepository) Reserved(ctx context.Context, inventoryIDs []string, at time.Time) (map[string]int, error) {
+	reservedByInventory := map[string]int{}
+	if len(inventoryIDs) == 0 {
+		return reservedByInventory, nil
+	}
+
+	args := []interface{}{at}
+	placeholders := make([]string, len(inventoryIDs))
+	for index, inventoryID := range inventoryIDs {
+		args = append(args, inventoryID)
+		placeholders[index] = fmt.Sprintf("$%d", len(args))
+	}
+
+	rows, err := r.db.Query(ctx, fmt.Sprintf(reserved, strings.Join(placeholders, ", ")), args...)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var inventoryID string
+		var quantity int
+		if err = rows.Scan(&inventoryID, &quantity); err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+		reservedByInventory[inventoryID] = quantity
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return reservedByInventory, nil
+}
+
+func (r *reservationRepository) Expire(ctx context.Context, at time.Time) (int64, error) {
+	expired, err := r.db.Exec(ctx, expire, at)
+	if err != nil {
+		return 0, errors.NewInternalServerError(err.Error())
+	}
+
+	return expired, nil
+}
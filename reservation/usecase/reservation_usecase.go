@@ -0,0 +1,263 @@
+package usecase
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTTL = 15 * time.Minute
+	maxTTL     = 7 * 24 * time.Hour
+)
+
+type reservationUseCase struct {
+	reservationRepository domain.ReservationRepository
+	itemRepository        domain.ItemRepository
+	inventoryRepository   domain.InventoryRepository
+	locationRepository    domain.LocationRepository
+	lotRepository         domain.LotRepository
+	inventoryUseCase      domain.InventoryUseCase
+	watcher               domain.StockWatcher
+	transactor            domain.Transactor
+	timeout               time.Duration
+}
+
+// NewReservationUseCase checks availability against inventoryRepository and takes confirmed reservations out of stock
+// through inventoryUseCase, in the same transaction as the change to the reservation. Stock at the quarantine
+// locations of locationRepository or in blocked lots of lotRepository can't be reserved. watcher is told about the item
+// whenever its available stock changes. Quantities reserved in another unit of an item are converted to its base unit
+// with the units in itemRepository
+func NewReservationUseCase(reservationRepository domain.ReservationRepository, itemRepository domain.ItemRepository,
+	inventoryRepository domain.InventoryRepository, locationRepository domain.LocationRepository,
+	lotRepository domain.LotRepository, inventoryUseCase domain.InventoryUseCase, watcher domain.StockWatcher,
+	transactor domain.Transactor, timeout time.Duration) domain.ReservationUseCase {
+	return &reservationUseCase{reservationRepository: reservationRepository, itemRepository: itemRepository,
+		inventoryRepository: inventoryRepository, locationRepository: locationRepository, lotRepository: lotRepository,
+		inventoryUseCase: inventoryUseCase, watcher: watcher, transactor: transactor, timeout: timeout}
+}
+
+func (r *reservationUseCase) GetAll(ctx context.Context, count int, offset int,
+	filter domain.ReservationFilter) ([]domain.Reservation, error) {
+	c, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	reservations, err := r.reservationRepository.GetAll(c, count, offset, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(reservations) == 0 {
+		return nil, errors.NewNotFoundError("no reservations found")
+	}
+
+	now := time.Now()
+	for index := range reservations {
+		showExpiry(&reservations[index], now)
+	}
+	return reservations, nil
+}
+
+func (r *reservationUseCase) GetOne(ctx context.Context, id string) (*domain.Reservation, error) {
+	c, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	reservation, err := r.reservationRepository.GetOne(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if reservation.ID == "" {
+		return nil, errors.NewNotFoundError("no such reservation found")
+	}
+
+	showExpiry(reservation, time.Now())
+	return reservation, nil
+}
+
+func (r *reservationUseCase) Reserve(ctx context.Context, reservation *domain.Reservation) (*domain.Reservation, error) {
+	ttl := time.Duration(reservation.TTLSeconds) * time.Second
+	if reservation.TTLSeconds == 0 {
+		ttl = defaultTTL
+	}
+	if ttl <= 0 || ttl > maxTTL {
+		return nil, errors.NewBadRequestError("invalid request. TTL must be between 1 and " +
+			strconv.Itoa(int(maxTTL/time.Second)) + " seconds")
+	}
+
+	return r.reserve(ctx, reservation, ttl)
+}
+
+func (r *reservationUseCase) Allocate(ctx context.Context, reservation *domain.Reservation) (*domain.Reservation, error) {
+	return r.reserve(ctx, reservation, 0)
+}
+
+// reserve holds stock for ttl, or until the reservation is confirmed or released if ttl is 0
+func (r *reservationUseCase) reserve(ctx context.Context, reservation *domain.Reservation,
+	ttl time.Duration) (*domain.Reservation, error) {
+	c, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if reservation.ItemID == "" {
+		return nil, errors.NewBadRequestError("invalid request. Item is required")
+	}
+	if strings.TrimSpace(reservation.Owner) == "" {
+		return nil, errors.NewBadRequestError("invalid request. Owner is required")
+	}
+	if reservation.Quantity <= 0 {
+		return nil, errors.NewBadRequestError("invalid request. Quantity must be more than 0")
+	}
+	if reservation.LocationID == "" {
+		reservation.LocationID = domain.DefaultLocationID
+	}
+	if reservation.Unit != "" {
+		item, err := r.itemRepository.GetOne(c, reservation.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		factor, ok := item.UnitFactor(reservation.Unit)
+		if item.ID == "" || !ok {
+			return nil, errors.NewBadRequestError("invalid request. Item " + reservation.ItemID + " isn't counted in " +
+				reservation.Unit)
+		}
+		if reservation.Quantity, ok = domain.ScaleQuantity(reservation.Quantity, factor); !ok {
+			return nil, errors.NewBadRequestError("invalid request. Quantity is too large for item " +
+				reservation.ItemID)
+		}
+		reservation.Unit = ""
+	}
+
+	location, err := r.locationRepository.GetOne(c, reservation.LocationID)
+	if err != nil {
+		return nil, err
+	}
+	if location.Type == domain.LocationQuarantine {
+		return nil, errors.NewConflictError("stock at quarantine location " + location.ID + " can't be reserved")
+	}
+
+	err = r.transactor.WithinTransaction(c, func(c context.Context) error {
+		inventory, err := r.inventoryRepository.GetInventoryAtLocation(c, reservation.ItemID, reservation.LocationID)
+		if err != nil {
+			return err
+		}
+		if inventory.ID == "" {
+			return errors.NewConflictError("insufficient stock. The location holds none of item " + reservation.ItemID)
+		}
+		// reservations are checked against the quantity on hand, which mustn't change until this one is saved
+		inventory, err = r.inventoryRepository.GetByIDForUpdate(c, inventory.ID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		reserved, err := r.reservationRepository.Reserved(c, []string{inventory.ID}, now)
+		if err != nil {
+			return err
+		}
+		lots, err := r.lotRepository.GetForInventory(c, []string{inventory.ID})
+		if err != nil {
+			return err
+		}
+		available := inventory.Quantity - reserved[inventory.ID]
+		for _, lot := range lots {
+			if lot.Blocked {
+				available -= lot.Quantity
+			}
+		}
+		if available < reservation.Quantity {
+			return errors.NewConflictError("insufficient stock of item " + reservation.ItemID + ". Only " +
+				strconv.Itoa(available) + " available")
+		}
+
+		reservation.ID = uuid.NewString()
+		reservation.InventoryID = inventory.ID
+		reservation.Status = domain.ReservationActive
+		reservation.ExpiresAt = nil
+		if ttl > 0 {
+			expiresAt := now.Add(ttl)
+			reservation.ExpiresAt = &expiresAt
+		}
+		reservation.CreatedAt = now
+		reservation.UpdatedAt = now
+		_, err = r.reservationRepository.Save(c, reservation)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.watcher.StockChanged(reservation.ItemID)
+	reservation.TTLSeconds = 0
+	return reservation, nil
+}
+
+func (r *reservationUseCase) Confirm(ctx context.Context, id string,
+	confirmation domain.ReservationConfirmation) (*domain.Reservation, error) {
+	return r.update(ctx, id, domain.ReservationConfirmed, func(c context.Context, reservation *domain.Reservation) error {
+		_, err := r.inventoryUseCase.MoveStock(c, reservation.ItemID, reservation.LocationID, domain.StockAdjustment{
+			Delta: -reservation.Quantity, Reason: domain.ReasonSold, Serials: confirmation.Serials})
+		return err
+	})
+}
+
+func (r *reservationUseCase) Release(ctx context.Context, id string) (*domain.Reservation, error) {
+	return r.update(ctx, id, domain.ReservationReleased, nil)
+}
+
+// update moves an active reservation to status, running apply in the same transaction
+func (r *reservationUseCase) update(ctx context.Context, id string, status domain.ReservationStatus,
+	apply func(c context.Context, reservation *domain.Reservation) error) (*domain.Reservation, error) {
+	c, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var reservation *domain.Reservation
+	err := r.transactor.WithinTransaction(c, func(c context.Context) error {
+		var err error
+		reservation, err = r.reservationRepository.GetOneForUpdate(c, id)
+		if err != nil {
+			return err
+		}
+		if reservation.ID == "" {
+			return errors.NewNotFoundError("no such reservation found")
+		}
+
+		now := time.Now()
+		showExpiry(reservation, now)
+		if reservation.Status != domain.ReservationActive {
+			return errors.NewConflictError("only active reservations can be " + string(status) + ", this one is " +
+				string(reservation.Status))
+		}
+
+		if apply != nil {
+			if err = apply(c, reservation); err != nil {
+				return err
+			}
+		}
+		reservation.Status = status
+		reservation.UpdatedAt = now
+		_, err = r.reservationRepository.Edit(c, reservation)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.watcher.StockChanged(reservation.ItemID)
+	return reservation, nil
+}
+
+func (r *reservationUseCase) ExpireReservations(ctx context.Context) (int64, error) {
+	c, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.reservationRepository.Expire(c, time.Now())
+}
+
+// showExpiry reports active reservations past their expiry as expired, even before they are marked as such
+func showExpiry(reservation *domain.Reservation, now time.Time) {
+	if reservation.Status == domain.ReservationActive && reservation.Expired(now) {
+		reservation.Status = domain.ReservationExpired
+	}
+}
@@ -0,0 +1,195 @@
+package usecase
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/inventory/repository"
+	usecase2 "github.com/nuzurie/shopify/inventory/usecase"
+	repository2 "github.com/nuzurie/shopify/item/repository"
+	repository3 "github.com/nuzurie/shopify/location/repository"
+	repository4 "github.com/nuzurie/shopify/lot/repository"
+	repository5 "github.com/nuzurie/shopify/reservation/repository"
+	repository6 "github.com/nuzurie/shopify/serial/repository"
+	"github.com/nuzurie/shopify/utils/errors"
+	repository7 "github.com/nuzurie/shopify/valuation/repository"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestReserve(t *testing.T) {
+	reservationUseCase, inventoryUseCase, _ := newReservationUseCase(t)
+	ctx := context.Background()
+	if _, err := inventoryUseCase.MoveStock(ctx, "hat", domain.QuarantineLocationID,
+		domain.StockAdjustment{Delta: 5, Reason: domain.ReasonReturned}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name        string
+		reservation domain.Reservation
+		want        int
+	}{
+		{"some", domain.Reservation{ItemID: "hat", Quantity: 6, Owner: "cart"}, 0},
+		{"more than available", domain.Reservation{ItemID: "hat", Quantity: 5, Owner: "cart"}, http.StatusConflict},
+		{"the rest", domain.Reservation{ItemID: "hat", Quantity: 4, Owner: "cart", TTLSeconds: 60}, 0},
+		{"none left", domain.Reservation{ItemID: "hat", Quantity: 1, Owner: "cart"}, http.StatusConflict},
+		{"elsewhere", domain.Reservation{ItemID: "hat", LocationID: "east", Quantity: 1, Owner: "cart"},
+			http.StatusConflict},
+		{"in quarantine", domain.Reservation{ItemID: "hat", LocationID: domain.QuarantineLocationID, Quantity: 1,
+			Owner: "cart"}, http.StatusConflict},
+		{"no quantity", domain.Reservation{ItemID: "hat", Owner: "cart"}, http.StatusBadRequest},
+		{"no owner", domain.Reservation{ItemID: "hat", Quantity: 1, Owner: " "}, http.StatusBadRequest},
+		{"too long", domain.Reservation{ItemID: "hat", Quantity: 1, Owner: "cart", TTLSeconds: 8 * 24 * 60 * 60},
+			http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		reservation := c.reservation
+		reserved, err := reservationUseCase.Reserve(ctx, &reservation)
+		if got := statusOf(err); got != c.want {
+			t.Errorf("%s: Reserve failed with %d (%v), want %d", c.name, got, err, c.want)
+			continue
+		}
+		if err == nil && (reserved.Status != domain.ReservationActive || reserved.ExpiresAt == nil ||
+			reserved.Expired(time.Now())) {
+			t.Errorf("%s: reserved %+v, want an active reservation", c.name, reserved)
+		}
+	}
+}
+
+func TestExpiryAndRelease(t *testing.T) {
+	reservationUseCase, inventoryUseCase, store := newReservationUseCase(t)
+	ctx := context.Background()
+	reserve := func(quantity int) *domain.Reservation {
+		reservation, err := reservationUseCase.Reserve(ctx,
+			&domain.Reservation{ItemID: "hat", Quantity: quantity, Owner: "cart"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return reservation
+	}
+	// expire makes a reservation run out without waiting for it
+	expire := func(id string) {
+		reservation := store.Reservations[id]
+		expiresAt := time.Now().Add(-time.Second)
+		reservation.ExpiresAt = &expiresAt
+		store.Reservations[id] = reservation
+	}
+
+	released := reserve(3)
+	expired := reserve(2)
+	confirmed := reserve(1)
+	expire(expired.ID)
+	// allocations hold their units until they are confirmed or released
+	allocated, err := reservationUseCase.Allocate(ctx, &domain.Reservation{ItemID: "hat", Quantity: 4, Owner: "order"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allocated.ExpiresAt != nil {
+		t.Errorf("allocation expires at %v, want it not to", allocated.ExpiresAt)
+	}
+
+	steps := []struct {
+		name      string
+		change    func() (*domain.Reservation, error)
+		want      int
+		status    domain.ReservationStatus
+		quantity  int
+		available int
+	}{
+		{"expired before it is marked", func() (*domain.Reservation, error) {
+			return reservationUseCase.GetOne(ctx, expired.ID)
+		}, 0, domain.ReservationExpired, 10, 2},
+		{"release", func() (*domain.Reservation, error) {
+			return reservationUseCase.Release(ctx, released.ID)
+		}, 0, domain.ReservationReleased, 10, 5},
+		{"release twice", func() (*domain.Reservation, error) {
+			return reservationUseCase.Release(ctx, released.ID)
+		}, http.StatusConflict, "", 10, 5},
+		{"confirm once expired", func() (*domain.Reservation, error) {
+			return reservationUseCase.Confirm(ctx, expired.ID, domain.ReservationConfirmation{})
+		}, http.StatusConflict, "", 10, 5},
+		{"confirm", func() (*domain.Reservation, error) {
+			return reservationUseCase.Confirm(ctx, confirmed.ID, domain.ReservationConfirmation{})
+		}, 0, domain.ReservationConfirmed, 9, 5},
+		{"release once confirmed", func() (*domain.Reservation, error) {
+			return reservationUseCase.Release(ctx, confirmed.ID)
+		}, http.StatusConflict, "", 9, 5},
+		{"unknown reservation", func() (*domain.Reservation, error) {
+			return reservationUseCase.Release(ctx, "missing")
+		}, http.StatusNotFound, "", 9, 5},
+	}
+	for _, step := range steps {
+		reservation, err := step.change()
+		if got := statusOf(err); got != step.want {
+			t.Errorf("%s: failed with %d (%v), want %d", step.name, got, err, step.want)
+			continue
+		}
+		if err == nil && reservation.Status != step.status {
+			t.Errorf("%s: reservation is %s, want %s", step.name, reservation.Status, step.status)
+		}
+		stock, err := inventoryUseCase.GetInventoryForItem(ctx, "hat")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stock.Quantity != step.quantity || stock.Available != step.available {
+			t.Errorf("%s: %d on hand and %d available, want %d and %d", step.name, stock.Quantity, stock.Available,
+				step.quantity, step.available)
+		}
+	}
+
+	// only the reservation that ran out while active is marked as expired
+	if expiredCount, err := reservationUseCase.ExpireReservations(ctx); err != nil || expiredCount != 1 {
+		t.Errorf("ExpireReservations = %d, %v, want 1", expiredCount, err)
+	}
+	if expiredCount, err := reservationUseCase.ExpireReservations(ctx); err != nil || expiredCount != 0 {
+		t.Errorf("ExpireReservations again = %d, %v, want 0", expiredCount, err)
+	}
+	if reservation := store.Reservations[expired.ID]; reservation.Status != domain.ReservationExpired {
+		t.Errorf("reservation is %s once expired, want %s", reservation.Status, domain.ReservationExpired)
+	}
+}
+
+// ignoreStockChanges is a StockWatcher for tests that don't evaluate reorder points
+type ignoreStockChanges struct{}
+
+func (ignoreStockChanges) StockChanged(string) {}
+
+// newReservationUseCase works on a store holding 10 hats at the default location
+func newReservationUseCase(t *testing.T) (domain.ReservationUseCase, domain.InventoryUseCase, *memory.Store) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	store.Locations["east"] = domain.Location{ID: "east", Name: "East", Type: domain.LocationStorage}
+	itemRepository := repository2.NewMemoryItemRepository(store)
+	if _, err := itemRepository.Save(ctx, &domain.Item{ID: "hat", Name: "hat", Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	inventoryRepository := repository.NewMemoryInventoryRepository(store)
+	reservationRepository := repository5.NewMemoryReservationRepository(store)
+	locationRepository := repository3.NewMemoryLocationRepository(store)
+	lotRepository := repository4.NewMemoryLotRepository(store)
+	inventoryUseCase := usecase2.NewInventoryUseCase(itemRepository, inventoryRepository,
+		repository.NewMemoryMovementRepository(store), reservationRepository, locationRepository, lotRepository,
+		repository6.NewMemorySerialRepository(store), repository7.NewMemoryCostRepository(store), ignoreStockChanges{},
+		store, time.Second)
+	if _, err := inventoryUseCase.MoveStock(ctx, "hat", domain.DefaultLocationID,
+		domain.StockAdjustment{Delta: 10, Reason: domain.ReasonReceived}); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewReservationUseCase(reservationRepository, itemRepository, inventoryRepository, locationRepository,
+		lotRepository, inventoryUseCase, ignoreStockChanges{}, store, time.Second), inventoryUseCase, store
+}
+
+// statusOf is the status code of err, or 0 if there is none
+func statusOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	if restError, ok := err.(*errors.RestError); ok {
+		return restError.Code
+	}
+	return http.StatusInternalServerError
+}
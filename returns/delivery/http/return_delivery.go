@@ -0,0 +1,168 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type ReturnHandler struct {
+	useCase domain.ReturnUseCase
+}
+
+func NewReturnHandler(useCase domain.ReturnUseCase) *ReturnHandler {
+	return &ReturnHandler{useCase: useCase}
+}
+
+func (h *ReturnHandler) GetAll(c *gin.Context) {
+	var filter domain.ReturnFilter
+	filter.ItemID, _ = c.GetQuery("item")
+	filter.SalesOrderID, _ = c.GetQuery("sales_order")
+	status, _ := c.GetQuery("status")
+	filter.Status = domain.ReturnStatus(status)
+
+	var count int64
+	if countQuery, ok := c.GetQuery("count"); ok {
+		count, _ = strconv.ParseInt(countQuery, 10, 64)
+	} else {
+		count = 20
+	}
+
+	var offset int64
+	if offsetQuery, ok := c.GetQuery("offset"); ok {
+		offset, _ = strconv.ParseInt(offsetQuery, 10, 64)
+	}
+
+	ctx := c.Request.Context()
+	returns, err := h.useCase.GetAll(ctx, int(count), int(offset), filter)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, returns)
+}
+
+func (h *ReturnHandler) GetOne(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	customerReturn, err := h.useCase.GetOne(ctx, id)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, customerReturn)
+}
+
+func (h *ReturnHandler) Create(c *gin.Context) {
+	var customerReturn domain.Return
+	if err := c.ShouldBindJSON(&customerReturn); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid return body"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	created, err := h.useCase.Create(ctx, &customerReturn)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *ReturnHandler) Inspect(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	var inspection domain.ReturnInspection
+	if err := c.ShouldBindJSON(&inspection); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid inspection body"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	customerReturn, err := h.useCase.Inspect(ctx, id, inspection)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, customerReturn)
+}
+
+func (h *ReturnHandler) WriteOffs(c *gin.Context) {
+	var from, to time.Time
+	var err error
+	if fromQuery, ok := c.GetQuery("from"); ok {
+		if from, err = parseTime(fromQuery); err != nil {
+			c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid from date"))
+			return
+		}
+	}
+	if toQuery, ok := c.GetQuery("to"); ok {
+		if to, err = parseTime(toQuery); err != nil {
+			c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid to date"))
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	writeOffs, err := h.useCase.WriteOffs(ctx, from, to)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, writeOffs)
+}
+
+// parseTime accepts RFC 3339 timestamps or plain dates, which are taken as midnight UTC
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"sort"
+	"time"
+)
+
+type memoryReturnRepository struct {
+	store *memory.Store
+}
+
+// NewMemoryReturnRepository keeps customer returns in store instead of a database. store must be shared with the sales
+// order and location repositories so returns can only reference existing ones
+func NewMemoryReturnRepository(store *memory.Store) domain.ReturnRepository {
+	return &memoryReturnRepository{store: store}
+}
+
+func (r *memoryReturnRepository) GetAll(ctx context.Context, count int, offset int,
+	filter domain.ReturnFilter) ([]domain.Return, error) {
+	defer r.store.Read(ctx)()
+
+	var returns []domain.Return
+	for _, customerReturn := range r.store.Returns {
+		if (filter.ItemID == "" || customerReturn.ItemID == filter.ItemID) &&
+			(filter.SalesOrderID == "" || customerReturn.SalesOrderID == filter.SalesOrderID) &&
+			(filter.Status == "" || customerReturn.Status == filter.Status) {
+			returns = append(returns, customerReturn)
+		}
+	}
+	sort.Slice(returns, func(a, b int) bool {
+		if !returns[a].CreatedAt.Equal(returns[b].CreatedAt) {
+			return returns[a].CreatedAt.After(returns[b].CreatedAt)
+		}
+		return returns[a].ID < returns[b].ID
+	})
+
+	start, end := memory.Page(len(returns), count, offset)
+	return returns[start:end], nil
+}
+
+func (r *memoryReturnRepository) GetOne(ctx context.Context, id string) (*domain.Return, error) {
+	defer r.store.Read(ctx)()
+
+	customerReturn := r.store.Returns[id]
+	return &customerReturn, nil
+}
+
+// GetOneForUpdate needs no lock of its own, transactions hold the whole store
+func (r *memoryReturnRepository) GetOneForUpdate(ctx context.Context, id string) (*domain.Return, error) {
+	return r.GetOne(ctx, id)
+}
+
+func (r *memoryReturnRepository) Save(ctx context.Context, customerReturn *domain.Return) (*domain.Return, error) {
+	defer r.store.Write(ctx)()
+
+	if _, ok := r.store.SalesOrders[customerReturn.SalesOrderID]; customerReturn.SalesOrderID != "" && !ok {
+		return nil, errors.NewBadRequestError("no sales order or location with such ID exists")
+	}
+	if _, ok := r.store.Locations[customerReturn.LocationID]; customerReturn.LocationID != "" && !ok {
+		return nil, errors.NewBadRequestError("no sales order or location with such ID exists")
+	}
+	if _, ok := r.store.Returns[customerReturn.ID]; ok {
+		return nil, errors.NewConflictError("return already exists")
+	}
+	r.store.Returns[customerReturn.ID] = *customerReturn
+	return customerReturn, nil
+}
+
+func (r *memoryReturnRepository) Edit(ctx context.Context, customerReturn *domain.Return) (*domain.Return, error) {
+	defer r.store.Write(ctx)()
+
+	if _, ok := r.store.Locations[customerReturn.LocationID]; customerReturn.LocationID != "" && !ok {
+		return nil, errors.NewBadRequestError("no location with such ID exists")
+	}
+	if existing, ok := r.store.Returns[customerReturn.ID]; ok {
+		existing.Condition = customerReturn.Condition
+		existing.Status = customerReturn.Status
+		existing.LocationID = customerReturn.LocationID
+		existing.Notes = customerReturn.Notes
+		existing.UpdatedAt = customerReturn.UpdatedAt
+		existing.InspectedAt = customerReturn.InspectedAt
+		r.store.Returns[customerReturn.ID] = existing
+	}
+	return customerReturn, nil
+}
+
+func (r *memoryReturnRepository) Returned(ctx context.Context, salesOrderID string, itemID string) (int, error) {
+	defer r.store.Read(ctx)()
+
+	quantity := 0
+	for _, customerReturn := range r.store.Returns {
+		if customerReturn.SalesOrderID == salesOrderID && customerReturn.ItemID == itemID {
+			quantity += customerReturn.Quantity
+		}
+	}
+	return quantity, nil
+}
+
+func (r *memoryReturnRepository) WriteOffs(ctx context.Context, from time.Time,
+	to time.Time) ([]domain.WriteOff, error) {
+	defer r.store.Read(ctx)()
+
+	byItem := map[string]*domain.WriteOff{}
+	var writeOffs []domain.WriteOff
+	for _, customerReturn := range r.store.Returns {
+		if customerReturn.Status != domain.ReturnWrittenOff || customerReturn.InspectedAt == nil ||
+			customerReturn.InspectedAt.Before(from) || !customerReturn.InspectedAt.Before(to) {
+			continue
+		}
+		if byItem[customerReturn.ItemID] == nil {
+			byItem[customerReturn.ItemID] = &domain.WriteOff{ItemID: customerReturn.ItemID}
+		}
+		byItem[customerReturn.ItemID].Quantity += customerReturn.Quantity
+		byItem[customerReturn.ItemID].Returns++
+	}
+	for _, writeOff := range byItem {
+		writeOffs = append(writeOffs, *writeOff)
+	}
+	sort.Slice(writeOffs, func(a, b int) bool {
+		return writeOffs[a].ItemID < writeOffs[b].ItemID
+	})
+	return writeOffs, nil
+}
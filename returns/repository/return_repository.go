@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/nuzurie/shopify/db"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"strings"
+	"time"
+)
+
+type returnRepository struct {
+	db db.DB
+}
+
+const (
+	getAll = `SELECT id, item_id, sales_order_id, quantity, reason, condition, status, location_id, notes, created_at,
+			updated_at, inspected_at FROM customer_return WHERE %s ORDER BY created_at DESC, id LIMIT $%d OFFSET $%d`
+	getByID = `SELECT id, item_id, sales_order_id, quantity, reason, condition, status, location_id, notes, created_at,
+			updated_at, inspected_at FROM customer_return WHERE id=$1`
+	save = `INSERT INTO customer_return (id, item_id, sales_order_id, quantity, reason, condition, status, location_id,
+			notes, created_at, updated_at, inspected_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	update = `UPDATE customer_return SET condition=$2, status=$3, location_id=$4, notes=$5, updated_at=$6,
+			inspected_at=$7 WHERE id=$1`
+	returned  = `SELECT COALESCE(SUM(quantity), 0) FROM customer_return WHERE sales_order_id=$1 AND item_id=$2`
+	writeOffs = `SELECT item_id, SUM(quantity), COUNT(*) FROM customer_return
+			WHERE status='written_off' AND inspected_at>=$1 AND inspected_at<$2 GROUP BY item_id ORDER BY item_id`
+	// SQLite has no row locks, but it only ever runs one transaction at a time
+	forUpdate = ` FOR UPDATE`
+)
+
+// NewReturnRepository stores customer returns in a SQL database, Postgres or SQLite. The schema must be migrated
+func NewReturnRepository(database db.DB) domain.ReturnRepository {
+	return &returnRepository{db: database}
+}
+
+func (r *returnRepository) GetAll(ctx context.Context, count int, offset int,
+	filter domain.ReturnFilter) ([]domain.Return, error) {
+	conditions := []string{"1=1"}
+	var args []interface{}
+	if filter.ItemID != "" {
+		args = append(args, filter.ItemID)
+		conditions = append(conditions, fmt.Sprintf("item_id=$%d", len(args)))
+	}
+	if filter.SalesOrderID != "" {
+		args = append(args, filter.SalesOrderID)
+		conditions = append(conditions, fmt.Sprintf("sales_order_id=$%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status=$%d", len(args)))
+	}
+
+	query := fmt.Sprintf(getAll, strings.Join(conditions, " AND "), len(args)+1, len(args)+2)
+	rows, err := r.db.Query(ctx, query, append(args, count, offset)...)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var returns []domain.Return
+	for rows.Next() {
+		customerReturn, err := scan(rows)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		returns = append(returns, *customerReturn)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return returns, nil
+}
+
+func (r *returnRepository) GetOne(ctx context.Context, id string) (*domain.Return, error) {
+	return r.getOne(ctx, getByID, id)
+}
+
+func (r *returnRepository) GetOneForUpdate(ctx context.Context, id string) (*domain.Return, error) {
+	query := getByID
+	if r.db.Dialect() == domain.Postgres {
+		query += forUpdate
+	}
+	return r.getOne(ctx, query, id)
+}
+
+func (r *returnRepository) getOne(ctx context.Context, query string, id string) (*domain.Return, error) {
+	customerReturn, err := scan(r.db.QueryRow(ctx, query, id))
+	if err == db.ErrNoRows {
+		return &domain.Return{}, nil
+	}
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return customerReturn, nil
+}
+
+// scan reads a return, whose sales order and location may be NULL
+func scan(row db.Row) (*domain.Return, error) {
+	var customerReturn domain.Return
+	var salesOrderID, locationID *string
+	err := row.Scan(&customerReturn.ID, &customerReturn.ItemID, &salesOrderID, &customerReturn.Quantity,
+		&customerReturn.Reason, &customerReturn.Condition, &customerReturn.Status, &locationID, &customerReturn.Notes,
+		&customerReturn.CreatedAt, &customerReturn.UpdatedAt, &customerReturn.InspectedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if salesOrderID != nil {
+		customerReturn.SalesOrderID = *salesOrderID
+	}
+	if locationID != nil {
+		customerReturn.LocationID = *locationID
+	}
+	return &customerReturn, nil
+}
+
+func (r *returnRepository) Save(ctx context.Context, customerReturn *domain.Return) (*domain.Return, error) {
+	_, err := r.db.Exec(ctx, save, customerReturn.ID, customerReturn.ItemID, nullable(customerReturn.SalesOrderID),
+		customerReturn.Quantity, customerReturn.Reason, customerReturn.Condition, customerReturn.Status,
+		nullable(customerReturn.LocationID), customerReturn.Notes, customerReturn.CreatedAt, customerReturn.UpdatedAt,
+		customerReturn.InspectedAt)
+	if err != nil {
+		if db.IsForeignKeyViolation(err) {
+			return nil, errors.NewBadRequestError("no sales order or location with such ID exists")
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return customerReturn, nil
+}
+
+func (r *returnRepository) Edit(ctx context.Context, customerReturn *domain.Return) (*domain.Return, error) {
+	_, err := r.db.Exec(ctx, update, customerReturn.ID, customerReturn.Condition, customerReturn.Status,
+		nullable(customerReturn.LocationID), customerReturn.Notes, customerReturn.UpdatedAt, customerReturn.InspectedAt)
+	if err != nil {
+		if db.IsForeignKeyViolation(err) {
+			return nil, errors.NewBadRequestError("no location with such ID exists")
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return customerReturn, nil
+}
+
+func (r *returnRepository) Returned(ctx context.Context, salesOrderID string, itemID string) (int, error) {
+	var quantity int
+	if err := r.db.QueryRow(ctx, returned, salesOrderID, itemID).Scan(&quantity); err != nil {
+		return 0, errors.NewInternalServerError(err.Error())
+	}
+
+	return quantity, nil
+}
+
+func (r *returnRepository) WriteOffs(ctx context.Context, from time.Time, to time.Time) ([]domain.WriteOff, error) {
+	rows, err := r.db.Query(ctx, writeOffs, from, to)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var writeOffs []domain.WriteOff
+	for rows.Next() {
+		var writeOff domain.WriteOff
+		if err = rows.Scan(&writeOff.ItemID, &writeOff.Quantity, &writeOff.Returns); err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		writeOffs = append(writeOffs, writeOff)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return writeOffs, nil
+}
+
+// nullable stores empty references as NULL, so they aren't taken for IDs the foreign keys must find
+func nullable(id string) interface{} {
+	if id == "" {
+		return nil
+	}
+	return id
+}
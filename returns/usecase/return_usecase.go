@@ -0,0 +1,273 @@
+package usecase
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultReportPeriod is how far back the write-off report goes unless told otherwise
+const defaultReportPeriod = 30 * 24 * time.Hour
+
+type returnUseCase struct {
+	returnRepository     domain.ReturnRepository
+	itemRepository       domain.ItemRepository
+	salesOrderRepository domain.SalesOrderRepository
+	locationRepository   domain.LocationRepository
+	inventoryUseCase     domain.InventoryUseCase
+	transactor           domain.Transactor
+	timeout              time.Duration
+}
+
+// NewReturnUseCase checks returns against the sales orders they are made for and moves the stock inspection decides on
+// through inventoryUseCase, in the same transaction as the change to the return
+func NewReturnUseCase(returnRepository domain.ReturnRepository, itemRepository domain.ItemRepository,
+	salesOrderRepository domain.SalesOrderRepository, locationRepository domain.LocationRepository,
+	inventoryUseCase domain.InventoryUseCase, transactor domain.Transactor, timeout time.Duration) domain.ReturnUseCase {
+	return &returnUseCase{returnRepository: returnRepository, itemRepository: itemRepository,
+		salesOrderRepository: salesOrderRepository, locationRepository: locationRepository,
+		inventoryUseCase: inventoryUseCase, transactor: transactor, timeout: timeout}
+}
+
+func (r *returnUseCase) GetAll(ctx context.Context, count int, offset int,
+	filter domain.ReturnFilter) ([]domain.Return, error) {
+	c, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	returns, err := r.returnRepository.GetAll(c, count, offset, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(returns) == 0 {
+		return nil, errors.NewNotFoundError("no returns found")
+	}
+
+	return returns, nil
+}
+
+func (r *returnUseCase) GetOne(ctx context.Context, id string) (*domain.Return, error) {
+	c, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	customerReturn, err := r.returnRepository.GetOne(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if customerReturn.ID == "" {
+		return nil, errors.NewNotFoundError("no such return found")
+	}
+
+	return customerReturn, nil
+}
+
+func (r *returnUseCase) Create(ctx context.Context, customerReturn *domain.Return) (*domain.Return, error) {
+	c, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	customerReturn.Reason = strings.TrimSpace(customerReturn.Reason)
+	if customerReturn.Quantity <= 0 {
+		return nil, errors.NewBadRequestError("invalid request. Quantity must be more than 0")
+	}
+	if customerReturn.Reason == "" {
+		return nil, errors.NewBadRequestError("invalid request. Reason is required")
+	}
+	if !customerReturn.Condition.IsValid() {
+		return nil, errors.NewBadRequestError(
+			"invalid request. Condition must be one of new, opened, used, damaged or defective")
+	}
+
+	item, err := r.itemRepository.GetOne(c, customerReturn.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	if item.ID == "" {
+		return nil, errors.NewBadRequestError("no item with such ID exists")
+	}
+	factor, ok := item.UnitFactor(customerReturn.Unit)
+	if !ok {
+		return nil, errors.NewBadRequestError("invalid request. Item " + customerReturn.ItemID + " isn't counted in " +
+			customerReturn.Unit)
+	}
+	if customerReturn.Quantity, ok = domain.ScaleQuantity(customerReturn.Quantity, factor); !ok {
+		return nil, errors.NewBadRequestError("invalid request. Quantity is too large for item " +
+			customerReturn.ItemID)
+	}
+	customerReturn.Unit = ""
+
+	customerReturn.ID = uuid.NewString()
+	customerReturn.Status = domain.ReturnPending
+	customerReturn.LocationID = ""
+	customerReturn.CreatedAt = time.Now()
+	customerReturn.UpdatedAt = customerReturn.CreatedAt
+	customerReturn.InspectedAt = nil
+	err = r.transactor.WithinTransaction(c, func(c context.Context) error {
+		if customerReturn.SalesOrderID != "" {
+			if err := r.checkReturnable(c, customerReturn); err != nil {
+				return err
+			}
+		}
+
+		_, err := r.returnRepository.Save(c, customerReturn)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return customerReturn, nil
+}
+
+// checkReturnable makes sure the sales order shipped at least the units returned for it so far, these included
+func (r *returnUseCase) checkReturnable(c context.Context, customerReturn *domain.Return) error {
+	// the order is locked so that returns made for it at the same time are counted one after the other
+	order, err := r.salesOrderRepository.GetOneForUpdate(c, customerReturn.SalesOrderID)
+	if err != nil {
+		return err
+	}
+	if order.ID == "" {
+		return errors.NewBadRequestError("no sales order with such ID exists")
+	}
+	if order.Status != domain.SalesOrderShipped {
+		return errors.NewConflictError("only shipped sales orders can be returned, this one is " +
+			string(order.Status))
+	}
+
+	shipped := 0
+	for _, line := range order.Lines {
+		if line.ItemID == customerReturn.ItemID {
+			shipped += line.Quantity
+		}
+	}
+	if shipped == 0 {
+		return errors.NewBadRequestError("the sales order has no line for item " + customerReturn.ItemID)
+	}
+
+	returned, err := r.returnRepository.Returned(c, order.ID, customerReturn.ItemID)
+	if err != nil {
+		return err
+	}
+	if returned+customerReturn.Quantity > shipped {
+		return errors.NewConflictError("can't return " + strconv.Itoa(customerReturn.Quantity) + " of item " +
+			customerReturn.ItemID + ", only " + strconv.Itoa(shipped-returned) + " shipped and not yet returned")
+	}
+	return nil
+}
+
+func (r *returnUseCase) Inspect(ctx context.Context, id string,
+	inspection domain.ReturnInspection) (*domain.Return, error) {
+	c, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var status domain.ReturnStatus
+	var locationType domain.LocationType
+	switch inspection.Disposition {
+	case domain.DispositionRestock:
+		status, locationType = domain.ReturnRestocked, domain.LocationStorage
+		if inspection.LocationID == "" {
+			inspection.LocationID = domain.DefaultLocationID
+		}
+	case domain.DispositionQuarantine, domain.DispositionWriteOff:
+		status, locationType = domain.ReturnQuarantined, domain.LocationQuarantine
+		if inspection.Disposition == domain.DispositionWriteOff {
+			status = domain.ReturnWrittenOff
+		}
+		if inspection.LocationID == "" {
+			inspection.LocationID = domain.QuarantineLocationID
+		}
+	default:
+		return nil, errors.NewBadRequestError("invalid request. Disposition must be restock, quarantine or write_off")
+	}
+	if inspection.Condition != "" && !inspection.Condition.IsValid() {
+		return nil, errors.NewBadRequestError(
+			"invalid request. Condition must be one of new, opened, used, damaged or defective")
+	}
+
+	var customerReturn *domain.Return
+	err := r.transactor.WithinTransaction(c, func(c context.Context) error {
+		var err error
+		customerReturn, err = r.returnRepository.GetOneForUpdate(c, id)
+		if err != nil {
+			return err
+		}
+		if customerReturn.ID == "" {
+			return errors.NewNotFoundError("no such return found")
+		}
+		if customerReturn.Status != domain.ReturnPending {
+			return errors.NewConflictError("only pending returns can be inspected, this one is " +
+				string(customerReturn.Status))
+		}
+
+		location, err := r.locationRepository.GetOne(c, inspection.LocationID)
+		if err != nil {
+			return err
+		}
+		if location.ID == "" {
+			return errors.NewBadRequestError("no location with such ID exists")
+		}
+		if location.Type != locationType {
+			return errors.NewBadRequestError("returns can't be " + string(status) + " to location " + location.ID +
+				", it isn't a " + string(locationType) + " location")
+		}
+
+		_, err = r.inventoryUseCase.MoveStock(c, customerReturn.ItemID, location.ID, domain.StockAdjustment{
+			Delta: customerReturn.Quantity, Reason: domain.ReasonReturned, Serials: inspection.Serials})
+		if err != nil {
+			return err
+		}
+		if inspection.Disposition == domain.DispositionWriteOff {
+			_, err = r.inventoryUseCase.MoveStock(c, customerReturn.ItemID, location.ID, domain.StockAdjustment{
+				Delta: -customerReturn.Quantity, Reason: domain.ReasonWrittenOff, Serials: inspection.Serials})
+			if err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		customerReturn.Status = status
+		customerReturn.LocationID = location.ID
+		if inspection.Condition != "" {
+			customerReturn.Condition = inspection.Condition
+		}
+		if notes := strings.TrimSpace(inspection.Notes); notes != "" {
+			customerReturn.Notes = notes
+		}
+		customerReturn.InspectedAt = &now
+		customerReturn.UpdatedAt = now
+		_, err = r.returnRepository.Edit(c, customerReturn)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return customerReturn, nil
+}
+
+func (r *returnUseCase) WriteOffs(ctx context.Context, from time.Time, to time.Time) ([]domain.WriteOff, error) {
+	c, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultReportPeriod)
+	}
+	if !from.Before(to) {
+		return nil, errors.NewBadRequestError("invalid request. from must be before to")
+	}
+
+	writeOffs, err := r.returnRepository.WriteOffs(c, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(writeOffs) == 0 {
+		return nil, errors.NewNotFoundError("no write-offs found")
+	}
+
+	return writeOffs, nil
+}
@@ -0,0 +1,174 @@
+package usecase
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/inventory/repository"
+	usecase2 "github.com/nuzurie/shopify/inventory/usecase"
+	repository2 "github.com/nuzurie/shopify/item/repository"
+	repository3 "github.com/nuzurie/shopify/location/repository"
+	repository4 "github.com/nuzurie/shopify/lot/repository"
+	repository5 "github.com/nuzurie/shopify/reservation/repository"
+	repository9 "github.com/nuzurie/shopify/returns/repository"
+	repository8 "github.com/nuzurie/shopify/salesorder/repository"
+	repository6 "github.com/nuzurie/shopify/serial/repository"
+	"github.com/nuzurie/shopify/utils/errors"
+	repository7 "github.com/nuzurie/shopify/valuation/repository"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestReturnShippedOnly(t *testing.T) {
+	returnUseCase, _ := newReturnUseCase(t)
+	ctx := context.Background()
+
+	// the shipped order sent 5 hats and a scarf, the packed one hasn't left yet
+	cases := []struct {
+		name           string
+		customerReturn domain.Return
+		want           int
+	}{
+		{"some", domain.Return{ItemID: "hat", SalesOrderID: "shipped", Quantity: 3}, 0},
+		{"more than left", domain.Return{ItemID: "hat", SalesOrderID: "shipped", Quantity: 3}, http.StatusConflict},
+		{"the rest", domain.Return{ItemID: "hat", SalesOrderID: "shipped", Quantity: 2}, 0},
+		{"once all is returned", domain.Return{ItemID: "hat", SalesOrderID: "shipped", Quantity: 1},
+			http.StatusConflict},
+		{"another item", domain.Return{ItemID: "scarf", SalesOrderID: "shipped", Quantity: 1}, 0},
+		{"an item not on the order", domain.Return{ItemID: "glove", SalesOrderID: "shipped", Quantity: 1},
+			http.StatusBadRequest},
+		{"an order not shipped", domain.Return{ItemID: "hat", SalesOrderID: "packed", Quantity: 1},
+			http.StatusConflict},
+		{"an unknown order", domain.Return{ItemID: "hat", SalesOrderID: "missing", Quantity: 1},
+			http.StatusBadRequest},
+		{"without an order", domain.Return{ItemID: "hat", Quantity: 8}, 0},
+		{"an unknown item", domain.Return{ItemID: "missing", Quantity: 1}, http.StatusBadRequest},
+		{"no quantity", domain.Return{ItemID: "hat"}, http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		customerReturn := c.customerReturn
+		customerReturn.Reason = "too small"
+		customerReturn.Condition = domain.ConditionNew
+		created, err := returnUseCase.Create(ctx, &customerReturn)
+		if got := statusOf(err); got != c.want {
+			t.Errorf("%s: Create failed with %d (%v), want %d", c.name, got, err, c.want)
+			continue
+		}
+		if err == nil && created.Status != domain.ReturnPending {
+			t.Errorf("%s: return is %s, want %s", c.name, created.Status, domain.ReturnPending)
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	cases := []struct {
+		name       string
+		inspection domain.ReturnInspection
+		want       int
+		status     domain.ReturnStatus
+		location   string
+		quantity   int
+	}{
+		{"restock", domain.ReturnInspection{Disposition: domain.DispositionRestock}, 0, domain.ReturnRestocked,
+			domain.DefaultLocationID, 2},
+		{"quarantine", domain.ReturnInspection{Disposition: domain.DispositionQuarantine}, 0,
+			domain.ReturnQuarantined, domain.QuarantineLocationID, 2},
+		{"write off", domain.ReturnInspection{Disposition: domain.DispositionWriteOff}, 0, domain.ReturnWrittenOff,
+			domain.QuarantineLocationID, 0},
+		{"restock into quarantine", domain.ReturnInspection{Disposition: domain.DispositionRestock,
+			LocationID: domain.QuarantineLocationID}, http.StatusBadRequest, domain.ReturnPending, "", 0},
+		{"unknown disposition", domain.ReturnInspection{Disposition: "resell"}, http.StatusBadRequest,
+			domain.ReturnPending, "", 0},
+	}
+	for _, c := range cases {
+		returnUseCase, inventoryUseCase := newReturnUseCase(t)
+		ctx := context.Background()
+		customerReturn, err := returnUseCase.Create(ctx, &domain.Return{ItemID: "hat", SalesOrderID: "shipped",
+			Quantity: 2, Reason: "too small", Condition: domain.ConditionOpened})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = returnUseCase.Inspect(ctx, customerReturn.ID, c.inspection)
+		if got := statusOf(err); got != c.want {
+			t.Errorf("%s: Inspect failed with %d (%v), want %d", c.name, got, err, c.want)
+		}
+		if customerReturn, err = returnUseCase.GetOne(ctx, customerReturn.ID); err != nil {
+			t.Fatal(err)
+		}
+		if customerReturn.Status != c.status || customerReturn.LocationID != c.location {
+			t.Errorf("%s: return is %s at %q, want %s at %q", c.name, customerReturn.Status,
+				customerReturn.LocationID, c.status, c.location)
+		}
+
+		quantity := 0
+		stock, err := inventoryUseCase.GetInventoryForItem(ctx, "hat")
+		if err == nil {
+			quantity = stock.Quantity
+		} else if statusOf(err) != http.StatusNotFound {
+			t.Fatal(err)
+		}
+		if quantity != c.quantity {
+			t.Errorf("%s: %d hats in stock, want %d", c.name, quantity, c.quantity)
+		}
+
+		if c.status != domain.ReturnPending {
+			if _, err = returnUseCase.Inspect(ctx, customerReturn.ID, c.inspection); statusOf(err) != http.StatusConflict {
+				t.Errorf("%s: inspecting twice failed with %v, want %d", c.name, err, http.StatusConflict)
+			}
+		}
+		writeOffs, err := returnUseCase.WriteOffs(ctx, time.Time{}, time.Time{})
+		writtenOff := c.status == domain.ReturnWrittenOff
+		if writtenOff && (err != nil || len(writeOffs) != 1 || writeOffs[0].Quantity != 2) {
+			t.Errorf("%s: WriteOffs = %+v, %v, want 2 hats", c.name, writeOffs, err)
+		}
+		if !writtenOff && statusOf(err) != http.StatusNotFound {
+			t.Errorf("%s: WriteOffs = %+v, %v, want none", c.name, writeOffs, err)
+		}
+	}
+}
+
+// ignoreStockChanges is a StockWatcher for tests that don't evaluate reorder points
+type ignoreStockChanges struct{}
+
+func (ignoreStockChanges) StockChanged(string) {}
+
+// newReturnUseCase works on a store holding a shipped sales order for 5 hats and a scarf, and a packed one for a hat.
+// None of them are in stock
+func newReturnUseCase(t *testing.T) (domain.ReturnUseCase, domain.InventoryUseCase) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	itemRepository := repository2.NewMemoryItemRepository(store)
+	for _, name := range []string{"hat", "scarf", "glove"} {
+		if _, err := itemRepository.Save(ctx, &domain.Item{ID: name, Name: name, Version: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.SalesOrders["shipped"] = domain.SalesOrder{ID: "shipped", Customer: "ada",
+		LocationID: domain.DefaultLocationID, Status: domain.SalesOrderShipped,
+		Lines: []domain.SalesOrderLine{{ItemID: "hat", Quantity: 5}, {ItemID: "scarf", Quantity: 1}}}
+	store.SalesOrders["packed"] = domain.SalesOrder{ID: "packed", Customer: "ada",
+		LocationID: domain.DefaultLocationID, Status: domain.SalesOrderPacked,
+		Lines: []domain.SalesOrderLine{{ItemID: "hat", Quantity: 1}}}
+
+	locationRepository := repository3.NewMemoryLocationRepository(store)
+	inventoryUseCase := usecase2.NewInventoryUseCase(itemRepository, repository.NewMemoryInventoryRepository(store),
+		repository.NewMemoryMovementRepository(store), repository5.NewMemoryReservationRepository(store),
+		locationRepository, repository4.NewMemoryLotRepository(store), repository6.NewMemorySerialRepository(store),
+		repository7.NewMemoryCostRepository(store), ignoreStockChanges{}, store, time.Second)
+	return NewReturnUseCase(repository9.NewMemoryReturnRepository(store), itemRepository,
+		repository8.NewMemorySalesOrderRepository(store), locationRepository, inventoryUseCase, store,
+		time.Second), inventoryUseCase
+}
+
+// statusOf is the status code of err, or 0 if there is none
+func statusOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	if restError, ok := err.(*errors.RestError); ok {
+		return restError.Code
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"sort"
+)
+
+type memorySalesOrderRepository struct {
+	store *memory.Store
+}
+
+// NewMemorySalesOrderRepository keeps sales orders in store instead of a database. store must be shared with the
+// location repository so orders can only reference existing locations
+func NewMemorySalesOrderRepository(store *memory.Store) domain.SalesOrderRepository {
+	return &memorySalesOrderRepository{store: store}
+}
+
+func (s *memorySalesOrderRepository) GetAll(ctx context.Context, count int, offset int, customer string,
+	status domain.SalesOrderStatus) ([]domain.SalesOrder, error) {
+	defer s.store.Read(ctx)()
+
+	var orders []domain.SalesOrder
+	for _, order := range s.store.SalesOrders {
+		if (customer == "" || order.Customer == customer) && (status == "" || order.Status == status) {
+			orders = append(orders, copySalesOrder(order))
+		}
+	}
+	sort.Slice(orders, func(a, b int) bool {
+		if !orders[a].CreatedAt.Equal(orders[b].CreatedAt) {
+			return orders[a].CreatedAt.After(orders[b].CreatedAt)
+		}
+		return orders[a].ID < orders[b].ID
+	})
+
+	start, end := memory.Page(len(orders), count, offset)
+	return orders[start:end], nil
+}
+
+func (s *memorySalesOrderRepository) GetOne(ctx context.Context, id string) (*domain.SalesOrder, error) {
+	defer s.store.Read(ctx)()
+
+	order := copySalesOrder(s.store.SalesOrders[id])
+	return &order, nil
+}
+
+// GetOneForUpdate needs no lock of its own, transactions hold the whole store
+func (s *memorySalesOrderRepository) GetOneForUpdate(ctx context.Context, id string) (*domain.SalesOrder, error) {
+	return s.GetOne(ctx, id)
+}
+
+func (s *memorySalesOrderRepository) Save(ctx context.Context, order *domain.SalesOrder) (*domain.SalesOrder, error) {
+	defer s.store.Write(ctx)()
+
+	if _, ok := s.store.Locations[order.LocationID]; !ok {
+		return nil, errors.NewBadRequestError("no location with such ID exists")
+	}
+	if _, ok := s.store.SalesOrders[order.ID]; ok {
+		return nil, errors.NewConflictError("sales order already exists")
+	}
+	s.store.SalesOrders[order.ID] = copySalesOrder(*order)
+	return order, nil
+}
+
+func (s *memorySalesOrderRepository) Edit(ctx context.Context, order *domain.SalesOrder) (*domain.SalesOrder, error) {
+	defer s.store.Write(ctx)()
+
+	if existing, ok := s.store.SalesOrders[order.ID]; ok {
+		existing.Status = order.Status
+		existing.UpdatedAt = order.UpdatedAt
+		existing.ShippedAt = order.ShippedAt
+		existing.CancelledAt = order.CancelledAt
+		s.store.SalesOrders[order.ID] = existing
+	}
+	return order, nil
+}
+
+// copySalesOrder keeps the lines held by the store from being changed through the orders handed out
+func copySalesOrder(order domain.SalesOrder) domain.SalesOrder {
+	order.Lines = append([]domain.SalesOrderLine(nil), order.Lines...)
+	return order
+}
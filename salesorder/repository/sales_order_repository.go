@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/nuzurie/shopify/db"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"strings"
+)
+
+type salesOrderRepository struct {
+	db db.DB
+}
+
+const (
+	getAll = `SELECT id, customer, location_id, status, created_at, updated_at, shipped_at, cancelled_at
+			FROM sales_order WHERE %s ORDER BY created_at DESC, id LIMIT $%d OFFSET $%d`
+	getByID = `SELECT id, customer, location_id, status, created_at, updated_at, shipped_at, cancelled_at
+			FROM sales_order WHERE id=$1`
+	getLines = `SELECT item_id, quantity, reservation_id FROM sales_order_line WHERE sales_order_id=$1 ORDER BY item_id`
+	save     = `INSERT INTO sales_order (id, customer, location_id, status, created_at, updated_at, shipped_at,
+			cancelled_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	saveLine = `INSERT INTO sales_order_line (sales_order_id, item_id, quantity, reservation_id)
+			VALUES ($1, $2, $3, $4)`
+	update = `UPDATE sales_order SET status=$2, updated_at=$3, shipped_at=$4, cancelled_at=$5 WHERE id=$1`
+	// SQLite has no row locks, but it only ever runs one transaction at a time
+	forUpdate = ` FOR UPDATE`
+)
+
+// NewSalesOrderRepository stores sales orders in a SQL database, Postgres or SQLite. The schema must be migrated
+func NewSalesOrderRepository(database db.DB) domain.SalesOrderRepository {
+	return &salesOrderRepository{db: database}
+}
+
+func (s *salesOrderRepository) GetAll(ctx context.Context, count int, offset int, customer string,
+	status domain.SalesOrderStatus) ([]domain.SalesOrder, error) {
+	conditions, args := []string{"1=1"}, []interface{}{}
+	if customer != "" {
+		args = append(args, customer)
+		conditions = append(conditions, fmt.Sprintf("customer=$%d", len(args)))
+	}
+	if status != "" {
+		args = append(args, status)
+		conditions = append(conditions, fmt.Sprintf("status=$%d", len(args)))
+	}
+	query := fmt.Sprintf(getAll, strings.Join(conditions, " AND "), len(args)+1, len(args)+2)
+	rows, err := s.db.Query(ctx, query, append(args, count, offset)...)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var orders []domain.SalesOrder
+	for rows.Next() {
+		var order domain.SalesOrder
+		err = rows.Scan(&order.ID, &order.Customer, &order.LocationID, &order.Status, &order.CreatedAt,
+			&order.UpdatedAt, &order.ShippedAt, &order.CancelledAt)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		orders = append(orders, order)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	rows.Close()
+
+	for index := range orders {
+		if orders[index].Lines, err = s.getLines(ctx, orders[index].ID); err != nil {
+			return nil, err
+		}
+	}
+	return orders, nil
+}
+
+func (s *salesOrderRepository) GetOne(ctx context.Context, id string) (*domain.SalesOrder, error) {
+	return s.getOne(ctx, getByID, id)
+}
+
+func (s *salesOrderRepository) GetOneForUpdate(ctx context.Context, id string) (*domain.SalesOrder, error) {
+	query := getByID
+	if s.db.Dialect() == domain.Postgres {
+		query += forUpdate
+	}
+	return s.getOne(ctx, query, id)
+}
+
+func (s *salesOrderRepository) getOne(ctx context.Context, query string, id string) (*domain.SalesOrder, error) {
+	var order domain.SalesOrder
+	err := s.db.QueryRow(ctx, query, id).
+		Scan(&order.ID, &order.Customer, &order.LocationID, &order.Status, &order.CreatedAt, &order.UpdatedAt,
+			&order.ShippedAt, &order.CancelledAt)
+	if err == db.ErrNoRows {
+		return &order, nil
+	}
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	if order.Lines, err = s.getLines(ctx, order.ID); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (s *salesOrderRepository) getLines(ctx context.Context, orderID string) ([]domain.SalesOrderLine, error) {
+	rows, err := s.db.Query(ctx, getLines, orderID)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var lines []domain.SalesOrderLine
+	for rows.Next() {
+		var line domain.SalesOrderLine
+		if err = rows.Scan(&line.ItemID, &line.Quantity, &line.ReservationID); err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		lines = append(lines, line)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return lines, nil
+}
+
+func (s *salesOrderRepository) Save(ctx context.Context, order *domain.SalesOrder) (*domain.SalesOrder, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, save, order.ID, order.Customer, order.LocationID, order.Status, order.CreatedAt,
+		order.UpdatedAt, order.ShippedAt, order.CancelledAt)
+	if err != nil {
+		if db.IsForeignKeyViolation(err) {
+			return nil, errors.NewBadRequestError("no location with such ID exists")
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	for _, line := range order.Lines {
+		_, err = tx.Exec(ctx, saveLine, order.ID, line.ItemID, line.Quantity, line.ReservationID)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return order, nil
+}
+
+func (s *salesOrderRepository) Edit(ctx context.Context, order *domain.SalesOrder) (*domain.SalesOrder, error) {
+	_, err := s.db.Exec(ctx, update, order.ID, order.Status, order.UpdatedAt, order.ShippedAt, order.CancelledAt)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return order, nil
+}
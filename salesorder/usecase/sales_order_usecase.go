@@ -0,0 +1,248 @@
+package usecase
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"strings"
+	"time"
+)
+
+type salesOrderUseCase struct {
+	salesOrderRepository domain.SalesOrderRepository
+	itemRepository       domain.ItemRepository
+	locationRepository   domain.LocationRepository
+	reservationUseCase   domain.ReservationUseCase
+	transactor           domain.Transactor
+	timeout              time.Duration
+}
+
+// NewSalesOrderUseCase allocates and ships stock through reservationUseCase, in the same transaction as the change to
+// the order. Lines ordered in another unit of an item are converted to its base unit with the units in itemRepository
+func NewSalesOrderUseCase(salesOrderRepository domain.SalesOrderRepository, itemRepository domain.ItemRepository,
+	locationRepository domain.LocationRepository, reservationUseCase domain.ReservationUseCase,
+	transactor domain.Transactor, timeout time.Duration) domain.SalesOrderUseCase {
+	return &salesOrderUseCase{salesOrderRepository: salesOrderRepository, itemRepository: itemRepository,
+		locationRepository: locationRepository, reservationUseCase: reservationUseCase, transactor: transactor,
+		timeout: timeout}
+}
+
+func (s *salesOrderUseCase) GetAll(ctx context.Context, count int, offset int, customer string,
+	status domain.SalesOrderStatus) ([]domain.SalesOrder, error) {
+	c, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	orders, err := s.salesOrderRepository.GetAll(c, count, offset, customer, status)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, errors.NewNotFoundError("no sales orders found")
+	}
+
+	return orders, nil
+}
+
+func (s *salesOrderUseCase) GetOne(ctx context.Context, id string) (*domain.SalesOrder, error) {
+	c, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	order, err := s.salesOrderRepository.GetOne(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.ID == "" {
+		return nil, errors.NewNotFoundError("no such sales order found")
+	}
+
+	return order, nil
+}
+
+func (s *salesOrderUseCase) Create(ctx context.Context, order *domain.SalesOrder) (*domain.SalesOrder, error) {
+	c, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	order.Customer = strings.TrimSpace(order.Customer)
+	if order.Customer == "" {
+		return nil, errors.NewBadRequestError("invalid request. Customer is required")
+	}
+	if order.LocationID == "" {
+		order.LocationID = domain.DefaultLocationID
+	}
+	if len(order.Lines) == 0 {
+		return nil, errors.NewBadRequestError("invalid request. A sales order needs at least one line")
+	}
+	location, err := s.locationRepository.GetOne(c, order.LocationID)
+	if err != nil {
+		return nil, err
+	}
+	if location.ID == "" {
+		return nil, errors.NewBadRequestError("no location with ID " + order.LocationID + " exists")
+	}
+
+	seen := map[string]bool{}
+	for index, line := range order.Lines {
+		if line.Quantity <= 0 {
+			return nil, errors.NewBadRequestError("invalid request. Quantity must be more than 0")
+		}
+		if seen[line.ItemID] {
+			return nil, errors.NewBadRequestError("invalid request. Item " + line.ItemID + " is on more than one line")
+		}
+		seen[line.ItemID] = true
+
+		item, err := s.itemRepository.GetOne(c, line.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		if item.ID == "" {
+			return nil, errors.NewBadRequestError("no item with ID " + line.ItemID + " exists")
+		}
+		factor, ok := item.UnitFactor(line.Unit)
+		if !ok {
+			return nil, errors.NewBadRequestError("invalid request. Item " + line.ItemID + " isn't counted in " +
+				line.Unit)
+		}
+		if line.Quantity, ok = domain.ScaleQuantity(line.Quantity, factor); !ok {
+			return nil, errors.NewBadRequestError("invalid request. Quantity is too large for item " + line.ItemID)
+		}
+		order.Lines[index] = domain.SalesOrderLine{ItemID: line.ItemID, Quantity: line.Quantity}
+	}
+
+	order.ID = uuid.NewString()
+	order.Status = domain.SalesOrderAllocated
+	order.CreatedAt = time.Now()
+	order.UpdatedAt = order.CreatedAt
+	order.ShippedAt = nil
+	order.CancelledAt = nil
+	err = s.transactor.WithinTransaction(c, func(c context.Context) error {
+		for index, line := range order.Lines {
+			reservation, err := s.reservationUseCase.Allocate(c, &domain.Reservation{ItemID: line.ItemID,
+				LocationID: order.LocationID, Quantity: line.Quantity, Owner: owner(order.ID)})
+			if err != nil {
+				return err
+			}
+			order.Lines[index].ReservationID = reservation.ID
+		}
+
+		_, err := s.salesOrderRepository.Save(c, order)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func (s *salesOrderUseCase) Pick(ctx context.Context, id string) (*domain.SalesOrder, error) {
+	return s.advance(ctx, id, domain.SalesOrderAllocated, domain.SalesOrderPicked, nil)
+}
+
+func (s *salesOrderUseCase) Pack(ctx context.Context, id string) (*domain.SalesOrder, error) {
+	return s.advance(ctx, id, domain.SalesOrderPicked, domain.SalesOrderPacked, nil)
+}
+
+func (s *salesOrderUseCase) Ship(ctx context.Context, id string,
+	shipment domain.SalesOrderShipment) (*domain.SalesOrder, error) {
+	return s.advance(ctx, id, domain.SalesOrderPacked, domain.SalesOrderShipped,
+		func(c context.Context, order *domain.SalesOrder) error {
+			shipped := map[string]bool{}
+			for _, line := range order.Lines {
+				shipped[line.ItemID] = true
+			}
+			for itemID := range shipment.Serials {
+				if !shipped[itemID] {
+					return errors.NewBadRequestError("invalid request. Item " + itemID + " isn't on the sales order")
+				}
+			}
+
+			for _, line := range order.Lines {
+				confirmation := domain.ReservationConfirmation{Serials: shipment.Serials[line.ItemID]}
+				if _, err := s.reservationUseCase.Confirm(c, line.ReservationID, confirmation); err != nil {
+					return err
+				}
+			}
+
+			now := time.Now()
+			order.ShippedAt = &now
+			return nil
+		})
+}
+
+func (s *salesOrderUseCase) Cancel(ctx context.Context, id string) (*domain.SalesOrder, error) {
+	return s.update(ctx, id, func(c context.Context, order *domain.SalesOrder) error {
+		switch order.Status {
+		case domain.SalesOrderAllocated, domain.SalesOrderPicked, domain.SalesOrderPacked:
+		default:
+			return errors.NewConflictError("only sales orders that haven't shipped can be cancelled, this one is " +
+				string(order.Status))
+		}
+
+		for _, line := range order.Lines {
+			if _, err := s.reservationUseCase.Release(c, line.ReservationID); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		order.Status = domain.SalesOrderCancelled
+		order.CancelledAt = &now
+		return nil
+	})
+}
+
+// advance moves an order from one status to the next, running apply in the same transaction
+func (s *salesOrderUseCase) advance(ctx context.Context, id string, from domain.SalesOrderStatus,
+	to domain.SalesOrderStatus, apply func(c context.Context, order *domain.SalesOrder) error) (*domain.SalesOrder, error) {
+	return s.update(ctx, id, func(c context.Context, order *domain.SalesOrder) error {
+		if order.Status != from {
+			return errors.NewConflictError("only " + string(from) + " sales orders can be " + string(to) +
+				", this one is " + string(order.Status))
+		}
+
+		if apply != nil {
+			if err := apply(c, order); err != nil {
+				return err
+			}
+		}
+		order.Status = to
+		return nil
+	})
+}
+
+// update applies change to the sales order with the given id and saves it, in one transaction with the stock it moves
+func (s *salesOrderUseCase) update(ctx context.Context, id string,
+	change func(c context.Context, order *domain.SalesOrder) error) (*domain.SalesOrder, error) {
+	c, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var order *domain.SalesOrder
+	err := s.transactor.WithinTransaction(c, func(c context.Context) error {
+		var err error
+		order, err = s.salesOrderRepository.GetOneForUpdate(c, id)
+		if err != nil {
+			return err
+		}
+		if order.ID == "" {
+			return errors.NewNotFoundError("no such sales order found")
+		}
+
+		if err = change(c, order); err != nil {
+			return err
+		}
+		order.UpdatedAt = time.Now()
+		_, err = s.salesOrderRepository.Edit(c, order)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// owner is who the reservations allocating the stock of an order are held for
+func owner(orderID string) string {
+	return "sales_order:" + orderID
+}
@@ -0,0 +1,104 @@
+package usecase
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/inventory/repository"
+	usecase2 "github.com/nuzurie/shopify/inventory/usecase"
+	repository2 "github.com/nuzurie/shopify/item/repository"
+	repository3 "github.com/nuzurie/shopify/location/repository"
+	repository4 "github.com/nuzurie/shopify/lot/repository"
+	repository5 "github.com/nuzurie/shopify/reservation/repository"
+	usecase3 "github.com/nuzurie/shopify/reservation/usecase"
+	repository8 "github.com/nuzurie/shopify/salesorder/repository"
+	repository6 "github.com/nuzurie/shopify/serial/repository"
+	"github.com/nuzurie/shopify/utils/errors"
+	repository7 "github.com/nuzurie/shopify/valuation/repository"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCreate(t *testing.T) {
+	cases := []struct {
+		name       string
+		locationID string
+		lines      []domain.SalesOrderLine
+		want       int
+		// allocated is the quantity of hats reserved for the order
+		allocated int
+	}{
+		{"in the base unit", "", []domain.SalesOrderLine{{ItemID: "hat", Quantity: 4}}, 0, 4},
+		{"in another unit", "", []domain.SalesOrderLine{{ItemID: "hat", Quantity: 2, Unit: "pair"}}, 0, 4},
+		{"unknown unit", "", []domain.SalesOrderLine{{ItemID: "hat", Quantity: 2, Unit: "box"}},
+			http.StatusBadRequest, 0},
+		{"unknown item", "", []domain.SalesOrderLine{{ItemID: "hat", Quantity: 1}, {ItemID: "scarf", Quantity: 1}},
+			http.StatusBadRequest, 0},
+		{"unknown location", "west", []domain.SalesOrderLine{{ItemID: "hat", Quantity: 1}},
+			http.StatusBadRequest, 0},
+		{"more than in stock", "", []domain.SalesOrderLine{{ItemID: "hat", Quantity: 11}}, http.StatusConflict, 0},
+	}
+	for _, c := range cases {
+		salesOrderUseCase, inventoryUseCase := newSalesOrderUseCase(t)
+		ctx := context.Background()
+
+		_, err := salesOrderUseCase.Create(ctx, &domain.SalesOrder{Customer: "Jo", LocationID: c.locationID,
+			Lines: c.lines})
+		if got := statusOf(err); got != c.want {
+			t.Errorf("%s: Create failed with %d (%v), want %d", c.name, got, err, c.want)
+		}
+		stock, err := inventoryUseCase.GetInventoryForItem(ctx, "hat")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stock.Reserved != c.allocated {
+			t.Errorf("%s: %d hats reserved, want %d", c.name, stock.Reserved, c.allocated)
+		}
+	}
+}
+
+// ignoreStockChanges is a StockWatcher for tests that don't evaluate reorder points
+type ignoreStockChanges struct{}
+
+func (ignoreStockChanges) StockChanged(string) {}
+
+// newSalesOrderUseCase works on a store holding 10 hats at the default location, also counted in pairs
+func newSalesOrderUseCase(t *testing.T) (domain.SalesOrderUseCase, domain.InventoryUseCase) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	itemRepository := repository2.NewMemoryItemRepository(store)
+	if _, err := itemRepository.Save(ctx, &domain.Item{ID: "hat", Name: "hat", BaseUnit: domain.DefaultBaseUnit,
+		Units: []domain.UnitConversion{{Unit: "pair", Factor: 2}}, Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	inventoryRepository := repository.NewMemoryInventoryRepository(store)
+	reservationRepository := repository5.NewMemoryReservationRepository(store)
+	locationRepository := repository3.NewMemoryLocationRepository(store)
+	lotRepository := repository4.NewMemoryLotRepository(store)
+	inventoryUseCase := usecase2.NewInventoryUseCase(itemRepository, inventoryRepository,
+		repository.NewMemoryMovementRepository(store), reservationRepository, locationRepository, lotRepository,
+		repository6.NewMemorySerialRepository(store), repository7.NewMemoryCostRepository(store), ignoreStockChanges{},
+		store, time.Second)
+	if _, err := inventoryUseCase.MoveStock(ctx, "hat", domain.DefaultLocationID,
+		domain.StockAdjustment{Delta: 10, Reason: domain.ReasonReceived}); err != nil {
+		t.Fatal(err)
+	}
+
+	reservationUseCase := usecase3.NewReservationUseCase(reservationRepository, itemRepository, inventoryRepository,
+		locationRepository, lotRepository, inventoryUseCase, ignoreStockChanges{}, store, time.Second)
+	return NewSalesOrderUseCase(repository8.NewMemorySalesOrderRepository(store), itemRepository, locationRepository,
+		reservationUseCase, store, time.Second), inventoryUseCase
+}
+
+// statusOf is the status code of err, or 0 if there is none
+func statusOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	if restError, ok := err.(*errors.RestError); ok {
+		return restError.Code
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"net/http"
+	"strconv"
+)
+
+type SerialHandler struct {
+	useCase domain.SerialUseCase
+}
+
+func NewSerialHandler(useCase domain.SerialUseCase) *SerialHandler {
+	return &SerialHandler{useCase: useCase}
+}
+
+func (h *SerialHandler) GetAll(c *gin.Context) {
+	var filter domain.SerialFilter
+	filter.ItemID, _ = c.GetQuery("item")
+	filter.SerialNumber, _ = c.GetQuery("serial_number")
+	filter.LocationID, _ = c.GetQuery("location")
+	status, _ := c.GetQuery("status")
+	filter.Status = domain.SerialStatus(status)
+
+	var count int64
+	if countQuery, ok := c.GetQuery("count"); ok {
+		count, _ = strconv.ParseInt(countQuery, 10, 64)
+	} else {
+		count = 20
+	}
+
+	var offset int64
+	if offsetQuery, ok := c.GetQuery("offset"); ok {
+		offset, _ = strconv.ParseInt(offsetQuery, 10, 64)
+	}
+
+	ctx := c.Request.Context()
+	serials, err := h.useCase.GetAll(ctx, int(count), int(offset), filter)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, serials)
+}
+
+func (h *SerialHandler) GetOne(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	serial, err := h.useCase.GetOne(ctx, id)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, serial)
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"sort"
+)
+
+type memorySerialRepository struct {
+	store *memory.Store
+}
+
+// NewMemorySerialRepository keeps serials in store instead of a database. store must be shared with the item
+// repository so serials go along with their item
+func NewMemorySerialRepository(store *memory.Store) domain.SerialRepository {
+	return &memorySerialRepository{store: store}
+}
+
+func (s *memorySerialRepository) GetAll(ctx context.Context, count int, offset int,
+	filter domain.SerialFilter) ([]domain.Serial, error) {
+	defer s.store.Read(ctx)()
+
+	var serials []domain.Serial
+	for _, serial := range s.store.Serials {
+		if (filter.ItemID == "" || serial.ItemID == filter.ItemID) &&
+			(filter.SerialNumber == "" || serial.SerialNumber == filter.SerialNumber) &&
+			(filter.Status == "" || serial.Status == filter.Status) &&
+			(filter.LocationID == "" || serial.LocationID == filter.LocationID) {
+			serials = append(serials, serial)
+		}
+	}
+	sortSerials(serials)
+
+	start, end := memory.Page(len(serials), count, offset)
+	return serials[start:end], nil
+}
+
+func (s *memorySerialRepository) GetOne(ctx context.Context, id string) (*domain.Serial, error) {
+	defer s.store.Read(ctx)()
+
+	serial := s.store.Serials[id]
+	return &serial, nil
+}
+
+// GetByNumbersForUpdate needs no lock of its own, transactions hold the whole store
+func (s *memorySerialRepository) GetByNumbersForUpdate(ctx context.Context, itemID string,
+	serialNumbers []string) ([]domain.Serial, error) {
+	defer s.store.Read(ctx)()
+
+	wanted := map[string]bool{}
+	for _, serialNumber := range serialNumbers {
+		wanted[serialNumber] = true
+	}
+	var serials []domain.Serial
+	for _, serial := range s.store.Serials {
+		if serial.ItemID == itemID && wanted[serial.SerialNumber] {
+			serials = append(serials, serial)
+		}
+	}
+	sortSerials(serials)
+	return serials, nil
+}
+
+func (s *memorySerialRepository) GetOnHand(ctx context.Context, itemID string,
+	locationID string) ([]domain.Serial, error) {
+	defer s.store.Read(ctx)()
+
+	var serials []domain.Serial
+	for _, serial := range s.store.Serials {
+		if serial.ItemID == itemID && serial.LocationID == locationID && serial.Status.IsOnHand() {
+			serials = append(serials, serial)
+		}
+	}
+	sortSerials(serials)
+	return serials, nil
+}
+
+func sortSerials(serials []domain.Serial) {
+	sort.Slice(serials, func(a, b int) bool {
+		if serials[a].ItemID != serials[b].ItemID {
+			return serials[a].ItemID < serials[b].ItemID
+		}
+		return serials[a].SerialNumber < serials[b].SerialNumber
+	})
+}
+
+func (s *memorySerialRepository) Save(ctx context.Context, serial *domain.Serial) (*domain.Serial, error) {
+	defer s.store.Write(ctx)()
+
+	if _, ok := s.store.Items[serial.ItemID]; !ok {
+		return nil, errors.NewBadRequestError("no item with such ID exists")
+	}
+	for _, existing := range s.store.Serials {
+		if existing.ItemID == serial.ItemID && existing.SerialNumber == serial.SerialNumber {
+			return nil, errors.NewConflictError("serial " + serial.SerialNumber + " of item " + serial.ItemID +
+				" already exists")
+		}
+	}
+	if _, ok := s.store.Serials[serial.ID]; ok {
+		return nil, errors.NewConflictError("serial already exists")
+	}
+	saved := *serial
+	saved.History = nil
+	s.store.Serials[serial.ID] = saved
+	return serial, nil
+}
+
+func (s *memorySerialRepository) Edit(ctx context.Context, serial *domain.Serial) (*domain.Serial, error) {
+	defer s.store.Write(ctx)()
+
+	if existing, ok := s.store.Serials[serial.ID]; ok {
+		existing.Status = serial.Status
+		existing.LocationID = serial.LocationID
+		existing.UpdatedAt = serial.UpdatedAt
+		s.store.Serials[serial.ID] = existing
+	}
+	return serial, nil
+}
+
+func (s *memorySerialRepository) SaveEvent(ctx context.Context,
+	event *domain.SerialEvent) (*domain.SerialEvent, error) {
+	defer s.store.Write(ctx)()
+
+	if _, ok := s.store.Serials[event.SerialID]; !ok {
+		return nil, errors.NewBadRequestError("no serial with such ID exists")
+	}
+	s.store.SerialEvents = append(s.store.SerialEvents, *event)
+	return event, nil
+}
+
+// GetHistory relies on events being appended in the order they happen
+func (s *memorySerialRepository) GetHistory(ctx context.Context, serialID string) ([]domain.SerialEvent, error) {
+	defer s.store.Read(ctx)()
+
+	var history []domain.SerialEvent
+	for _, event := range s.store.SerialEvents {
+		if event.SerialID == serialID {
+			history = append(history, event)
+		}
+	}
+	return history, nil
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/nuzurie/shopify/db"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"strings"
+)
+
+type serialRepository struct {
+	db db.DB
+}
+
+const (
+	getAll = `SELECT id, item_id, serial_number, status, location_id, created_at, updated_at FROM serial
+			WHERE %s ORDER BY item_id, serial_number LIMIT $%d OFFSET $%d`
+	getByID      = `SELECT id, item_id, serial_number, status, location_id, created_at, updated_at FROM serial WHERE id=$1`
+	getByNumbers = `SELECT id, item_id, serial_number, status, location_id, created_at, updated_at FROM serial
+			WHERE item_id=$1 AND serial_number IN (%s) ORDER BY serial_number`
+	getOnHand = `SELECT id, item_id, serial_number, status, location_id, created_at, updated_at FROM serial
+			WHERE item_id=$1 AND location_id=$2 AND status IN ('in_stock', 'returned') ORDER BY serial_number`
+	save = `INSERT INTO serial (id, item_id, serial_number, status, location_id, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	update    = `UPDATE serial SET status=$2, location_id=$3, updated_at=$4 WHERE id=$1`
+	saveEvent = `INSERT INTO serial_event (id, serial_id, status, location_id, reason, movement_id, actor, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	getHistory = `SELECT id, serial_id, status, location_id, reason, movement_id, actor, created_at FROM serial_event
+			WHERE serial_id=$1 ORDER BY created_at, id`
+	// SQLite has no row locks, but it only ever runs one transaction at a time
+	forUpdate = ` FOR UPDATE`
+)
+
+// NewSerialRepository stores serials and their history in a SQL database, Postgres or SQLite. The schema must be
+// migrated
+func NewSerialRepository(database db.DB) domain.SerialRepository {
+	return &serialRepository{db: database}
+}
+
+func (s *serialRepository) GetAll(ctx context.Context, count int, offset int,
+	filter domain.SerialFilter) ([]domain.Serial, error) {
+	conditions := []string{"1=1"}
+	var args []interface{}
+	if filter.ItemID != "" {
+		args = append(args, filter.ItemID)
+		conditions = append(conditions, fmt.Sprintf("item_id=$%d", len(args)))
+	}
+	if filter.SerialNumber != "" {
+		args = append(args, filter.SerialNumber)
+		conditions = append(conditions, fmt.Sprintf("serial_number=$%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status=$%d", len(args)))
+	}
+	if filter.LocationID != "" {
+		args = append(args, filter.LocationID)
+		conditions = append(conditions, fmt.Sprintf("location_id=$%d", len(args)))
+	}
+
+	query := fmt.Sprintf(getAll, strings.Join(conditions, " AND "), len(args)+1, len(args)+2)
+	return s.query(ctx, query, append(args, count, offset)...)
+}
+
+func (s *serialRepository) GetByNumbersForUpdate(ctx context.Context, itemID string,
+	serialNumbers []string) ([]domain.Serial, error) {
+	if len(serialNumbers) == 0 {
+		return nil, nil
+	}
+
+	args := []interface{}{itemID}
+	placeholders := make([]string, len(serialNumbers))
+	for index, serialNumber := range serialNumbers {
+		args = append(args, serialNumber)
+		placeholders[index] = fmt.Sprintf("$%d", len(args))
+	}
+	query := fmt.Sprintf(getByNumbers, strings.Join(placeholders, ", "))
+	if s.db.Dialect() == domain.Postgres {
+		query += forUpdate
+	}
+	return s.query(ctx, query, args...)
+}
+
+func (s *serialRepository) GetOnHand(ctx context.Context, itemID string, locationID string) ([]domain.Serial, error) {
+	return s.query(ctx, getOnHand, itemID, locationID)
+}
+
+func (s *serialRepository) query(ctx context.Context, query string, args ...interface{}) ([]domain.Serial, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var serials []domain.Serial
+	for rows.Next() {
+		serial, err := scan(rows)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		serials = append(serials, *serial)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return serials, nil
+}
+
+func (s *serialRepository) GetOne(ctx context.Context, id string) (*domain.Serial, error) {
+	serial, err := scan(s.db.QueryRow(ctx, getByID, id))
+	if err == db.ErrNoRows {
+		return &domain.Serial{}, nil
+	}
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return serial, nil
+}
+
+// scan reads a serial, whose location is NULL once it left
+func scan(row db.Row) (*domain.Serial, error) {
+	var serial domain.Serial
+	var locationID *string
+	err := row.Scan(&serial.ID, &serial.ItemID, &serial.SerialNumber, &serial.Status, &locationID, &serial.CreatedAt,
+		&serial.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if locationID != nil {
+		serial.LocationID = *locationID
+	}
+	return &serial, nil
+}
+
+func (s *serialRepository) Save(ctx context.Context, serial *domain.Serial) (*domain.Serial, error) {
+	_, err := s.db.Exec(ctx, save, serial.ID, serial.ItemID, serial.SerialNumber, serial.Status,
+		nullable(serial.LocationID), serial.CreatedAt, serial.UpdatedAt)
+	if err != nil {
+		if db.IsForeignKeyViolation(err) {
+			return nil, errors.NewBadRequestError("no item with such ID exists")
+		}
+		if db.IsUniqueViolation(err) {
+			return nil, errors.NewConflictError("serial " + serial.SerialNumber + " of item " + serial.ItemID +
+				" already exists")
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return serial, nil
+}
+
+func (s *serialRepository) Edit(ctx context.Context, serial *domain.Serial) (*domain.Serial, error) {
+	_, err := s.db.Exec(ctx, update, serial.ID, serial.Status, nullable(serial.LocationID), serial.UpdatedAt)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return serial, nil
+}
+
+func (s *serialRepository) SaveEvent(ctx context.Context, event *domain.SerialEvent) (*domain.SerialEvent, error) {
+	_, err := s.db.Exec(ctx, saveEvent, event.ID, event.SerialID, event.Status, event.LocationID, event.Reason,
+		event.MovementID, event.Actor, event.CreatedAt)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return event, nil
+}
+
+func (s *serialRepository) GetHistory(ctx context.Context, serialID string) ([]domain.SerialEvent, error) {
+	rows, err := s.db.Query(ctx, getHistory, serialID)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var history []domain.SerialEvent
+	for rows.Next() {
+		var event domain.SerialEvent
+		err = rows.Scan(&event.ID, &event.SerialID, &event.Status, &event.LocationID, &event.Reason,
+			&event.MovementID, &event.Actor, &event.CreatedAt)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		history = append(history, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return history, nil
+}
+
+// nullable stores an empty location as NULL
+func nullable(id string) interface{} {
+	if id == "" {
+		return nil
+	}
+	return id
+}
@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"time"
+)
+
+type serialUseCase struct {
+	serialRepository domain.SerialRepository
+	timeout          time.Duration
+}
+
+// NewSerialUseCase looks serials up. Serials are only ever created and moved by the stock movements of their item
+func NewSerialUseCase(serialRepository domain.SerialRepository, timeout time.Duration) domain.SerialUseCase {
+	return &serialUseCase{serialRepository: serialRepository, timeout: timeout}
+}
+
+func (s *serialUseCase) GetAll(ctx context.Context, count int, offset int,
+	filter domain.SerialFilter) ([]domain.Serial, error) {
+	c, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return nil, errors.NewBadRequestError("invalid status")
+	}
+
+	serials, err := s.serialRepository.GetAll(c, count, offset, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(serials) == 0 {
+		return nil, errors.NewNotFoundError("no serials found")
+	}
+
+	return serials, nil
+}
+
+func (s *serialUseCase) GetOne(ctx context.Context, id string) (*domain.Serial, error) {
+	c, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	serial, err := s.serialRepository.GetOne(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if serial.ID == "" {
+		return nil, errors.NewNotFoundError("no such serial found")
+	}
+
+	serial.History, err = s.serialRepository.GetHistory(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return serial, nil
+}
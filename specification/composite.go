@@ -0,0 +1,78 @@
+package specification
+
+import (
+	"github.com/nuzurie/shopify/domain"
+	"strings"
+)
+
+type composite struct {
+	operator string
+	specs    []domain.Specification
+}
+
+// And is satisfied when every spec is. An empty And matches everything
+func And(specs ...domain.Specification) domain.Specification {
+	return composite{operator: "AND", specs: specs}
+}
+
+// Or is satisfied when at least one spec is. An empty Or matches nothing
+func Or(specs ...domain.Specification) domain.Specification {
+	return composite{operator: "OR", specs: specs}
+}
+
+func (c composite) FilterQuery(dialect domain.Dialect, argIndex int) (string, []interface{}) {
+	if len(c.specs) == 0 {
+		if c.operator == "AND" {
+			return "1=1", nil
+		}
+		return "1=0", nil
+	}
+
+	var conditions []string
+	var args []interface{}
+	for _, spec := range c.specs {
+		query, specArgs := spec.FilterQuery(dialect, argIndex+len(args))
+		conditions = append(conditions, "("+query+")")
+		args = append(args, specArgs...)
+	}
+	return strings.Join(conditions, " "+c.operator+" "), args
+}
+
+func (c composite) IsSatisfiedBy(item domain.Item) bool {
+	return c.evaluate(record{item: item})
+}
+
+func (c composite) evaluate(r record) bool {
+	for _, spec := range c.specs {
+		satisfied := evaluate(spec, r)
+		if c.operator == "AND" && !satisfied {
+			return false
+		}
+		if c.operator == "OR" && satisfied {
+			return true
+		}
+	}
+	return c.operator == "AND"
+}
+
+type not struct {
+	spec domain.Specification
+}
+
+// Not is satisfied when spec isn't
+func Not(spec domain.Specification) domain.Specification {
+	return not{spec: spec}
+}
+
+func (n not) FilterQuery(dialect domain.Dialect, argIndex int) (string, []interface{}) {
+	query, args := n.spec.FilterQuery(dialect, argIndex)
+	return "NOT (" + query + ")", args
+}
+
+func (n not) IsSatisfiedBy(item domain.Item) bool {
+	return n.evaluate(record{item: item})
+}
+
+func (n not) evaluate(r record) bool {
+	return !evaluate(n.spec, r)
+}
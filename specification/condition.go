@@ -0,0 +1,141 @@
+package specification
+
+import (
+	"fmt"
+	"github.com/nuzurie/shopify/domain"
+	"strings"
+)
+
+type condition struct {
+	field    Field
+	operator string
+	value    interface{}
+}
+
+// Eq matches rows where field equals value
+func Eq(field Field, value interface{}) domain.Specification {
+	return condition{field: field, operator: "=", value: value}
+}
+
+// LessThan matches rows where field is strictly less than value
+func LessThan(field Field, value interface{}) domain.Specification {
+	return condition{field: field, operator: "<", value: value}
+}
+
+// GreaterThan matches rows where field is strictly greater than value
+func GreaterThan(field Field, value interface{}) domain.Specification {
+	return condition{field: field, operator: ">", value: value}
+}
+
+// Range matches rows where field lies between min and max, both inclusive. A nil bound leaves that side open
+func Range(field Field, min, max interface{}) domain.Specification {
+	var specs []domain.Specification
+	if min != nil {
+		specs = append(specs, condition{field: field, operator: ">=", value: min})
+	}
+	if max != nil {
+		specs = append(specs, condition{field: field, operator: "<=", value: max})
+	}
+	return And(specs...)
+}
+
+// Prefix matches rows where field starts with prefix, ignoring case. SQLite only folds the case of ASCII letters
+func Prefix(field Field, prefix string) domain.Specification {
+	return condition{field: field, operator: "ILIKE", value: likePattern(prefix) + "%"}
+}
+
+// Contains matches rows where field contains value, ignoring case. SQLite only folds the case of ASCII letters
+func Contains(field Field, value string) domain.Specification {
+	return condition{field: field, operator: "ILIKE", value: "%" + likePattern(value) + "%"}
+}
+
+func (c condition) FilterQuery(dialect domain.Dialect, argIndex int) (string, []interface{}) {
+	// SQLite has no ILIKE, but its LIKE already ignores case. It has no default escape character though
+	if c.operator == "ILIKE" && dialect == domain.SQLite {
+		return fmt.Sprintf(`%s LIKE %s ESCAPE '\'`, c.field, placeholder(argIndex)), []interface{}{c.value}
+	}
+	return fmt.Sprintf("%s %s %s", c.field, c.operator, placeholder(argIndex)), []interface{}{c.value}
+}
+
+func (c condition) IsSatisfiedBy(item domain.Item) bool {
+	return c.evaluate(record{item: item})
+}
+
+func (c condition) evaluate(r record) bool {
+	value, ok := c.field.value(r)
+	if !ok {
+		return false
+	}
+
+	if c.operator == "ILIKE" {
+		text, ok := value.(string)
+		pattern, _ := c.value.(string)
+		return ok && iLike(text, pattern)
+	}
+
+	order, ok := compare(value, c.value)
+	if !ok {
+		return false
+	}
+	switch c.operator {
+	case "=":
+		return order == 0
+	case "<":
+		return order < 0
+	case ">":
+		return order > 0
+	case "<=":
+		return order <= 0
+	case ">=":
+		return order >= 0
+	}
+	return false
+}
+
+type in struct {
+	field  Field
+	values []interface{}
+}
+
+// In matches rows where field equals one of values. An empty set matches nothing
+func In(field Field, values ...interface{}) domain.Specification {
+	return in{field: field, values: values}
+}
+
+func (i in) FilterQuery(dialect domain.Dialect, argIndex int) (string, []interface{}) {
+	if len(i.values) == 0 {
+		return "1=0", nil
+	}
+
+	placeholders := make([]string, len(i.values))
+	for index := range i.values {
+		placeholders[index] = placeholder(argIndex + index)
+	}
+	return fmt.Sprintf("%s IN (%s)", i.field, strings.Join(placeholders, ", ")), i.values
+}
+
+func (i in) IsSatisfiedBy(item domain.Item) bool {
+	return i.evaluate(record{item: item})
+}
+
+func (i in) evaluate(r record) bool {
+	value, ok := i.field.value(r)
+	if !ok {
+		return false
+	}
+	for _, v := range i.values {
+		if order, ok := compare(value, v); ok && order == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func placeholder(argIndex int) string {
+	return fmt.Sprintf("$%d", argIndex)
+}
+
+// likePattern escapes the LIKE wildcards in value so it is matched literally
+func likePattern(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
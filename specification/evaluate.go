@@ -0,0 +1,138 @@
+package specification
+
+import (
+	"github.com/nuzurie/shopify/domain"
+	"github.com/shopspring/decimal"
+	"strings"
+	"unicode/utf8"
+)
+
+// record is what a specification is evaluated against in memory. inventory is nil when evaluating a bare item
+type record struct {
+	item      domain.Item
+	inventory *domain.InventoryItem
+}
+
+// evaluator is implemented by the specifications of this package so they can be evaluated against inventory fields
+// as well as item fields
+type evaluator interface {
+	evaluate(r record) bool
+}
+
+func evaluate(spec domain.Specification, r record) bool {
+	if e, ok := spec.(evaluator); ok {
+		return e.evaluate(r)
+	}
+	return spec.IsSatisfiedBy(r.item)
+}
+
+// value returns the value of the field in r. Inventory fields are missing when r is a bare item, which no condition
+// matches
+func (f Field) value(r record) (interface{}, bool) {
+	switch f {
+	case ItemID:
+		return r.item.ID, true
+	case Name:
+		return r.item.Name, true
+	case Description:
+		return r.item.Description, true
+	case Price:
+		return r.item.Price, true
+	case Quantity:
+		if r.inventory == nil {
+			return nil, false
+		}
+		return r.inventory.Quantity, true
+	case LocationID:
+		if r.inventory == nil {
+			return nil, false
+		}
+		return r.inventory.LocationID, true
+	}
+	return nil, false
+}
+
+// compare orders a and b the way Postgres would compare the column with the bound argument. Numbers are compared by
+// exact value whatever their Go type, strings byte-wise
+func compare(a, b interface{}) (int, bool) {
+	if x, ok := toDecimal(a); ok {
+		y, ok := toDecimal(b)
+		if !ok {
+			return 0, false
+		}
+		return x.Cmp(y), true
+	}
+
+	x, ok := a.(string)
+	if !ok {
+		return 0, false
+	}
+	y, ok := b.(string)
+	if !ok {
+		return 0, false
+	}
+	return strings.Compare(x, y), true
+}
+
+// toDecimal converts a number to a decimal. Floats are taken at the shortest decimal that rounds to them, as they are
+// when stored
+func toDecimal(v interface{}) (decimal.Decimal, bool) {
+	switch n := v.(type) {
+	case int:
+		return decimal.NewFromInt(int64(n)), true
+	case int32:
+		return decimal.NewFromInt32(n), true
+	case int64:
+		return decimal.NewFromInt(n), true
+	case float32:
+		return decimal.NewFromFloat32(n), true
+	case float64:
+		return decimal.NewFromFloat(n), true
+	case decimal.Decimal:
+		return n, true
+	}
+	return decimal.Decimal{}, false
+}
+
+// iLike matches value against an ILIKE pattern, where % matches any run of characters, _ a single character and a
+// backslash escapes the next character
+func iLike(value, pattern string) bool {
+	return like(strings.ToLower(value), strings.ToLower(pattern))
+}
+
+func like(value, pattern string) bool {
+	for len(pattern) > 0 {
+		p, size := utf8.DecodeRuneInString(pattern)
+		pattern = pattern[size:]
+		switch p {
+		case '%':
+			for {
+				if like(value, pattern) {
+					return true
+				}
+				if value == "" {
+					return false
+				}
+				_, size = utf8.DecodeRuneInString(value)
+				value = value[size:]
+			}
+		case '_':
+			if value == "" {
+				return false
+			}
+			_, size = utf8.DecodeRuneInString(value)
+			value = value[size:]
+		default:
+			if p == '\\' && len(pattern) > 0 {
+				p, size = utf8.DecodeRuneInString(pattern)
+				pattern = pattern[size:]
+			}
+			v, size := utf8.DecodeRuneInString(value)
+			if value == "" || v != p {
+				return false
+			}
+			value = value[size:]
+		}
+	}
+	return value == ""
+}
@@ -0,0 +1,15 @@
+package specification
+
+// Field is a column a specification can filter on. Only the fields declared here can be rendered, so column names
+// never come from user input.
+type Field string
+
+const (
+	ItemID      Field = "item.id"
+	Name        Field = "item.name"
+	Description Field = "item.description"
+	// Price is the price of an item in its own currency. Use PriceIn to compare prices in a given currency
+	Price      Field = "item.price"
+	Quantity   Field = "inventory.quantity"
+	LocationID Field = "inventory.location_id"
+)
@@ -1,35 +1,47 @@
 package specification
 
 import (
-	"fmt"
 	"github.com/nuzurie/shopify/domain"
 )
 
 type InventorySpecification struct {
 	ItemSpecification domain.Specification
-	postgresQuery     string
+	filter            domain.Specification
 }
 
-func NewInventorySpecification(minQuantity, maxQuantity int,
+// NewInventorySpecification filters inventory by a quantity range, its location, a lot it holds and the specification
+// of its item. A maxQuantity of -1 leaves the range open, and an empty locationID or lotNumber matches any
+func NewInventorySpecification(minQuantity, maxQuantity int, locationID string, lotNumber string,
 	itemSpecification domain.Specification) domain.InventorySpecification {
-	var minQuantityQuery, maxQuantityQuery string
-
-	minQuantityQuery = fmt.Sprintf("quantity>=%d", minQuantity)
+	filter := Range(Quantity, minQuantity, maxQuantity)
 	if maxQuantity == -1 {
-		maxQuantityQuery = "1=1"
-	} else {
-		maxQuantityQuery = fmt.Sprintf("quantity<=%d", maxQuantity)
+		filter = Range(Quantity, minQuantity, nil)
+	}
+	if locationID != "" {
+		filter = And(filter, Eq(LocationID, locationID))
+	}
+	if lotNumber != "" {
+		filter = And(filter, HasLot(lotNumber))
 	}
 
-	query := fmt.Sprintf("%s AND %s", minQuantityQuery, maxQuantityQuery)
+	return InventorySpecification{ItemSpecification: itemSpecification, filter: filter}
+}
+
+// ForInventory wraps a specification that may mix item and inventory fields, e.g.
+// Or(LessThan(Price, 5), And(Contains(Name, "sale"), GreaterThan(Quantity, 100)))
+func ForInventory(filter domain.Specification) domain.InventorySpecification {
+	return InventorySpecification{ItemSpecification: And(), filter: filter}
+}
 
-	return InventorySpecification{ItemSpecification: itemSpecification, postgresQuery: query}
+func (i InventorySpecification) FilterQuery(dialect domain.Dialect, argIndex int) (string, []interface{}) {
+	return i.filter.FilterQuery(dialect, argIndex)
 }
 
-func (i InventorySpecification) FilterQuery() string {
-	return i.postgresQuery
+func (i InventorySpecification) ItemFilterQuery(dialect domain.Dialect, argIndex int) (string, []interface{}) {
+	return i.ItemSpecification.FilterQuery(dialect, argIndex)
 }
 
-func (i InventorySpecification) ItemFilterQuery() string {
-	return i.ItemSpecification.FilterQuery()
+func (i InventorySpecification) IsSatisfiedBy(inventory domain.InventoryItem) bool {
+	r := record{item: inventory.Item, inventory: &inventory}
+	return evaluate(i.ItemSpecification, r) && evaluate(i.filter, r)
 }
@@ -1,40 +1,28 @@
 package specification
 
 import (
-	"fmt"
 	"github.com/nuzurie/shopify/domain"
+	"github.com/shopspring/decimal"
 )
 
-type ItemSpecification struct {
-	postgresQuery string
-}
-
+// NewItemSpecification filters items by name and description keywords and a price range in currency,
+// domain.DefaultCurrency if empty. A nil bound leaves that side of the range open
 func NewItemSpecification(name, description string,
-	minPrice, maxPrice float64) domain.Specification {
-	var nameQuery, descriptionQuery, minPriceQuery, maxPriceQuery string
-	if name == "" {
-		nameQuery = "1=1"
-	} else {
-		nameQuery = fmt.Sprintf("name ILIKE '%%%s%%'", name)
+	minPrice, maxPrice *decimal.Decimal, currency string) domain.Specification {
+	var specs []domain.Specification
+	if name != "" {
+		specs = append(specs, Contains(Name, name))
 	}
-
-	if description == "" {
-		descriptionQuery = "1=1"
-	} else {
-		descriptionQuery = fmt.Sprintf("description ILIKE '%%%s%%'", description)
+	if description != "" {
+		specs = append(specs, Contains(Description, description))
 	}
 
-	minPriceQuery = fmt.Sprintf("price>=%f", minPrice)
-	if maxPrice == -1 {
-		maxPriceQuery = "1=1"
-	} else {
-		maxPriceQuery = fmt.Sprintf("price<=%f", maxPrice)
+	if minPrice != nil || maxPrice != nil {
+		if currency == "" {
+			currency = domain.DefaultCurrency
+		}
+		specs = append(specs, PriceIn(currency, minPrice, maxPrice))
 	}
 
-	query := fmt.Sprintf("%s AND %s AND %s AND %s", nameQuery, descriptionQuery, minPriceQuery, maxPriceQuery)
-	return ItemSpecification{postgresQuery: query}
-}
-
-func (i ItemSpecification) FilterQuery() string {
-	return i.postgresQuery
+	return And(specs...)
 }
@@ -0,0 +1,36 @@
+package specification
+
+import (
+	"fmt"
+	"github.com/nuzurie/shopify/domain"
+)
+
+type hasLot struct {
+	lotNumber string
+}
+
+// HasLot matches inventory holding stock of the lot with the given number. Bare items have no lots, so none match
+func HasLot(lotNumber string) domain.Specification {
+	return hasLot{lotNumber: lotNumber}
+}
+
+func (h hasLot) FilterQuery(dialect domain.Dialect, argIndex int) (string, []interface{}) {
+	return fmt.Sprintf(`EXISTS (SELECT 1 FROM lot WHERE lot.inventory_id = inventory.id AND lot.lot_number = %s
+		AND lot.quantity > 0)`, placeholder(argIndex)), []interface{}{h.lotNumber}
+}
+
+func (h hasLot) IsSatisfiedBy(item domain.Item) bool {
+	return h.evaluate(record{item: item})
+}
+
+func (h hasLot) evaluate(r record) bool {
+	if r.inventory == nil {
+		return false
+	}
+	for _, lot := range r.inventory.Lots {
+		if lot.LotNumber == h.lotNumber && lot.Quantity > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package specification
+
+import (
+	"fmt"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/shopspring/decimal"
+)
+
+type priceIn struct {
+	currency string
+	min      *decimal.Decimal
+	max      *decimal.Decimal
+}
+
+// PriceIn matches items whose price in currency, their own or the one of their price list, lies between min and max,
+// both inclusive. A nil bound leaves that side open. Items with no price in currency never match
+func PriceIn(currency string, min, max *decimal.Decimal) domain.Specification {
+	return priceIn{currency: currency, min: min, max: max}
+}
+
+// FilterQuery casts the bounds so that SQLite, which stores prices as REAL, compares them as numbers too. The
+// comparison is made false rather than NULL for items with no price in currency, so NOT agrees with IsSatisfiedBy
+func (p priceIn) FilterQuery(dialect domain.Dialect, argIndex int) (string, []interface{}) {
+	price := fmt.Sprintf(`CASE WHEN item.currency = %s THEN item.price ELSE (SELECT item_price.amount FROM item_price
+		WHERE item_price.item_id = item.id AND item_price.currency = %s) END`,
+		placeholder(argIndex), placeholder(argIndex+1))
+	args := []interface{}{p.currency, p.currency}
+
+	switch {
+	case p.min != nil && p.max != nil:
+		return fmt.Sprintf("COALESCE(%s BETWEEN CAST(%s AS NUMERIC) AND CAST(%s AS NUMERIC), FALSE)",
+			price, placeholder(argIndex+2), placeholder(argIndex+3)), append(args, p.min.String(), p.max.String())
+	case p.min != nil:
+		return fmt.Sprintf("COALESCE(%s >= CAST(%s AS NUMERIC), FALSE)", price, placeholder(argIndex+2)),
+			append(args, p.min.String())
+	case p.max != nil:
+		return fmt.Sprintf("COALESCE(%s <= CAST(%s AS NUMERIC), FALSE)", price, placeholder(argIndex+2)),
+			append(args, p.max.String())
+	}
+	return price + " IS NOT NULL", args
+}
+
+func (p priceIn) IsSatisfiedBy(item domain.Item) bool {
+	return p.evaluate(record{item: item})
+}
+
+func (p priceIn) evaluate(r record) bool {
+	price, ok := r.item.PriceIn(p.currency)
+	if !ok {
+		return false
+	}
+	return (p.min == nil || price.GreaterThanOrEqual(*p.min)) && (p.max == nil || price.LessThanOrEqual(*p.max))
+}
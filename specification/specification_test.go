@@ -0,0 +1,330 @@
+package specification
+
+import (
+	"context"
+	"fmt"
+	"github.com/nuzurie/shopify/db"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/shopspring/decimal"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func TestIsSatisfiedBy(t *testing.T) {
+	item := domain.Item{ID: "abc", Name: "Summer SALE hat", Description: "100% wool_blend",
+		Price: decimal.RequireFromString("4.99"), Currency: "USD",
+		Prices: []domain.ItemPrice{{Currency: "EUR", Amount: decimal.RequireFromString("4.50")}}}
+	inventory := domain.InventoryItem{ID: "inv", Item: item, LocationID: "east", Quantity: 150,
+		Lots: []domain.Lot{{LotNumber: "L1", Quantity: 20}, {LotNumber: "L2", Quantity: 0}}}
+
+	itemCases := []struct {
+		name string
+		spec domain.Specification
+		want bool
+	}{
+		{"empty and", And(), true},
+		{"empty or", Or(), false},
+		{"eq", Eq(ItemID, "abc"), true},
+		{"eq is case sensitive", Eq(Name, "summer sale hat"), false},
+		{"contains ignores case", Contains(Name, "sale"), true},
+		{"contains escapes percent", Contains(Description, "0% w"), true},
+		{"contains escapes underscore", Contains(Description, "wool-blend"), false},
+		{"prefix", Prefix(Name, "summer"), true},
+		{"prefix anchors start", Prefix(Name, "sale"), false},
+		{"range", Range(Price, 1, 5), true},
+		{"open range", Range(Price, 5.0, nil), false},
+		{"less than", LessThan(Price, 5), true},
+		{"in", In(ItemID, "x", "abc"), true},
+		{"empty in", In(ItemID), false},
+		{"not", Not(Contains(Name, "winter")), true},
+		{"inventory field on item", GreaterThan(Quantity, 0), false},
+		{"location on item", Eq(LocationID, ""), false},
+		{"range is exact", Range(Price, 4.99, 4.99), true},
+		{"price in own currency", PriceIn("USD", amount("4.99"), nil), true},
+		{"price in listed currency", PriceIn("EUR", nil, amount("4.50")), true},
+		{"price above listed currency", PriceIn("EUR", amount("4.51"), nil), false},
+		{"price in unlisted currency", PriceIn("GBP", nil, nil), false},
+		{"not price in unlisted currency", Not(PriceIn("GBP", amount("0"), nil)), true},
+		{"item specification", NewItemSpecification("hat", "wool", nil, nil, ""), true},
+		{"item specification price", NewItemSpecification("", "", amount("0"), amount("4"), ""), false},
+		{"item specification currency", NewItemSpecification("", "", amount("4"), amount("5"), "EUR"), true},
+		{"lot on item", HasLot("L1"), false},
+	}
+	for _, c := range itemCases {
+		if got := c.spec.IsSatisfiedBy(item); got != c.want {
+			t.Errorf("%s: IsSatisfiedBy = %v, want %v", c.name, got, c.want)
+		}
+	}
+
+	inventoryCases := []struct {
+		name string
+		spec domain.InventorySpecification
+		want bool
+	}{
+		{"quantity range", NewInventorySpecification(100, -1, "", "", And()), true},
+		{"quantity and item", NewInventorySpecification(0, 200, "", "", NewItemSpecification("boots", "", nil, nil, "")), false},
+		{"location", NewInventorySpecification(0, -1, "east", "", And()), true},
+		{"other location", NewInventorySpecification(0, -1, "west", "", And()), false},
+		{"lot", NewInventorySpecification(0, -1, "", "L1", And()), true},
+		{"empty lot", NewInventorySpecification(0, -1, "", "L2", And()), false},
+		{"mixed fields", ForInventory(Or(LessThan(Price, 1), And(Contains(Name, "sale"), GreaterThan(Quantity, 100)))), true},
+	}
+	for _, c := range inventoryCases {
+		if got := c.spec.IsSatisfiedBy(inventory); got != c.want {
+			t.Errorf("%s: IsSatisfiedBy = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+// TestSQLAgreement renders generated specifications against generated rows and checks SQLite returns exactly the rows
+// IsSatisfiedBy accepts
+func TestSQLAgreement(t *testing.T) {
+	database, err := db.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	checkSQLAgreement(t, database)
+}
+
+// TestPostgresAgreement is TestSQLAgreement for Postgres. It needs TEST_DATABASE_URL to point at a Postgres database
+func TestPostgresAgreement(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	database, err := db.Open(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	checkSQLAgreement(t, database)
+}
+
+// checkSQLAgreement works on temporary tables within a transaction that is rolled back, so it leaves no trace
+func checkSQLAgreement(t *testing.T, database db.DB) {
+	ctx := context.Background()
+	tx, err := database.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback(ctx)
+
+	random := rand.New(rand.NewSource(1))
+	inventoryItems := generateInventory(random, 200)
+	for _, statement := range []string{
+		`CREATE TEMPORARY TABLE item (id text PRIMARY KEY, name text NOT NULL, description text, price numeric,
+			currency text)`,
+		`CREATE TEMPORARY TABLE item_price (item_id text, currency text, amount numeric)`,
+		`CREATE TEMPORARY TABLE inventory (id text PRIMARY KEY, quantity int, item_id text, location_id text)`,
+		`CREATE TEMPORARY TABLE lot (id text PRIMARY KEY, inventory_id text, lot_number text, quantity int)`,
+	} {
+		if _, err := tx.Exec(ctx, statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, inventory := range inventoryItems {
+		item := inventory.Item
+		if _, err := tx.Exec(ctx, `INSERT INTO item (id, name, description, price, currency)
+			VALUES ($1, $2, $3, $4, $5)`, item.ID, item.Name, item.Description, item.Price, item.Currency); err != nil {
+			t.Fatal(err)
+		}
+		for _, price := range item.Prices {
+			if _, err := tx.Exec(ctx, `INSERT INTO item_price (item_id, currency, amount) VALUES ($1, $2, $3)`,
+				item.ID, price.Currency, price.Amount); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO inventory (id, quantity, item_id, location_id) VALUES ($1, $2, $3, $4)`,
+			inventory.ID, inventory.Quantity, item.ID, inventory.LocationID); err != nil {
+			t.Fatal(err)
+		}
+		for _, lot := range inventory.Lots {
+			if _, err := tx.Exec(ctx, `INSERT INTO lot (id, inventory_id, lot_number, quantity) VALUES ($1, $2, $3, $4)`,
+				lot.ID, inventory.ID, lot.LotNumber, lot.Quantity); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	dialect := database.Dialect()
+	for n := 0; n < 500; n++ {
+		itemSpec := generateSpecification(random, 3, false)
+		query, args := itemSpec.FilterQuery(dialect, 1)
+		matched := queryIDs(t, tx, "SELECT item.id FROM item WHERE "+query, args)
+		for _, inventory := range inventoryItems {
+			if want := itemSpec.IsSatisfiedBy(inventory.Item); matched[inventory.Item.ID] != want {
+				t.Fatalf("item %+v: SQL %q %v matched %v, IsSatisfiedBy %v",
+					inventory.Item, query, args, matched[inventory.Item.ID], want)
+			}
+		}
+
+		inventorySpec := ForInventory(generateSpecification(random, 3, true))
+		if random.Intn(2) == 0 {
+			inventorySpec = NewInventorySpecification(random.Intn(50), random.Intn(100)-1, generateLocationID(random),
+				generateLotNumber(random), itemSpec)
+		}
+		itemQuery, args := inventorySpec.ItemFilterQuery(dialect, 1)
+		inventoryQuery, inventoryArgs := inventorySpec.FilterQuery(dialect, len(args)+1)
+		query = fmt.Sprintf(`SELECT inventory.id FROM inventory JOIN item ON item.id = inventory.item_id
+			WHERE (%s) AND (%s)`, itemQuery, inventoryQuery)
+		args = append(args, inventoryArgs...)
+		matched = queryIDs(t, tx, query, args)
+		for _, inventory := range inventoryItems {
+			if want := inventorySpec.IsSatisfiedBy(inventory); matched[inventory.ID] != want {
+				t.Fatalf("inventory %+v: SQL %q %v matched %v, IsSatisfiedBy %v",
+					inventory, query, args, matched[inventory.ID], want)
+			}
+		}
+	}
+}
+
+func queryIDs(t *testing.T, querier db.Querier, query string, args []interface{}) map[string]bool {
+	rows, err := querier.Query(context.Background(), query, args...)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	defer rows.Close()
+
+	ids := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		ids[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return ids
+}
+
+var words = []string{"hat", "Sale", "WOOL", "blend", "50%", "off_cut", `back\slash`, "creme", "brulee", ""}
+
+func generateText(random *rand.Rand) string {
+	text := words[random.Intn(len(words))]
+	for random.Intn(2) == 0 {
+		text += " " + words[random.Intn(len(words))]
+	}
+	return text
+}
+
+func generateInventory(random *rand.Rand, n int) []domain.InventoryItem {
+	inventoryItems := make([]domain.InventoryItem, n)
+	for index := range inventoryItems {
+		inventoryItems[index] = domain.InventoryItem{
+			ID: fmt.Sprintf("inventory-%d", index),
+			Item: domain.Item{
+				ID:          fmt.Sprintf("item-%d", index),
+				Name:        generateText(random),
+				Description: generateText(random),
+				Price:       generateAmount(random, 10000),
+				Currency:    currencies[random.Intn(len(currencies))],
+			},
+			LocationID: generateLocationID(random),
+			Quantity:   random.Intn(200),
+		}
+		for _, currency := range currencies {
+			if currency != inventoryItems[index].Item.Currency && random.Intn(2) == 0 {
+				inventoryItems[index].Item.Prices = append(inventoryItems[index].Item.Prices,
+					domain.ItemPrice{Currency: currency, Amount: generateAmount(random, 10000)})
+			}
+		}
+		for lot := random.Intn(3); lot > 0; lot-- {
+			inventoryItems[index].Lots = append(inventoryItems[index].Lots, domain.Lot{
+				ID:        fmt.Sprintf("lot-%d-%d", index, lot),
+				LotNumber: fmt.Sprintf("L%d", lot+random.Intn(2)),
+				Quantity:  random.Intn(3),
+			})
+		}
+	}
+	return inventoryItems
+}
+
+var currencies = []string{"USD", "EUR", "JPY"}
+
+// generateAmount picks an amount in cents below max
+func generateAmount(random *rand.Rand, max int) decimal.Decimal {
+	return decimal.New(int64(random.Intn(max)), -2)
+}
+
+// generatePriceBound picks an amount in cents below max, or none
+func generatePriceBound(random *rand.Rand, max int) *decimal.Decimal {
+	if random.Intn(3) == 0 {
+		return nil
+	}
+	bound := generateAmount(random, max)
+	return &bound
+}
+
+// generateLocationID picks one of a few locations, or none
+func generateLocationID(random *rand.Rand) string {
+	if n := random.Intn(4); n > 0 {
+		return fmt.Sprintf("location-%d", n)
+	}
+	return ""
+}
+
+// generateLotNumber picks one of a few lot numbers, or none
+func generateLotNumber(random *rand.Rand) string {
+	if n := random.Intn(4); n > 0 {
+		return fmt.Sprintf("L%d", n)
+	}
+	return ""
+}
+
+// generateSpecification builds a random specification tree of at most the given depth. Quantity, location and lot
+// conditions are only generated for inventory specifications
+func generateSpecification(random *rand.Rand, depth int, inventory bool) domain.Specification {
+	choices := 9
+	if depth > 0 {
+		choices = 12
+	}
+
+	switch random.Intn(choices) {
+	case 0:
+		return Eq(ItemID, fmt.Sprintf("item-%d", random.Intn(200)))
+	case 1:
+		return In(ItemID, fmt.Sprintf("item-%d", random.Intn(200)), fmt.Sprintf("item-%d", random.Intn(200)))
+	case 2:
+		return Contains(Name, words[random.Intn(len(words))])
+	case 3:
+		return Prefix(Description, words[random.Intn(len(words))])
+	case 4:
+		return LessThan(Price, float64(random.Intn(10000))/100)
+	case 5:
+		return Range(Price, float64(random.Intn(5000))/100, float64(5000+random.Intn(5000))/100)
+	case 6:
+		if inventory {
+			return GreaterThan(Quantity, random.Intn(200))
+		}
+		return Eq(Name, words[random.Intn(len(words))])
+	case 7:
+		if inventory && random.Intn(2) == 0 {
+			return HasLot(generateLotNumber(random))
+		}
+		if inventory {
+			return Eq(LocationID, generateLocationID(random))
+		}
+		return Eq(Name, words[random.Intn(len(words))])
+	case 8:
+		return PriceIn(currencies[random.Intn(len(currencies))], generatePriceBound(random, 5000),
+			generatePriceBound(random, 10000))
+	case 9:
+		return Not(generateSpecification(random, depth-1, inventory))
+	case 10:
+		return And(generateSpecification(random, depth-1, inventory), generateSpecification(random, depth-1, inventory))
+	default:
+		return Or(generateSpecification(random, depth-1, inventory), generateSpecification(random, depth-1, inventory))
+	}
+}
+
+func amount(value string) *decimal.Decimal {
+	amount := decimal.RequireFromString(value)
+	return &amount
+}
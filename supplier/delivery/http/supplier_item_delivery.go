@@ -0,0 +1,181 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"net/http"
+	"strconv"
+)
+
+type SupplierItemHandler struct {
+	useCase domain.SupplierItemUseCase
+}
+
+func NewSupplierItemHandler(useCase domain.SupplierItemUseCase) *SupplierItemHandler {
+	return &SupplierItemHandler{useCase: useCase}
+}
+
+func (h *SupplierItemHandler) GetForSupplier(c *gin.Context) {
+	supplierID := c.Param("id")
+	if supplierID == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	var count int64
+	if countQuery, ok := c.GetQuery("count"); ok {
+		count, _ = strconv.ParseInt(countQuery, 10, 64)
+	} else {
+		count = 20
+	}
+
+	var offset int64
+	if offsetQuery, ok := c.GetQuery("offset"); ok {
+		offset, _ = strconv.ParseInt(offsetQuery, 10, 64)
+	}
+
+	ctx := c.Request.Context()
+	supplierItems, err := h.useCase.GetForSupplier(ctx, supplierID, int(count), int(offset))
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, supplierItems)
+}
+
+func (h *SupplierItemHandler) GetForItem(c *gin.Context) {
+	itemID := c.Param("id")
+	if itemID == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	supplierItems, err := h.useCase.GetForItem(ctx, itemID)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, supplierItems)
+}
+
+func (h *SupplierItemHandler) GetOne(c *gin.Context) {
+	supplierID, itemID := c.Param("id"), c.Param("itemId")
+	if supplierID == "" || itemID == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	supplierItem, err := h.useCase.GetOne(ctx, supplierID, itemID)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, supplierItem)
+}
+
+func (h *SupplierItemHandler) Create(c *gin.Context) {
+	supplierID := c.Param("id")
+	if supplierID == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	var supplierItem domain.SupplierItem
+	if err := c.ShouldBindJSON(&supplierItem); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid supplier item body"))
+		return
+	}
+
+	supplierItem.SupplierID = supplierID
+	ctx := c.Request.Context()
+	created, err := h.useCase.Create(ctx, &supplierItem)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *SupplierItemHandler) Update(c *gin.Context) {
+	supplierID, itemID := c.Param("id"), c.Param("itemId")
+	if supplierID == "" || itemID == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	var supplierItem domain.SupplierItem
+	if err := c.ShouldBindJSON(&supplierItem); err != nil {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid supplier item body"))
+		return
+	}
+
+	supplierItem.SupplierID, supplierItem.ItemID = supplierID, itemID
+	ctx := c.Request.Context()
+	updated, err := h.useCase.Update(ctx, &supplierItem)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+func (h *SupplierItemHandler) Delete(c *gin.Context) {
+	supplierID, itemID := c.Param("id"), c.Param("itemId")
+	if supplierID == "" || itemID == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	err := h.useCase.Delete(ctx, supplierID, itemID)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "deleted successfully"})
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"sort"
+)
+
+type memorySupplierItemRepository struct {
+	store *memory.Store
+}
+
+// NewMemorySupplierItemRepository keeps the items of suppliers in store instead of a database. store must be shared
+// with the supplier and item repositories so only existing suppliers and items can be linked
+func NewMemorySupplierItemRepository(store *memory.Store) domain.SupplierItemRepository {
+	return &memorySupplierItemRepository{store: store}
+}
+
+func (s *memorySupplierItemRepository) GetForSupplier(ctx context.Context, supplierID string, count int,
+	offset int) ([]domain.SupplierItem, error) {
+	defer s.store.Read(ctx)()
+
+	var supplierItems []domain.SupplierItem
+	for _, supplierItem := range s.store.SupplierItems {
+		if supplierItem.SupplierID == supplierID {
+			supplierItems = append(supplierItems, supplierItem)
+		}
+	}
+	sort.Slice(supplierItems, func(a, b int) bool {
+		return supplierItems[a].ItemID < supplierItems[b].ItemID
+	})
+
+	start, end := memory.Page(len(supplierItems), count, offset)
+	return supplierItems[start:end], nil
+}
+
+func (s *memorySupplierItemRepository) GetForItem(ctx context.Context, itemID string) ([]domain.SupplierItem, error) {
+	defer s.store.Read(ctx)()
+
+	var supplierItems []domain.SupplierItem
+	for _, supplierItem := range s.store.SupplierItems {
+		if supplierItem.ItemID == itemID {
+			supplierItems = append(supplierItems, supplierItem)
+		}
+	}
+	sort.Slice(supplierItems, func(a, b int) bool {
+		if supplierItems[a].Preferred != supplierItems[b].Preferred {
+			return supplierItems[a].Preferred
+		}
+		return supplierItems[a].SupplierID < supplierItems[b].SupplierID
+	})
+
+	return supplierItems, nil
+}
+
+func (s *memorySupplierItemRepository) GetOne(ctx context.Context, supplierID string,
+	itemID string) (*domain.SupplierItem, error) {
+	defer s.store.Read(ctx)()
+
+	supplierItem := s.store.SupplierItems[memory.SupplierItemKey(supplierID, itemID)]
+	return &supplierItem, nil
+}
+
+func (s *memorySupplierItemRepository) Save(ctx context.Context,
+	supplierItem *domain.SupplierItem) (*domain.SupplierItem, error) {
+	defer s.store.Write(ctx)()
+
+	_, supplierExists := s.store.Suppliers[supplierItem.SupplierID]
+	_, itemExists := s.store.Items[supplierItem.ItemID]
+	if !supplierExists || !itemExists {
+		return nil, errors.NewBadRequestError("no supplier or item with such ID exists")
+	}
+	key := memory.SupplierItemKey(supplierItem.SupplierID, supplierItem.ItemID)
+	if _, ok := s.store.SupplierItems[key]; ok {
+		return nil, errors.NewConflictError("the supplier already supplies the item")
+	}
+	s.store.SupplierItems[key] = *supplierItem
+	return supplierItem, nil
+}
+
+func (s *memorySupplierItemRepository) Edit(ctx context.Context,
+	supplierItem *domain.SupplierItem) (*domain.SupplierItem, error) {
+	defer s.store.Write(ctx)()
+
+	key := memory.SupplierItemKey(supplierItem.SupplierID, supplierItem.ItemID)
+	if _, ok := s.store.SupplierItems[key]; ok {
+		s.store.SupplierItems[key] = *supplierItem
+	}
+	return supplierItem, nil
+}
+
+func (s *memorySupplierItemRepository) Delete(ctx context.Context, supplierID string, itemID string) error {
+	defer s.store.Write(ctx)()
+
+	delete(s.store.SupplierItems, memory.SupplierItemKey(supplierID, itemID))
+	return nil
+}
+
+func (s *memorySupplierItemRepository) ClearPreferred(ctx context.Context, itemID string) error {
+	defer s.store.Write(ctx)()
+
+	for key, supplierItem := range s.store.SupplierItems {
+		if supplierItem.ItemID == itemID && supplierItem.Preferred {
+			supplierItem.Preferred = false
+			s.store.SupplierItems[key] = supplierItem
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"sort"
+)
+
+type memorySupplierRepository struct {
+	store *memory.Store
+}
+
+// NewMemorySupplierRepository keeps suppliers in store instead of a database. store must be shared with the purchase
+// order repository so suppliers with orders can't be deleted
+func NewMemorySupplierRepository(store *memory.Store) domain.SupplierRepository {
+	return &memorySupplierRepository{store: store}
+}
+
+func (s *memorySupplierRepository) GetAll(ctx context.Context, count int, offset int) ([]domain.Supplier, error) {
+	defer s.store.Read(ctx)()
+
+	var suppliers []domain.Supplier
+	for _, supplier := range s.store.Suppliers {
+		suppliers = append(suppliers, supplier)
+	}
+	sort.Slice(suppliers, func(a, b int) bool {
+		if suppliers[a].Name != suppliers[b].Name {
+			return suppliers[a].Name < suppliers[b].Name
+		}
+		return suppliers[a].ID < suppliers[b].ID
+	})
+
+	start, end := memory.Page(len(suppliers), count, offset)
+	return suppliers[start:end], nil
+}
+
+func (s *memorySupplierRepository) GetOne(ctx context.Context, id string) (*domain.Supplier, error) {
+	defer s.store.Read(ctx)()
+
+	supplier := s.store.Suppliers[id]
+	return &supplier, nil
+}
+
+func (s *memorySupplierRepository) Save(ctx context.Context, supplier *domain.Supplier) (*domain.Supplier, error) {
+	defer s.store.Write(ctx)()
+
+	if _, ok := s.store.Suppliers[supplier.ID]; ok {
+		return nil, errors.NewConflictError("supplier already exists")
+	}
+	s.store.Suppliers[supplier.ID] = *supplier
+	return supplier, nil
+}
+
+func (s *memorySupplierRepository) Edit(ctx context.Context, supplier *domain.Supplier) (*domain.Supplier, error) {
+	defer s.store.Write(ctx)()
+
+	if existing, ok := s.store.Suppliers[supplier.ID]; ok {
+		updated := *supplier
+		updated.CreatedAt = existing.CreatedAt
+		s.store.Suppliers[supplier.ID] = updated
+	}
+	return supplier, nil
+}
+
+func (s *memorySupplierRepository) Delete(ctx context.Context, id string) error {
+	defer s.store.Write(ctx)()
+
+	for _, order := range s.store.PurchaseOrders {
+		if order.SupplierID == id {
+			return errors.NewConflictError("can't delete supplier while purchase orders reference it")
+		}
+	}
+	for key, supplierItem := range s.store.SupplierItems {
+		if supplierItem.SupplierID == id {
+			delete(s.store.SupplierItems, key)
+		}
+	}
+	delete(s.store.Suppliers, id)
+	return nil
+}
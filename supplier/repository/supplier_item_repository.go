@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+)
+
+type supplierItemRepository struct {
+	db db.DB
+}
+
+const (
+	getSupplierItems = `SELECT supplier_id, item_id, supplier_sku, unit_cost, preferred, created_at, updated_at
+			FROM supplier_item WHERE supplier_id=$1 ORDER BY item_id LIMIT $2 OFFSET $3`
+	getItemSuppliers = `SELECT supplier_id, item_id, supplier_sku, unit_cost, preferred, created_at, updated_at
+			FROM supplier_item WHERE item_id=$1 ORDER BY preferred DESC, supplier_id`
+	getSupplierItem = `SELECT supplier_id, item_id, supplier_sku, unit_cost, preferred, created_at, updated_at
+			FROM supplier_item WHERE supplier_id=$1 AND item_id=$2`
+	saveSupplierItem = `INSERT INTO supplier_item (supplier_id, item_id, supplier_sku, unit_cost, preferred, created_at,
+			updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	updateSupplierItem = `UPDATE supplier_item SET supplier_sku=$3, unit_cost=$4, preferred=$5, updated_at=$6
+			WHERE supplier_id=$1 AND item_id=$2`
+	deleteSupplierItem = `DELETE FROM supplier_item WHERE supplier_id=$1 AND item_id=$2`
+	clearPreferred     = `UPDATE supplier_item SET preferred=false WHERE item_id=$1 AND preferred`
+)
+
+// NewSupplierItemRepository stores the items of suppliers in a SQL database, Postgres or SQLite. The schema must be
+// migrated
+func NewSupplierItemRepository(database db.DB) domain.SupplierItemRepository {
+	return &supplierItemRepository{db: database}
+}
+
+func (s *supplierItemRepository) GetForSupplier(ctx context.Context, supplierID string, count int,
+	offset int) ([]domain.SupplierItem, error) {
+	return s.query(ctx, getSupplierItems, supplierID, count, offset)
+}
+
+func (s *supplierItemRepository) GetForItem(ctx context.Context, itemID string) ([]domain.SupplierItem, error) {
+	return s.query(ctx, getItemSuppliers, itemID)
+}
+
+func (s *supplierItemRepository) query(ctx context.Context, query string,
+	args ...interface{}) ([]domain.SupplierItem, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var supplierItems []domain.SupplierItem
+	for rows.Next() {
+		var supplierItem domain.SupplierItem
+		err = rows.Scan(&supplierItem.SupplierID, &supplierItem.ItemID, &supplierItem.SupplierSKU,
+			&supplierItem.UnitCost, &supplierItem.Preferred, &supplierItem.CreatedAt, &supplierItem.UpdatedAt)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		supplierItems = append(supplierItems, supplierItem)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return supplierItems, nil
+}
+
+func (s *supplierItemRepository) GetOne(ctx context.Context, supplierID string,
+	itemID string) (*domain.SupplierItem, error) {
+	var supplierItem domain.SupplierItem
+	err := s.db.QueryRow(ctx, getSupplierItem, supplierID, itemID).
+		Scan(&supplierItem.SupplierID, &supplierItem.ItemID, &supplierItem.SupplierSKU, &supplierItem.UnitCost,
+			&supplierItem.Preferred, &supplierItem.CreatedAt, &supplierItem.UpdatedAt)
+	if err != nil && err != db.ErrNoRows {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return &supplierItem, nil
+}
+
+func (s *supplierItemRepository) Save(ctx context.Context, supplierItem *domain.SupplierItem) (*domain.SupplierItem, error) {
+	_, err := s.db.Exec(ctx, saveSupplierItem, supplierItem.SupplierID, supplierItem.ItemID, supplierItem.SupplierSKU,
+		supplierItem.UnitCost, supplierItem.Preferred, supplierItem.CreatedAt, supplierItem.UpdatedAt)
+	if err != nil {
+		if db.IsForeignKeyViolation(err) {
+			return nil, errors.NewBadRequestError("no supplier or item with such ID exists")
+		}
+		if db.IsUniqueViolation(err) {
+			return nil, errors.NewConflictError("the supplier already supplies the item")
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return supplierItem, nil
+}
+
+func (s *supplierItemRepository) Edit(ctx context.Context, supplierItem *domain.SupplierItem) (*domain.SupplierItem, error) {
+	_, err := s.db.Exec(ctx, updateSupplierItem, supplierItem.SupplierID, supplierItem.ItemID,
+		supplierItem.SupplierSKU, supplierItem.UnitCost, supplierItem.Preferred, supplierItem.UpdatedAt)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return supplierItem, nil
+}
+
+func (s *supplierItemRepository) Delete(ctx context.Context, supplierID string, itemID string) error {
+	_, err := s.db.Exec(ctx, deleteSupplierItem, supplierID, itemID)
+	if err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+
+	return nil
+}
+
+func (s *supplierItemRepository) ClearPreferred(ctx context.Context, itemID string) error {
+	_, err := s.db.Exec(ctx, clearPreferred, itemID)
+	if err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+)
+
+type supplierRepository struct {
+	db db.DB
+}
+
+const (
+	getByID = `SELECT id, name, contact_name, email, phone, lead_time_days, minimum_order_quantity, currency, created_at,
+			updated_at FROM supplier WHERE id=$1`
+	getAll = `SELECT id, name, contact_name, email, phone, lead_time_days, minimum_order_quantity, currency, created_at,
+			updated_at FROM supplier ORDER BY name, id LIMIT $1 OFFSET $2`
+	save = `INSERT INTO supplier (id, name, contact_name, email, phone, lead_time_days, minimum_order_quantity, currency,
+			created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	update = `UPDATE supplier SET name=$2, contact_name=$3, email=$4, phone=$5, lead_time_days=$6,
+			minimum_order_quantity=$7, currency=$8, updated_at=$9 WHERE id=$1`
+	deleteByID = `DELETE FROM supplier WHERE id=$1`
+)
+
+// NewSupplierRepository stores suppliers in a SQL database, Postgres or SQLite. The schema must be migrated
+func NewSupplierRepository(database db.DB) domain.SupplierRepository {
+	return &supplierRepository{db: database}
+}
+
+func (s *supplierRepository) GetAll(ctx context.Context, count int, offset int) ([]domain.Supplier, error) {
+	rows, err := s.db.Query(ctx, getAll, count, offset)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var suppliers []domain.Supplier
+	for rows.Next() {
+		var supplier domain.Supplier
+		err = rows.Scan(&supplier.ID, &supplier.Name, &supplier.ContactName, &supplier.Email, &supplier.Phone,
+			&supplier.LeadTimeDays, &supplier.MinimumOrderQuantity, &supplier.Currency, &supplier.CreatedAt,
+			&supplier.UpdatedAt)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		suppliers = append(suppliers, supplier)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return suppliers, nil
+}
+
+func (s *supplierRepository) GetOne(ctx context.Context, id string) (*domain.Supplier, error) {
+	var supplier domain.Supplier
+	err := s.db.QueryRow(ctx, getByID, id).
+		Scan(&supplier.ID, &supplier.Name, &supplier.ContactName, &supplier.Email, &supplier.Phone,
+			&supplier.LeadTimeDays, &supplier.MinimumOrderQuantity, &supplier.Currency, &supplier.CreatedAt,
+			&supplier.UpdatedAt)
+	if err != nil && err != db.ErrNoRows {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return &supplier, nil
+}
+
+func (s *supplierRepository) Save(ctx context.Context, supplier *domain.Supplier) (*domain.Supplier, error) {
+	_, err := s.db.Exec(ctx, save, supplier.ID, supplier.Name, supplier.ContactName, supplier.Email, supplier.Phone,
+		supplier.LeadTimeDays, supplier.MinimumOrderQuantity, supplier.Currency, supplier.CreatedAt, supplier.UpdatedAt)
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, errors.NewConflictError("supplier already exists")
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return supplier, nil
+}
+
+func (s *supplierRepository) Edit(ctx context.Context, supplier *domain.Supplier) (*domain.Supplier, error) {
+	_, err := s.db.Exec(ctx, update, supplier.ID, supplier.Name, supplier.ContactName, supplier.Email, supplier.Phone,
+		supplier.LeadTimeDays, supplier.MinimumOrderQuantity, supplier.Currency, supplier.UpdatedAt)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return supplier, nil
+}
+
+func (s *supplierRepository) Delete(ctx context.Context, id string) error {
+	_, err := s.db.Exec(ctx, deleteByID, id)
+	if err != nil {
+		if db.IsForeignKeyViolation(err) {
+			return errors.NewConflictError("can't delete supplier while purchase orders reference it")
+		}
+		return errors.NewInternalServerError(err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package usecase
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"strings"
+	"time"
+)
+
+type supplierItemUseCase struct {
+	supplierItemRepository domain.SupplierItemRepository
+	transactor             domain.Transactor
+	timeout                time.Duration
+}
+
+// NewSupplierItemUseCase changes the preferred supplier of an item in one transaction through transactor
+func NewSupplierItemUseCase(repository domain.SupplierItemRepository, transactor domain.Transactor,
+	timeout time.Duration) domain.SupplierItemUseCase {
+	return &supplierItemUseCase{supplierItemRepository: repository, transactor: transactor, timeout: timeout}
+}
+
+func (s *supplierItemUseCase) GetForSupplier(ctx context.Context, supplierID string, count int,
+	offset int) ([]domain.SupplierItem, error) {
+	c, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	supplierItems, err := s.supplierItemRepository.GetForSupplier(c, supplierID, count, offset)
+	if err != nil {
+		return nil, err
+	}
+	if len(supplierItems) == 0 {
+		return nil, errors.NewNotFoundError("no items found for the supplier")
+	}
+
+	return supplierItems, nil
+}
+
+func (s *supplierItemUseCase) GetForItem(ctx context.Context, itemID string) ([]domain.SupplierItem, error) {
+	c, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	supplierItems, err := s.supplierItemRepository.GetForItem(c, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if len(supplierItems) == 0 {
+		return nil, errors.NewNotFoundError("no suppliers found for the item")
+	}
+
+	return supplierItems, nil
+}
+
+func (s *supplierItemUseCase) GetOne(ctx context.Context, supplierID string,
+	itemID string) (*domain.SupplierItem, error) {
+	c, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	supplierItem, err := s.supplierItemRepository.GetOne(c, supplierID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if supplierItem.SupplierID == "" {
+		return nil, errors.NewNotFoundError("the supplier doesn't supply the item")
+	}
+
+	return supplierItem, nil
+}
+
+func (s *supplierItemUseCase) Create(ctx context.Context, supplierItem *domain.SupplierItem) (*domain.SupplierItem, error) {
+	c, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if err := validateSupplierItem(supplierItem); err != nil {
+		return nil, err
+	}
+
+	supplierItem.CreatedAt = time.Now()
+	supplierItem.UpdatedAt = supplierItem.CreatedAt
+	err := s.transactor.WithinTransaction(c, func(c context.Context) error {
+		if supplierItem.Preferred {
+			if err := s.supplierItemRepository.ClearPreferred(c, supplierItem.ItemID); err != nil {
+				return err
+			}
+		}
+		_, err := s.supplierItemRepository.Save(c, supplierItem)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return supplierItem, nil
+}
+
+func (s *supplierItemUseCase) Update(ctx context.Context, supplierItem *domain.SupplierItem) (*domain.SupplierItem, error) {
+	c, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if err := validateSupplierItem(supplierItem); err != nil {
+		return nil, err
+	}
+
+	err := s.transactor.WithinTransaction(c, func(c context.Context) error {
+		existing, err := s.supplierItemRepository.GetOne(c, supplierItem.SupplierID, supplierItem.ItemID)
+		if err != nil {
+			return err
+		}
+		if existing.SupplierID == "" {
+			return errors.NewNotFoundError("the supplier doesn't supply the item")
+		}
+
+		if supplierItem.Preferred && !existing.Preferred {
+			if err = s.supplierItemRepository.ClearPreferred(c, supplierItem.ItemID); err != nil {
+				return err
+			}
+		}
+		supplierItem.CreatedAt = existing.CreatedAt
+		supplierItem.UpdatedAt = time.Now()
+		_, err = s.supplierItemRepository.Edit(c, supplierItem)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return supplierItem, nil
+}
+
+func (s *supplierItemUseCase) Delete(ctx context.Context, supplierID string, itemID string) error {
+	c, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	existing, err := s.supplierItemRepository.GetOne(c, supplierID, itemID)
+	if err != nil {
+		return err
+	}
+	if existing.SupplierID == "" {
+		return errors.NewNotFoundError("the supplier doesn't supply the item")
+	}
+
+	return s.supplierItemRepository.Delete(c, supplierID, itemID)
+}
+
+func validateSupplierItem(supplierItem *domain.SupplierItem) error {
+	if supplierItem.SupplierID == "" || supplierItem.ItemID == "" {
+		return errors.NewBadRequestError("invalid request. Supplier and item are required")
+	}
+	if supplierItem.UnitCost.IsNegative() {
+		return errors.NewBadRequestError("invalid request. Unit cost can't be negative")
+	}
+	supplierItem.SupplierSKU = strings.TrimSpace(supplierItem.SupplierSKU)
+	return nil
+}
@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+type supplierUseCase struct {
+	supplierRepository domain.SupplierRepository
+	timeout            time.Duration
+}
+
+func NewSupplierUseCase(repository domain.SupplierRepository, timeout time.Duration) domain.SupplierUseCase {
+	return &supplierUseCase{supplierRepository: repository, timeout: timeout}
+}
+
+func (s *supplierUseCase) GetAll(ctx context.Context, count int, offset int) ([]domain.Supplier, error) {
+	c, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	suppliers, err := s.supplierRepository.GetAll(c, count, offset)
+	if err != nil {
+		return nil, err
+	}
+	if len(suppliers) == 0 {
+		return nil, errors.NewNotFoundError("no suppliers found")
+	}
+
+	return suppliers, nil
+}
+
+func (s *supplierUseCase) GetOne(ctx context.Context, id string) (*domain.Supplier, error) {
+	c, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	supplier, err := s.supplierRepository.GetOne(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if supplier.ID == "" {
+		return nil, errors.NewNotFoundError("no such supplier exists")
+	}
+
+	return supplier, nil
+}
+
+func (s *supplierUseCase) Create(ctx context.Context, supplier *domain.Supplier) (*domain.Supplier, error) {
+	c, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if err := validate(supplier); err != nil {
+		return nil, err
+	}
+
+	supplier.ID = uuid.NewString()
+	supplier.CreatedAt = time.Now()
+	supplier.UpdatedAt = supplier.CreatedAt
+	return s.supplierRepository.Save(c, supplier)
+}
+
+func (s *supplierUseCase) Update(ctx context.Context, supplier *domain.Supplier) (*domain.Supplier, error) {
+	c, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if err := validate(supplier); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.supplierRepository.GetOne(c, supplier.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing.ID == "" {
+		return nil, errors.NewNotFoundError("no such supplier exists")
+	}
+
+	supplier.CreatedAt = existing.CreatedAt
+	supplier.UpdatedAt = time.Now()
+	return s.supplierRepository.Edit(c, supplier)
+}
+
+func (s *supplierUseCase) Delete(ctx context.Context, id string) error {
+	c, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	existing, err := s.supplierRepository.GetOne(c, id)
+	if err != nil {
+		return err
+	}
+	if existing.ID == "" {
+		return errors.NewNotFoundError("no such supplier exists")
+	}
+
+	return s.supplierRepository.Delete(c, id)
+}
+
+// validate checks the supplier's details, defaulting the minimum order quantity and currency
+func validate(supplier *domain.Supplier) error {
+	if strings.TrimSpace(supplier.Name) == "" {
+		return errors.NewBadRequestError("invalid request. Supplier name can't be empty")
+	}
+	if supplier.Email != "" {
+		if _, err := mail.ParseAddress(supplier.Email); err != nil {
+			return errors.NewBadRequestError("invalid request. Email isn't a valid address")
+		}
+	}
+	if supplier.LeadTimeDays < 0 {
+		return errors.NewBadRequestError("invalid request. Lead time can't be negative")
+	}
+	if supplier.MinimumOrderQuantity < 0 {
+		return errors.NewBadRequestError("invalid request. Minimum order quantity can't be negative")
+	}
+	if supplier.MinimumOrderQuantity == 0 {
+		supplier.MinimumOrderQuantity = 1
+	}
+	if supplier.Currency == "" {
+		supplier.Currency = domain.DefaultCurrency
+	}
+	if !currencyCode.MatchString(supplier.Currency) {
+		return errors.NewBadRequestError("invalid request. Currency must be a three letter ISO 4217 code, e.g. USD")
+	}
+	return nil
+}
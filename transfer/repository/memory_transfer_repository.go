@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"sort"
+)
+
+type memoryTransferRepository struct {
+	store *memory.Store
+}
+
+// NewMemoryTransferRepository keeps transfers in store instead of a database. store must be shared with the location
+// repository so transfers can only reference existing locations
+func NewMemoryTransferRepository(store *memory.Store) domain.TransferRepository {
+	return &memoryTransferRepository{store: store}
+}
+
+func (t *memoryTransferRepository) GetAll(ctx context.Context, count int, offset int,
+	status domain.TransferStatus) ([]domain.Transfer, error) {
+	defer t.store.Read(ctx)()
+
+	var transfers []domain.Transfer
+	for _, transfer := range t.store.Transfers {
+		if status == "" || transfer.Status == status {
+			transfers = append(transfers, copyTransfer(transfer))
+		}
+	}
+	sort.Slice(transfers, func(a, b int) bool {
+		if !transfers[a].CreatedAt.Equal(transfers[b].CreatedAt) {
+			return transfers[a].CreatedAt.After(transfers[b].CreatedAt)
+		}
+		return transfers[a].ID < transfers[b].ID
+	})
+
+	start, end := memory.Page(len(transfers), count, offset)
+	return transfers[start:end], nil
+}
+
+func (t *memoryTransferRepository) GetOne(ctx context.Context, id string) (*domain.Transfer, error) {
+	defer t.store.Read(ctx)()
+
+	transfer := copyTransfer(t.store.Transfers[id])
+	return &transfer, nil
+}
+
+// GetOneForUpdate needs no lock of its own, transactions hold the whole store
+func (t *memoryTransferRepository) GetOneForUpdate(ctx context.Context, id string) (*domain.Transfer, error) {
+	return t.GetOne(ctx, id)
+}
+
+func (t *memoryTransferRepository) Save(ctx context.Context, transfer *domain.Transfer) (*domain.Transfer, error) {
+	defer t.store.Write(ctx)()
+
+	_, sourceExists := t.store.Locations[transfer.SourceLocationID]
+	_, destinationExists := t.store.Locations[transfer.DestinationLocationID]
+	if !sourceExists || !destinationExists {
+		return nil, errors.NewBadRequestError("no location with such ID exists")
+	}
+	if _, ok := t.store.Transfers[transfer.ID]; ok {
+		return nil, errors.NewConflictError("transfer already exists")
+	}
+	t.store.Transfers[transfer.ID] = copyTransfer(*transfer)
+	return transfer, nil
+}
+
+func (t *memoryTransferRepository) Edit(ctx context.Context, transfer *domain.Transfer) (*domain.Transfer, error) {
+	defer t.store.Write(ctx)()
+
+	if _, ok := t.store.Transfers[transfer.ID]; ok {
+		t.store.Transfers[transfer.ID] = copyTransfer(*transfer)
+	}
+	return transfer, nil
+}
+
+// copyTransfer keeps the lines held by the store from being changed through the transfers handed out
+func copyTransfer(transfer domain.Transfer) domain.Transfer {
+	transfer.Lines = append([]domain.TransferLine(nil), transfer.Lines...)
+	return transfer
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/nuzurie/shopify/db"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+)
+
+type transferRepository struct {
+	db db.DB
+}
+
+const (
+	getAll = `SELECT id, source_location_id, destination_location_id, status, created_at, updated_at, shipped_at,
+			received_at FROM transfer WHERE %s ORDER BY created_at DESC, id LIMIT $%d OFFSET $%d`
+	getByID = `SELECT id, source_location_id, destination_location_id, status, created_at, updated_at, shipped_at,
+			received_at FROM transfer WHERE id=$1`
+	getLines = `SELECT item_id, quantity, lot_id, shipped, received, discrepancy FROM transfer_line WHERE transfer_id=$1
+			ORDER BY item_id`
+	save = `INSERT INTO transfer (id, source_location_id, destination_location_id, status, created_at, updated_at,
+			shipped_at, received_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	saveLine = `INSERT INTO transfer_line (transfer_id, item_id, quantity, lot_id, shipped, received, discrepancy)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	update     = `UPDATE transfer SET status=$2, updated_at=$3, shipped_at=$4, received_at=$5 WHERE id=$1`
+	updateLine = `UPDATE transfer_line SET shipped=$3, received=$4, discrepancy=$5 WHERE transfer_id=$1 AND item_id=$2`
+	// SQLite has no row locks, but it only ever runs one transaction at a time
+	forUpdate = ` FOR UPDATE`
+)
+
+// NewTransferRepository stores transfers in a SQL database, Postgres or SQLite. The schema must be migrated
+func NewTransferRepository(database db.DB) domain.TransferRepository {
+	return &transferRepository{db: database}
+}
+
+func (t *transferRepository) GetAll(ctx context.Context, count int, offset int,
+	status domain.TransferStatus) ([]domain.Transfer, error) {
+	condition, args := "1=1", []interface{}{}
+	if status != "" {
+		condition, args = "status=$1", append(args, status)
+	}
+	query := fmt.Sprintf(getAll, condition, len(args)+1, len(args)+2)
+	rows, err := t.db.Query(ctx, query, append(args, count, offset)...)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var transfers []domain.Transfer
+	for rows.Next() {
+		var transfer domain.Transfer
+		err = rows.Scan(&transfer.ID, &transfer.SourceLocationID, &transfer.DestinationLocationID, &transfer.Status,
+			&transfer.CreatedAt, &transfer.UpdatedAt, &transfer.ShippedAt, &transfer.ReceivedAt)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		transfers = append(transfers, transfer)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	rows.Close()
+
+	for index := range transfers {
+		if transfers[index].Lines, err = t.getLines(ctx, transfers[index].ID); err != nil {
+			return nil, err
+		}
+	}
+	return transfers, nil
+}
+
+func (t *transferRepository) GetOne(ctx context.Context, id string) (*domain.Transfer, error) {
+	return t.getOne(ctx, getByID, id)
+}
+
+func (t *transferRepository) GetOneForUpdate(ctx context.Context, id string) (*domain.Transfer, error) {
+	query := getByID
+	if t.db.Dialect() == domain.Postgres {
+		query += forUpdate
+	}
+	return t.getOne(ctx, query, id)
+}
+
+func (t *transferRepository) getOne(ctx context.Context, query string, id string) (*domain.Transfer, error) {
+	var transfer domain.Transfer
+	err := t.db.QueryRow(ctx, query, id).
+		Scan(&transfer.ID, &transfer.SourceLocationID, &transfer.DestinationLocationID, &transfer.Status,
+			&transfer.CreatedAt, &transfer.UpdatedAt, &transfer.ShippedAt, &transfer.ReceivedAt)
+	if err == db.ErrNoRows {
+		return &transfer, nil
+	}
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	if transfer.Lines, err = t.getLines(ctx, transfer.ID); err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func (t *transferRepository) getLines(ctx context.Context, transferID string) ([]domain.TransferLine, error) {
+	rows, err := t.db.Query(ctx, getLines, transferID)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var lines []domain.TransferLine
+	for rows.Next() {
+		var line domain.TransferLine
+		err = rows.Scan(&line.ItemID, &line.Quantity, &line.LotID, &line.Shipped, &line.Received, &line.Discrepancy)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		lines = append(lines, line)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return lines, nil
+}
+
+func (t *transferRepository) Save(ctx context.Context, transfer *domain.Transfer) (*domain.Transfer, error) {
+	tx, err := t.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, save, transfer.ID, transfer.SourceLocationID, transfer.DestinationLocationID, transfer.Status,
+		transfer.CreatedAt, transfer.UpdatedAt, transfer.ShippedAt, transfer.ReceivedAt)
+	if err != nil {
+		if db.IsForeignKeyViolation(err) {
+			return nil, errors.NewBadRequestError("no location with such ID exists")
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	for _, line := range transfer.Lines {
+		_, err = tx.Exec(ctx, saveLine, transfer.ID, line.ItemID, line.Quantity, line.LotID, line.Shipped,
+			line.Received, line.Discrepancy)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return transfer, nil
+}
+
+func (t *transferRepository) Edit(ctx context.Context, transfer *domain.Transfer) (*domain.Transfer, error) {
+	tx, err := t.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, update, transfer.ID, transfer.Status, transfer.UpdatedAt, transfer.ShippedAt,
+		transfer.ReceivedAt)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	for _, line := range transfer.Lines {
+		_, err = tx.Exec(ctx, updateLine, transfer.ID, line.ItemID, line.Shipped, line.Received, line.Discrepancy)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return transfer, nil
+}
@@ -0,0 +1,428 @@
+package usecase
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"strconv"
+	"time"
+)
+
+type transferUseCase struct {
+	transferRepository  domain.TransferRepository
+	itemRepository      domain.ItemRepository
+	inventoryRepository domain.InventoryRepository
+	lotRepository       domain.LotRepository
+	inventoryUseCase    domain.InventoryUseCase
+	transactor          domain.Transactor
+	timeout             time.Duration
+}
+
+// NewTransferUseCase moves stock through inventoryUseCase, in the same transaction as the change to the transfer. The
+// lots lines ship from and arrive in are kept in lotRepository
+func NewTransferUseCase(transferRepository domain.TransferRepository, itemRepository domain.ItemRepository,
+	inventoryRepository domain.InventoryRepository, lotRepository domain.LotRepository,
+	inventoryUseCase domain.InventoryUseCase, transactor domain.Transactor, timeout time.Duration) domain.TransferUseCase {
+	return &transferUseCase{transferRepository: transferRepository, itemRepository: itemRepository,
+		inventoryRepository: inventoryRepository, lotRepository: lotRepository, inventoryUseCase: inventoryUseCase,
+		transactor: transactor, timeout: timeout}
+}
+
+func (t *transferUseCase) GetAll(ctx context.Context, count int, offset int,
+	status domain.TransferStatus) ([]domain.Transfer, error) {
+	c, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	transfers, err := t.transferRepository.GetAll(c, count, offset, status)
+	if err != nil {
+		return nil, err
+	}
+	if len(transfers) == 0 {
+		return nil, errors.NewNotFoundError("no transfers found")
+	}
+
+	for index := range transfers {
+		fillInTransit(&transfers[index])
+	}
+	return transfers, nil
+}
+
+func (t *transferUseCase) GetOne(ctx context.Context, id string) (*domain.Transfer, error) {
+	c, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	transfer, err := t.transferRepository.GetOne(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.ID == "" {
+		return nil, errors.NewNotFoundError("no such transfer found")
+	}
+
+	fillInTransit(transfer)
+	return transfer, nil
+}
+
+func (t *transferUseCase) Create(ctx context.Context, transfer *domain.Transfer) (*domain.Transfer, error) {
+	c, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	if transfer.SourceLocationID == "" || transfer.DestinationLocationID == "" {
+		return nil, errors.NewBadRequestError("invalid request. Source and destination locations are required")
+	}
+	if transfer.SourceLocationID == transfer.DestinationLocationID {
+		return nil, errors.NewBadRequestError("invalid request. Source and destination locations must differ")
+	}
+	if len(transfer.Lines) == 0 {
+		return nil, errors.NewBadRequestError("invalid request. A transfer needs at least one line")
+	}
+
+	seen := map[string]bool{}
+	for index, line := range transfer.Lines {
+		if line.Quantity <= 0 {
+			return nil, errors.NewBadRequestError("invalid request. Quantity must be more than 0")
+		}
+		if seen[line.ItemID] {
+			return nil, errors.NewBadRequestError("invalid request. Item " + line.ItemID + " is on more than one line")
+		}
+		seen[line.ItemID] = true
+
+		item, err := t.itemRepository.GetOne(c, line.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		if item.ID == "" {
+			return nil, errors.NewBadRequestError("no item with ID " + line.ItemID + " exists")
+		}
+		factor, ok := item.UnitFactor(line.Unit)
+		if !ok {
+			return nil, errors.NewBadRequestError("invalid request. Item " + line.ItemID + " isn't counted in " +
+				line.Unit)
+		}
+		if line.Quantity, ok = domain.ScaleQuantity(line.Quantity, factor); !ok {
+			return nil, errors.NewBadRequestError("invalid request. Quantity is too large for item " + line.ItemID)
+		}
+		// units in transit have no location, so serials couldn't say where they are
+		if item.Serialized {
+			return nil, errors.NewBadRequestError("invalid request. Item " + line.ItemID +
+				" is serialized, and serialized items can't be transferred")
+		}
+		if line.LotID != "" {
+			lot, err := t.lotRepository.GetOne(c, line.LotID)
+			if err != nil {
+				return nil, err
+			}
+			if lot.ID == "" || lot.ItemID != line.ItemID || lot.LocationID != transfer.SourceLocationID {
+				return nil, errors.NewBadRequestError("invalid request. No lot with ID " + line.LotID + " holds item " +
+					line.ItemID + " at the source location")
+			}
+		}
+		transfer.Lines[index] = domain.TransferLine{ItemID: line.ItemID, Quantity: line.Quantity, LotID: line.LotID}
+	}
+
+	transfer.ID = uuid.NewString()
+	transfer.Status = domain.TransferCreated
+	transfer.CreatedAt = time.Now()
+	transfer.UpdatedAt = transfer.CreatedAt
+	transfer.ShippedAt = nil
+	transfer.ReceivedAt = nil
+	if _, err := t.transferRepository.Save(c, transfer); err != nil {
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+func (t *transferUseCase) Ship(ctx context.Context, id string) (*domain.Transfer, error) {
+	return t.update(ctx, id, func(c context.Context, transfer *domain.Transfer) error {
+		if transfer.Status != domain.TransferCreated {
+			return errors.NewConflictError("only created transfers can be shipped, this one is " + string(transfer.Status))
+		}
+
+		for index, line := range transfer.Lines {
+			if err := t.shipFromLot(c, transfer.SourceLocationID, line); err != nil {
+				return err
+			}
+			_, err := t.inventoryUseCase.MoveStock(c, line.ItemID, transfer.SourceLocationID,
+				domain.StockAdjustment{Delta: -line.Quantity, Reason: domain.ReasonTransferOut})
+			if err != nil {
+				return err
+			}
+			transfer.Lines[index].Shipped = line.Quantity
+		}
+
+		now := time.Now()
+		transfer.Status = domain.TransferShipped
+		transfer.ShippedAt = &now
+		return nil
+	})
+}
+
+func (t *transferUseCase) Receive(ctx context.Context, id string, receipt domain.TransferReceipt) (*domain.Transfer, error) {
+	if len(receipt.Lines) == 0 && !receipt.Close {
+		return nil, errors.NewBadRequestError("invalid request. A receipt needs at least one line or to close the transfer")
+	}
+
+	return t.update(ctx, id, func(c context.Context, transfer *domain.Transfer) error {
+		if transfer.Status != domain.TransferShipped && transfer.Status != domain.TransferPartiallyReceived {
+			return errors.NewConflictError("only shipped transfers can be received, this one is " + string(transfer.Status))
+		}
+
+		lines := map[string]int{}
+		for index, line := range transfer.Lines {
+			lines[line.ItemID] = index
+		}
+		for _, received := range receipt.Lines {
+			index, ok := lines[received.ItemID]
+			if !ok {
+				return errors.NewBadRequestError("invalid request. Item " + received.ItemID + " isn't on the transfer")
+			}
+			if received.Quantity <= 0 {
+				return errors.NewBadRequestError("invalid request. Quantity must be more than 0")
+			}
+			if received.Unit != "" {
+				item, err := t.itemRepository.GetOne(c, received.ItemID)
+				if err != nil {
+					return err
+				}
+				factor, ok := item.UnitFactor(received.Unit)
+				if !ok {
+					return errors.NewBadRequestError("invalid request. Item " + received.ItemID + " isn't counted in " +
+						received.Unit)
+				}
+				if received.Quantity, ok = domain.ScaleQuantity(received.Quantity, factor); !ok {
+					return errors.NewBadRequestError("invalid request. Quantity is too large for item " +
+						received.ItemID)
+				}
+			}
+
+			// units that were never shipped come from nowhere, so unlike those in transit they are costed as found
+			line := transfer.Lines[index]
+			inTransit := received.Quantity
+			if outstanding := line.Shipped - line.Received; inTransit > outstanding {
+				inTransit = outstanding
+				if inTransit < 0 {
+					inTransit = 0
+				}
+			}
+			if err := t.moveStock(c, received.ItemID, transfer.DestinationLocationID, inTransit,
+				domain.ReasonTransferIn); err != nil {
+				return err
+			}
+			if err := t.moveStock(c, received.ItemID, transfer.DestinationLocationID, received.Quantity-inTransit,
+				domain.ReasonCorrection); err != nil {
+				return err
+			}
+			if err := t.receiveIntoLot(c, transfer.DestinationLocationID, line, received.Quantity); err != nil {
+				return err
+			}
+			transfer.Lines[index].Received += received.Quantity
+		}
+
+		transfer.Status = domain.TransferReceived
+		for _, line := range transfer.Lines {
+			if line.Received < line.Shipped && !receipt.Close {
+				transfer.Status = domain.TransferPartiallyReceived
+			}
+		}
+		if transfer.Status == domain.TransferReceived {
+			// whatever didn't arrive is written off rather than left in transit forever. It never reached the
+			// destination, so it is lost from transit and its cost leaves the books
+			for index, line := range transfer.Lines {
+				transfer.Lines[index].Discrepancy = line.Shipped - line.Received
+				if lost := transfer.Lines[index].Discrepancy; lost > 0 {
+					if err := t.inventoryUseCase.WriteOffInTransit(c, line.ItemID, lost); err != nil {
+						return err
+					}
+				}
+			}
+			now := time.Now()
+			transfer.ReceivedAt = &now
+		}
+		return nil
+	})
+}
+
+// shipFromLot takes the units line ships out of its lot at the source, before they leave the inventory so that they
+// aren't taken out of its other lots as well. Lines without a lot can only ship the stock held outside lots
+func (t *transferUseCase) shipFromLot(c context.Context, sourceLocationID string, line domain.TransferLine) error {
+	if line.LotID == "" {
+		inventory, err := t.inventoryRepository.GetInventoryAtLocation(c, line.ItemID, sourceLocationID)
+		if err != nil || inventory.ID == "" {
+			return err
+		}
+		lots, err := t.lotRepository.GetForInventory(c, []string{inventory.ID})
+		if err != nil {
+			return err
+		}
+		outside := inventory.Quantity
+		for _, lot := range lots {
+			outside -= lot.Quantity
+		}
+		if outside < line.Quantity && outside < inventory.Quantity {
+			return errors.NewConflictError("insufficient stock. Only " + strconv.Itoa(outside) + " units of item " +
+				line.ItemID + " at the source are held outside lots, the line must name the lot to ship the rest from")
+		}
+		return nil
+	}
+
+	lot, err := t.lotRepository.GetOneForUpdate(c, line.LotID)
+	if err != nil {
+		return err
+	}
+	if lot.ID == "" {
+		return errors.NewConflictError("lot " + line.LotID + " no longer exists")
+	}
+	if lot.Quantity < line.Quantity {
+		return errors.NewConflictError("insufficient stock. Lot " + lot.LotNumber + " holds only " +
+			strconv.Itoa(lot.Quantity))
+	}
+	lot.Quantity -= line.Quantity
+	lot.UpdatedAt = time.Now()
+	_, err = t.lotRepository.Edit(c, lot)
+	return err
+}
+
+// receiveIntoLot puts quantity units of line that arrived at the destination into the lot of the same number as the
+// one they shipped from, creating it with the dates and block of that lot if the destination doesn't hold it yet
+func (t *transferUseCase) receiveIntoLot(c context.Context, destinationLocationID string, line domain.TransferLine,
+	quantity int) error {
+	if line.LotID == "" {
+		return nil
+	}
+	shipped, err := t.lotRepository.GetOne(c, line.LotID)
+	if err != nil {
+		return err
+	}
+	if shipped.ID == "" {
+		// the lot went with its inventory at the source, so there is nothing left to say which lot the units are from
+		return nil
+	}
+	inventory, err := t.inventoryRepository.GetInventoryAtLocation(c, line.ItemID, destinationLocationID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	lot, err := t.lotRepository.GetByNumber(c, inventory.ID, shipped.LotNumber)
+	if err != nil {
+		return err
+	}
+	if lot.ID != "" {
+		lot.Quantity += quantity
+		lot.UpdatedAt = now
+		_, err = t.lotRepository.Edit(c, lot)
+		return err
+	}
+	_, err = t.lotRepository.Save(c, &domain.Lot{
+		ID:             uuid.NewString(),
+		InventoryID:    inventory.ID,
+		ItemID:         line.ItemID,
+		LocationID:     destinationLocationID,
+		LotNumber:      shipped.LotNumber,
+		Quantity:       quantity,
+		ManufacturedAt: shipped.ManufacturedAt,
+		ExpiresAt:      shipped.ExpiresAt,
+		Blocked:        shipped.Blocked,
+		BlockReason:    shipped.BlockReason,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	})
+	return err
+}
+
+// moveStock moves delta units of the item at the location for reason, if there are any to move
+func (t *transferUseCase) moveStock(c context.Context, itemID string, locationID string, delta int,
+	reason domain.AdjustmentReason) error {
+	if delta == 0 {
+		return nil
+	}
+	_, err := t.inventoryUseCase.MoveStock(c, itemID, locationID, domain.StockAdjustment{Delta: delta, Reason: reason})
+	return err
+}
+
+func (t *transferUseCase) Cancel(ctx context.Context, id string) (*domain.Transfer, error) {
+	return t.update(ctx, id, func(c context.Context, transfer *domain.Transfer) error {
+		switch transfer.Status {
+		case domain.TransferCreated:
+		case domain.TransferShipped:
+			for index, line := range transfer.Lines {
+				_, err := t.inventoryUseCase.MoveStock(c, line.ItemID, transfer.SourceLocationID,
+					domain.StockAdjustment{Delta: line.Shipped, Reason: domain.ReasonTransferCancelled})
+				if err != nil {
+					return err
+				}
+				if err = t.returnToLot(c, line); err != nil {
+					return err
+				}
+				transfer.Lines[index].Shipped = 0
+			}
+		default:
+			return errors.NewConflictError("only transfers nothing was received for can be cancelled, this one is " +
+				string(transfer.Status))
+		}
+
+		transfer.Status = domain.TransferCancelled
+		return nil
+	})
+}
+
+// returnToLot puts the units line shipped back into the lot they shipped from, unless it went with its inventory
+func (t *transferUseCase) returnToLot(c context.Context, line domain.TransferLine) error {
+	if line.LotID == "" || line.Shipped == 0 {
+		return nil
+	}
+	lot, err := t.lotRepository.GetOneForUpdate(c, line.LotID)
+	if err != nil || lot.ID == "" {
+		return err
+	}
+	lot.Quantity += line.Shipped
+	lot.UpdatedAt = time.Now()
+	_, err = t.lotRepository.Edit(c, lot)
+	return err
+}
+
+// update applies change to the transfer with the given id and saves it, in one transaction with the stock it moves
+func (t *transferUseCase) update(ctx context.Context, id string,
+	change func(c context.Context, transfer *domain.Transfer) error) (*domain.Transfer, error) {
+	c, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	var transfer *domain.Transfer
+	err := t.transactor.WithinTransaction(c, func(c context.Context) error {
+		var err error
+		transfer, err = t.transferRepository.GetOneForUpdate(c, id)
+		if err != nil {
+			return err
+		}
+		if transfer.ID == "" {
+			return errors.NewNotFoundError("no such transfer found")
+		}
+
+		if err = change(c, transfer); err != nil {
+			return err
+		}
+		transfer.UpdatedAt = time.Now()
+		_, err = t.transferRepository.Edit(c, transfer)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fillInTransit(transfer)
+	return transfer, nil
+}
+
+// fillInTransit works out what is still on its way. Nothing is once the transfer is received, what didn't arrive is
+// recorded as a discrepancy instead
+func fillInTransit(transfer *domain.Transfer) {
+	for index, line := range transfer.Lines {
+		transfer.Lines[index].InTransit = 0
+		open := transfer.Status == domain.TransferShipped || transfer.Status == domain.TransferPartiallyReceived
+		if open && line.Shipped > line.Received {
+			transfer.Lines[index].InTransit = line.Shipped - line.Received
+		}
+	}
+}
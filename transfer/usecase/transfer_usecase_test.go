@@ -0,0 +1,302 @@
+package usecase
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/inventory/repository"
+	usecase2 "github.com/nuzurie/shopify/inventory/usecase"
+	repository2 "github.com/nuzurie/shopify/item/repository"
+	repository3 "github.com/nuzurie/shopify/location/repository"
+	repository4 "github.com/nuzurie/shopify/lot/repository"
+	repository5 "github.com/nuzurie/shopify/reservation/repository"
+	repository6 "github.com/nuzurie/shopify/serial/repository"
+	repository8 "github.com/nuzurie/shopify/transfer/repository"
+	"github.com/nuzurie/shopify/utils/errors"
+	repository7 "github.com/nuzurie/shopify/valuation/repository"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"testing"
+	"time"
+)
+
+const destinationID = "east"
+
+func TestReceive(t *testing.T) {
+	cases := []struct {
+		name        string
+		receipts    []domain.TransferReceipt
+		status      domain.TransferStatus
+		discrepancy int
+		received    int
+	}{
+		{"in full", []domain.TransferReceipt{{Lines: []domain.TransferReceiptLine{{ItemID: "hat", Quantity: 20}}}},
+			domain.TransferReceived, 0, 20},
+		{"partially", []domain.TransferReceipt{{Lines: []domain.TransferReceiptLine{{ItemID: "hat", Quantity: 12}}}},
+			domain.TransferPartiallyReceived, 0, 12},
+		{"closed short", []domain.TransferReceipt{
+			{Lines: []domain.TransferReceiptLine{{ItemID: "hat", Quantity: 12}}},
+			{Lines: []domain.TransferReceiptLine{{ItemID: "hat", Quantity: 5}}, Close: true}},
+			domain.TransferReceived, 3, 17},
+		{"closed with nothing received", []domain.TransferReceipt{{Close: true}}, domain.TransferReceived, 20, 0},
+		{"more than shipped", []domain.TransferReceipt{
+			{Lines: []domain.TransferReceiptLine{{ItemID: "hat", Quantity: 15}}},
+			{Lines: []domain.TransferReceiptLine{{ItemID: "hat", Quantity: 7}}}},
+			domain.TransferReceived, -2, 22},
+	}
+	for _, c := range cases {
+		transferUseCase, inventoryUseCase, store := newTransferUseCase(t)
+		ctx := context.Background()
+		transfer := ship(t, transferUseCase, 20)
+
+		var err error
+		for _, receipt := range c.receipts {
+			if transfer, err = transferUseCase.Receive(ctx, transfer.ID, receipt); err != nil {
+				t.Fatalf("%s: Receive failed with %v", c.name, err)
+			}
+		}
+		line := transfer.Lines[0]
+		inTransit := 0
+		if c.status != domain.TransferReceived {
+			inTransit = 20 - c.received
+		}
+		if transfer.Status != c.status || line.Discrepancy != c.discrepancy || line.Received != c.received ||
+			line.InTransit != inTransit {
+			t.Errorf("%s: %s with line %+v, want %s with %d received, %d in transit and a discrepancy of %d", c.name,
+				transfer.Status, line, c.status, c.received, inTransit, c.discrepancy)
+		}
+
+		stock, err := inventoryUseCase.GetInventoryForItem(ctx, "hat")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, location := range stock.Locations {
+			want := 0
+			if location.LocationID == destinationID {
+				want = c.received
+			}
+			if location.Quantity != want {
+				t.Errorf("%s: %d at %s, want %d", c.name, location.Quantity, location.LocationID, want)
+			}
+		}
+
+		// what is lost in transit leaves the books once the transfer is received, and what is found joins them
+		onBooks := 20
+		if c.status == domain.TransferReceived {
+			onBooks = c.received
+		}
+		valuation, err := repository7.NewMemoryCostRepository(store).Valuation(ctx, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		value := decimal.Zero
+		for _, itemValuation := range valuation {
+			value = value.Add(itemValuation.Value)
+		}
+		if want := decimal.NewFromInt(int64(onBooks * 2)); !value.Equal(want) {
+			t.Errorf("%s: valued at %s, want %s", c.name, value, want)
+		}
+		// and is recorded once, against no inventory
+		var lost []domain.StockMovement
+		for _, movement := range store.Movements {
+			if movement.Reason == domain.ReasonLostInTransit || movement.Reason == domain.ReasonLost {
+				lost = append(lost, movement)
+			}
+		}
+		if c.discrepancy > 0 && (len(lost) != 1 || lost[0].Reason != domain.ReasonLostInTransit ||
+			lost[0].InventoryID != "" || lost[0].Delta != -c.discrepancy) {
+			t.Errorf("%s: lost %+v, want %d lost in transit", c.name, lost, c.discrepancy)
+		}
+		if c.discrepancy <= 0 && len(lost) != 0 {
+			t.Errorf("%s: lost %+v, want nothing lost", c.name, lost)
+		}
+	}
+}
+
+func TestTransferStatus(t *testing.T) {
+	transferUseCase, _, _ := newTransferUseCase(t)
+	ctx := context.Background()
+	created, err := transferUseCase.Create(ctx, &domain.Transfer{SourceLocationID: domain.DefaultLocationID,
+		DestinationLocationID: destinationID, Lines: []domain.TransferLine{{ItemID: "hat", Quantity: 25}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shipped := ship(t, transferUseCase, 5)
+	received := ship(t, transferUseCase, 5)
+	if _, err = transferUseCase.Receive(ctx, received.ID,
+		domain.TransferReceipt{Lines: []domain.TransferReceiptLine{{ItemID: "hat", Quantity: 1}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		change func() (*domain.Transfer, error)
+		want   int
+	}{
+		{"receive before shipping", func() (*domain.Transfer, error) {
+			return transferUseCase.Receive(ctx, created.ID, domain.TransferReceipt{Close: true})
+		}, http.StatusConflict},
+		{"ship more than in stock", func() (*domain.Transfer, error) {
+			return transferUseCase.Ship(ctx, created.ID)
+		}, http.StatusConflict},
+		{"receive an item not on the transfer", func() (*domain.Transfer, error) {
+			return transferUseCase.Receive(ctx, shipped.ID,
+				domain.TransferReceipt{Lines: []domain.TransferReceiptLine{{ItemID: "scarf", Quantity: 1}}})
+		}, http.StatusBadRequest},
+		{"empty receipt", func() (*domain.Transfer, error) {
+			return transferUseCase.Receive(ctx, shipped.ID, domain.TransferReceipt{})
+		}, http.StatusBadRequest},
+		{"cancel once some was received", func() (*domain.Transfer, error) {
+			return transferUseCase.Cancel(ctx, received.ID)
+		}, http.StatusConflict},
+		{"cancel once shipped", func() (*domain.Transfer, error) {
+			return transferUseCase.Cancel(ctx, shipped.ID)
+		}, 0},
+		{"ship once cancelled", func() (*domain.Transfer, error) {
+			return transferUseCase.Ship(ctx, shipped.ID)
+		}, http.StatusConflict},
+		{"unknown transfer", func() (*domain.Transfer, error) {
+			return transferUseCase.Ship(ctx, "missing")
+		}, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		if _, err := c.change(); statusOf(err) != c.want {
+			t.Errorf("%s: failed with %d (%v), want %d", c.name, statusOf(err), err, c.want)
+		}
+	}
+}
+
+func TestTransferLots(t *testing.T) {
+	cases := []struct {
+		name     string
+		lotID    string
+		quantity int
+		// cancel cancels the transfer once shipped instead of receiving it
+		cancel bool
+		want   int
+		// source and destination are what lot L1 holds at either end once the transfer was received or cancelled
+		source      int
+		destination int
+	}{
+		{"from a lot", "lot", 5, false, 0, 3, 5},
+		{"cancelled", "lot", 5, true, 0, 8, 0},
+		{"more than the lot holds", "lot", 9, false, http.StatusConflict, 8, 0},
+		{"outside lots", "", 20, false, 0, 8, 0},
+		{"more than is held outside lots", "", 21, false, http.StatusConflict, 8, 0},
+	}
+	for _, c := range cases {
+		transferUseCase, inventoryUseCase, store := newTransferUseCase(t)
+		ctx := context.Background()
+		lotRepository := repository4.NewMemoryLotRepository(store)
+		// 8 more hats arrive in lot L1, next to the 20 held outside lots
+		inventory, err := inventoryUseCase.MoveStock(ctx, "hat", domain.DefaultLocationID,
+			domain.StockAdjustment{Delta: 8, Reason: domain.ReasonReceived})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expiresAt := time.Now().AddDate(0, 1, 0).UTC()
+		if _, err = lotRepository.Save(ctx, &domain.Lot{ID: "lot", InventoryID: inventory.ID, ItemID: "hat",
+			LocationID: domain.DefaultLocationID, LotNumber: "L1", Quantity: 8, ExpiresAt: &expiresAt}); err != nil {
+			t.Fatal(err)
+		}
+
+		transfer, err := transferUseCase.Create(ctx, &domain.Transfer{SourceLocationID: domain.DefaultLocationID,
+			DestinationLocationID: destinationID,
+			Lines:                 []domain.TransferLine{{ItemID: "hat", Quantity: c.quantity, LotID: c.lotID}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if transfer, err = transferUseCase.Ship(ctx, transfer.ID); statusOf(err) != c.want {
+			t.Errorf("%s: Ship failed with %d (%v), want %d", c.name, statusOf(err), err, c.want)
+		}
+		if err == nil && c.cancel {
+			_, err = transferUseCase.Cancel(ctx, transfer.ID)
+		} else if err == nil {
+			_, err = transferUseCase.Receive(ctx, transfer.ID, domain.TransferReceipt{
+				Lines: []domain.TransferReceiptLine{{ItemID: "hat", Quantity: c.quantity}}})
+		}
+		if statusOf(err) != c.want {
+			t.Fatalf("%s: failed with %v", c.name, err)
+		}
+
+		lots, err := lotRepository.GetAll(ctx, 10, 0, domain.LotFilter{ItemID: "hat", LotNumber: "L1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		held := map[string]int{}
+		for _, lot := range lots {
+			held[lot.LocationID] = lot.Quantity
+			if lot.ExpiresAt == nil || !lot.ExpiresAt.Equal(expiresAt) {
+				t.Errorf("%s: lot at %s expires at %v, want %v", c.name, lot.LocationID, lot.ExpiresAt, expiresAt)
+			}
+		}
+		if held[domain.DefaultLocationID] != c.source || held[destinationID] != c.destination {
+			t.Errorf("%s: lot L1 holds %d at the source and %d at the destination, want %d and %d", c.name,
+				held[domain.DefaultLocationID], held[destinationID], c.source, c.destination)
+		}
+	}
+
+	transferUseCase, _, _ := newTransferUseCase(t)
+	_, err := transferUseCase.Create(context.Background(), &domain.Transfer{SourceLocationID: domain.DefaultLocationID,
+		DestinationLocationID: destinationID, Lines: []domain.TransferLine{{ItemID: "hat", Quantity: 1, LotID: "missing"}}})
+	if statusOf(err) != http.StatusBadRequest {
+		t.Errorf("unknown lot: Create failed with %d (%v), want %d", statusOf(err), err, http.StatusBadRequest)
+	}
+}
+
+// ship ships quantity of the 20 hats in stock at the default location to the destination
+func ship(t *testing.T, transferUseCase domain.TransferUseCase, quantity int) *domain.Transfer {
+	ctx := context.Background()
+	transfer, err := transferUseCase.Create(ctx, &domain.Transfer{SourceLocationID: domain.DefaultLocationID,
+		DestinationLocationID: destinationID, Lines: []domain.TransferLine{{ItemID: "hat", Quantity: quantity}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transfer, err = transferUseCase.Ship(ctx, transfer.ID); err != nil {
+		t.Fatal(err)
+	}
+	return transfer
+}
+
+// ignoreStockChanges is a StockWatcher for tests that don't evaluate reorder points
+type ignoreStockChanges struct{}
+
+func (ignoreStockChanges) StockChanged(string) {}
+
+// newTransferUseCase works on a store holding 20 hats at the default location, each costing 2
+func newTransferUseCase(t *testing.T) (domain.TransferUseCase, domain.InventoryUseCase, *memory.Store) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	store.Locations[destinationID] = domain.Location{ID: destinationID, Name: "East", Type: domain.LocationStorage}
+	itemRepository := repository2.NewMemoryItemRepository(store)
+	if _, err := itemRepository.Save(ctx, &domain.Item{ID: "hat", Name: "hat", BaseUnit: domain.DefaultBaseUnit,
+		Currency: domain.DefaultCurrency, CostingMethod: domain.DefaultCostingMethod, Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	inventoryRepository := repository.NewMemoryInventoryRepository(store)
+	lotRepository := repository4.NewMemoryLotRepository(store)
+	inventoryUseCase := usecase2.NewInventoryUseCase(itemRepository, inventoryRepository,
+		repository.NewMemoryMovementRepository(store), repository5.NewMemoryReservationRepository(store),
+		repository3.NewMemoryLocationRepository(store), lotRepository, repository6.NewMemorySerialRepository(store),
+		repository7.NewMemoryCostRepository(store), ignoreStockChanges{}, store, time.Second)
+	unitCost := decimal.NewFromInt(2)
+	if _, err := inventoryUseCase.MoveStock(ctx, "hat", domain.DefaultLocationID,
+		domain.StockAdjustment{Delta: 20, Reason: domain.ReasonReceived, UnitCost: &unitCost}); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewTransferUseCase(repository8.NewMemoryTransferRepository(store), itemRepository, inventoryRepository,
+		lotRepository, inventoryUseCase, store, time.Second), inventoryUseCase, store
+}
+
+// statusOf is the status code of err, or 0 if there is none
+func statusOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	if restError, ok := err.(*errors.RestError); ok {
+		return restError.Code
+	}
+	return http.StatusInternalServerError
+}
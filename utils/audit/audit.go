@@ -0,0 +1,30 @@
+package audit
+
+import "context"
+
+type actorKey struct{}
+
+type correlationIDKey struct{}
+
+// WithActor returns a context recording who is making the changes done with it
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns who is making the changes done with ctx, or "anonymous" when nobody said
+func Actor(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return "anonymous"
+}
+
+// WithCorrelationID returns a context tying the changes done with it to a single request
+func WithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, correlationID)
+}
+
+func CorrelationID(ctx context.Context) string {
+	correlationID, _ := ctx.Value(correlationIDKey{}).(string)
+	return correlationID
+}
@@ -51,3 +51,17 @@ func NewUnauthorizedError(message string) *RestError {
 		Message: message,
 	}
 }
+
+// NewPreconditionFailedError returns error with status code 412
+func NewPreconditionFailedError(message string) *RestError {
+	return &RestError{
+		Code:    http.StatusPreconditionFailed,
+		Message: message,
+	}
+}
+
+// IsNotFound reports whether err is a RestError with status code 404
+func IsNotFound(err error) bool {
+	restErr, ok := err.(*RestError)
+	return ok && restErr.Code == http.StatusNotFound
+}
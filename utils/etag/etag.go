@@ -0,0 +1,36 @@
+// Package etag converts between resource versions and the entity tags of the ETag and If-Match headers
+package etag
+
+import (
+	"github.com/nuzurie/shopify/utils/errors"
+	"strconv"
+	"strings"
+)
+
+// Format returns the entity tag of a resource at version
+func Format(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// Parse returns the version an If-Match header asks for. An absent header and * match any version, which is
+// reported as 0
+func Parse(ifMatch string) (int, *errors.RestError) {
+	ifMatch = strings.TrimSpace(ifMatch)
+	if ifMatch == "" || ifMatch == "*" {
+		return 0, nil
+	}
+
+	// If-Match uses strong comparison, which a weak tag never passes
+	if strings.HasPrefix(ifMatch, "W/") {
+		return 0, errors.NewPreconditionFailedError("weak ETags never match")
+	}
+	tag, err := strconv.Unquote(ifMatch)
+	if err != nil {
+		return 0, errors.NewBadRequestError("invalid If-Match. Expected a single ETag")
+	}
+	version, err := strconv.Atoi(tag)
+	if err != nil || version <= 0 {
+		return 0, errors.NewPreconditionFailedError("resource has been modified")
+	}
+	return version, nil
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type ValuationHandler struct {
+	useCase domain.ValuationUseCase
+}
+
+func NewValuationHandler(useCase domain.ValuationUseCase) *ValuationHandler {
+	return &ValuationHandler{useCase: useCase}
+}
+
+// Valuation values the stock as it was at the time given by at, or as it is now
+func (h *ValuationHandler) Valuation(c *gin.Context) {
+	var at time.Time
+	if atQuery, ok := c.GetQuery("at"); ok {
+		var err error
+		if at, err = parseTime(atQuery); err != nil {
+			c.JSON(http.StatusBadRequest, errors.NewBadRequestError("invalid at date"))
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	valuation, err := h.useCase.Valuation(ctx, at)
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, valuation)
+}
+
+func (h *ValuationHandler) GetLayers(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, errors.NewBadRequestError("id not provided"))
+		return
+	}
+
+	var count int64
+	if countQuery, ok := c.GetQuery("count"); ok {
+		count, _ = strconv.ParseInt(countQuery, 10, 64)
+	} else {
+		count = 20
+	}
+
+	var offset int64
+	if offsetQuery, ok := c.GetQuery("offset"); ok {
+		offset, _ = strconv.ParseInt(offsetQuery, 10, 64)
+	}
+
+	ctx := c.Request.Context()
+	layers, err := h.useCase.GetLayers(ctx, id, int(count), int(offset))
+	if err != nil {
+		switch v := err.(type) {
+		case *errors.RestError:
+			c.JSON(v.Code, v)
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, errors.NewInternalServerError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, layers)
+}
+
+// parseTime accepts RFC 3339 timestamps or plain dates, which are taken as midnight UTC
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
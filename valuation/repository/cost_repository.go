@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type costRepository struct {
+	db db.DB
+}
+
+const (
+	getLayers = `SELECT id, item_id, movement_id, quantity, unit_cost, remaining, remaining_value, created_at, updated_at
+			FROM cost_layer WHERE item_id=$1 ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3`
+	getOpenLayers = `SELECT id, item_id, movement_id, quantity, unit_cost, remaining, remaining_value, created_at,
+			updated_at FROM cost_layer WHERE item_id=$1 AND remaining>0 ORDER BY created_at, id`
+	saveLayer = `INSERT INTO cost_layer (id, item_id, movement_id, quantity, unit_cost, remaining, remaining_value,
+			created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	updateLayer     = `UPDATE cost_layer SET remaining=$2, remaining_value=$3, updated_at=$4 WHERE id=$1`
+	saveConsumption = `INSERT INTO cost_consumption (id, item_id, layer_id, movement_id, quantity, cost, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	// valuation lists what the layers brought in, then what the consumptions took out of them. Pooling layers moves
+	// value between them, which leaves the sums alone. They are taken in Go rather than SQL, since SQLite keeps costs
+	// as text and would sum them as floats
+	valuation = `SELECT item_id, quantity, unit_cost, true FROM cost_layer WHERE created_at<=$1
+			UNION ALL
+			SELECT item_id, quantity, cost, false FROM cost_consumption WHERE created_at<=$2
+			ORDER BY item_id`
+	// SQLite has no row locks, but it only ever runs one transaction at a time
+	forUpdate = ` FOR UPDATE`
+)
+
+// NewCostRepository stores cost layers and what was consumed from them in a SQL database, Postgres or SQLite. The
+// schema must be migrated
+func NewCostRepository(database db.DB) domain.CostRepository {
+	return &costRepository{db: database}
+}
+
+func (r *costRepository) GetLayers(ctx context.Context, itemID string, count int,
+	offset int) ([]domain.CostLayer, error) {
+	return r.query(ctx, getLayers, itemID, count, offset)
+}
+
+func (r *costRepository) GetOpenLayersForUpdate(ctx context.Context, itemID string) ([]domain.CostLayer, error) {
+	query := getOpenLayers
+	if r.db.Dialect() == domain.Postgres {
+		query += forUpdate
+	}
+	return r.query(ctx, query, itemID)
+}
+
+func (r *costRepository) query(ctx context.Context, query string, args ...interface{}) ([]domain.CostLayer, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var layers []domain.CostLayer
+	for rows.Next() {
+		var layer domain.CostLayer
+		err = rows.Scan(&layer.ID, &layer.ItemID, &layer.MovementID, &layer.Quantity, &layer.UnitCost,
+			&layer.Remaining, &layer.RemainingValue, &layer.CreatedAt, &layer.UpdatedAt)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		layers = append(layers, layer)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return layers, nil
+}
+
+func (r *costRepository) SaveLayer(ctx context.Context, layer *domain.CostLayer) (*domain.CostLayer, error) {
+	_, err := r.db.Exec(ctx, saveLayer, layer.ID, layer.ItemID, layer.MovementID, layer.Quantity, layer.UnitCost,
+		layer.Remaining, layer.RemainingValue, layer.CreatedAt, layer.UpdatedAt)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return layer, nil
+}
+
+func (r *costRepository) EditLayer(ctx context.Context, layer *domain.CostLayer) (*domain.CostLayer, error) {
+	_, err := r.db.Exec(ctx, updateLayer, layer.ID, layer.Remaining, layer.RemainingValue, layer.UpdatedAt)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return layer, nil
+}
+
+func (r *costRepository) SaveConsumption(ctx context.Context,
+	consumption *domain.CostConsumption) (*domain.CostConsumption, error) {
+	_, err := r.db.Exec(ctx, saveConsumption, consumption.ID, consumption.ItemID, consumption.LayerID,
+		consumption.MovementID, consumption.Quantity, consumption.Cost, consumption.CreatedAt)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return consumption, nil
+}
+
+func (r *costRepository) Valuation(ctx context.Context, at time.Time) ([]domain.ItemValuation, error) {
+	rows, err := r.db.Query(ctx, valuation, at, at)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	defer rows.Close()
+
+	var valuations []domain.ItemValuation
+	var current domain.ItemValuation
+	for rows.Next() {
+		var itemID string
+		var quantity int
+		var amount decimal.Decimal
+		var layer bool
+		if err = rows.Scan(&itemID, &quantity, &amount, &layer); err != nil {
+			return nil, errors.NewInternalServerError(err.Error())
+		}
+
+		if itemID != current.ItemID {
+			if current.Quantity != 0 {
+				valuations = append(valuations, current)
+			}
+			current = domain.ItemValuation{ItemID: itemID}
+		}
+		// a layer brings in its units at their unit cost, a consumption takes its units out at its cost
+		if layer {
+			current.Quantity += quantity
+			current.Value = current.Value.Add(amount.Mul(decimal.NewFromInt(int64(quantity))))
+		} else {
+			current.Quantity -= quantity
+			current.Value = current.Value.Sub(amount)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	if current.Quantity != 0 {
+		valuations = append(valuations, current)
+	}
+
+	return valuations, nil
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"github.com/shopspring/decimal"
+	"sort"
+	"time"
+)
+
+type memoryCostRepository struct {
+	store *memory.Store
+}
+
+// NewMemoryCostRepository keeps cost layers and what was consumed from them in store instead of a database
+func NewMemoryCostRepository(store *memory.Store) domain.CostRepository {
+	return &memoryCostRepository{store: store}
+}
+
+func (r *memoryCostRepository) GetLayers(ctx context.Context, itemID string, count int,
+	offset int) ([]domain.CostLayer, error) {
+	defer r.store.Read(ctx)()
+
+	layers := r.layers(itemID, false)
+	for left, right := 0, len(layers)-1; left < right; left, right = left+1, right-1 {
+		layers[left], layers[right] = layers[right], layers[left]
+	}
+
+	start, end := memory.Page(len(layers), count, offset)
+	return layers[start:end], nil
+}
+
+// GetOpenLayersForUpdate needs no lock of its own, transactions hold the whole store
+func (r *memoryCostRepository) GetOpenLayersForUpdate(ctx context.Context,
+	itemID string) ([]domain.CostLayer, error) {
+	defer r.store.Read(ctx)()
+
+	return r.layers(itemID, true), nil
+}
+
+// layers returns the layers of an item oldest first. The store must be locked
+func (r *memoryCostRepository) layers(itemID string, open bool) []domain.CostLayer {
+	var layers []domain.CostLayer
+	for _, layer := range r.store.CostLayers {
+		if layer.ItemID == itemID && (!open || layer.Remaining > 0) {
+			layers = append(layers, layer)
+		}
+	}
+	sort.Slice(layers, func(a, b int) bool {
+		if !layers[a].CreatedAt.Equal(layers[b].CreatedAt) {
+			return layers[a].CreatedAt.Before(layers[b].CreatedAt)
+		}
+		return layers[a].ID < layers[b].ID
+	})
+	return layers
+}
+
+func (r *memoryCostRepository) SaveLayer(ctx context.Context, layer *domain.CostLayer) (*domain.CostLayer, error) {
+	defer r.store.Write(ctx)()
+
+	if _, ok := r.store.CostLayers[layer.ID]; ok {
+		return nil, errors.NewConflictError("cost layer already exists")
+	}
+	r.store.CostLayers[layer.ID] = *layer
+	return layer, nil
+}
+
+func (r *memoryCostRepository) EditLayer(ctx context.Context, layer *domain.CostLayer) (*domain.CostLayer, error) {
+	defer r.store.Write(ctx)()
+
+	existing, ok := r.store.CostLayers[layer.ID]
+	if !ok {
+		return layer, nil
+	}
+	existing.Remaining = layer.Remaining
+	existing.RemainingValue = layer.RemainingValue
+	existing.UpdatedAt = layer.UpdatedAt
+	r.store.CostLayers[layer.ID] = existing
+	return layer, nil
+}
+
+func (r *memoryCostRepository) SaveConsumption(ctx context.Context,
+	consumption *domain.CostConsumption) (*domain.CostConsumption, error) {
+	defer r.store.Write(ctx)()
+
+	r.store.CostConsumptions = append(r.store.CostConsumptions, *consumption)
+	return consumption, nil
+}
+
+func (r *memoryCostRepository) Valuation(ctx context.Context, at time.Time) ([]domain.ItemValuation, error) {
+	defer r.store.Read(ctx)()
+
+	sums := map[string]*domain.ItemValuation{}
+	sum := func(itemID string) *domain.ItemValuation {
+		if _, ok := sums[itemID]; !ok {
+			sums[itemID] = &domain.ItemValuation{ItemID: itemID}
+		}
+		return sums[itemID]
+	}
+	for _, layer := range r.store.CostLayers {
+		if !layer.CreatedAt.After(at) {
+			valuation := sum(layer.ItemID)
+			valuation.Quantity += layer.Quantity
+			valuation.Value = valuation.Value.Add(layer.UnitCost.Mul(decimal.NewFromInt(int64(layer.Quantity))))
+		}
+	}
+	for _, consumption := range r.store.CostConsumptions {
+		if !consumption.CreatedAt.After(at) {
+			valuation := sum(consumption.ItemID)
+			valuation.Quantity -= consumption.Quantity
+			valuation.Value = valuation.Value.Sub(consumption.Cost)
+		}
+	}
+
+	var valuations []domain.ItemValuation
+	for _, valuation := range sums {
+		if valuation.Quantity != 0 {
+			valuations = append(valuations, *valuation)
+		}
+	}
+	sort.Slice(valuations, func(a, b int) bool {
+		return valuations[a].ItemID < valuations[b].ItemID
+	})
+	return valuations, nil
+}
@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/utils/errors"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type valuationUseCase struct {
+	costRepository domain.CostRepository
+	timeout        time.Duration
+}
+
+// NewValuationUseCase values stock from the cost layers in costRepository. Layers are only ever created and consumed
+// by the stock movements of their item
+func NewValuationUseCase(costRepository domain.CostRepository, timeout time.Duration) domain.ValuationUseCase {
+	return &valuationUseCase{costRepository: costRepository, timeout: timeout}
+}
+
+func (v *valuationUseCase) Valuation(ctx context.Context, at time.Time) (*domain.Valuation, error) {
+	c, cancel := context.WithTimeout(ctx, v.timeout)
+	defer cancel()
+
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	items, err := v.costRepository.Valuation(c, at)
+	if err != nil {
+		return nil, err
+	}
+
+	valuation := domain.Valuation{At: at, Items: []domain.ItemValuation{}}
+	for _, item := range items {
+		// SQLite sums values as floating point, which can leave digits past those of any cost
+		item.Value = item.Value.Round(domain.CostPlaces)
+		item.UnitCost = item.Value.DivRound(decimal.NewFromInt(int64(item.Quantity)), domain.CostPlaces)
+		valuation.Quantity += item.Quantity
+		valuation.Value = valuation.Value.Add(item.Value)
+		valuation.Items = append(valuation.Items, item)
+	}
+	return &valuation, nil
+}
+
+func (v *valuationUseCase) GetLayers(ctx context.Context, itemID string, count int,
+	offset int) ([]domain.CostLayer, error) {
+	c, cancel := context.WithTimeout(ctx, v.timeout)
+	defer cancel()
+
+	layers, err := v.costRepository.GetLayers(c, itemID, count, offset)
+	if err != nil {
+		return nil, err
+	}
+	if len(layers) == 0 {
+		return nil, errors.NewNotFoundError("no cost layers found for the item")
+	}
+
+	return layers, nil
+}
@@ -0,0 +1,140 @@
+package usecase
+
+import (
+	"context"
+	"github.com/nuzurie/shopify/db/memory"
+	"github.com/nuzurie/shopify/domain"
+	"github.com/nuzurie/shopify/inventory/repository"
+	usecase2 "github.com/nuzurie/shopify/inventory/usecase"
+	repository2 "github.com/nuzurie/shopify/item/repository"
+	repository3 "github.com/nuzurie/shopify/location/repository"
+	repository4 "github.com/nuzurie/shopify/lot/repository"
+	repository5 "github.com/nuzurie/shopify/reservation/repository"
+	repository6 "github.com/nuzurie/shopify/serial/repository"
+	repository7 "github.com/nuzurie/shopify/valuation/repository"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func TestCostingMethods(t *testing.T) {
+	// 10 units are received at each of 1, 2 and 4, then 10 and 5 of them sold
+	cases := []struct {
+		method   domain.CostingMethod
+		costs    []string
+		value    string
+		unitCost string
+	}{
+		{domain.CostingFIFO, []string{"10", "10"}, "50", "3.333333"},
+		{domain.CostingLIFO, []string{"40", "10"}, "20", "1.333333"},
+		{domain.CostingAverage, []string{"23.333333", "11.666667"}, "35", "2.333333"},
+	}
+	for _, c := range cases {
+		store := memory.NewStore()
+		inventoryUseCase := newInventoryUseCase(t, store, c.method)
+		valuationUseCase := NewValuationUseCase(repository7.NewMemoryCostRepository(store), time.Second)
+		ctx := context.Background()
+
+		var inventory *domain.InventoryItem
+		var err error
+		for _, cost := range []int64{1, 2, 4} {
+			unitCost := decimal.NewFromInt(cost)
+			inventory, err = inventoryUseCase.MoveStock(ctx, "hat", domain.DefaultLocationID,
+				domain.StockAdjustment{Delta: 10, Reason: domain.ReasonReceived, UnitCost: &unitCost})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, sold := range []int{10, 5} {
+			if _, err = inventoryUseCase.AdjustQuantity(ctx, inventory.ID,
+				domain.StockAdjustment{Delta: -sold, Reason: domain.ReasonSold}, 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		movements, err := inventoryUseCase.GetMovements(ctx, inventory.ID, 10, 0, domain.MovementFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sales := movements[3:]
+		for index, cost := range c.costs {
+			if sales[index].Cost == nil || !sales[index].Cost.Equal(decimal.RequireFromString(cost)) {
+				t.Errorf("%s: sale %d cost %v, want %s", c.method, index+1, sales[index].Cost, cost)
+			}
+		}
+
+		valuation, err := valuationUseCase.Valuation(ctx, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if valuation.Quantity != 15 || !valuation.Value.Equal(decimal.RequireFromString(c.value)) ||
+			len(valuation.Items) != 1 || !valuation.Items[0].UnitCost.Equal(decimal.RequireFromString(c.unitCost)) {
+			t.Errorf("%s: valuation %+v, want 15 units worth %s at %s each", c.method, valuation, c.value,
+				c.unitCost)
+		}
+	}
+}
+
+func TestCostWithoutUnitCost(t *testing.T) {
+	received := func(cost int64) domain.StockAdjustment {
+		unitCost := decimal.NewFromInt(cost)
+		return domain.StockAdjustment{Delta: 5, Reason: domain.ReasonReceived, UnitCost: &unitCost}
+	}
+	soldOut := domain.StockAdjustment{Delta: -5, Reason: domain.ReasonSold}
+
+	cases := []struct {
+		name   string
+		before []domain.StockAdjustment
+		cost   string
+	}{
+		{"never received", nil, "0"},
+		{"at the average of the units on hand", []domain.StockAdjustment{received(1), received(2)}, "7.5"},
+		{"at the latest cost once none are on hand", []domain.StockAdjustment{received(3), soldOut}, "15"},
+	}
+	for _, c := range cases {
+		store := memory.NewStore()
+		inventoryUseCase := newInventoryUseCase(t, store, domain.CostingFIFO)
+		ctx := context.Background()
+
+		for _, adjustment := range c.before {
+			if _, err := inventoryUseCase.MoveStock(ctx, "hat", domain.DefaultLocationID, adjustment); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		inventory, err := inventoryUseCase.MoveStock(ctx, "hat", domain.DefaultLocationID,
+			domain.StockAdjustment{Delta: 5, Reason: domain.ReasonCorrection})
+		if err != nil {
+			t.Fatal(err)
+		}
+		movements, err := inventoryUseCase.GetMovements(ctx, inventory.ID, 10, 0, domain.MovementFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		corrected := movements[len(movements)-1]
+		if corrected.Cost == nil || !corrected.Cost.Equal(decimal.RequireFromString(c.cost)) {
+			t.Errorf("%s: costed at %v, want %s", c.name, corrected.Cost, c.cost)
+		}
+	}
+}
+
+// ignoreStockChanges is a StockWatcher for tests that don't evaluate reorder points
+type ignoreStockChanges struct{}
+
+func (ignoreStockChanges) StockChanged(string) {}
+
+// newInventoryUseCase works on a store holding a hat costed by method, none of it in stock
+func newInventoryUseCase(t *testing.T, store *memory.Store, method domain.CostingMethod) domain.InventoryUseCase {
+	itemRepository := repository2.NewMemoryItemRepository(store)
+	if _, err := itemRepository.Save(context.Background(), &domain.Item{ID: "hat", Name: "hat",
+		BaseUnit: domain.DefaultBaseUnit, Currency: domain.DefaultCurrency, CostingMethod: method,
+		Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	return usecase2.NewInventoryUseCase(itemRepository, repository.NewMemoryInventoryRepository(store),
+		repository.NewMemoryMovementRepository(store), repository5.NewMemoryReservationRepository(store),
+		repository3.NewMemoryLocationRepository(store), repository4.NewMemoryLotRepository(store),
+		repository6.NewMemorySerialRepository(store), repository7.NewMemoryCostRepository(store),
+		ignoreStockChanges{}, store, time.Second)
+}